@@ -0,0 +1,861 @@
+package api
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ActivityPub federation exposes public communities as Group actors and public prayer requests as Note objects so that other
+// Pregxas instances, or any ActivityPub server, can follow a community. Remote servers send a Pray activity, an extension
+// in the pregxas namespace, to count a prayer made by one of their users. Federation is off unless PREGXAS_FEDERATION_ENABLED
+// is set
+
+// ActivityPubFollower is a remote actor following a community
+type ActivityPubFollower struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	ActorID     string `json:"actorId" db:"actorId"`
+	// Inbox is where activities are delivered; it is the actor's shared inbox when the server has one
+	Inbox            string `json:"inbox" db:"inbox"`
+	FollowActivityID string `json:"followActivityId" db:"followActivityId"`
+	Created          string `json:"created" db:"created"`
+}
+
+// ActivityPubRemoteActor is a cached copy of a remote actor, used to verify the signatures on its activities
+type ActivityPubRemoteActor struct {
+	ActorID     string `json:"actorId" db:"actorId"`
+	Inbox       string `json:"inbox" db:"inbox"`
+	SharedInbox string `json:"sharedInbox" db:"sharedInbox"`
+	KeyID       string `json:"keyId" db:"keyId"`
+	PublicKey   string `json:"publicKey" db:"publicKey"`
+	Fetched     string `json:"fetched" db:"fetched"`
+}
+
+// ActivityPubDelivery is an activity sent, or waiting to be sent, to a remote inbox
+type ActivityPubDelivery struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	Inbox       string `json:"inbox" db:"inbox"`
+	Activity    string `json:"activity" db:"activity"`
+	Status      string `json:"status" db:"status"`
+	Attempts    int64  `json:"attempts" db:"attempts"`
+	LastError   string `json:"lastError" db:"lastError"`
+	NextAttempt string `json:"nextAttempt" db:"nextAttempt"`
+	Created     string `json:"created" db:"created"`
+	Delivered   string `json:"delivered" db:"delivered"`
+}
+
+type activityPubKey struct {
+	CommunityID int64  `db:"communityId"`
+	PublicKey   string `db:"publicKey"`
+	PrivateKey  string `db:"privateKey"`
+	Created     string `db:"created"`
+}
+
+const (
+	// ActivityPubContentType is the media type for ActivityPub documents
+	ActivityPubContentType = "application/activity+json"
+	// ActivityPubMaxAttempts is how many times a delivery is tried before it is marked failed
+	ActivityPubMaxAttempts = 8
+	// ActivityPubSignatureMaxSkew is how far the Date on a signed request can be from now
+	ActivityPubSignatureMaxSkew = time.Hour
+	// ActivityPubActivityTypePray is the extension activity for a prayer made by a remote user
+	ActivityPubActivityTypePray = "Pray"
+
+	// ActivityPubDeliveryStatusPending is waiting to be sent or retried
+	ActivityPubDeliveryStatusPending = "pending"
+	// ActivityPubDeliveryStatusDelivered was accepted by the remote inbox
+	ActivityPubDeliveryStatusDelivered = "delivered"
+	// ActivityPubDeliveryStatusFailed ran out of attempts
+	ActivityPubDeliveryStatusFailed = "failed"
+
+	activityStreamsContext       = "https://www.w3.org/ns/activitystreams"
+	activityStreamsPublic        = "https://www.w3.org/ns/activitystreams#Public"
+	activityPubSecurityContext   = "https://w3id.org/security/v1"
+	activityPubPregxasNamespace  = "https://pregxas.com/ns#"
+	activityPubRemoteActorMaxAge = 24 * time.Hour
+	// activityPubActorFetchInterval is how long to wait before fetching the same remote actor again, whether the last fetch
+	// failed or was a refresh for a key that didn't verify
+	activityPubActorFetchInterval = time.Minute
+	activityPubMaxBodyLength      = 1 << 20
+	activityPubLastErrorMaxLen    = 1024
+)
+
+var (
+	errActivityPubSignature     = errors.New("the request signature could not be verified")
+	errActivityPubUnknownObject = errors.New("the object could not be found")
+	errActivityPubActorMismatch = errors.New("the activity's actor does not match the signature")
+)
+
+var activityPubClient = newOutboundHTTPClient(10 * time.Second)
+
+// activityPubActorFetches remembers when each remote actor was last fetched. Actors are fetched from the keyId of unsigned,
+// unverified inbox requests, so without this anyone could make the server fetch a URL as often as they liked
+var activityPubActorFetches = struct {
+	sync.Mutex
+	fetched map[string]time.Time
+}{fetched: map[string]time.Time{}}
+
+// isValidFederationURL checks remote URLs before they are fetched or delivered to; tests replace it to reach a local server
+var isValidFederationURL = IsValidWebhookURL
+
+// activityPubContext is the JSON-LD context on every document, including the pregxas extensions
+var activityPubContext = []interface{}{
+	activityStreamsContext,
+	activityPubSecurityContext,
+	map[string]string{
+		"pregxas":       activityPubPregxasNamespace,
+		"Pray":          "pregxas:Pray",
+		"prayerCount":   "pregxas:prayerCount",
+		"requestStatus": "pregxas:requestStatus",
+	},
+}
+
+// GetActivityPubDomain gets the host used in WebFinger addresses, such as the example.com in prayer-group@example.com
+func GetActivityPubDomain() string {
+	parsed, err := url.Parse(Config.RootAPIURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// GetActivityPubCommunityActorID gets the id of a community's actor
+func GetActivityPubCommunityActorID(communityID int64) string {
+	return fmt.Sprintf("%s/ap/communities/%d", activityPubBaseURL(), communityID)
+}
+
+// GetActivityPubRequestID gets the id of a prayer request's Note
+func GetActivityPubRequestID(requestID int64) string {
+	return fmt.Sprintf("%s/ap/requests/%d", activityPubBaseURL(), requestID)
+}
+
+// IsCommunityFederated checks if a community is exposed over ActivityPub; only public communities that aren't archived are
+func IsCommunityFederated(community *Community) bool {
+	return Config.FederationEnabled && community.Privacy == "public" && !community.IsArchived()
+}
+
+// GetFederatedCommunityByShortCode gets a federated community for a WebFinger lookup
+func GetFederatedCommunityByShortCode(shortCode string) (*Community, error) {
+	community, err := GetCommunityByShortCode(shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if !IsCommunityFederated(community) {
+		return nil, errActivityPubUnknownObject
+	}
+	return community, nil
+}
+
+// BuildActivityPubCommunityActor builds the Group actor for a community, generating its signing key the first time
+func BuildActivityPubCommunityActor(community *Community) (map[string]interface{}, error) {
+	key, err := getActivityPubKey(community.ID)
+	if err != nil {
+		return nil, err
+	}
+	id := GetActivityPubCommunityActorID(community.ID)
+	actor := map[string]interface{}{
+		"@context":          activityPubContext,
+		"id":                id,
+		"type":              "Group",
+		"preferredUsername": community.ShortCode,
+		"name":              community.Name,
+		"summary":           html.EscapeString(community.Description),
+		"inbox":             id + "/inbox",
+		"outbox":            id + "/outbox",
+		"followers":         id + "/followers",
+		"published":         community.Created,
+		"publicKey": map[string]string{
+			"id":           id + "#main-key",
+			"owner":        id,
+			"publicKeyPem": key.PublicKey,
+		},
+	}
+	if community.LogoURL != "" {
+		actor["icon"] = map[string]string{
+			"type": "Image",
+			"url":  community.LogoURL,
+		}
+	}
+	return actor, nil
+}
+
+// BuildActivityPubNote builds the Note for a public prayer request. Notes are attributed to the community sharing them rather
+// than the member who wrote them
+func BuildActivityPubNote(request *PrayerRequest, communityID int64) map[string]interface{} {
+	actorID := GetActivityPubCommunityActorID(communityID)
+	return map[string]interface{}{
+		"@context":      activityPubContext,
+		"id":            GetActivityPubRequestID(request.ID),
+		"type":          "Note",
+		"attributedTo":  actorID,
+		"name":          request.Title,
+		"content":       fmt.Sprintf("<p>%s</p>", html.EscapeString(request.Body)),
+		"published":     request.Created,
+		"to":            []string{activityStreamsPublic},
+		"cc":            []string{actorID + "/followers"},
+		"prayerCount":   request.PrayerCount,
+		"requestStatus": request.Status,
+	}
+}
+
+// BuildActivityPubCreate wraps a request's Note in the Create activity a community sends to its followers
+func BuildActivityPubCreate(request *PrayerRequest, communityID int64) map[string]interface{} {
+	actorID := GetActivityPubCommunityActorID(communityID)
+	note := BuildActivityPubNote(request, communityID)
+	delete(note, "@context")
+	return map[string]interface{}{
+		"@context":  activityPubContext,
+		"id":        fmt.Sprintf("%s/activities/create/%d", actorID, request.ID),
+		"type":      "Create",
+		"actor":     actorID,
+		"published": request.Created,
+		"to":        []string{activityStreamsPublic},
+		"cc":        []string{actorID + "/followers"},
+		"object":    note,
+	}
+}
+
+// GetFederatedPrayerRequest gets a request that can be shown over ActivityPub along with the federated community it is
+// attributed to. The request must be public and approved in at least one federated community
+func GetFederatedPrayerRequest(requestID int64) (*PrayerRequest, int64, error) {
+	if !Config.FederationEnabled {
+		return nil, 0, errActivityPubUnknownObject
+	}
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || request.Privacy != "public" {
+		return nil, 0, errActivityPubUnknownObject
+	}
+	communityID := int64(0)
+	err = Config.DbConn.Get(&communityID, `SELECT c.id FROM Communities c, PrayerRequestCommunityLinks prcl
+		WHERE prcl.prayerRequestId = ? AND prcl.communityId = c.id AND prcl.status = ? AND c.privacy = 'public'
+		AND c.archived = '1970-01-01 00:00:00' ORDER BY prcl.added LIMIT 1`, requestID, PrayerRequestCommunityLinkStatusApproved)
+	if err != nil {
+		return nil, 0, errActivityPubUnknownObject
+	}
+	return request, communityID, nil
+}
+
+// GetActivityPubOutbox gets a page of the Create activities for the public requests shared in a community, newest first
+func GetActivityPubOutbox(communityID int64, count, offset int) ([]map[string]interface{}, int64, error) {
+	total := int64(0)
+	err := Config.DbConn.Get(&total, `SELECT COUNT(*) FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = ? AND pr.privacy = 'public'`,
+		communityID, PrayerRequestCommunityLinkStatusApproved)
+	if err != nil {
+		return nil, 0, err
+	}
+	requests := []PrayerRequest{}
+	err = Config.DbConn.Select(&requests, `SELECT pr.*, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount
+		FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = ? AND pr.privacy = 'public'
+		ORDER BY pr.created DESC LIMIT ?,?`, communityID, PrayerRequestCommunityLinkStatusApproved, offset, count)
+	if err != nil {
+		return nil, 0, err
+	}
+	items := []map[string]interface{}{}
+	for i := range requests {
+		requests[i].processForAPI()
+		create := BuildActivityPubCreate(&requests[i], communityID)
+		delete(create, "@context")
+		items = append(items, create)
+	}
+	return items, total, nil
+}
+
+// GetActivityPubFollowers gets the remote followers of a community
+func GetActivityPubFollowers(communityID int64) ([]ActivityPubFollower, error) {
+	followers := []ActivityPubFollower{}
+	err := Config.DbConn.Select(&followers, "SELECT * FROM ActivityPubFollowers WHERE communityId = ? ORDER BY id", communityID)
+	return followers, err
+}
+
+// HandleActivityPubActivity processes an activity a verified remote actor sent to a community's inbox. Activities that
+// aren't supported are ignored
+func HandleActivityPubActivity(community *Community, actor *ActivityPubRemoteActor, activity map[string]interface{}) error {
+	if activityPubObjectID(activity["actor"]) != actor.ActorID {
+		return errActivityPubActorMismatch
+	}
+	activityID := activityPubObjectID(activity["id"])
+	switch activity["type"] {
+	case "Follow":
+		if activityPubObjectID(activity["object"]) != GetActivityPubCommunityActorID(community.ID) {
+			return errActivityPubUnknownObject
+		}
+		return addActivityPubFollower(community, actor, activityID, activity)
+	case ActivityPubActivityTypePray:
+		requestID, ok := parseActivityPubRequestID(activityPubObjectID(activity["object"]))
+		if !ok {
+			return errActivityPubUnknownObject
+		}
+		return addActivityPubPrayer(community.ID, requestID, actor.ActorID, activityID)
+	case "Undo":
+		undone, _ := activity["object"].(map[string]interface{})
+		undoneID := activityPubObjectID(activity["object"])
+		if undone != nil && activityPubObjectID(undone["actor"]) != "" && activityPubObjectID(undone["actor"]) != actor.ActorID {
+			return errActivityPubActorMismatch
+		}
+		_, err := Config.DbConn.Exec("DELETE FROM ActivityPubFollowers WHERE communityId = ? AND actorId = ? AND (followActivityId = ? OR ?)",
+			community.ID, actor.ActorID, undoneID, undone != nil && undone["type"] == "Follow")
+		if err != nil {
+			return err
+		}
+		return removeActivityPubPrayer(actor.ActorID, undoneID)
+	}
+	return nil
+}
+
+// QueueActivityPubRequestCreated sends a request that was just shared in a community to the community's followers. Nothing is
+// sent unless the community is federated and the request is public
+func QueueActivityPubRequestCreated(requestID, communityID int64) error {
+	if !Config.FederationEnabled {
+		return nil
+	}
+	community, err := GetCommunityByID(communityID)
+	if err != nil || !IsCommunityFederated(community) {
+		return err
+	}
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || request.Privacy != "public" {
+		return err
+	}
+	inboxes := []string{}
+	err = Config.DbConn.Select(&inboxes, "SELECT DISTINCT inbox FROM ActivityPubFollowers WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	activity := BuildActivityPubCreate(request, communityID)
+	for i := range inboxes {
+		_, err = queueActivityPubDelivery(communityID, inboxes[i], activity)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverPendingActivityPubActivities sends any deliveries that are due, such as retries
+func DeliverPendingActivityPubActivities() error {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, `SELECT id FROM ActivityPubDeliveries WHERE status = ? AND nextAttempt <= NOW() ORDER BY id LIMIT 100`,
+		ActivityPubDeliveryStatusPending)
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		attemptActivityPubDelivery(ids[i])
+	}
+	return nil
+}
+
+// GetActivityPubDeliveries gets the deliveries for a community, newest first
+func GetActivityPubDeliveries(communityID int64, count, offset int) ([]ActivityPubDelivery, error) {
+	deliveries := []ActivityPubDelivery{}
+	err := Config.DbConn.Select(&deliveries, "SELECT * FROM ActivityPubDeliveries WHERE communityId = ? ORDER BY id DESC LIMIT ?,?",
+		communityID, offset, count)
+	return deliveries, err
+}
+
+// DeleteActivityPubDataForCommunity deletes a community's key, followers, and deliveries
+func DeleteActivityPubDataForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM ActivityPubKeys WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM ActivityPubFollowers WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM ActivityPubDeliveries WHERE communityId = ?", communityID)
+	return err
+}
+
+// DeleteActivityPubPrayersForRequest deletes the record of remote prayers for a request
+func DeleteActivityPubPrayersForRequest(requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM ActivityPubPrayers WHERE prayerRequestId = ?", requestID)
+	return err
+}
+
+// VerifyActivityPubRequest verifies the HTTP signature on an inbox request and returns the remote actor that signed it. If
+// the cached key doesn't verify, the actor is fetched again in case the key was rotated
+func VerifyActivityPubRequest(r *http.Request, body []byte) (*ActivityPubRemoteActor, error) {
+	params := parseActivityPubSignatureHeader(r.Header.Get("Signature"))
+	keyID := params["keyId"]
+	if keyID == "" {
+		return nil, errActivityPubSignature
+	}
+	actorID := strings.SplitN(keyID, "#", 2)[0]
+
+	actor, err := GetActivityPubRemoteActor(actorID, false)
+	if err != nil {
+		return nil, err
+	}
+	if actor.KeyID != keyID {
+		return nil, errActivityPubSignature
+	}
+	publicKey, err := parseActivityPubPublicKey(actor.PublicKey)
+	if err == nil {
+		err = VerifyActivityPubSignature(r, body, publicKey)
+	}
+	if err == nil {
+		return actor, nil
+	}
+	actor, err = GetActivityPubRemoteActor(actorID, true)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err = parseActivityPubPublicKey(actor.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	err = VerifyActivityPubSignature(r, body, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return actor, nil
+}
+
+// VerifyActivityPubSignature checks a request's Signature header against the public key. The signature must cover the
+// request target, host, and date, and the digest when there is a body; the date must be recent and the digest must match
+func VerifyActivityPubSignature(r *http.Request, body []byte, publicKey *rsa.PublicKey) error {
+	params := parseActivityPubSignatureHeader(r.Header.Get("Signature"))
+	if params["signature"] == "" || (params["algorithm"] != "" && params["algorithm"] != "rsa-sha256" && params["algorithm"] != "hs2019") {
+		return errActivityPubSignature
+	}
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	required := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		required = append(required, "digest")
+	}
+	for i := range required {
+		if !containsString(headers, required[i]) {
+			return errActivityPubSignature
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil || date.Before(time.Now().Add(-ActivityPubSignatureMaxSkew)) || date.After(time.Now().Add(ActivityPubSignatureMaxSkew)) {
+		return errActivityPubSignature
+	}
+	if len(body) > 0 && r.Header.Get("Digest") != activityPubDigest(body) {
+		return errActivityPubSignature
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return errActivityPubSignature
+	}
+	hashed := sha256.Sum256([]byte(activityPubSigningString(r, headers)))
+	if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature) != nil {
+		return errActivityPubSignature
+	}
+	return nil
+}
+
+// SignActivityPubRequest adds the Date, Digest, and Signature headers to an outgoing request
+func SignActivityPubRequest(req *http.Request, body []byte, keyID string, privateKey *rsa.PrivateKey) error {
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	headers := []string{"(request-target)", "host", "date"}
+	if len(body) > 0 {
+		req.Header.Set("Digest", activityPubDigest(body))
+		headers = append(headers, "digest")
+	}
+	hashed := sha256.Sum256([]byte(activityPubSigningString(req, headers)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+	return nil
+}
+
+// GetActivityPubRemoteActor gets a remote actor from the cache, fetching it if it isn't cached, is stale, or refresh is set
+func GetActivityPubRemoteActor(actorID string, refresh bool) (*ActivityPubRemoteActor, error) {
+	actor := &ActivityPubRemoteActor{}
+	if !refresh {
+		err := Config.DbConn.Get(actor, "SELECT * FROM ActivityPubRemoteActors WHERE actorId = ? AND fetched > ?",
+			actorID, time.Now().UTC().Add(-activityPubRemoteActorMaxAge).Format("2006-01-02 15:04:05"))
+		if err == nil {
+			return actor, nil
+		}
+	}
+
+	if !allowActivityPubActorFetch(actorID) {
+		return nil, errActivityPubSignature
+	}
+	document, err := fetchActivityPubDocument(actorID)
+	if err != nil {
+		return nil, err
+	}
+	if activityPubObjectID(document["id"]) != actorID {
+		return nil, errActivityPubSignature
+	}
+	key, _ := document["publicKey"].(map[string]interface{})
+	if key == nil || activityPubObjectID(key["owner"]) != actorID {
+		return nil, errActivityPubSignature
+	}
+	actor.ActorID = actorID
+	actor.Inbox = activityPubObjectID(document["inbox"])
+	actor.KeyID = activityPubObjectID(key["id"])
+	actor.PublicKey, _ = key["publicKeyPem"].(string)
+	if endpoints, ok := document["endpoints"].(map[string]interface{}); ok {
+		actor.SharedInbox = activityPubObjectID(endpoints["sharedInbox"])
+	}
+	if !isValidFederationURL(actor.Inbox) || (actor.SharedInbox != "" && !isValidFederationURL(actor.SharedInbox)) {
+		return nil, errActivityPubUnknownObject
+	}
+	actor.Fetched = time.Now().UTC().Format("2006-01-02 15:04:05")
+	_, err = Config.DbConn.NamedExec(`INSERT INTO ActivityPubRemoteActors (actorId, inbox, sharedInbox, keyId, publicKey, fetched)
+		VALUES (:actorId, :inbox, :sharedInbox, :keyId, :publicKey, :fetched)
+		ON DUPLICATE KEY UPDATE inbox = :inbox, sharedInbox = :sharedInbox, keyId = :keyId, publicKey = :publicKey, fetched = :fetched`, actor)
+	return actor, err
+}
+
+// allowActivityPubActorFetch checks if the actor can be fetched now, and records the fetch if so. Entries older than the
+// interval are dropped as new ones are added so the map doesn't grow without bound
+func allowActivityPubActorFetch(actorID string) bool {
+	activityPubActorFetches.Lock()
+	defer activityPubActorFetches.Unlock()
+	now := time.Now()
+	if last, ok := activityPubActorFetches.fetched[actorID]; ok && now.Sub(last) < activityPubActorFetchInterval {
+		return false
+	}
+	for id, last := range activityPubActorFetches.fetched {
+		if now.Sub(last) >= activityPubActorFetchInterval {
+			delete(activityPubActorFetches.fetched, id)
+		}
+	}
+	activityPubActorFetches.fetched[actorID] = now
+	return true
+}
+
+func addActivityPubFollower(community *Community, actor *ActivityPubRemoteActor, activityID string, follow map[string]interface{}) error {
+	inbox := actor.SharedInbox
+	if inbox == "" {
+		inbox = actor.Inbox
+	}
+	res, err := Config.DbConn.Exec(`INSERT INTO ActivityPubFollowers (communityId, actorId, inbox, followActivityId, created)
+		VALUES (?, ?, ?, ?, NOW()) ON DUPLICATE KEY UPDATE inbox = VALUES(inbox), followActivityId = VALUES(followActivityId)`,
+		community.ID, actor.ActorID, inbox, activityID)
+	if err != nil {
+		return err
+	}
+	followerID, _ := res.LastInsertId()
+
+	actorID := GetActivityPubCommunityActorID(community.ID)
+	delete(follow, "@context")
+	accept := map[string]interface{}{
+		"@context": activityPubContext,
+		"id":       fmt.Sprintf("%s/activities/accept/%d", actorID, followerID),
+		"type":     "Accept",
+		"actor":    actorID,
+		"object":   follow,
+	}
+	_, err = queueActivityPubDelivery(community.ID, actor.Inbox, accept)
+	return err
+}
+
+// addActivityPubPrayer counts a prayer made by a remote actor. Remote prayers are saved without a local user, and each actor
+// is held to the same timeout between prayers as local users; a repeated activity is only counted once
+func addActivityPubPrayer(communityID, requestID int64, actorID, activityID string) error {
+	found := int64(0)
+	err := Config.DbConn.Get(&found, `SELECT COUNT(*) FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl
+		WHERE pr.id = ? AND pr.privacy = 'public' AND prcl.prayerRequestId = pr.id AND prcl.communityId = ? AND prcl.status = ?`,
+		requestID, communityID, PrayerRequestCommunityLinkStatusApproved)
+	if err != nil || found == 0 {
+		return errActivityPubUnknownObject
+	}
+
+	now := time.Now()
+	recent := int64(0)
+	err = Config.DbConn.Get(&recent, `SELECT COUNT(*) FROM ActivityPubPrayers WHERE (activityId = ?)
+		OR (actorId = ? AND prayerRequestId = ? AND whenPrayed > ?)`, activityID, actorID, requestID,
+		now.Add(-time.Minute*PrayerTimeoutInMinutes).Format("2006-01-02 15:04:05"))
+	if err != nil || recent > 0 {
+		return err
+	}
+
+	when := now.Format("2006-01-02 15:04:05")
+	_, err = Config.DbConn.Exec("INSERT INTO ActivityPubPrayers (activityId, actorId, prayerRequestId, whenPrayed) VALUES (?, ?, ?, ?)",
+		activityID, actorID, requestID, when)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("INSERT INTO Prayers (userId, prayerRequestId, whenPrayed) VALUES (0, ?, ?)", requestID, when)
+	return err
+}
+
+// removeActivityPubPrayer takes back a remote prayer after an Undo
+func removeActivityPubPrayer(actorID, activityID string) error {
+	prayer := struct {
+		PrayerRequestID int64  `db:"prayerRequestId"`
+		WhenPrayed      string `db:"whenPrayed"`
+	}{}
+	err := Config.DbConn.Get(&prayer, "SELECT prayerRequestId, whenPrayed FROM ActivityPubPrayers WHERE activityId = ? AND actorId = ?",
+		activityID, actorID)
+	if err != nil {
+		// there was nothing to undo
+		return nil
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM Prayers WHERE userId = 0 AND prayerRequestId = ? AND whenPrayed = ? LIMIT 1",
+		prayer.PrayerRequestID, prayer.WhenPrayed)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM ActivityPubPrayers WHERE activityId = ?", activityID)
+	return err
+}
+
+func queueActivityPubDelivery(communityID int64, inbox string, activity interface{}) (int64, error) {
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return 0, err
+	}
+	res, err := Config.DbConn.Exec(`INSERT INTO ActivityPubDeliveries (communityId, inbox, activity, status, attempts, nextAttempt, created)
+		VALUES (?, ?, ?, ?, 0, NOW(), NOW())`, communityID, inbox, string(payload), ActivityPubDeliveryStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	go attemptActivityPubDelivery(id)
+	return id, nil
+}
+
+// attemptActivityPubDelivery sends a delivery once, claiming it the same way webhook deliveries are claimed
+func attemptActivityPubDelivery(deliveryID int64) error {
+	res, err := Config.DbConn.Exec(`UPDATE ActivityPubDeliveries SET nextAttempt = DATE_ADD(NOW(), INTERVAL 5 MINUTE)
+		WHERE id = ? AND status = ? AND nextAttempt <= NOW()`, deliveryID, ActivityPubDeliveryStatusPending)
+	if err != nil {
+		return err
+	}
+	if claimed, _ := res.RowsAffected(); claimed == 0 {
+		return nil
+	}
+	delivery := ActivityPubDelivery{}
+	err = Config.DbConn.Get(&delivery, "SELECT * FROM ActivityPubDeliveries WHERE id = ?", deliveryID)
+	if err != nil {
+		return err
+	}
+
+	sendErr := sendActivityPubDelivery(&delivery)
+	delivery.Attempts++
+	if sendErr == nil {
+		_, err = Config.DbConn.Exec("UPDATE ActivityPubDeliveries SET status = ?, attempts = ?, lastError = '', delivered = NOW() WHERE id = ?",
+			ActivityPubDeliveryStatusDelivered, delivery.Attempts, deliveryID)
+		return err
+	}
+	lastError := sendErr.Error()
+	if len(lastError) > activityPubLastErrorMaxLen {
+		lastError = lastError[:activityPubLastErrorMaxLen]
+	}
+	status := ActivityPubDeliveryStatusPending
+	if delivery.Attempts >= ActivityPubMaxAttempts {
+		status = ActivityPubDeliveryStatusFailed
+	}
+	delay := int64(WebhookRetryDelay(delivery.Attempts) / time.Minute)
+	_, err = Config.DbConn.Exec(`UPDATE ActivityPubDeliveries SET status = ?, attempts = ?, lastError = ?,
+		nextAttempt = DATE_ADD(NOW(), INTERVAL ? MINUTE) WHERE id = ?`, status, delivery.Attempts, lastError, delay, deliveryID)
+	return err
+}
+
+func sendActivityPubDelivery(delivery *ActivityPubDelivery) error {
+	if !isValidFederationURL(delivery.Inbox) {
+		return fmt.Errorf("%s is not a valid inbox", delivery.Inbox)
+	}
+	key, err := getActivityPubKey(delivery.CommunityID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := parseActivityPubPrivateKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+	body := []byte(delivery.Activity)
+	req, err := http.NewRequest(http.MethodPost, delivery.Inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ActivityPubContentType)
+	req.Header.Set("User-Agent", "Pregxas-ActivityPub")
+	err = SignActivityPubRequest(req, body, GetActivityPubCommunityActorID(delivery.CommunityID)+"#main-key", privateKey)
+	if err != nil {
+		return err
+	}
+	resp, err := activityPubClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, activityPubMaxBodyLength))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("inbox responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func fetchActivityPubDocument(documentURL string) (map[string]interface{}, error) {
+	if !isValidFederationURL(documentURL) {
+		return nil, errActivityPubUnknownObject
+	}
+	req, err := http.NewRequest(http.MethodGet, documentURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ActivityPubContentType)
+	req.Header.Set("User-Agent", "Pregxas-ActivityPub")
+	resp, err := activityPubClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responded with %d", documentURL, resp.StatusCode)
+	}
+	document := map[string]interface{}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, activityPubMaxBodyLength)).Decode(&document)
+	return document, err
+}
+
+// getActivityPubKey gets a community's signing key, generating one the first time it is needed
+func getActivityPubKey(communityID int64) (*activityPubKey, error) {
+	key := &activityPubKey{}
+	err := Config.DbConn.Get(key, "SELECT * FROM ActivityPubKeys WHERE communityId = ?", communityID)
+	if err == nil {
+		return key, nil
+	}
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+	// if two requests generate a key at once, the first one saved wins
+	_, err = Config.DbConn.Exec("INSERT IGNORE INTO ActivityPubKeys (communityId, publicKey, privateKey, created) VALUES (?, ?, ?, NOW())",
+		communityID, string(publicPEM), string(privatePEM))
+	if err != nil {
+		return nil, err
+	}
+	err = Config.DbConn.Get(key, "SELECT * FROM ActivityPubKeys WHERE communityId = ?", communityID)
+	return key, err
+}
+
+func parseActivityPubPrivateKey(input string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(input))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func parseActivityPubPublicKey(input string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(input))
+	if block == nil {
+		return nil, errActivityPubSignature
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	publicKey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errActivityPubSignature
+	}
+	return publicKey, nil
+}
+
+// parseActivityPubSignatureHeader splits a header such as keyId="a",headers="b c",signature="d" into its parameters
+func parseActivityPubSignatureHeader(input string) map[string]string {
+	params := map[string]string{}
+	for _, part := range strings.Split(input, ",") {
+		pieces := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(pieces) != 2 {
+			continue
+		}
+		params[pieces[0]] = strings.Trim(pieces[1], `"`)
+	}
+	return params
+}
+
+func activityPubSigningString(r *http.Request, headers []string) string {
+	lines := []string{}
+	for i := range headers {
+		switch headers[i] {
+		case "(request-target)":
+			lines = append(lines, fmt.Sprintf("(request-target): %s %s", strings.ToLower(r.Method), r.URL.RequestURI()))
+		case "host":
+			host := r.Host
+			if host == "" {
+				host = r.URL.Host
+			}
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", headers[i], r.Header.Get(headers[i])))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func activityPubDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// activityPubObjectID gets the id of a property that may be a bare id or an embedded object
+func activityPubObjectID(input interface{}) string {
+	switch v := input.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		id, _ := v["id"].(string)
+		return id
+	}
+	return ""
+}
+
+func parseActivityPubRequestID(input string) (int64, bool) {
+	prefix := activityPubBaseURL() + "/ap/requests/"
+	if !strings.HasPrefix(input, prefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(input, prefix), 10, 64)
+	return id, err == nil
+}
+
+func activityPubBaseURL() string {
+	return strings.TrimSuffix(Config.RootAPIURL, "/")
+}
+
+func containsString(haystack []string, needle string) bool {
+	for i := range haystack {
+		if haystack[i] == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// activityPubOutboxPageSize is how many activities are in each page of an outbox
+const activityPubOutboxPageSize = 20
+
+// WebFingerRoute resolves an address such as acct:prayer-group@example.com, where prayer-group is a community's short code,
+// to the community's actor
+func WebFingerRoute(w http.ResponseWriter, r *http.Request) {
+	resource := r.URL.Query().Get("resource")
+	community, ok := getWebFingerCommunity(resource)
+	if !ok {
+		SendError(w, http.StatusNotFound, "webfinger_not_found", "that resource could not be found", nil)
+		return
+	}
+	actorID := GetActivityPubCommunityActorID(community.ID)
+	response, _ := json.Marshal(map[string]interface{}{
+		"subject": fmt.Sprintf("acct:%s@%s", community.ShortCode, GetActivityPubDomain()),
+		"aliases": []string{actorID},
+		"links": []map[string]string{
+			{
+				"rel":  "self",
+				"type": ActivityPubContentType,
+				"href": actorID,
+			},
+		},
+	})
+	w.Header().Set("Content-Type", "application/jrd+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+	return
+}
+
+// GetActivityPubCommunityRoute gets a community's Group actor
+func GetActivityPubCommunityRoute(w http.ResponseWriter, r *http.Request) {
+	community, ok := getActivityPubCommunityFromRequest(w, r)
+	if !ok {
+		return
+	}
+	actor, err := BuildActivityPubCommunityActor(community)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "activitypub_error", "could not build the actor", err)
+		return
+	}
+	sendActivityPub(w, http.StatusOK, actor)
+	return
+}
+
+// GetActivityPubOutboxRoute gets a community's outbox. Without a page it is the collection summary; with one it is a page
+// of Create activities, newest first
+func GetActivityPubOutboxRoute(w http.ResponseWriter, r *http.Request) {
+	community, ok := getActivityPubCommunityFromRequest(w, r)
+	if !ok {
+		return
+	}
+	outboxID := GetActivityPubCommunityActorID(community.ID) + "/outbox"
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	count, offset := 0, 0
+	if page > 0 {
+		count, offset = activityPubOutboxPageSize, (page-1)*activityPubOutboxPageSize
+	}
+	items, total, err := GetActivityPubOutbox(community.ID, count, offset)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "activitypub_error", "could not get the outbox", err)
+		return
+	}
+
+	if page < 1 {
+		sendActivityPub(w, http.StatusOK, map[string]interface{}{
+			"@context":   activityPubContext,
+			"id":         outboxID,
+			"type":       "OrderedCollection",
+			"totalItems": total,
+			"first":      outboxID + "?page=1",
+		})
+		return
+	}
+	collectionPage := map[string]interface{}{
+		"@context":     activityPubContext,
+		"id":           fmt.Sprintf("%s?page=%d", outboxID, page),
+		"type":         "OrderedCollectionPage",
+		"partOf":       outboxID,
+		"totalItems":   total,
+		"orderedItems": items,
+	}
+	if int64(page*activityPubOutboxPageSize) < total {
+		collectionPage["next"] = fmt.Sprintf("%s?page=%d", outboxID, page+1)
+	}
+	if page > 1 {
+		collectionPage["prev"] = fmt.Sprintf("%s?page=%d", outboxID, page-1)
+	}
+	sendActivityPub(w, http.StatusOK, collectionPage)
+	return
+}
+
+// GetActivityPubFollowersRoute gets how many remote actors follow a community. The followers themselves aren't listed
+func GetActivityPubFollowersRoute(w http.ResponseWriter, r *http.Request) {
+	community, ok := getActivityPubCommunityFromRequest(w, r)
+	if !ok {
+		return
+	}
+	followers, err := GetActivityPubFollowers(community.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "activitypub_error", "could not get the followers", err)
+		return
+	}
+	sendActivityPub(w, http.StatusOK, map[string]interface{}{
+		"@context":   activityPubContext,
+		"id":         GetActivityPubCommunityActorID(community.ID) + "/followers",
+		"type":       "OrderedCollection",
+		"totalItems": len(followers),
+	})
+	return
+}
+
+// PostActivityPubInboxRoute receives an activity for a community from a remote server. The request must carry a valid HTTP
+// signature from the activity's actor
+func PostActivityPubInboxRoute(w http.ResponseWriter, r *http.Request) {
+	community, ok := getActivityPubCommunityFromRequest(w, r)
+	if !ok {
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, activityPubMaxBodyLength))
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "activitypub_invalid_activity", "could not read the activity", nil)
+		return
+	}
+	activity := map[string]interface{}{}
+	err = json.Unmarshal(body, &activity)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "activitypub_invalid_activity", "the activity is not valid JSON", nil)
+		return
+	}
+
+	actor, err := VerifyActivityPubRequest(r, body)
+	if err != nil {
+		SendError(w, http.StatusUnauthorized, "activitypub_invalid_signature", "the request signature could not be verified", nil)
+		return
+	}
+
+	err = HandleActivityPubActivity(community, actor, activity)
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusAccepted)
+	case errActivityPubActorMismatch:
+		SendError(w, http.StatusForbidden, "activitypub_actor_mismatch", err.Error(), nil)
+	case errActivityPubUnknownObject:
+		SendError(w, http.StatusNotFound, "activitypub_unknown_object", err.Error(), nil)
+	default:
+		SendError(w, http.StatusInternalServerError, "activitypub_error", "could not process the activity", err)
+	}
+	return
+}
+
+// GetActivityPubRequestRoute gets the Note for a public request shared in a federated community
+func GetActivityPubRequestRoute(w http.ResponseWriter, r *http.Request) {
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if requestIDErr != nil {
+		SendError(w, http.StatusNotFound, "activitypub_not_found", "that request could not be found", nil)
+		return
+	}
+	request, communityID, err := GetFederatedPrayerRequest(requestID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "activitypub_not_found", "that request could not be found", nil)
+		return
+	}
+	sendActivityPub(w, http.StatusOK, BuildActivityPubNote(request, communityID))
+	return
+}
+
+// getActivityPubCommunityFromRequest loads the federated community in the url, sending a 404 if there isn't one
+func getActivityPubCommunityFromRequest(w http.ResponseWriter, r *http.Request) (*Community, bool) {
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil || !Config.FederationEnabled {
+		SendError(w, http.StatusNotFound, "activitypub_not_found", "that community could not be found", nil)
+		return nil, false
+	}
+	community, err := GetCommunityByID(communityID)
+	if err != nil || !IsCommunityFederated(community) {
+		SendError(w, http.StatusNotFound, "activitypub_not_found", "that community could not be found", nil)
+		return nil, false
+	}
+	return community, true
+}
+
+// getWebFingerCommunity finds the community for a WebFinger resource, which is either an acct address on this domain or
+// the actor's id
+func getWebFingerCommunity(resource string) (*Community, bool) {
+	if !Config.FederationEnabled {
+		return nil, false
+	}
+	if strings.HasPrefix(resource, "acct:") {
+		parts := strings.SplitN(strings.TrimPrefix(resource, "acct:"), "@", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[1], GetActivityPubDomain()) {
+			return nil, false
+		}
+		community, err := GetFederatedCommunityByShortCode(parts[0])
+		return community, err == nil
+	}
+	prefix := activityPubBaseURL() + "/ap/communities/"
+	if !strings.HasPrefix(resource, prefix) {
+		return nil, false
+	}
+	communityID, err := strconv.ParseInt(strings.TrimPrefix(resource, prefix), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	community, err := GetCommunityByID(communityID)
+	if err != nil || !IsCommunityFederated(community) {
+		return nil, false
+	}
+	return community, true
+}
+
+// sendActivityPub sends an ActivityPub document, which is not wrapped in the usual data envelope
+func sendActivityPub(w http.ResponseWriter, code int, document interface{}) {
+	response, _ := json.Marshal(document)
+	w.Header().Set("Content-Type", ActivityPubContentType)
+	w.WriteHeader(code)
+	w.Write(response)
+}
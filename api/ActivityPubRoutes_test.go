@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	federationEnabled := Config.FederationEnabled
+	Config.FederationEnabled = false
+	defer func() { Config.FederationEnabled = federationEnabled }()
+
+	member := User{}
+	err := CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      fmt.Sprintf("AP Routes %d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("ap_%d", rand.Int63n(999999999)),
+		Privacy:   "public",
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	closed := Community{
+		Name:      fmt.Sprintf("AP Private %d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("app_%d", rand.Int63n(999999999)),
+		Privacy:   "private",
+	}
+	err = CreateCommunity(&closed)
+	require.Nil(t, err)
+	defer DeleteCommunity(closed.ID)
+
+	request := PrayerRequest{
+		Title:     "Routes",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+	hidden := PrayerRequest{
+		Title:     "Hidden",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&hidden)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(hidden.ID)
+	AddPrayerRequestToCommunity(hidden.ID, closed.ID)
+
+	resource := url.QueryEscape(fmt.Sprintf("acct:%s@%s", community.ShortCode, GetActivityPubDomain()))
+
+	// nothing is exposed until federation is enabled
+	code, _, _ := TestAPICall(http.MethodGet, "/.well-known/webfinger?resource="+resource, b, WebFingerRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d", community.ID), b, GetActivityPubCommunityRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/requests/%d", request.ID), b, GetActivityPubRequestRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	Config.FederationEnabled = true
+	actorID := GetActivityPubCommunityActorID(community.ID)
+
+	code, res, _ := TestAPICall(http.MethodGet, "/.well-known/webfinger?resource="+resource, b, WebFingerRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	jrd := map[string]interface{}{}
+	json.Unmarshal(res.Bytes(), &jrd)
+	links := jrd["links"].([]interface{})
+	assert.Equal(t, actorID, links[0].(map[string]interface{})["href"])
+
+	// private communities can't be found
+	code, _, _ = TestAPICall(http.MethodGet, "/.well-known/webfinger?resource="+url.QueryEscape(fmt.Sprintf("acct:%s@%s", closed.ShortCode, GetActivityPubDomain())), b, WebFingerRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/.well-known/webfinger?resource="+url.QueryEscape(fmt.Sprintf("acct:%s@elsewhere.example", community.ShortCode)), b, WebFingerRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d", closed.ID), b, GetActivityPubCommunityRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d", community.ID), b, GetActivityPubCommunityRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	actor := map[string]interface{}{}
+	json.Unmarshal(res.Bytes(), &actor)
+	assert.Equal(t, actorID, actor["id"])
+	assert.Equal(t, "Group", actor["type"])
+	assert.Equal(t, community.ShortCode, actor["preferredUsername"])
+	assert.Equal(t, actorID+"/inbox", actor["inbox"])
+	publicKey := actor["publicKey"].(map[string]interface{})
+	_, err = parseActivityPubPublicKey(publicKey["publicKeyPem"].(string))
+	assert.Nil(t, err)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d/outbox", community.ID), b, GetActivityPubOutboxRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	outbox := map[string]interface{}{}
+	json.Unmarshal(res.Bytes(), &outbox)
+	assert.Equal(t, float64(1), outbox["totalItems"])
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d/outbox?page=1", community.ID), b, GetActivityPubOutboxRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	outbox = map[string]interface{}{}
+	json.Unmarshal(res.Bytes(), &outbox)
+	items := outbox["orderedItems"].([]interface{})
+	require.Equal(t, 1, len(items))
+	assert.Equal(t, "Create", items[0].(map[string]interface{})["type"])
+	assert.Nil(t, outbox["next"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/communities/%d/followers", community.ID), b, GetActivityPubFollowersRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/requests/%d", request.ID), b, GetActivityPubRequestRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	note := map[string]interface{}{}
+	json.Unmarshal(res.Bytes(), &note)
+	assert.Equal(t, "Note", note["type"])
+	assert.Equal(t, actorID, note["attributedTo"])
+	assert.Equal(t, "Routes", note["name"])
+
+	// requests only shared in private communities aren't exposed
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/ap/requests/%d", hidden.ID), b, GetActivityPubRequestRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// unsigned activities are refused
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/ap/communities/%d/inbox", community.ID), bytes.NewBufferString(`{"type":"Follow"}`), PostActivityPubInboxRoute, "", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/ap/communities/%d/inbox", community.ID), bytes.NewBufferString(`not json`), PostActivityPubInboxRoute, "", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,303 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	mathRand "math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActivityPubSignatures(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+
+	body := []byte(`{"type":"Follow"}`)
+	req, err := http.NewRequest(http.MethodPost, "https://remote.example/ap/communities/1/inbox", bytes.NewReader(body))
+	require.Nil(t, err)
+	err = SignActivityPubRequest(req, body, "https://local.example/actor#main-key", key)
+	require.Nil(t, err)
+
+	params := parseActivityPubSignatureHeader(req.Header.Get("Signature"))
+	assert.Equal(t, "https://local.example/actor#main-key", params["keyId"])
+	assert.Equal(t, "rsa-sha256", params["algorithm"])
+	assert.Equal(t, "(request-target) host date digest", params["headers"])
+
+	assert.Nil(t, VerifyActivityPubSignature(req, body, &key.PublicKey))
+	assert.NotNil(t, VerifyActivityPubSignature(req, body, &other.PublicKey))
+	assert.NotNil(t, VerifyActivityPubSignature(req, []byte(`{"type":"Undo"}`), &key.PublicKey))
+
+	// the signature covers the date, and old dates are refused
+	req.Header.Set("Date", time.Now().UTC().Add(-2*ActivityPubSignatureMaxSkew).Format(http.TimeFormat))
+	assert.NotNil(t, VerifyActivityPubSignature(req, body, &key.PublicKey))
+	err = SignActivityPubRequest(req, body, "https://local.example/actor#main-key", key)
+	require.Nil(t, err)
+	req.Header.Set("Date", time.Now().UTC().Add(-2*ActivityPubSignatureMaxSkew).Format(http.TimeFormat))
+	assert.NotNil(t, VerifyActivityPubSignature(req, body, &key.PublicKey))
+
+	// requests without a signature are refused
+	unsigned, _ := http.NewRequest(http.MethodPost, "https://remote.example/ap/communities/1/inbox", bytes.NewReader(body))
+	assert.NotNil(t, VerifyActivityPubSignature(unsigned, body, &key.PublicKey))
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.Nil(t, err)
+	parsed, err := parseActivityPubPublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})))
+	require.Nil(t, err)
+	assert.Equal(t, key.PublicKey.N, parsed.N)
+	_, err = parseActivityPubPublicKey("not a key")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "https://remote.example/a", activityPubObjectID("https://remote.example/a"))
+	assert.Equal(t, "https://remote.example/b", activityPubObjectID(map[string]interface{}{"id": "https://remote.example/b"}))
+	assert.Equal(t, "", activityPubObjectID(7))
+
+	// the same actor isn't fetched again right away
+	actorID := fmt.Sprintf("https://remote.example/actors/%d", time.Now().UnixNano())
+	assert.True(t, allowActivityPubActorFetch(actorID))
+	assert.False(t, allowActivityPubActorFetch(actorID))
+	assert.True(t, allowActivityPubActorFetch(actorID+"/other"))
+}
+
+// fakeActivityPubInstance is a remote server with one actor. Its inbox only records activities whose signature verifies
+// against the community's key
+type fakeActivityPubInstance struct {
+	server    *httptest.Server
+	key       *rsa.PrivateKey
+	verifyKey *rsa.PublicKey
+	mu        sync.Mutex
+	received  []map[string]interface{}
+	rejected  int
+}
+
+func newFakeActivityPubInstance(t *testing.T) *fakeActivityPubInstance {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.Nil(t, err)
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.Nil(t, err)
+	publicPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+
+	instance := &fakeActivityPubInstance{key: key}
+	instance.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/actor":
+			actorID := instance.server.URL + "/actor"
+			w.Header().Set("Content-Type", ActivityPubContentType)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id":    actorID,
+				"type":  "Person",
+				"inbox": instance.server.URL + "/inbox",
+				"publicKey": map[string]string{
+					"id":           actorID + "#main-key",
+					"owner":        actorID,
+					"publicKeyPem": publicPEM,
+				},
+			})
+		case "/inbox":
+			body, _ := ioutil.ReadAll(r.Body)
+			instance.mu.Lock()
+			defer instance.mu.Unlock()
+			if instance.verifyKey == nil || VerifyActivityPubSignature(r, body, instance.verifyKey) != nil {
+				instance.rejected++
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			activity := map[string]interface{}{}
+			json.Unmarshal(body, &activity)
+			instance.received = append(instance.received, activity)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return instance
+}
+
+func (instance *fakeActivityPubInstance) actorID() string {
+	return instance.server.URL + "/actor"
+}
+
+// send posts a signed activity to a community's inbox
+func (instance *fakeActivityPubInstance) send(communityID int64, activity map[string]interface{}) int {
+	body, _ := json.Marshal(activity)
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/ap/communities/%d/inbox", communityID), bytes.NewReader(body))
+	req.Header.Set("Content-Type", ActivityPubContentType)
+	SignActivityPubRequest(req, body, instance.actorID()+"#main-key", instance.key)
+	rr := httptest.NewRecorder()
+	SetupApp().ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// waitFor polls the inbox until it has received count activities, since deliveries are sent in the background
+func (instance *fakeActivityPubInstance) waitFor(t *testing.T, count int) []map[string]interface{} {
+	for i := 0; i < 50; i++ {
+		instance.mu.Lock()
+		received := instance.received
+		instance.mu.Unlock()
+		if len(received) >= count {
+			return received
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.FailNow(t, fmt.Sprintf("expected %d activities", count))
+	return nil
+}
+
+func TestActivityPubFederation(t *testing.T) {
+	ConfigSetup()
+	federationEnabled := Config.FederationEnabled
+	Config.FederationEnabled = true
+	defer func() { Config.FederationEnabled = federationEnabled }()
+	isValidFederationURL = func(string) bool { return true }
+	defer func() { isValidFederationURL = IsValidWebhookURL }()
+	isBlockedOutboundIP = func(net.IP) bool { return false }
+	defer func() { isBlockedOutboundIP = isBlockedIP }()
+
+	remote := newFakeActivityPubInstance(t)
+	defer remote.server.Close()
+	impostor := newFakeActivityPubInstance(t)
+	defer impostor.server.Close()
+
+	member := User{}
+	err := CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      fmt.Sprintf("Federated %d", mathRand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("fed_%d", mathRand.Int63n(999999999)),
+		Privacy:   "public",
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	key, err := getActivityPubKey(community.ID)
+	require.Nil(t, err)
+	communityKey, err := parseActivityPubPublicKey(key.PublicKey)
+	require.Nil(t, err)
+	remote.verifyKey = communityKey
+	impostor.verifyKey = communityKey
+	communityActorID := GetActivityPubCommunityActorID(community.ID)
+
+	// a follow signed by someone other than its actor is refused
+	follow := map[string]interface{}{
+		"id":     remote.actorID() + "/follows/1",
+		"type":   "Follow",
+		"actor":  remote.actorID(),
+		"object": communityActorID,
+	}
+	code := impostor.send(community.ID, follow)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// a real follow is saved and accepted with a signed Accept
+	code = remote.send(community.ID, follow)
+	require.Equal(t, http.StatusAccepted, code)
+	followers, err := GetActivityPubFollowers(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(followers))
+	assert.Equal(t, remote.actorID(), followers[0].ActorID)
+	assert.Equal(t, remote.server.URL+"/inbox", followers[0].Inbox)
+	received := remote.waitFor(t, 1)
+	assert.Equal(t, "Accept", received[0]["type"])
+	assert.Equal(t, communityActorID, received[0]["actor"])
+
+	// new public requests in the community are sent to followers; private ones aren't
+	public := PrayerRequest{
+		Title:     "Federated",
+		Body:      "Please pray <for us>",
+		CreatedBy: member.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&public)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(public.ID)
+	AddPrayerRequestToCommunity(public.ID, community.ID)
+	private := PrayerRequest{
+		Title:     "Private",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+		Privacy:   "private",
+	}
+	err = CreatePrayerRequest(&private)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(private.ID)
+	AddPrayerRequestToCommunity(private.ID, community.ID)
+
+	err = QueueActivityPubRequestCreated(private.ID, community.ID)
+	require.Nil(t, err)
+	err = QueueActivityPubRequestCreated(public.ID, community.ID)
+	require.Nil(t, err)
+	received = remote.waitFor(t, 2)
+	require.Equal(t, 2, len(received))
+	assert.Equal(t, "Create", received[1]["type"])
+	note := received[1]["object"].(map[string]interface{})
+	assert.Equal(t, GetActivityPubRequestID(public.ID), note["id"])
+	assert.Equal(t, "<p>Please pray &lt;for us&gt;</p>", note["content"])
+	remote.mu.Lock()
+	assert.Equal(t, 0, remote.rejected)
+	remote.mu.Unlock()
+
+	// remote prayers count once per activity and respect the prayer timeout
+	pray := map[string]interface{}{
+		"id":     remote.actorID() + "/prayers/1",
+		"type":   ActivityPubActivityTypePray,
+		"actor":  remote.actorID(),
+		"object": GetActivityPubRequestID(public.ID),
+	}
+	code = remote.send(community.ID, pray)
+	require.Equal(t, http.StatusAccepted, code)
+	code = remote.send(community.ID, pray)
+	require.Equal(t, http.StatusAccepted, code)
+	pray["id"] = remote.actorID() + "/prayers/2"
+	code = remote.send(community.ID, pray)
+	require.Equal(t, http.StatusAccepted, code)
+	found, err := GetPrayerRequest(public.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, found.PrayerCount)
+
+	// private requests can't be prayed for remotely
+	pray["id"] = remote.actorID() + "/prayers/3"
+	pray["object"] = GetActivityPubRequestID(private.ID)
+	code = remote.send(community.ID, pray)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// undoing the prayer takes it back
+	code = remote.send(community.ID, map[string]interface{}{
+		"id":     remote.actorID() + "/undo/1",
+		"type":   "Undo",
+		"actor":  remote.actorID(),
+		"object": remote.actorID() + "/prayers/1",
+	})
+	require.Equal(t, http.StatusAccepted, code)
+	found, err = GetPrayerRequest(public.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, found.PrayerCount)
+
+	// and undoing the follow removes the follower
+	code = remote.send(community.ID, map[string]interface{}{
+		"id":     remote.actorID() + "/undo/2",
+		"type":   "Undo",
+		"actor":  remote.actorID(),
+		"object": follow,
+	})
+	require.Equal(t, http.StatusAccepted, code)
+	followers, err = GetActivityPubFollowers(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(followers))
+}
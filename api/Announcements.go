@@ -0,0 +1,168 @@
+package api
+
+import (
+	"time"
+)
+
+// CommunityAnnouncement is a post from a community's admins to all of its members
+type CommunityAnnouncement struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	CreatedBy   int64  `json:"createdBy" db:"createdBy"`
+	Title       string `json:"title" db:"title"`
+	Body        string `json:"body" db:"body"`
+	// Pinned announcements are always listed first
+	Pinned string `json:"pinned" db:"pinned"`
+	// Expires is when the announcement stops being shown to members; blank means it never expires
+	Expires string `json:"expires" db:"expires"`
+	Created string `json:"created" db:"created"`
+	Updated string `json:"updated" db:"updated"`
+
+	// Username is only populated on reads and is the username of the admin who posted it
+	Username string `json:"username" db:"username"`
+	// Read is only populated on reads and is whether the requesting user has read the announcement
+	Read string `json:"read" db:"read"`
+}
+
+const (
+	// CommunityAnnouncementPinnedYes pins the announcement to the top of the feed
+	CommunityAnnouncementPinnedYes = "yes"
+	// CommunityAnnouncementPinnedNo is the default and sorts the announcement by when it was created
+	CommunityAnnouncementPinnedNo = "no"
+)
+
+// CreateCommunityAnnouncement creates a new announcement in a community
+func CreateCommunityAnnouncement(input *CommunityAnnouncement) error {
+	input.processForDB()
+	defer input.processForAPI()
+	query := `INSERT INTO CommunityAnnouncements (communityId, createdBy, title, body, pinned, expires, created, updated) 
+		VALUES (:communityId, :createdBy, :title, :body, :pinned, :expires, NOW(), NOW())`
+	res, err := Config.DbConn.NamedExec(query, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// UpdateCommunityAnnouncement updates the content, pin, and expiration of an announcement
+func UpdateCommunityAnnouncement(input *CommunityAnnouncement) error {
+	input.processForDB()
+	defer input.processForAPI()
+	_, err := Config.DbConn.NamedExec(`UPDATE CommunityAnnouncements SET title = :title, body = :body, pinned = :pinned, expires = :expires, updated = NOW() 
+		WHERE id = :id AND communityId = :communityId LIMIT 1`, input)
+	return err
+}
+
+// GetCommunityAnnouncement gets a single announcement in a community, along with whether the user has read it
+func GetCommunityAnnouncement(communityID, announcementID, userID int64) (*CommunityAnnouncement, error) {
+	announcement := &CommunityAnnouncement{}
+	err := Config.DbConn.Get(announcement, `SELECT ca.*, u.username, 
+		IF((SELECT COUNT(*) FROM CommunityAnnouncementReads car WHERE car.announcementId = ca.id AND car.userId = ?) > 0, 'yes', 'no') AS 'read' 
+		FROM CommunityAnnouncements ca, Users u WHERE ca.id = ? AND ca.communityId = ? AND ca.createdBy = u.id`, userID, announcementID, communityID)
+	announcement.processForAPI()
+	return announcement, err
+}
+
+// GetCommunityAnnouncements gets the announcements in a community with pinned announcements first and the newest next. Expired announcements
+// are only returned if includeExpired is true, which should be limited to admins
+func GetCommunityAnnouncements(communityID, userID int64, includeExpired bool, count, offset int) ([]CommunityAnnouncement, error) {
+	announcements := []CommunityAnnouncement{}
+	expiredClause := " AND (ca.expires = '1970-01-01 00:00:00' OR ca.expires > NOW()) "
+	if includeExpired {
+		expiredClause = ""
+	}
+	err := Config.DbConn.Select(&announcements, `SELECT ca.*, u.username, 
+		IF((SELECT COUNT(*) FROM CommunityAnnouncementReads car WHERE car.announcementId = ca.id AND car.userId = ?) > 0, 'yes', 'no') AS 'read' 
+		FROM CommunityAnnouncements ca, Users u WHERE ca.communityId = ? AND ca.createdBy = u.id `+expiredClause+`
+		ORDER BY ca.pinned DESC, ca.created DESC LIMIT ?,?`, userID, communityID, offset, count)
+	for i := range announcements {
+		announcements[i].processForAPI()
+	}
+	return announcements, err
+}
+
+// GetUnreadCommunityAnnouncementCount gets the number of active announcements in a community the user has not read, for badges
+func GetUnreadCommunityAnnouncementCount(communityID, userID int64) (int64, error) {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) FROM CommunityAnnouncements ca 
+		WHERE ca.communityId = ? AND (ca.expires = '1970-01-01 00:00:00' OR ca.expires > NOW()) 
+		AND ca.id NOT IN (SELECT car.announcementId FROM CommunityAnnouncementReads car WHERE car.userId = ?)`, communityID, userID)
+	return count, err
+}
+
+// MarkCommunityAnnouncementRead marks an announcement as read by the user
+func MarkCommunityAnnouncementRead(announcementID, userID int64) error {
+	_, err := Config.DbConn.Exec("INSERT IGNORE INTO CommunityAnnouncementReads (announcementId, userId, readOn) VALUES (?, ?, NOW())", announcementID, userID)
+	return err
+}
+
+// MarkAllCommunityAnnouncementsRead marks every announcement in the community as read by the user
+func MarkAllCommunityAnnouncementsRead(communityID, userID int64) error {
+	_, err := Config.DbConn.Exec(`INSERT IGNORE INTO CommunityAnnouncementReads (announcementId, userId, readOn) 
+		SELECT ca.id, ?, NOW() FROM CommunityAnnouncements ca WHERE ca.communityId = ?`, userID, communityID)
+	return err
+}
+
+// DeleteCommunityAnnouncement deletes an announcement and its read tracking
+func DeleteCommunityAnnouncement(communityID, announcementID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM CommunityAnnouncements WHERE id = ? AND communityId = ? LIMIT 1", announcementID, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunityAnnouncementReads WHERE announcementId = ?", announcementID)
+	return err
+}
+
+// DeleteCommunityAnnouncementsForCommunity deletes all of the announcements in a community and their read tracking
+func DeleteCommunityAnnouncementsForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE cas FROM CommunityAnnouncementReads cas 
+		INNER JOIN CommunityAnnouncements ca ON ca.id = cas.announcementId WHERE ca.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunityAnnouncements WHERE communityId = ?", communityID)
+	return err
+}
+
+// processForDB ensures data consistency
+func (input *CommunityAnnouncement) processForDB() {
+	if input.Pinned != CommunityAnnouncementPinnedYes {
+		input.Pinned = CommunityAnnouncementPinnedNo
+	}
+
+	if input.Expires == "" {
+		input.Expires = "1970-01-01 00:00:00"
+	} else {
+		parsed, err := ParseTime(input.Expires)
+		if err != nil {
+			parsed, _ = time.Parse("2006-01-02", "1970-01-01")
+		}
+		input.Expires = parsed.UTC().Format("2006-01-02 15:04:05")
+	}
+}
+
+// processForAPI cleans up the output
+func (input *CommunityAnnouncement) processForAPI() {
+	if input == nil {
+		return
+	}
+	if input.Expires == "1970-01-01 00:00:00" {
+		input.Expires = ""
+	} else {
+		input.Expires, _ = ParseTimeToISO(input.Expires)
+	}
+	if input.Created == "1970-01-01 00:00:00" {
+		input.Created = ""
+	} else {
+		input.Created, _ = ParseTimeToISO(input.Created)
+	}
+	if input.Updated == "1970-01-01 00:00:00" {
+		input.Updated = ""
+	} else {
+		input.Updated, _ = ParseTimeToISO(input.Updated)
+	}
+	if input.Read == "" {
+		input.Read = "no"
+	}
+}
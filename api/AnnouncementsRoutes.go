@@ -0,0 +1,321 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *CommunityAnnouncement) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreateCommunityAnnouncementRoute allows a community admin to post an announcement. Members are emailed based on their notification preferences
+func CreateCommunityAnnouncementRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := CommunityAnnouncement{}
+	render.Bind(r, &input)
+	input.Title, _ = sanitize(input.Title)
+	input.Body, _ = sanitize(input.Body)
+	if input.Title == "" || input.Body == "" {
+		SendError(w, http.StatusBadRequest, "community_announcement_missing_data", "title and body are required", input)
+		return
+	}
+	if input.Expires != "" {
+		if _, err := ParseTime(input.Expires); err != nil {
+			SendError(w, http.StatusBadRequest, "community_announcement_invalid_expires", "expires must be a valid date time", input)
+			return
+		}
+	}
+	input.CommunityID = communityID
+	input.CreatedBy = jwtUser.ID
+
+	err = CreateCommunityAnnouncement(&input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_announcement_create_error", "could not create that announcement", err)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err == nil {
+		emailContent := fmt.Sprintf(`<p>A new announcement was posted in the %s community.</p>
+	<h3>%s</h3>
+	<p>%s</p>
+	`, community.Name, input.Title, input.Body)
+		NotifyCommunityMembers(communityID, jwtUser.ID, NotificationTypeCommunityAnnouncement, fmt.Sprintf("New Announcement in %s", community.Name), emailContent)
+	}
+
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetCommunityAnnouncementsRoute gets the announcements for a community along with whether the user has read each one. Admins
+// can pass includeExpired=true to see expired announcements
+func GetCommunityAnnouncementsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	includeExpired := role == "admin" && r.URL.Query().Get("includeExpired") == "true"
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	announcements, err := GetCommunityAnnouncements(communityID, jwtUser.ID, includeExpired, count, offset)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_announcements_error", "could not get the announcements", err)
+		return
+	}
+	SendPage(w, r, http.StatusOK, announcements, offsetPage(count, offset, len(announcements)))
+	return
+}
+
+// GetCommunityAnnouncementsUnreadCountRoute gets the number of unread announcements for the user in a community
+func GetCommunityAnnouncementsUnreadCountRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	unread, err := GetUnreadCommunityAnnouncementCount(communityID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_announcements_error", "could not get the unread count", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]int64{
+		"unread": unread,
+	})
+	return
+}
+
+// GetCommunityAnnouncementRoute gets a single announcement
+func GetCommunityAnnouncementRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	announcementID, announcementIDErr := strconv.ParseInt(chi.URLParam(r, "announcementID"), 10, 64)
+	if communityIDErr != nil || announcementIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	announcement, err := GetCommunityAnnouncement(communityID, announcementID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_announcement_not_found", "that announcement could not be found", nil)
+		return
+	}
+	Send(w, http.StatusOK, announcement)
+	return
+}
+
+// UpdateCommunityAnnouncementRoute allows an admin to update an announcement, including pinning it or changing when it expires
+func UpdateCommunityAnnouncementRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	announcementID, announcementIDErr := strconv.ParseInt(chi.URLParam(r, "announcementID"), 10, 64)
+	if communityIDErr != nil || announcementIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	announcement, err := GetCommunityAnnouncement(communityID, announcementID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_announcement_not_found", "that announcement could not be found", nil)
+		return
+	}
+
+	input := CommunityAnnouncement{}
+	render.Bind(r, &input)
+	if input.Title != "" {
+		announcement.Title, _ = sanitize(input.Title)
+	}
+	if input.Body != "" {
+		announcement.Body, _ = sanitize(input.Body)
+	}
+	if input.Pinned != "" {
+		announcement.Pinned = input.Pinned
+	}
+	if input.Expires != "" {
+		// "never" clears the expiration
+		if input.Expires == "never" {
+			announcement.Expires = ""
+		} else {
+			if _, err := ParseTime(input.Expires); err != nil {
+				SendError(w, http.StatusBadRequest, "community_announcement_invalid_expires", "expires must be a valid date time or never", input)
+				return
+			}
+			announcement.Expires = input.Expires
+		}
+	}
+
+	err = UpdateCommunityAnnouncement(announcement)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_announcement_update_error", "could not update that announcement", err)
+		return
+	}
+	Send(w, http.StatusOK, announcement)
+	return
+}
+
+// DeleteCommunityAnnouncementRoute allows an admin to delete an announcement
+func DeleteCommunityAnnouncementRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	announcementID, announcementIDErr := strconv.ParseInt(chi.URLParam(r, "announcementID"), 10, 64)
+	if communityIDErr != nil || announcementIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = DeleteCommunityAnnouncement(communityID, announcementID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_announcement_delete_error", "could not delete that announcement", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// MarkCommunityAnnouncementReadRoute marks an announcement as read by the user
+func MarkCommunityAnnouncementReadRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	announcementID, announcementIDErr := strconv.ParseInt(chi.URLParam(r, "announcementID"), 10, 64)
+	if communityIDErr != nil || announcementIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	_, err = GetCommunityAnnouncement(communityID, announcementID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_announcement_not_found", "that announcement could not be found", nil)
+		return
+	}
+
+	MarkCommunityAnnouncementRead(announcementID, jwtUser.ID)
+	unread, _ := GetUnreadCommunityAnnouncementCount(communityID, jwtUser.ID)
+	Send(w, http.StatusOK, map[string]int64{
+		"unread": unread,
+	})
+	return
+}
+
+// MarkAllCommunityAnnouncementsReadRoute marks all of a community's announcements as read by the user
+func MarkAllCommunityAnnouncementsReadRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	MarkAllCommunityAnnouncementsRead(communityID, jwtUser.ID)
+	Send(w, http.StatusOK, map[string]int64{
+		"unread": 0,
+	})
+	return
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityAnnouncementsRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	outsider := User{}
+	err = CreateTestUser(&outsider)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&outsider)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	url := fmt.Sprintf("/communities/%d/announcements", community.ID)
+
+	// members and outsiders cannot post
+	b.Reset()
+	enc.Encode(map[string]string{
+		"title": "Hello",
+		"body":  "World",
+	})
+	code, _, _ := TestAPICall(http.MethodPost, url, b, CreateCommunityAnnouncementRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPost, url, b, CreateCommunityAnnouncementRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// missing data
+	b.Reset()
+	enc.Encode(map[string]string{
+		"title": "Hello",
+	})
+	code, _, _ = TestAPICall(http.MethodPost, url, b, CreateCommunityAnnouncementRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"title": "Hello",
+		"body":  "World",
+	})
+	code, res, _ := TestAPICall(http.MethodPost, url, b, CreateCommunityAnnouncementRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	announcement := CommunityAnnouncement{}
+	mapstructure.Decode(body, &announcement)
+	require.NotZero(t, announcement.ID)
+	defer DeleteCommunityAnnouncement(community.ID, announcement.ID)
+
+	// outsiders cannot read the feed
+	code, _, _ = TestAPICall(http.MethodGet, url, b, GetCommunityAnnouncementsRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, url, b, GetCommunityAnnouncementsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+	found := CommunityAnnouncement{}
+	mapstructure.Decode(bodyA[0], &found)
+	assert.Equal(t, "no", found.Read)
+
+	code, res, _ = TestAPICall(http.MethodGet, url+"/unread", b, GetCommunityAnnouncementsUnreadCountRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	unread, _ := convertTestJSONFloatToInt(body["unread"])
+	assert.Equal(t, int64(1), unread)
+
+	// mark it read
+	code, res, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/%d/read", url, announcement.ID), b, MarkCommunityAnnouncementReadRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	unread, _ = convertTestJSONFloatToInt(body["unread"])
+	assert.Equal(t, int64(0), unread)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("%s/%d", url, announcement.ID), b, GetCommunityAnnouncementRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	mapstructure.Decode(body, &found)
+	assert.Equal(t, "yes", found.Read)
+
+	// members cannot update or delete
+	b.Reset()
+	enc.Encode(map[string]string{
+		"pinned": CommunityAnnouncementPinnedYes,
+	})
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("%s/%d", url, announcement.ID), b, UpdateCommunityAnnouncementRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"pinned": CommunityAnnouncementPinnedYes,
+	})
+	code, res, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("%s/%d", url, announcement.ID), b, UpdateCommunityAnnouncementRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	mapstructure.Decode(body, &found)
+	assert.Equal(t, CommunityAnnouncementPinnedYes, found.Pinned)
+	assert.Equal(t, "Hello", found.Title)
+
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("%s/%d", url, announcement.ID), b, DeleteCommunityAnnouncementRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("%s/%d", url, announcement.ID), b, DeleteCommunityAnnouncementRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("%s/%d", url, announcement.ID), b, GetCommunityAnnouncementRoute, member.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityAnnouncementsCRUD(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	first := CommunityAnnouncement{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		Title:       "First",
+		Body:        "The first announcement",
+	}
+	err = CreateCommunityAnnouncement(&first)
+	require.Nil(t, err)
+	assert.NotZero(t, first.ID)
+	assert.Equal(t, CommunityAnnouncementPinnedNo, first.Pinned)
+	defer DeleteCommunityAnnouncement(community.ID, first.ID)
+
+	pinned := CommunityAnnouncement{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		Title:       "Pinned",
+		Body:        "The pinned announcement",
+		Pinned:      CommunityAnnouncementPinnedYes,
+	}
+	err = CreateCommunityAnnouncement(&pinned)
+	require.Nil(t, err)
+	defer DeleteCommunityAnnouncement(community.ID, pinned.ID)
+
+	expired := CommunityAnnouncement{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		Title:       "Expired",
+		Body:        "The expired announcement",
+		Expires:     time.Now().Add(-24 * time.Hour).Format("2006-01-02 15:04:05"),
+	}
+	err = CreateCommunityAnnouncement(&expired)
+	require.Nil(t, err)
+	defer DeleteCommunityAnnouncement(community.ID, expired.ID)
+
+	// pinned comes first and expired is hidden
+	announcements, err := GetCommunityAnnouncements(community.ID, member.ID, false, 100, 0)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(announcements))
+	assert.Equal(t, pinned.ID, announcements[0].ID)
+	assert.Equal(t, first.ID, announcements[1].ID)
+	assert.Equal(t, "no", announcements[0].Read)
+
+	announcements, err = GetCommunityAnnouncements(community.ID, member.ID, true, 100, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(announcements))
+
+	// read tracking
+	unread, err := GetUnreadCommunityAnnouncementCount(community.ID, member.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), unread)
+
+	err = MarkCommunityAnnouncementRead(first.ID, member.ID)
+	assert.Nil(t, err)
+	// marking twice is fine
+	err = MarkCommunityAnnouncementRead(first.ID, member.ID)
+	assert.Nil(t, err)
+	unread, _ = GetUnreadCommunityAnnouncementCount(community.ID, member.ID)
+	assert.Equal(t, int64(1), unread)
+	found, err := GetCommunityAnnouncement(community.ID, first.ID, member.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "yes", found.Read)
+	assert.Equal(t, admin.Username, found.Username)
+
+	err = MarkAllCommunityAnnouncementsRead(community.ID, member.ID)
+	assert.Nil(t, err)
+	unread, _ = GetUnreadCommunityAnnouncementCount(community.ID, member.ID)
+	assert.Equal(t, int64(0), unread)
+
+	// update
+	found.Title = "Updated"
+	found.Pinned = CommunityAnnouncementPinnedYes
+	err = UpdateCommunityAnnouncement(found)
+	assert.Nil(t, err)
+	found, err = GetCommunityAnnouncement(community.ID, first.ID, member.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Updated", found.Title)
+	assert.Equal(t, CommunityAnnouncementPinnedYes, found.Pinned)
+	assert.Equal(t, "", found.Expires)
+
+	// delete
+	err = DeleteCommunityAnnouncement(community.ID, first.ID)
+	assert.Nil(t, err)
+	_, err = GetCommunityAnnouncement(community.ID, first.ID, member.ID)
+	assert.NotNil(t, err)
+}
@@ -2,7 +2,7 @@ package api
 
 import (
 	"fmt"
-	"strings"
+	"regexp"
 	"time"
 )
 
@@ -21,6 +21,39 @@ type Community struct {
 	PlanPaidThrough      string `json:"planPaidThrough,omitempty" db:"planPaidThrough"`
 	PlanDiscountPercent  int64  `json:"planDiscountPercent,omitempty" db:"planDiscountPercent"`
 	StripeSubscriptionID string `json:"stripeSubscriptionId,omitempty" db:"stripeSubscriptionId"`
+	// RequestModeration determines whether requests added by members must be reviewed by an admin before they are shown
+	RequestModeration string `json:"requestModeration,omitempty" db:"requestModeration"`
+	// RequestExpiryDays overrides the site's request expiry for requests shared with the community; 0 uses the site's
+	RequestExpiryDays int64 `json:"requestExpiryDays" db:"requestExpiryDays"`
+	// Logo is the storage key of the community's logo; clients should use LogoURL
+	Logo    string `json:"-" db:"logo"`
+	LogoURL string `json:"logoUrl,omitempty" db:"-"`
+	// AccentColor is a hex color, such as #336699, used in emails and available to clients
+	AccentColor string `json:"accentColor,omitempty" db:"accentColor"`
+	// EmailFooter is added to the bottom of every email sent on behalf of the community
+	EmailFooter string `json:"emailFooter,omitempty" db:"emailFooter"`
+	// Archived is when the community was archived; archived communities are hidden, read-only, and purged after the retention window
+	Archived   string `json:"archived,omitempty" db:"archived"`
+	ArchivedBy int64  `json:"archivedBy,omitempty" db:"archivedBy"`
+	// PurgeAfter is when an archived community will be permanently deleted
+	PurgeAfter string `json:"purgeAfter,omitempty" db:"-"`
+	// Categories, Denomination, Language, and the location are shown in the public directory. Categories come from a fixed list
+	Categories   []string `json:"categories,omitempty" db:"-"`
+	Denomination string   `json:"denomination,omitempty" db:"denomination"`
+	// Language is a two letter ISO 639-1 code, such as en
+	Language  string   `json:"language,omitempty" db:"language"`
+	City      string   `json:"city,omitempty" db:"city"`
+	Region    string   `json:"region,omitempty" db:"region"`
+	Latitude  *float64 `json:"latitude,omitempty" db:"latitude"`
+	Longitude *float64 `json:"longitude,omitempty" db:"longitude"`
+	// Distance is only populated in directory searches near a point and is in kilometers
+	Distance *float64 `json:"distance,omitempty" db:"distance"`
+	// QuestionnaireVersion is the current version of the join-request questionnaire; 0 means there has never been one
+	QuestionnaireVersion int64 `json:"questionnaireVersion" db:"questionnaireVersion"`
+	// OwnerID is the user responsible for the community, including billing; the owner is always an admin
+	OwnerID int64 `json:"ownerId" db:"ownerId"`
+	// PendingOwnerID is set when the owner has started a transfer that the recipient has not yet accepted
+	PendingOwnerID int64 `json:"pendingOwnerId,omitempty" db:"pendingOwnerId"`
 	// UserStatus is only populated in queries in which a user is joined or invited to a community
 	UserStatus string `json:"userStatus,omitempty" db:"userStatus"`
 	// UserRole is only populated in queries in which a user is joined or invited to a community
@@ -37,16 +70,23 @@ type CommunityUserLink struct {
 	Role        string `json:"role" db:"role"`
 	Status      string `json:"status" db:"status"`
 	ShortCode   string `json:"shortCode" db:"shortCode"`
-	FirstName   string `json:"firstName" db:"firstName"`
-	LastName    string `json:"lastName" db:"lastName"`
-	Email       string `json:"email" db:"email"`
-	Username    string `json:"username" db:"username"`
+	// Joined is when the link was first accepted
+	Joined string `json:"joined,omitempty" db:"joined"`
+	// WaitlistPosition is the user's place in line for a full community, starting at 1; it is 0 when not waitlisted
+	WaitlistPosition int64  `json:"waitlistPosition,omitempty" db:"waitlistPosition"`
+	FirstName        string `json:"firstName" db:"firstName"`
+	LastName         string `json:"lastName" db:"lastName"`
+	Email            string `json:"email" db:"email"`
+	Username         string `json:"username" db:"username"`
+	// Answers are the user's answers to the join-request questionnaire, only populated for admins
+	Answers []CommunityQuestionAnswer `json:"answers,omitempty" db:"-"`
 }
 
 // CommunityPlan is the details for the plans
 type CommunityPlan struct {
 	AllowedUsers          int64 `json:"allowedUsers"`
 	AllowedActiveRequests int64 `json:"allowedActiveRequests"`
+	AllowedSubGroups      int64 `json:"allowedSubGroups"`
 	MonthlyPrice          int64 `json:"monthlyPrice"`
 }
 
@@ -54,16 +94,19 @@ var plans = map[string]CommunityPlan{
 	CommunityPlanFree: {
 		AllowedUsers:          50,
 		AllowedActiveRequests: 50,
+		AllowedSubGroups:      5,
 		MonthlyPrice:          0,
 	},
 	CommunityPlanBasic: {
 		AllowedUsers:          200,
 		AllowedActiveRequests: 500,
+		AllowedSubGroups:      25,
 		MonthlyPrice:          499,
 	},
 	CommunityPlanPro: {
 		AllowedUsers:          2000,
 		AllowedActiveRequests: 4000,
+		AllowedSubGroups:      200,
 		MonthlyPrice:          999,
 	},
 }
@@ -81,6 +124,24 @@ const (
 	// CommunityUserSignupStatusAccept indicates users can signup and will be accepted automatically
 	CommunityUserSignupStatusAccept = "auto_accept"
 
+	// CommunityRequestModerationAuto indicates requests added to the community are shown immediately
+	CommunityRequestModerationAuto = "auto_approve"
+
+	// CommunityRequestModerationReview indicates requests added by members must be approved by an admin before they are shown
+	CommunityRequestModerationReview = "review_required"
+
+	// CommunityLogoMaxBytes is the largest logo that can be uploaded
+	CommunityLogoMaxBytes = 1024 * 1024
+
+	// CommunityEmailFooterMaxLength is the longest custom email footer allowed
+	CommunityEmailFooterMaxLength = 1024
+
+	// CommunityUserRoleMember is a regular member of a community
+	CommunityUserRoleMember = "member"
+
+	// CommunityUserRoleAdmin is an administrator of a community
+	CommunityUserRoleAdmin = "admin"
+
 	// CommunityUserLinkStatusInvited indicates a user has been invited to join a community
 	CommunityUserLinkStatusInvited = "invited"
 
@@ -93,6 +154,9 @@ const (
 	// CommunityUserLinkStatusDeclined indicates a user has been denied access to a community
 	CommunityUserLinkStatusDeclined = "declined"
 
+	// CommunityUserLinkStatusWaitlisted indicates a user asked to join a full community and is waiting for a seat
+	CommunityUserLinkStatusWaitlisted = "waitlisted"
+
 	// CommunityPrivacyPrivate is a private community not listed in the public directory
 	CommunityPrivacyPrivate = "private"
 
@@ -114,7 +178,7 @@ func GetCommunityByShortCode(shortCode string) (*Community, error) {
 	found := &Community{}
 	err := Config.DbConn.Get(found, `SELECT c.*,
 	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
-	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id) AS requestCount 
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount 
 	FROM Communities c WHERE c.shortCode = ?`, shortCode)
 	found.processForAPI()
 	return found, err
@@ -126,7 +190,7 @@ func GetCommunityByName(name string) (*Community, error) {
 	found := &Community{}
 	err := Config.DbConn.Get(found, `SELECT c.*,
 	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
-	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id) AS requestCount 
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount 
 	FROM Communities c WHERE c.name = ?`, name)
 	found.processForAPI()
 	return found, err
@@ -137,7 +201,7 @@ func GetCommunityByID(id int64) (*Community, error) {
 	found := &Community{}
 	err := Config.DbConn.Get(found, `SELECT c.* ,
 	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
-	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id) AS requestCount
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
 	FROM Communities c WHERE c.id = ? LIMIT 1`, id)
 	found.processForAPI()
 	return found, err
@@ -147,12 +211,13 @@ func GetCommunityByID(id int64) (*Community, error) {
 func CreateCommunity(input *Community) error {
 	input.processForDB()
 	defer input.processForAPI()
-	result, err := Config.DbConn.NamedExec(`INSERT INTO Communities (name, description, shortCode, joinCode, created, userSignupStatus, privacy)
-		VALUES (:name, :description, :shortCode, :joinCode, NOW(), :userSignupStatus, :privacy)`, input)
+	result, err := Config.DbConn.NamedExec(`INSERT INTO Communities (name, description, shortCode, joinCode, created, userSignupStatus, privacy, requestModeration, ownerId)
+		VALUES (:name, :description, :shortCode, :joinCode, NOW(), :userSignupStatus, :privacy, :requestModeration, :ownerId)`, input)
 	if err != nil {
 		return err
 	}
 	input.ID, _ = result.LastInsertId()
+	IndexCommunity(input.ID)
 	return nil
 }
 
@@ -160,16 +225,28 @@ func CreateCommunity(input *Community) error {
 func UpdateCommunity(input *Community) error {
 	input.processForDB()
 	defer input.processForAPI()
-	_, err := Config.DbConn.NamedExec(`UPDATE Communities SET name = :name, description = :description, shortCode = :shortCode, joinCode = :joinCode, userSignupStatus = :userSignupStatus, privacy = :privacy WHERE id = :id`, input)
-	return err
+	_, err := Config.DbConn.NamedExec(`UPDATE Communities SET name = :name, description = :description, shortCode = :shortCode, joinCode = :joinCode, userSignupStatus = :userSignupStatus, privacy = :privacy, requestModeration = :requestModeration, requestExpiryDays = :requestExpiryDays, accentColor = :accentColor, emailFooter = :emailFooter, 
+		denomination = :denomination, language = :language, city = :city, region = :region, latitude = :latitude, longitude = :longitude WHERE id = :id`, input)
+	if err != nil {
+		return err
+	}
+	IndexCommunity(input.ID)
+	return nil
 }
 
-// DeleteCommunity deletes a community and all links
+// DeleteCommunity permanently deletes a community and all links. Users deleting a community should go through ArchiveCommunity instead
+// so that it can be restored; this is called once the retention window has passed
 func DeleteCommunity(id int64) error {
+	logo := ""
+	Config.DbConn.Get(&logo, "SELECT logo FROM Communities WHERE id = ?", id)
 	_, err := Config.DbConn.Exec("DELETE FROM Communities WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
+	RemoveFromSearch(SearchCollectionCommunities, id)
+	if logo != "" {
+		Config.Storage.Delete(logo)
+	}
 	_, err = Config.DbConn.Exec("DELETE FROM CommunityUserLinks WHERE communityId = ?", id)
 	if err != nil {
 		return err
@@ -178,6 +255,105 @@ func DeleteCommunity(id int64) error {
 	if err != nil {
 		return err
 	}
+	err = DeleteCommunityAnnouncementsForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeleteCommunityInvitesForCommunity(id)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunityCategoryLinks WHERE communityId = ?", id)
+	if err != nil {
+		return err
+	}
+	err = DeleteWebhooksForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeleteCommunityExportsForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeleteActivityPubDataForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeleteCommunityQuestionnaireForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeleteCommunitySubGroupsForCommunity(id)
+	if err != nil {
+		return err
+	}
+	err = DeletePrayerRequestSharesForCommunity(id)
+	if err != nil {
+		return err
+	}
+	return DeletePrayerVigilsForCommunity(id)
+}
+
+// IsArchived checks if the community has been archived
+func (input *Community) IsArchived() bool {
+	return input.Archived != "" && input.Archived != "1970-01-01 00:00:00"
+}
+
+// ArchiveCommunity archives a community, hiding it from its members until it is restored or purged. Any active subscription is
+// cancelled and the community is moved to the free plan
+func ArchiveCommunity(community *Community, userID int64) error {
+	if community.StripeSubscriptionID != "" {
+		err := Config.Payments.CancelSubscription(community.StripeSubscriptionID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := Config.DbConn.Exec(`UPDATE Communities SET archived = NOW(), archivedBy = ?, plan = ?, stripeSubscriptionId = '' WHERE id = ?`,
+		userID, CommunityPlanFree, community.ID)
+	return err
+}
+
+// RestoreCommunity restores an archived community
+func RestoreCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec("UPDATE Communities SET archived = '1970-01-01 00:00:00', archivedBy = 0 WHERE id = ?", communityID)
+	return err
+}
+
+// GetArchivedCommunities gets the archived communities, optionally limited to those owned by a user; passing 0 gets them all
+func GetArchivedCommunities(ownerID int64) ([]Community, error) {
+	comms := []Community{}
+	var err error
+	if ownerID == 0 {
+		err = Config.DbConn.Select(&comms, `SELECT c.*,
+	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c WHERE c.archived != '1970-01-01 00:00:00' ORDER BY c.archived`)
+	} else {
+		err = Config.DbConn.Select(&comms, `SELECT c.*,
+	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c WHERE c.archived != '1970-01-01 00:00:00' AND c.ownerId = ? ORDER BY c.archived`, ownerID)
+	}
+	for i := range comms {
+		comms[i].processForAPI()
+	}
+	return comms, err
+}
+
+// PurgeArchivedCommunities permanently deletes the communities that have been archived for longer than the retention window
+func PurgeArchivedCommunities() error {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, `SELECT id FROM Communities WHERE archived != '1970-01-01 00:00:00' 
+		AND archived < DATE_SUB(NOW(), INTERVAL ? DAY)`, Config.CommunityRetentionDays)
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		err = DeleteCommunity(ids[i])
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -186,57 +362,190 @@ func CreateCommunityUserLink(communityID, userID int64, role, status, shortCode
 	if role == "" {
 		role = "member"
 	}
-	_, err := Config.DbConn.Exec("INSERT INTO CommunityUserLinks (communityId, userId, role, status, shortCode) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE userId = userId", communityID, userID, role, status, shortCode)
+	_, err := Config.DbConn.Exec(`INSERT INTO CommunityUserLinks (communityId, userId, role, status, shortCode, joined) 
+		VALUES (?, ?, ?, ?, ?, IF(? = 'accepted', NOW(), '1970-01-01 00:00:00')) ON DUPLICATE KEY UPDATE userId = userId`, communityID, userID, role, status, shortCode, status)
 	return err
 }
 
-// UpdateCommunityUserLink updates the status of a link
+// UpdateCommunityUserLink updates the status of a link, recording when it is accepted
 func UpdateCommunityUserLink(communityID, userID int64, status string) error {
-	_, err := Config.DbConn.Exec("UPDATE CommunityUserLinks SET status = ? WHERE communityId = ? AND userId = ?", status, communityID, userID)
+	_, err := Config.DbConn.Exec(`UPDATE CommunityUserLinks SET joined = IF(? = 'accepted' AND status != 'accepted', NOW(), joined), status = ? 
+		WHERE communityId = ? AND userId = ?`, status, status, communityID, userID)
 	return err
 }
 
 // DeleteCommunityUserLink completely deletes a link between a user and a community and should only be used by the system
 func DeleteCommunityUserLink(communityID, userID int64) error {
+	link := CommunityUserLink{}
+	Config.DbConn.Get(&link, "SELECT status, waitlistPosition FROM CommunityUserLinks WHERE communityId = ? AND userId = ?", communityID, userID)
 	_, err := Config.DbConn.Exec("DELETE FROM CommunityUserLinks WHERE communityId = ? AND userId = ?", communityID, userID)
-	return err
+	if err != nil {
+		return err
+	}
+	if link.Status == CommunityUserLinkStatusWaitlisted {
+		err = removeFromCommunityWaitlist(communityID, userID, link.WaitlistPosition)
+		if err != nil {
+			return err
+		}
+	}
+	return RemoveUserFromCommunitySubGroups(communityID, userID)
 }
 
-// GetCommunityUserLinks gets the links for a community optionally filtered by status
-func GetCommunityUserLinks(communityID int64, status string) ([]CommunityUserLink, error) {
+// GetCommunityUserLinks gets a page of the links for a community optionally filtered by status. The waitlist is in waitlist order
+// and pages by offset, since positions shift as the line moves; everything else is by username, and a keyset cursor starts
+// the page from a username instead of the offset
+func GetCommunityUserLinks(communityID int64, status string, cursor *PageCursor, count, offset int) ([]CommunityUserLink, error) {
 	links := []CommunityUserLink{}
 	var err error
+	if status == CommunityUserLinkStatusWaitlisted {
+		err = Config.DbConn.Select(&links, `SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u
+			WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id ORDER BY cul.waitlistPosition LIMIT ?,?`, communityID, status, offset, count)
+		for i := range links {
+			links[i].processForAPI()
+		}
+		return links, err
+	}
+	where, order, args := keysetClause(cursor, "u.username", false)
 	if status != "invited" && status != "requested" && status != "accepted" && status != "declined" {
-		err = Config.DbConn.Select(&links, `SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u 
-			WHERE cul.communityId = ? AND cul.userId = u.id ORDER BY u.username`, communityID)
+		args = append([]interface{}{communityID}, args...)
+		args = append(args, offset, count)
+		err = Config.DbConn.Select(&links, fmt.Sprintf(`SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u 
+			WHERE cul.communityId = ? AND cul.userId = u.id %s ORDER BY %s LIMIT ?,?`, where, order), args...)
 	} else {
-		err = Config.DbConn.Select(&links, `SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u 
-			WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id ORDER BY u.username`, communityID, status)
+		args = append([]interface{}{communityID, status}, args...)
+		args = append(args, offset, count)
+		err = Config.DbConn.Select(&links, fmt.Sprintf(`SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u 
+			WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id %s ORDER BY %s LIMIT ?,?`, where, order), args...)
+	}
+	if cursor.IsKeyset() && cursor.Before {
+		for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
+			links[i], links[j] = links[j], links[i]
+		}
+	}
+	for i := range links {
+		links[i].processForAPI()
 	}
 	return links, err
 }
 
+// GetCountOfCommunityUserLinks gets how many links GetCommunityUserLinks would list across every page
+func GetCountOfCommunityUserLinks(communityID int64, status string) (int64, error) {
+	total := int64(0)
+	if status != CommunityUserLinkStatusWaitlisted && status != "invited" && status != "requested" && status != "accepted" && status != "declined" {
+		err := Config.DbConn.Get(&total, `SELECT COUNT(*) FROM CommunityUserLinks cul, Users u WHERE cul.communityId = ? AND cul.userId = u.id`, communityID)
+		return total, err
+	}
+	err := Config.DbConn.Get(&total, `SELECT COUNT(*) FROM CommunityUserLinks cul, Users u WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id`,
+		communityID, status)
+	return total, err
+}
+
 // GetCommunityUserLink gets the individual link, used for processing
 func GetCommunityUserLink(communityID, userID int64) (CommunityUserLink, error) {
 	link := CommunityUserLink{}
 	err := Config.DbConn.Get(&link, `SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u 
 		WHERE cul.communityId = ? AND cul.userId = ? AND cul.userId = u.id ORDER BY u.username`, communityID, userID)
-
+	link.processForAPI()
 	return link, err
 }
 
-// GetUserRoleForCommunity gets the user's role for the community
+// GetUserRoleForCommunity gets the user's role for the community; members of an archived community have no role, which keeps
+// it hidden and read-only
 func GetUserRoleForCommunity(communityID, userID int64) (string, error) {
 	role := struct {
 		Role string `db:"role"`
 	}{}
-	err := Config.DbConn.Get(&role, "SELECT role FROM CommunityUserLinks WHERE userId = ? AND communityId = ? AND status = 'accepted'", userID, communityID)
+	err := Config.DbConn.Get(&role, `SELECT cul.role FROM CommunityUserLinks cul, Communities c 
+		WHERE cul.userId = ? AND cul.communityId = ? AND cul.status = 'accepted' AND c.id = cul.communityId AND c.archived = '1970-01-01 00:00:00'`, userID, communityID)
 	if err != nil {
 		return "", err
 	}
 	return role.Role, nil
 }
 
+// communityLogoTypes maps the allowed logo content types to the extension the file is stored with
+var communityLogoTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+var accentColorRegex = regexp.MustCompile("^#[0-9a-fA-F]{6}$")
+
+// IsValidAccentColor checks if the input is a six digit hex color, such as #336699
+func IsValidAccentColor(input string) bool {
+	return accentColorRegex.MatchString(input)
+}
+
+// UpdateCommunityLogo sets the storage key of the community's logo; a blank key removes the logo
+func UpdateCommunityLogo(communityID int64, logo string) error {
+	_, err := Config.DbConn.Exec("UPDATE Communities SET logo = ? WHERE id = ?", logo, communityID)
+	return err
+}
+
+// UpdateCommunityUserLinkRole updates the role of a link
+func UpdateCommunityUserLinkRole(communityID, userID int64, role string) error {
+	_, err := Config.DbConn.Exec("UPDATE CommunityUserLinks SET role = ? WHERE communityId = ? AND userId = ?", role, communityID, userID)
+	return err
+}
+
+// GetCountOfAdminsInCommunity gets the count of accepted admins in a community
+func GetCountOfAdminsInCommunity(communityID int64) (int64, error) {
+	count := struct {
+		Count int64 `db:"count"`
+	}{}
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) AS count FROM CommunityUserLinks cul WHERE cul.communityId = ? AND cul.role = ? AND cul.status = ?`,
+		communityID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted)
+	return count.Count, err
+}
+
+// IsLastAdminInCommunity checks to see if removing or demoting the user would leave the community without an admin
+func IsLastAdminInCommunity(communityID, userID int64) bool {
+	role, err := GetUserRoleForCommunity(communityID, userID)
+	if err != nil || role != CommunityUserRoleAdmin {
+		return false
+	}
+	count, err := GetCountOfAdminsInCommunity(communityID)
+	if err != nil {
+		// if we can't tell, err on the side of not orphaning the community
+		return true
+	}
+	return count <= 1
+}
+
+// SetCommunityPendingOwner starts an ownership transfer to a user; passing 0 cancels a pending transfer
+func SetCommunityPendingOwner(communityID, userID int64) error {
+	_, err := Config.DbConn.Exec("UPDATE Communities SET pendingOwnerId = ? WHERE id = ?", userID, communityID)
+	return err
+}
+
+// SetCommunityOwner sets the owner of a community, clears any pending transfer, and ensures the new owner is an accepted admin
+func SetCommunityOwner(communityID, userID int64) error {
+	_, err := Config.DbConn.Exec("UPDATE Communities SET ownerId = ?, pendingOwnerId = 0 WHERE id = ?", userID, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`INSERT INTO CommunityUserLinks (communityId, userId, role, status, shortCode, joined) VALUES (?, ?, ?, ?, '', NOW()) 
+		ON DUPLICATE KEY UPDATE joined = IF(status != 'accepted', NOW(), joined), role = VALUES(role), status = VALUES(status)`, communityID, userID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted)
+	return err
+}
+
+// GetOrphanedCommunities gets the communities that have no owner or no accepted admins so that a platform admin can recover them
+func GetOrphanedCommunities() ([]Community, error) {
+	comms := []Community{}
+	err := Config.DbConn.Select(&comms, `SELECT c.*,
+	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c
+	WHERE c.ownerId = 0 
+	OR NOT EXISTS (SELECT 1 FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.role = 'admin' AND cul.status = 'accepted')
+	ORDER BY c.name`)
+	for i := range comms {
+		comms[i].processForAPI()
+	}
+	return comms, err
+}
+
 // GetCountOfUsersInCommunity gets the count of users in a community
 func GetCountOfUsersInCommunity(communityID int64) (int64, error) {
 	count := struct {
@@ -252,36 +561,22 @@ func GetCommunitiesForUser(userID int64) ([]Community, error) {
 	comms := []Community{}
 	err := Config.DbConn.Select(&comms, `SELECT c.*, cul.status AS userStatus, cul.role as userRole,
 	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
-	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id) AS requestCount
-	FROM Communities c, CommunityUserLinks cul WHERE cul.userId = ? AND cul.communityId = c.id ORDER BY c.name`, userID)
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c, CommunityUserLinks cul WHERE cul.userId = ? AND cul.communityId = c.id AND c.archived = '1970-01-01 00:00:00' ORDER BY c.name`, userID)
 	for i := range comms {
 		comms[i].processForAPI()
 	}
 	return comms, err
 }
 
-// GetPublicCommunities gets the public communities, sorted by either name or created
-func GetPublicCommunities(sortField, sortDir string, count, offset int) ([]Community, error) {
+// GetPendingCommunitiesForUser gets the communities the user has been invited to, has requested to join, or is waitlisted for
+func GetPendingCommunitiesForUser(userID int64) ([]Community, error) {
 	comms := []Community{}
-
-	// generally, string interpolation on SQL is Very Bad, but this is white listed so there is no
-	// security concerns
-	sortDir = strings.ToUpper(sortDir)
-	if sortDir != "DESC" {
-		sortDir = "ASC"
-	}
-	sortField = strings.ToLower(sortField)
-	if sortField != "name" && sortField != "created" {
-		sortField = "name"
-	}
-	err := Config.DbConn.Select(&comms, fmt.Sprintf(`
-	SELECT c.*, 
+	err := Config.DbConn.Select(&comms, `SELECT c.*, cul.status AS userStatus, cul.role as userRole,
 	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
-	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id) AS requestCount
-	FROM Communities c
-	WHERE c.privacy = 'public'
-	ORDER BY c.%s %s LIMIT ?,?`, sortField, sortDir), offset, count)
-
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c, CommunityUserLinks cul WHERE cul.userId = ? AND cul.communityId = c.id AND cul.status IN ('invited', 'requested', 'waitlisted') 
+	AND c.archived = '1970-01-01 00:00:00' ORDER BY c.name`, userID)
 	for i := range comms {
 		comms[i].processForAPI()
 		comms[i].clean()
@@ -326,6 +621,9 @@ func (input *Community) processForDB() {
 	if input.Privacy == "" {
 		input.Privacy = "private"
 	}
+	if input.RequestModeration == "" {
+		input.RequestModeration = CommunityRequestModerationAuto
+	}
 }
 
 func (input *Community) processForAPI() {
@@ -333,6 +631,10 @@ func (input *Community) processForAPI() {
 		input.UserSignupStatus = CommunityUserSignupStatusApproval
 	}
 
+	if input.RequestModeration == "" {
+		input.RequestModeration = CommunityRequestModerationAuto
+	}
+
 	if input.Created == "1970-01-01 00:00:00" {
 		input.Created = ""
 	} else {
@@ -342,10 +644,31 @@ func (input *Community) processForAPI() {
 	if input.PlanPaidThrough == "1970-01-01" {
 		input.PlanPaidThrough = ""
 	}
+
+	if input.Logo != "" {
+		input.LogoURL = Config.Storage.URL(input.Logo)
+	}
+
+	if input.Archived == "1970-01-01 00:00:00" {
+		input.Archived = ""
+	} else if archived, err := ParseTime(input.Archived); err == nil {
+		input.Archived = archived.UTC().Format(time.RFC3339)
+		input.PurgeAfter = archived.UTC().AddDate(0, 0, Config.CommunityRetentionDays).Format(time.RFC3339)
+	}
 }
 
 func (input *Community) clean() {
 	input.StripeSubscriptionID = ""
 	input.PlanDiscountPercent = 0
 	input.JoinCode = ""
+	input.PendingOwnerID = 0
+}
+
+// processForAPI ensures data consistency
+func (input *CommunityUserLink) processForAPI() {
+	if input.Joined == "1970-01-01 00:00:00" {
+		input.Joined = ""
+	} else {
+		input.Joined, _ = ParseTimeToISO(input.Joined)
+	}
 }
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"strconv"
@@ -64,6 +67,8 @@ func CreateCommunityRoute(w http.ResponseWriter, r *http.Request) {
 
 	input.PlanPaidThrough = time.Now().Format("2006-01-02")
 	input.PlanDiscountPercent = 0
+	input.OwnerID = jwtUser.ID
+	input.PendingOwnerID = 0
 
 	// verify the name doesn't already exist and isn't a
 	// reserved community name
@@ -158,16 +163,123 @@ func UpdateCommunityRoute(w http.ResponseWriter, r *http.Request) {
 		community.UserSignupStatus = input.UserSignupStatus
 	}
 
+	if input.RequestModeration == CommunityRequestModerationAuto || input.RequestModeration == CommunityRequestModerationReview {
+		community.RequestModeration = input.RequestModeration
+	}
+
+	// the request expiry goes back to the site's by passing -1
+	if input.RequestExpiryDays < 0 {
+		community.RequestExpiryDays = 0
+	} else if input.RequestExpiryDays > 0 {
+		if !IsValidPrayerRequestExpiryDays(input.RequestExpiryDays) {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_request_expiry", fmt.Sprintf("requestExpiryDays must be at most %d", PrayerRequestExpiryMaxDays), input)
+			return
+		}
+		community.RequestExpiryDays = input.RequestExpiryDays
+	}
+
+	// branding can be cleared by passing none
+	if input.AccentColor == "none" {
+		community.AccentColor = ""
+	} else if input.AccentColor != "" {
+		if !IsValidAccentColor(input.AccentColor) {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_accent_color", "accentColor must be a hex color such as #336699", input)
+			return
+		}
+		community.AccentColor = strings.ToLower(input.AccentColor)
+	}
+
+	if input.EmailFooter == "none" {
+		community.EmailFooter = ""
+	} else if input.EmailFooter != "" {
+		community.EmailFooter, _ = sanitize(input.EmailFooter)
+		if len(community.EmailFooter) > CommunityEmailFooterMaxLength {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_email_footer", fmt.Sprintf("emailFooter must be at most %d characters", CommunityEmailFooterMaxLength), input)
+			return
+		}
+	}
+
+	// directory listing; the text fields can be cleared by passing none, and clearing the city clears the coordinates as well
+	if input.Categories != nil {
+		for i := range input.Categories {
+			input.Categories[i] = strings.ToLower(input.Categories[i])
+			if !IsValidCommunityCategory(input.Categories[i]) {
+				SendError(w, http.StatusBadRequest, "community_update_invalid_category", "that category is not allowed", GetCommunityCategories())
+				return
+			}
+		}
+	}
+
+	if input.Denomination == "none" {
+		community.Denomination = ""
+	} else if input.Denomination != "" {
+		community.Denomination, _ = sanitize(input.Denomination)
+		if len(community.Denomination) > CommunityDenominationMaxLength {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_denomination", fmt.Sprintf("denomination must be at most %d characters", CommunityDenominationMaxLength), input)
+			return
+		}
+	}
+
+	if input.Language == "none" {
+		community.Language = ""
+	} else if input.Language != "" {
+		input.Language = strings.ToLower(input.Language)
+		if !IsValidCommunityLanguage(input.Language) {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_language", "language must be a two letter code such as en", input)
+			return
+		}
+		community.Language = input.Language
+	}
+
+	if input.Region == "none" {
+		community.Region = ""
+	} else if input.Region != "" {
+		community.Region, _ = sanitize(input.Region)
+		if len(community.Region) > CommunityLocationMaxLength {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_location", fmt.Sprintf("region must be at most %d characters", CommunityLocationMaxLength), input)
+			return
+		}
+	}
+
+	if input.City == "none" {
+		community.City = ""
+		community.Latitude = nil
+		community.Longitude = nil
+	} else if input.City != "" {
+		community.City, _ = sanitize(input.City)
+		if len(community.City) > CommunityLocationMaxLength {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_location", fmt.Sprintf("city must be at most %d characters", CommunityLocationMaxLength), input)
+			return
+		}
+	}
+
+	if input.Latitude != nil || input.Longitude != nil {
+		if input.Latitude == nil || input.Longitude == nil || !IsValidCoordinate(*input.Latitude, *input.Longitude) {
+			SendError(w, http.StatusBadRequest, "community_update_invalid_location", "latitude and longitude must be provided together and be in range", input)
+			return
+		}
+		community.Latitude = input.Latitude
+		community.Longitude = input.Longitude
+	}
+
 	err = UpdateCommunity(community)
 	if err != nil {
 		SendError(w, http.StatusForbidden, "community_update_error", "could not update that community", err)
 		return
 	}
+	if input.Categories != nil {
+		err = SetCommunityCategories(communityID, input.Categories)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_update_error", "could not update the categories", err)
+			return
+		}
+	}
+	community.Categories, _ = GetCommunityCategoriesForCommunity(communityID)
 	Send(w, http.StatusOK, community)
 	return
 }
 
-// DeleteCommunityRoute deletes a community, links to the prayers, and any users on the group
+// DeleteCommunityRoute archives a community, hiding it from its members; the owner or a platform admin can restore it until it is purged
 func DeleteCommunityRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -181,7 +293,7 @@ func DeleteCommunityRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = GetCommunityByID(communityID)
+	community, err := GetCommunityByID(communityID)
 	if err != nil {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
 		return
@@ -193,14 +305,17 @@ func DeleteCommunityRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = DeleteCommunity(communityID)
+	// the community is archived rather than deleted so that a mistake can be undone; it is purged once the retention window passes
+	err = ArchiveCommunity(community, jwtUser.ID)
 	if err != nil {
 		SendError(w, http.StatusForbidden, "community_delete_error", "could not delete that community", err)
 		return
 	}
 
-	Send(w, http.StatusOK, map[string]bool{
-		"deleted": true,
+	community, _ = GetCommunityByID(communityID)
+	Send(w, http.StatusOK, map[string]interface{}{
+		"archived":   true,
+		"purgeAfter": community.PurgeAfter,
 	})
 	return
 
@@ -225,6 +340,10 @@ func GetCommunityByIDRoute(w http.ResponseWriter, r *http.Request) {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
 		return
 	}
+	if community.IsArchived() {
+		SendError(w, http.StatusForbidden, "community_archived", "that community has been archived", nil)
+		return
+	}
 
 	role, joinErr := GetUserRoleForCommunity(communityID, jwtUser.ID)
 
@@ -233,8 +352,11 @@ func GetCommunityByIDRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	community.Categories, _ = GetCommunityCategoriesForCommunity(communityID)
+
 	// if the user is not an admin, we strip out some field
 	if role != "admin" {
+		community.PendingOwnerID = 0
 		community.JoinCode = ""
 		community.ShortCode = ""
 		community.UserSignupStatus = ""
@@ -261,20 +383,153 @@ func GetCommunitiesForUserRoute(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-// GetPublicCommunitiesRoute gets the public, non-site communities
+// GetPublicCommunitiesRoute searches the public directory. In addition to the standard paging and sorting, it accepts:
+// q - text to search for
+// category, denomination, language, region - exact filters
+// latitude, longitude - a point to sort by distance from (sortField=distance)
+// radius - when a point is provided, only communities within this many kilometers are returned
 func GetPublicCommunitiesRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
+	if !CheckPageCursor(w, r) {
+		return
+	}
 	_, _, count, offset, sortField, sortDir, _, _ := ProcessQuery(r)
-	communities, _ := GetPublicCommunities(sortField, sortDir, count, offset)
+
+	query := r.URL.Query()
+	filter := CommunityDirectoryFilter{
+		Query:        strings.TrimSpace(query.Get("q")),
+		Category:     strings.ToLower(query.Get("category")),
+		Denomination: query.Get("denomination"),
+		Language:     strings.ToLower(query.Get("language")),
+		Region:       query.Get("region"),
+	}
+
+	if query.Get("latitude") != "" || query.Get("longitude") != "" {
+		latitude, latErr := strconv.ParseFloat(query.Get("latitude"), 64)
+		longitude, lonErr := strconv.ParseFloat(query.Get("longitude"), 64)
+		if latErr != nil || lonErr != nil || !IsValidCoordinate(latitude, longitude) {
+			SendError(w, http.StatusBadRequest, "community_directory_invalid_location", "latitude and longitude must be provided together and be in range", nil)
+			return
+		}
+		filter.Latitude = &latitude
+		filter.Longitude = &longitude
+	}
+	if query.Get("radius") != "" {
+		radius, err := strconv.ParseFloat(query.Get("radius"), 64)
+		if err != nil || radius <= 0 || filter.Latitude == nil {
+			SendError(w, http.StatusBadRequest, "community_directory_invalid_radius", "radius must be a positive number of kilometers and requires latitude and longitude", nil)
+			return
+		}
+		filter.RadiusKM = radius
+	}
+
+	communities, err := SearchPublicCommunities(filter, sortField, sortDir, count, offset)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_directory_error", "could not search the directory", err)
+		return
+	}
+	SendPage(w, r, http.StatusOK, communities, offsetPage(count, offset, len(communities)))
+	return
+}
+
+// GetCommunityCategoriesRoute gets the categories a community can be listed under
+func GetCommunityCategoriesRoute(w http.ResponseWriter, r *http.Request) {
+	Send(w, http.StatusOK, GetCommunityCategories())
+	return
+}
+
+// GetMyPendingCommunitiesRoute gets the outstanding invitations and join requests for the current user
+func GetMyPendingCommunitiesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communities, _ := GetPendingCommunitiesForUser(jwtUser.ID)
 	Send(w, http.StatusOK, communities)
 	return
 }
 
-// RequestCommunityMembershipRoute allows a user to request membership in a public community
+// LeaveCommunityRoute allows a user to manage their own membership without an admin. Depending on the link status, the user
+// leaves the community, withdraws a pending join request, or dismisses an invitation. If removeRequests=true is passed on the
+// query string, the user's prayer requests are also removed from the community
+func LeaveCommunityRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil || community.IsArchived() {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	link, err := GetCommunityUserLink(communityID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_user_link_not_found", "you are not a member of that community", nil)
+		return
+	}
+
+	if link.Status == CommunityUserLinkStatusAccepted {
+		if community.OwnerID == jwtUser.ID {
+			SendError(w, http.StatusConflict, "community_owner_cannot_be_removed", "the owner must transfer ownership before leaving", nil)
+			return
+		}
+		if IsLastAdminInCommunity(communityID, jwtUser.ID) {
+			SendError(w, http.StatusConflict, "community_last_admin", "the community must have at least one admin", nil)
+			return
+		}
+	}
+
+	removeRequests, _ := strconv.ParseBool(r.URL.Query().Get("removeRequests"))
+	if removeRequests {
+		err = RemoveUsersPrayerRequestsFromCommunity(jwtUser.ID, communityID)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_leave_error", "could not remove your requests from that community", err)
+			return
+		}
+	}
+
+	err = DeleteCommunityUserLink(communityID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_leave_error", "could not leave that community", err)
+		return
+	}
+	// a pending transfer to someone who left can't be accepted, so it is cancelled
+	if community.PendingOwnerID == jwtUser.ID {
+		SetCommunityPendingOwner(communityID, 0)
+	}
+	PromoteCommunityWaitlist(communityID)
+
+	result := "left"
+	if link.Status == CommunityUserLinkStatusRequested || link.Status == CommunityUserLinkStatusWaitlisted {
+		result = "withdrawn"
+	} else if link.Status == CommunityUserLinkStatusInvited {
+		result = "dismissed"
+	}
+
+	Send(w, http.StatusOK, map[string]interface{}{
+		"result":          result,
+		"requestsRemoved": removeRequests,
+	})
+	return
+}
+
+// RequestCommunityMembershipRoute allows a user to request membership in a public community. If the community is full,
+// the user is put on its waitlist instead
 func RequestCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -294,15 +549,18 @@ func RequestCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	community, err := GetCommunityByID(communityID)
-	if err != nil {
+	if err != nil || community.IsArchived() {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
 		return
 	}
 
-	// figure out the plan status and number of users allowed
+	// figure out the plan status and number of users allowed; people asking to join a full community are put on the waitlist.
+	// The community stays full while anyone is waiting so newcomers can't take a seat ahead of them
 	plan := plans[community.Plan]
 	currentCount, _ := GetCountOfUsersInCommunity(community.ID)
-	if plan.AllowedUsers <= currentCount {
+	waitlistCount, _ := GetCountOfCommunityWaitlist(community.ID)
+	full := plan.AllowedUsers <= currentCount || waitlistCount > 0
+	if full && userID != jwtUser.ID {
 		SendError(w, http.StatusForbidden, "membership_full", "this community cannot accept anymore members", map[string]interface{}{
 			"currentCount": currentCount,
 			"allowed":      plan.AllowedUsers,
@@ -317,12 +575,65 @@ func RequestCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 			SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
 			return
 		}
+		// asking again doesn't change anything; people already waiting just get their place in line
+		existing, existingErr := GetCommunityUserLink(community.ID, jwtUser.ID)
+		if existingErr == nil && existing.Status == CommunityUserLinkStatusWaitlisted {
+			Send(w, http.StatusOK, map[string]interface{}{
+				"waitlisted": true,
+				"position":   existing.WaitlistPosition,
+			})
+			return
+		}
+		if existingErr == nil && (existing.Status == CommunityUserLinkStatusAccepted || existing.Status == CommunityUserLinkStatusRequested) {
+			SendError(w, http.StatusConflict, "membership_exists", "you have already joined or asked to join this community", map[string]interface{}{
+				"status": existing.Status,
+			})
+			return
+		}
+		// the questionnaire, if there is one, must be answered before joining
+		questionnaire, err := GetCommunityQuestionnaire(community.ID)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "membership_request_error", "could not request membership", err)
+			return
+		}
+		input := communityMembershipRequestInput{}
+		render.Bind(r, &input)
+		answers, err := questionnaire.ValidateAnswers(input.Answers)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "membership_request_invalid_answers", err.Error(), questionnaire)
+			return
+		}
+
+		if full {
+			position, err := JoinCommunityWaitlist(community.ID, jwtUser.ID, GenerateShortCode(communityID, jwtUser.ID))
+			if err != nil {
+				SendError(w, http.StatusBadRequest, "membership_request_error", "could not join the waitlist", err)
+				return
+			}
+			SaveCommunityQuestionAnswers(community.ID, jwtUser.ID, answers)
+			NotifyCommunityAdmins(community.ID, NotificationTypeCommunityWaitlist, fmt.Sprintf("Someone Joined the Waitlist for %s", community.Name),
+				fmt.Sprintf("<p>%s is number %d on the waitlist for %s. Upgrade the community's plan or remove inactive members to make room.</p>",
+					jwtUser.Username, position, community.Name))
+			Send(w, http.StatusOK, map[string]interface{}{
+				"waitlisted": true,
+				"position":   position,
+			})
+			return
+		}
+
 		// if the community auto accepts, just add them
 		if community.UserSignupStatus == CommunityUserSignupStatusAccept {
 			err = CreateCommunityUserLink(community.ID, jwtUser.ID, "member", "accepted", "")
+			if err != nil {
+				SendError(w, http.StatusBadRequest, "membership_request_error", "could not join", err)
+				return
+			}
+			SaveCommunityQuestionAnswers(community.ID, jwtUser.ID, answers)
+			QueueMemberJoinedWebhookEvent(community.ID, jwtUser.ID)
 			Send(w, http.StatusOK, map[string]bool{
 				"joined": true,
 			})
+			return
 		}
 		// generate a shortCode and create the request
 		code := GenerateShortCode(communityID, jwtUser.ID)
@@ -332,6 +643,11 @@ func RequestCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 			SendError(w, http.StatusBadRequest, "membership_request_error", "could not request membership", err)
 			return
 		}
+		err = SaveCommunityQuestionAnswers(community.ID, jwtUser.ID, answers)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "membership_request_error", "could not save the answers", err)
+			return
+		}
 
 		// TODO: send an email to the admin of the community
 
@@ -382,7 +698,7 @@ func RemoveCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = GetCommunityByID(communityID)
+	community, err := GetCommunityByID(communityID)
 	if err != nil {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
 		return
@@ -395,11 +711,27 @@ func RemoveCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the owner must transfer ownership before they can be removed
+	if community.OwnerID == userID {
+		SendError(w, http.StatusConflict, "community_owner_cannot_be_removed", "the owner must transfer ownership before being removed", nil)
+		return
+	}
+
+	// we never leave a community without an admin
+	if IsLastAdminInCommunity(communityID, userID) {
+		SendError(w, http.StatusConflict, "community_last_admin", "the community must have at least one admin", nil)
+		return
+	}
+
 	err = DeleteCommunityUserLink(communityID, userID)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, "community_user_link_error", "could not delete that link", err)
 		return
 	}
+	if community.PendingOwnerID == userID {
+		SetCommunityPendingOwner(communityID, 0)
+	}
+	PromoteCommunityWaitlist(communityID)
 
 	Send(w, http.StatusOK, map[string]bool{
 		"deleted": true,
@@ -424,10 +756,11 @@ func ProcessCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 
 	// similar to requesting, there are two branches here
 	// one for an admin approving a request and one for the user approving an invitation
+	// an admin can also change the role of an accepted member
 
 	input := CommunityUserLink{}
 	render.Bind(r, &input)
-	if input.ShortCode == "" && input.Status == "" {
+	if input.ShortCode == "" && input.Status == "" && input.Role == "" {
 		SendError(w, http.StatusBadRequest, "community_user_link_error", "shortCode and status is required", input)
 		return
 	}
@@ -439,6 +772,43 @@ func ProcessCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if link.Status == "accepted" && input.Role != "" {
+		role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+		if err != nil || role != "admin" {
+			SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
+			return
+		}
+		if input.Role != CommunityUserRoleMember && input.Role != CommunityUserRoleAdmin {
+			SendError(w, http.StatusBadRequest, "community_user_link_error", "role must be member or admin", input)
+			return
+		}
+		if input.Role == CommunityUserRoleMember {
+			community, err := GetCommunityByID(communityID)
+			if err != nil {
+				SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
+				return
+			}
+			// the owner must transfer ownership before they can be demoted
+			if community.OwnerID == userID {
+				SendError(w, http.StatusConflict, "community_owner_cannot_be_removed", "the owner must transfer ownership before being demoted", nil)
+				return
+			}
+			// we never leave a community without an admin
+			if IsLastAdminInCommunity(communityID, userID) {
+				SendError(w, http.StatusConflict, "community_last_admin", "the community must have at least one admin", nil)
+				return
+			}
+		}
+		err = UpdateCommunityUserLinkRole(communityID, userID, input.Role)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_user_link_error", "could not update the role", err)
+			return
+		}
+		link, _ = GetCommunityUserLink(communityID, userID)
+		Send(w, http.StatusOK, link)
+		return
+	}
+
 	if link.Status == "accepted" {
 		SendError(w, http.StatusBadRequest, "community_user_link_already_accepted", "link is already accepted and cannot be modified here", link)
 		return
@@ -462,6 +832,9 @@ func ProcessCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 			SendError(w, http.StatusForbidden, "community_user_link_error", "could not update the link", err)
 			return
 		}
+		if input.Status == CommunityUserLinkStatusAccepted {
+			QueueMemberJoinedWebhookEvent(communityID, userID)
+		}
 
 		link, _ = GetCommunityUserLink(communityID, userID)
 		Send(w, http.StatusOK, link)
@@ -486,13 +859,19 @@ func ProcessCommunityMembershipRoute(w http.ResponseWriter, r *http.Request) {
 		SendError(w, http.StatusForbidden, "community_user_link_error", "could not update the link", err)
 		return
 	}
+	// a declined request no longer holds a seat
+	if input.Status == CommunityUserLinkStatusDeclined {
+		PromoteCommunityWaitlist(communityID)
+	} else if input.Status == CommunityUserLinkStatusAccepted {
+		QueueMemberJoinedWebhookEvent(communityID, userID)
+	}
 
 	link, _ = GetCommunityUserLink(communityID, userID)
 	Send(w, http.StatusOK, link)
 	return
 }
 
-// GetCommunityLinksRoute gets the links for a community
+// GetCommunityLinksRoute gets a page of the links for a community
 func GetCommunityLinksRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -524,14 +903,384 @@ func GetCommunityLinksRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	links, _ := GetCommunityUserLinks(communityID, status)
+	// the waitlist pages by offset; every other status pages by username
+	waitlist := status == CommunityUserLinkStatusWaitlisted
+	var cursor *PageCursor
+	ok := true
+	if waitlist {
+		ok = CheckPageCursor(w, r)
+	} else {
+		cursor, ok = GetKeysetPageCursor(w, r, true)
+	}
+	if !ok {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	links, _ := GetCommunityUserLinks(communityID, status, cursor, count, offset)
 	// if they are an admin, remove all of the short codes
 	if role != "admin" {
 		for i := range links {
 			links[i].ShortCode = ""
 		}
+	} else {
+		// admins see the questionnaire answers alongside each link
+		answers, _ := GetCommunityQuestionAnswers(communityID)
+		for i := range links {
+			links[i].Answers = answers[links[i].UserID]
+		}
+	}
+
+	page := offsetPage(count, offset, len(links))
+	if !waitlist {
+		page = keysetPage(cursor, count, offset, len(links), func(i int) PageCursor { return PageCursor{Key: links[i].Username} })
+	}
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfCommunityUserLinks(communityID, status)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, links, page)
+	return
+}
+
+// TransferCommunityOwnershipRoute starts a transfer of ownership from the current owner to another accepted member. The
+// recipient must accept the transfer before it takes effect
+func TransferCommunityOwnershipRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	userID, userIDErr := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if communityIDErr != nil || userIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil || community.OwnerID != jwtUser.ID {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if userID == jwtUser.ID {
+		SendError(w, http.StatusBadRequest, "community_owner_transfer_error", "you already own this community", nil)
+		return
+	}
+
+	// the recipient must already be in the community
+	link, err := GetCommunityUserLink(communityID, userID)
+	if err != nil || link.Status != CommunityUserLinkStatusAccepted {
+		SendError(w, http.StatusBadRequest, "community_owner_transfer_error", "ownership can only be transferred to a member of the community", nil)
+		return
+	}
+
+	err = SetCommunityPendingOwner(communityID, userID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_owner_transfer_error", "could not start the transfer", err)
+		return
+	}
+
+	emailContent := fmt.Sprintf(`<p>You have been asked to become the owner of the %s community on <a href="%s">Pregxas</a>.</p>
+	<p>As the owner, you will be responsible for the community, including its subscription and billing. Please log in to accept or decline the transfer.</p>
+	<p>Thanks!</p>
+	`, community.Name, Config.WebURL)
+	emailBody := GenerateEmail(communityID, emailContent)
+	SendEmail(link.Email, "Community Ownership Transfer", emailBody)
+
+	Send(w, http.StatusOK, map[string]interface{}{
+		"pendingOwnerId": userID,
+	})
+	return
+}
+
+// CancelCommunityOwnershipTransferRoute cancels a pending transfer; it can be called by the owner or the recipient
+func CancelCommunityOwnershipTransferRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil || (community.OwnerID != jwtUser.ID && community.PendingOwnerID != jwtUser.ID) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = SetCommunityPendingOwner(communityID, 0)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_owner_transfer_error", "could not cancel the transfer", err)
+		return
+	}
+
+	Send(w, http.StatusOK, map[string]bool{
+		"cancelled": true,
+	})
+	return
+}
+
+// AcceptCommunityOwnershipRoute allows the recipient of a pending transfer to accept it. The previous owner remains an admin
+func AcceptCommunityOwnershipRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
 	}
 
-	Send(w, http.StatusOK, links)
+	community, err := GetCommunityByID(communityID)
+	if err != nil || community.IsArchived() || community.PendingOwnerID == 0 || community.PendingOwnerID != jwtUser.ID {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = SetCommunityOwner(communityID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_owner_transfer_error", "could not accept the transfer", err)
+		return
+	}
+
+	community, _ = GetCommunityByID(communityID)
+	Send(w, http.StatusOK, community)
+	return
+}
+
+// GetOrphanedCommunitiesRoute gets the communities without an owner or admin so a platform admin can recover them
+func GetOrphanedCommunitiesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communities, err := GetOrphanedCommunities()
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_orphaned_error", "could not get the orphaned communities", err)
+		return
+	}
+	Send(w, http.StatusOK, communities)
+	return
+}
+
+// AssignCommunityOwnerRoute allows a platform admin to assign a new owner to a community, such as when it has been orphaned.
+// Unlike a transfer, this takes effect immediately
+func AssignCommunityOwnerRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	userID, userIDErr := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if communityIDErr != nil || userIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	_, err = GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	_, err = GetUserByID(userID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_owner_assign_error", "that user does not exist", nil)
+		return
+	}
+
+	err = SetCommunityOwner(communityID, userID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_owner_assign_error", "could not assign that owner", err)
+		return
+	}
+
+	community, _ := GetCommunityByID(communityID)
+	Send(w, http.StatusOK, community)
+	return
+}
+
+// UploadCommunityLogoRoute allows an admin to upload a logo for the community as the logo field of a multipart form. The logo
+// must be a PNG, JPEG, or GIF image no larger than CommunityLogoMaxBytes
+func UploadCommunityLogoRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, CommunityLogoMaxBytes+1024)
+	file, _, err := r.FormFile("logo")
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_logo_missing", fmt.Sprintf("a logo file no larger than %d bytes is required", CommunityLogoMaxBytes), nil)
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(file, CommunityLogoMaxBytes+1))
+	if err != nil || len(data) > CommunityLogoMaxBytes {
+		SendError(w, http.StatusBadRequest, "community_logo_too_large", fmt.Sprintf("the logo must be no larger than %d bytes", CommunityLogoMaxBytes), nil)
+		return
+	}
+
+	extension, ok := communityLogoTypes[http.DetectContentType(data)]
+	if !ok {
+		SendError(w, http.StatusBadRequest, "community_logo_invalid_type", "the logo must be a png, jpeg, or gif image", nil)
+		return
+	}
+
+	// each upload gets a new key so that cached copies of the old logo are not shown
+	key := fmt.Sprintf("communities/%d/logo-%d%s", communityID, time.Now().UnixNano(), extension)
+	err = Config.Storage.Save(key, bytes.NewReader(data))
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "community_logo_save_error", "could not save that logo", err)
+		return
+	}
+	err = UpdateCommunityLogo(communityID, key)
+	if err != nil {
+		Config.Storage.Delete(key)
+		SendError(w, http.StatusBadRequest, "community_logo_save_error", "could not save that logo", err)
+		return
+	}
+	if community.Logo != "" {
+		Config.Storage.Delete(community.Logo)
+	}
+
+	community, _ = GetCommunityByID(communityID)
+	Send(w, http.StatusOK, community)
+	return
+}
+
+// DeleteCommunityLogoRoute allows an admin to remove the community's logo
+func DeleteCommunityLogoRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = UpdateCommunityLogo(communityID, "")
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_logo_delete_error", "could not remove that logo", err)
+		return
+	}
+	if community.Logo != "" {
+		Config.Storage.Delete(community.Logo)
+	}
+
+	community, _ = GetCommunityByID(communityID)
+	Send(w, http.StatusOK, community)
+	return
+}
+
+// RestoreCommunityRoute restores an archived community; only the owner or a platform admin can restore a community
+func RestoreCommunityRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil || (community.OwnerID != jwtUser.ID && jwtUser.PlatformRole != "admin") {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if !community.IsArchived() {
+		SendError(w, http.StatusConflict, "community_not_archived", "that community is not archived", nil)
+		return
+	}
+
+	err = RestoreCommunity(communityID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_restore_error", "could not restore that community", err)
+		return
+	}
+
+	community, _ = GetCommunityByID(communityID)
+	Send(w, http.StatusOK, community)
+	return
+}
+
+// GetMyArchivedCommunitiesRoute gets the archived communities the user owns so they can be restored
+func GetMyArchivedCommunitiesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communities, _ := GetArchivedCommunities(jwtUser.ID)
+	Send(w, http.StatusOK, communities)
+	return
+}
+
+// GetArchivedCommunitiesRoute gets all of the archived communities for a platform admin
+func GetArchivedCommunitiesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communities, _ := GetArchivedCommunities(0)
+	Send(w, http.StatusOK, communities)
 	return
 }
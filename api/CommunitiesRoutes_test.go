@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"mime/multipart"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/mapstructure"
@@ -308,3 +312,576 @@ func TestBadCommunityRouteCalls(t *testing.T) {
 	assert.Equal(t, http.StatusForbidden, code)
 
 }
+
+func TestCommunityOwnershipRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	owner := User{}
+	err := CreateTestUser(&owner)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&owner)
+
+	user := User{}
+	err = CreateTestUser(&user)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+
+	platformAdmin := User{
+		PlatformRole: "admin",
+	}
+	err = CreateTestUser(&platformAdmin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&platformAdmin)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   owner.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, owner.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, user.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// the owner cannot remove themselves, and the last admin cannot be removed
+	code, _, _ := TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/users/%d", community.ID, owner.ID), b, RemoveCommunityMembershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// admins can change the roles of accepted members, but the owner can't be demoted
+	enc := json.NewEncoder(b)
+	enc.Encode(map[string]string{"role": CommunityUserRoleAdmin})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/users/%d", community.ID, user.ID), b, ProcessCommunityMembershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	b.Reset()
+	enc.Encode(map[string]string{"role": CommunityUserRoleAdmin})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/users/%d", community.ID, user.ID), b, ProcessCommunityMembershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	role, _ := GetUserRoleForCommunity(community.ID, user.ID)
+	assert.Equal(t, CommunityUserRoleAdmin, role)
+	b.Reset()
+	enc.Encode(map[string]string{"role": CommunityUserRoleMember})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/users/%d", community.ID, owner.ID), b, ProcessCommunityMembershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+	b.Reset()
+	enc.Encode(map[string]string{"role": CommunityUserRoleMember})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/users/%d", community.ID, user.ID), b, ProcessCommunityMembershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	role, _ = GetUserRoleForCommunity(community.ID, user.ID)
+	assert.Equal(t, CommunityUserRoleMember, role)
+
+	// nor can the last admin of a community without an owner
+	orphaned := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&orphaned)
+	require.Nil(t, err)
+	defer DeleteCommunity(orphaned.ID)
+	CreateCommunityUserLink(orphaned.ID, user.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	b.Reset()
+	enc.Encode(map[string]string{"role": CommunityUserRoleMember})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/users/%d", orphaned.ID, user.ID), b, ProcessCommunityMembershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+	b.Reset()
+
+	// only the owner can start a transfer
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/owner/%d", community.ID, user.ID), b, TransferCommunityOwnershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/owner/%d", community.ID, platformAdmin.ID), b, TransferCommunityOwnershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/owner/%d", community.ID, user.ID), b, TransferCommunityOwnershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// nothing changes until it is accepted, and only the recipient can accept it
+	found, _ := GetCommunityByID(community.ID)
+	assert.Equal(t, owner.ID, found.OwnerID)
+	assert.Equal(t, user.ID, found.PendingOwnerID)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/owner", community.ID), b, AcceptCommunityOwnershipRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/owner", community.ID), b, AcceptCommunityOwnershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	found, _ = GetCommunityByID(community.ID)
+	assert.Equal(t, user.ID, found.OwnerID)
+	assert.Zero(t, found.PendingOwnerID)
+	role, _ = GetUserRoleForCommunity(community.ID, user.ID)
+	assert.Equal(t, CommunityUserRoleAdmin, role)
+
+	// now the old owner is just an admin and can be removed
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/users/%d", community.ID, owner.ID), b, RemoveCommunityMembershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// orphan it and recover it
+	UpdateCommunityUserLinkRole(community.ID, user.ID, CommunityUserRoleMember)
+	code, _, _ = TestAPICall(http.MethodGet, "/admin/communities/orphaned", b, GetOrphanedCommunitiesRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ := TestAPICall(http.MethodGet, "/admin/communities/orphaned", b, GetOrphanedCommunitiesRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.NotZero(t, len(bodyA))
+
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/admin/communities/%d/owner/%d", community.ID, platformAdmin.ID), b, AssignCommunityOwnerRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/admin/communities/%d/owner/%d", community.ID, platformAdmin.ID), b, AssignCommunityOwnerRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	found, _ = GetCommunityByID(community.ID)
+	assert.Equal(t, platformAdmin.ID, found.OwnerID)
+	role, _ = GetUserRoleForCommunity(community.ID, platformAdmin.ID)
+	assert.Equal(t, CommunityUserRoleAdmin, role)
+}
+
+func TestLeaveCommunityRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	owner := User{}
+	err := CreateTestUser(&owner)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&owner)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	invited := User{}
+	err = CreateTestUser(&invited)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&invited)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   owner.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, owner.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, invited.ID, CommunityUserRoleMember, CommunityUserLinkStatusInvited, "abc")
+
+	request := PrayerRequest{
+		Title:     "Leaving",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+
+	// the invited user sees the invitation in their pending list
+	code, res, _ := TestAPICall(http.MethodGet, "/me/communities/pending", b, GetMyPendingCommunitiesRoute, invited.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+	pending := Community{}
+	mapstructure.Decode(bodyA[0], &pending)
+	assert.Equal(t, community.ID, pending.ID)
+	assert.Equal(t, CommunityUserLinkStatusInvited, pending.UserStatus)
+
+	// bad calls
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, "/me/communities/a", b, LeaveCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// the owner cannot leave
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// dismiss the invite
+	code, res, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, invited.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, "dismissed", body["result"])
+
+	// the member leaves and takes their requests with them; a transfer waiting on them is cancelled
+	err = SetCommunityPendingOwner(community.ID, member.ID)
+	require.Nil(t, err)
+	code, res, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d?removeRequests=true", community.ID), b, LeaveCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, "left", body["result"])
+	_, err = GetCommunityUserLink(community.ID, member.ID)
+	assert.NotNil(t, err)
+	found, err := GetCommunityByID(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), found.PendingOwnerID)
+	comms, _ := GetCommunitiesPrayerRequestIsIn(request.ID)
+	assert.Zero(t, len(comms))
+
+	// leaving twice is not found
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCommunityBrandingRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      "Branding",
+		ShortCode: fmt.Sprintf("brand_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// accent color and footer validation
+	b.Reset()
+	enc.Encode(map[string]string{"accentColor": "blue"})
+	code, _, _ := TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{"emailFooter": strings.Repeat("a", CommunityEmailFooterMaxLength+1)})
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{"accentColor": "#AABBCC", "emailFooter": "<b>Thanks</b><script>alert('x')</script>"})
+	code, res, _ := TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, "#aabbcc", body["accentColor"])
+	assert.False(t, strings.Contains(body["emailFooter"].(string), "<script>"))
+
+	b.Reset()
+	enc.Encode(map[string]string{"accentColor": "none", "emailFooter": "none"})
+	code, res, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Nil(t, body["accentColor"])
+	assert.Nil(t, body["emailFooter"])
+
+	// logo uploads
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 32)...)
+	code, _ = testLogoUpload(t, community.ID, png, member.JWT)
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = testLogoUpload(t, community.ID, []byte("just some text"), admin.JWT)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = testLogoUpload(t, community.ID, make([]byte, CommunityLogoMaxBytes+1), admin.JWT)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, res = testLogoUpload(t, community.ID, png, admin.JWT)
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	logoURL := body["logoUrl"].(string)
+	assert.True(t, strings.HasSuffix(logoURL, ".png"))
+
+	found, err := GetCommunityByID(community.ID)
+	require.Nil(t, err)
+	key := found.Logo
+	assetPath := strings.TrimPrefix(logoURL, Config.RootAPIURL)
+	code, _, _ = TestAPICall(http.MethodGet, assetPath, b, GetAssetRoute, "", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/logo", community.ID), b, DeleteCommunityLogoRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/logo", community.ID), b, DeleteCommunityLogoRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Nil(t, body["logoUrl"])
+	_, err = Config.Storage.Open(key)
+	assert.NotNil(t, err)
+}
+
+func testLogoUpload(t *testing.T, communityID int64, data []byte, jwt string) (int, *bytes.Buffer) {
+	b := new(bytes.Buffer)
+	writer := multipart.NewWriter(b)
+	part, err := writer.CreateFormFile("logo", "logo")
+	require.Nil(t, err)
+	part.Write(data)
+	writer.Close()
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("/communities/%d/logo", communityID), b)
+	require.Nil(t, err)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.Header.Add("jwt", jwt)
+	rr := httptest.NewRecorder()
+	SetupApp().ServeHTTP(rr, req)
+	return rr.Code, rr.Body
+}
+
+func TestCommunityArchiveRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	owner := User{}
+	err := CreateTestUser(&owner)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&owner)
+	admin := User{}
+	err = CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	platformAdmin := User{
+		PlatformRole: "admin",
+	}
+	err = CreateTestUser(&platformAdmin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&platformAdmin)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   owner.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, owner.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// restoring a community that is not archived is a conflict
+	code, _, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/restore", community.ID), b, RestoreCommunityRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// members cannot archive, admins can
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d", community.ID), b, DeleteCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ := TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d", community.ID), b, DeleteCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, true, body["archived"])
+	assert.NotEqual(t, "", body["purgeAfter"])
+
+	// it is now hidden and read-only
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d", community.ID), b, GetCommunityByIDRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/requests", community.ID), b, GetCommunityPrayerRequestsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodGet, "/communities", b, GetCommunitiesForUserRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 0, len(bodyA))
+
+	// the owner and platform admins can find it
+	code, res, _ = TestAPICall(http.MethodGet, "/me/communities/archived", b, GetMyArchivedCommunitiesRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+	code, res, _ = TestAPICall(http.MethodGet, "/me/communities/archived", b, GetMyArchivedCommunitiesRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 0, len(bodyA))
+	code, _, _ = TestAPICall(http.MethodGet, "/admin/communities/archived", b, GetArchivedCommunitiesRoute, owner.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodGet, "/admin/communities/archived", b, GetArchivedCommunitiesRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	found := false
+	for i := range bodyA {
+		if int64(bodyA[i].(map[string]interface{})["id"].(float64)) == community.ID {
+			found = true
+		}
+	}
+	assert.True(t, found)
+
+	// only the owner or a platform admin can restore it
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/restore", community.ID), b, RestoreCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/restore", community.ID), b, RestoreCommunityRoute, owner.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Nil(t, body["archived"])
+
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d", community.ID), b, GetCommunityByIDRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// archive and restore by a platform admin
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d", community.ID), b, DeleteCommunityRoute, owner.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/restore", community.ID), b, RestoreCommunityRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestCommunityDirectoryRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	randID := rand.Int63n(999999999)
+	community := Community{
+		Name:      fmt.Sprintf("Directory_%d", randID),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		Privacy:   CommunityPrivacyPublic,
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+
+	code, res, _ := TestAPICall(http.MethodGet, "/communities/categories", b, GetCommunityCategoriesRoute, "", "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, len(GetCommunityCategories()), len(bodyA))
+
+	// bad listings
+	bad := []map[string]interface{}{
+		{"categories": []string{"book_club"}},
+		{"language": "english"},
+		{"latitude": 35.2},
+		{"latitude": 135.2, "longitude": 10},
+		{"denomination": strings.Repeat("a", CommunityDenominationMaxLength+1)},
+	}
+	for i := range bad {
+		b.Reset()
+		enc.Encode(bad[i])
+		code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("%v", bad[i]))
+	}
+
+	region := fmt.Sprintf("Region %d", randID)
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"categories":   []string{"Church", "youth"},
+		"denomination": "Methodist",
+		"language":     "EN",
+		"city":         "Charlotte",
+		"region":       region,
+		"latitude":     35.2271,
+		"longitude":    -80.8431,
+	})
+	code, res, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, []interface{}{"church", "youth"}, body["categories"])
+	assert.Equal(t, "en", body["language"])
+	assert.Equal(t, 35.2271, body["latitude"])
+
+	// search the directory
+	code, _, _ = TestAPICall(http.MethodGet, "/communities/public?latitude=35", b, GetPublicCommunitiesRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/communities/public?radius=10", b, GetPublicCommunitiesRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/public?region=%s&category=youth&language=en", url.QueryEscape(region)), b, GetPublicCommunitiesRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/public?region=%s&latitude=33.749&longitude=-84.388&radius=500&sortField=distance", url.QueryEscape(region)), b, GetPublicCommunitiesRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+	assert.InDelta(t, 365, bodyA[0].(map[string]interface{})["distance"].(float64), 5)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/public?region=%s&latitude=33.749&longitude=-84.388&radius=100", url.QueryEscape(region)), b, GetPublicCommunitiesRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 0, len(bodyA))
+
+	// clearing the city clears the coordinates
+	b.Reset()
+	enc.Encode(map[string]interface{}{"city": "none", "categories": []string{}})
+	code, res, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/communities/%d", community.ID), b, UpdateCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Nil(t, body["city"])
+	assert.Nil(t, body["latitude"])
+	assert.Nil(t, body["categories"])
+}
+
+func TestCommunityWaitlistRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	original := plans[CommunityPlanFree]
+	plans[CommunityPlanFree] = CommunityPlan{AllowedUsers: 2, AllowedActiveRequests: original.AllowedActiveRequests, AllowedSubGroups: original.AllowedSubGroups}
+	defer func() { plans[CommunityPlanFree] = original }()
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	waiting := User{}
+	err = CreateTestUser(&waiting)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&waiting)
+
+	community := Community{
+		Name:             fmt.Sprintf("Waitlist_%d", rand.Int63n(999999999)),
+		ShortCode:        fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		Privacy:          CommunityPrivacyPublic,
+		Plan:             CommunityPlanFree,
+		UserSignupStatus: CommunityUserSignupStatusApproval,
+		OwnerID:          admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// the community is full, so the user is waitlisted
+	code, res, _ := TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, waiting.ID), b, RequestCommunityMembershipRoute, waiting.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, true, body["waitlisted"])
+	assert.Equal(t, float64(1), body["position"])
+
+	// asking again keeps their place in line
+	code, res, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, waiting.ID), b, RequestCommunityMembershipRoute, waiting.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, true, body["waitlisted"])
+	assert.Equal(t, float64(1), body["position"])
+
+	// members can't ask to join again
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, member.ID), b, RequestCommunityMembershipRoute, member.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// admins can't invite more people into a full community
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, waiting.ID), b, RequestCommunityMembershipRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/users?status=waitlisted", community.ID), b, GetCommunityLinksRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+	assert.Equal(t, float64(1), bodyA[0].(map[string]interface{})["waitlistPosition"])
+
+	// removing the member frees a seat; the community requires approval so the user becomes a request
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/users/%d", community.ID, member.ID), b, RemoveCommunityMembershipRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	link, err := GetCommunityUserLink(community.ID, waiting.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityUserLinkStatusRequested, link.Status)
+	assert.Equal(t, int64(0), link.WaitlistPosition)
+}
@@ -125,3 +125,169 @@ func TestCommunityUserLinks(t *testing.T) {
 	require.Zero(t, len(comms))
 
 }
+
+func TestCommunityOwnership(t *testing.T) {
+	ConfigSetup()
+	randID := rand.Int63n(999999999)
+	owner := User{}
+	err := CreateTestUser(&owner)
+	assert.Nil(t, err)
+	defer DeleteUser(owner.ID)
+
+	user := User{}
+	err = CreateTestUser(&user)
+	assert.Nil(t, err)
+	defer DeleteUser(user.ID)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", randID),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   owner.ID,
+	}
+	err = CreateCommunity(&community)
+	assert.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	err = CreateCommunityUserLink(community.ID, owner.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	assert.Nil(t, err)
+	err = CreateCommunityUserLink(community.ID, user.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	assert.Nil(t, err)
+
+	found, err := GetCommunityByID(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, owner.ID, found.OwnerID)
+	assert.Zero(t, found.PendingOwnerID)
+
+	// the owner is the only admin
+	count, err := GetCountOfAdminsInCommunity(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.True(t, IsLastAdminInCommunity(community.ID, owner.ID))
+	assert.False(t, IsLastAdminInCommunity(community.ID, user.ID))
+
+	// start a transfer
+	err = SetCommunityPendingOwner(community.ID, user.ID)
+	assert.Nil(t, err)
+	found, err = GetCommunityByID(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, owner.ID, found.OwnerID)
+	assert.Equal(t, user.ID, found.PendingOwnerID)
+
+	// complete it
+	err = SetCommunityOwner(community.ID, user.ID)
+	assert.Nil(t, err)
+	found, err = GetCommunityByID(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, user.ID, found.OwnerID)
+	assert.Zero(t, found.PendingOwnerID)
+	role, err := GetUserRoleForCommunity(community.ID, user.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, CommunityUserRoleAdmin, role)
+	assert.False(t, IsLastAdminInCommunity(community.ID, owner.ID))
+
+	// demote both and make sure it shows as orphaned
+	err = UpdateCommunityUserLinkRole(community.ID, owner.ID, CommunityUserRoleMember)
+	assert.Nil(t, err)
+	err = UpdateCommunityUserLinkRole(community.ID, user.ID, CommunityUserRoleMember)
+	assert.Nil(t, err)
+	orphaned, err := GetOrphanedCommunities()
+	assert.Nil(t, err)
+	foundOrphan := false
+	for i := range orphaned {
+		if orphaned[i].ID == community.ID {
+			foundOrphan = true
+		}
+	}
+	assert.True(t, foundOrphan)
+}
+
+type testPaymentProcessor struct {
+	cancelled []string
+}
+
+func (p *testPaymentProcessor) CancelSubscription(subscriptionID string) error {
+	p.cancelled = append(p.cancelled, subscriptionID)
+	return nil
+}
+
+func TestCommunityArchive(t *testing.T) {
+	ConfigSetup()
+	payments := &testPaymentProcessor{}
+	original := Config.Payments
+	Config.Payments = payments
+	defer func() {
+		Config.Payments = original
+	}()
+
+	owner := User{}
+	err := CreateTestUser(&owner)
+	require.Nil(t, err)
+	defer DeleteUser(owner.ID)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		Privacy:   CommunityPrivacyPublic,
+		OwnerID:   owner.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, owner.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	_, err = Config.DbConn.Exec("UPDATE Communities SET plan = ?, stripeSubscriptionId = ? WHERE id = ?", CommunityPlanPro, "sub_123", community.ID)
+	require.Nil(t, err)
+
+	found, err := GetCommunityByID(community.ID)
+	require.Nil(t, err)
+	assert.False(t, found.IsArchived())
+
+	err = ArchiveCommunity(found, owner.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"sub_123"}, payments.cancelled)
+
+	found, err = GetCommunityByID(community.ID)
+	require.Nil(t, err)
+	assert.True(t, found.IsArchived())
+	assert.NotEqual(t, "", found.PurgeAfter)
+	assert.Equal(t, owner.ID, found.ArchivedBy)
+	assert.Equal(t, CommunityPlanFree, found.Plan)
+	assert.Equal(t, "", found.StripeSubscriptionID)
+
+	// archived communities are hidden
+	_, err = GetUserRoleForCommunity(community.ID, owner.ID)
+	assert.NotNil(t, err)
+	comms, err := GetCommunitiesForUser(owner.ID)
+	require.Nil(t, err)
+	for i := range comms {
+		assert.NotEqual(t, community.ID, comms[i].ID)
+	}
+	archived, err := GetArchivedCommunities(owner.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(archived))
+	assert.Equal(t, community.ID, archived[0].ID)
+
+	// it is not purged until the retention window passes
+	err = PurgeArchivedCommunities()
+	require.Nil(t, err)
+	_, err = GetCommunityByID(community.ID)
+	assert.Nil(t, err)
+
+	err = RestoreCommunity(community.ID)
+	require.Nil(t, err)
+	found, err = GetCommunityByID(community.ID)
+	require.Nil(t, err)
+	assert.False(t, found.IsArchived())
+	assert.Equal(t, "", found.PurgeAfter)
+	role, err := GetUserRoleForCommunity(community.ID, owner.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, CommunityUserRoleAdmin, role)
+
+	// archive it again, but long enough ago that it should be purged
+	err = ArchiveCommunity(found, owner.ID)
+	require.Nil(t, err)
+	_, err = Config.DbConn.Exec("UPDATE Communities SET archived = DATE_SUB(NOW(), INTERVAL ? DAY) WHERE id = ?", Config.CommunityRetentionDays+1, community.ID)
+	require.Nil(t, err)
+	err = PurgeArchivedCommunities()
+	require.Nil(t, err)
+	_, err = GetCommunityByID(community.ID)
+	assert.NotNil(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -41,6 +42,14 @@ type ConfigStruct struct {
 	MailShouldSend    bool
 	MailFromAddress   string
 	JWTSigningString  string
+	StoragePath       string
+	Storage           StorageBackend
+	Payments          PaymentProcessor
+	Search            SearchBackend
+	// CommunityRetentionDays is how long an archived community is kept before it is purged
+	CommunityRetentionDays int
+	// FederationEnabled exposes public communities and requests over ActivityPub
+	FederationEnabled bool
 	Logger            *logrus.Logger
 }
 
@@ -63,6 +72,34 @@ func ConfigSetup() *ConfigStruct {
 
 	c.Environment = envHelper("PREGXAS_ENV", "test")
 
+	// uploads, such as community logos, are kept on disk and served through the /assets route
+	c.StoragePath = envHelper("PREGXAS_STORAGE_PATH", "./uploads")
+	c.Storage = NewLocalStorage(c.StoragePath, fmt.Sprintf("%s/assets", strings.TrimSuffix(c.RootAPIURL, "/")))
+
+	c.Payments = &ManualPaymentProcessor{}
+
+	// search uses Sonic when an address is set and the in-process index otherwise
+	searchAddress := envHelper("PREGXAS_SEARCH_ADDRESS", "")
+	if searchAddress != "" {
+		c.Search = NewSonicSearch(searchAddress, envHelper("PREGXAS_SEARCH_PASSWORD", "CHANGE_TO_SOMETHING_SAFE"))
+	} else {
+		c.Search = NewMemorySearch()
+	}
+
+	retention, err := strconv.Atoi(envHelper("PREGXAS_COMMUNITY_RETENTION_DAYS", "30"))
+	if err != nil || retention < 1 {
+		fmt.Println("Warning: Could not convert PREGXAS_COMMUNITY_RETENTION_DAYS; set as 30")
+		retention = 30
+	}
+	c.CommunityRetentionDays = retention
+
+	federation, err := strconv.ParseBool(envHelper("PREGXAS_FEDERATION_ENABLED", "false"))
+	if err != nil {
+		fmt.Println("Warning: Could not convert PREGXAS_FEDERATION_ENABLED; set as false")
+		federation = false
+	}
+	c.FederationEnabled = federation
+
 	c.MailgunPrivateKey = os.Getenv("PREGXAS_EMAIL_PRIVATE")
 	c.MailgunPublicKey = os.Getenv("PREGXAS_EMAIL_PUBLIC")
 	c.MailgunDomain = os.Getenv("PREGXAS_EMAIL_DOMAIN")
@@ -243,6 +280,13 @@ func SetupApp() *chi.Mux {
 	// user routes
 	r.Get("/me", GetMyProfileRoute)
 	r.Patch("/me", UpdateMyProfileRoute)
+	r.Get("/me/communities/pending", GetMyPendingCommunitiesRoute)    // TODO: needs OAS3 docs
+	r.Delete("/me/communities/{communityID}", LeaveCommunityRoute)    // TODO: needs OAS3 docs
+	r.Get("/me/communities/archived", GetMyArchivedCommunitiesRoute)  // TODO: needs OAS3 docs
+	r.Get("/me/notifications", GetMyNotificationPreferencesRoute)     // TODO: needs OAS3 docs
+	r.Patch("/me/notifications", UpdateMyNotificationPreferenceRoute) // TODO: needs OAS3 docs
+	r.Get("/me/vigils", GetMyPrayerVigilSignupsRoute)                 // TODO: needs OAS3 docs
+	r.Get("/me/vigils/calendar", GetMyPrayerVigilCalendarURLRoute)    // TODO: needs OAS3 docs
 	r.Post("/users/login", LoginUserRoute)
 	r.Post("/users/logout", LogoutUserRoute)
 	r.Post("/users/refresh", RefreshAccessTokenRoute)        // TODO: needs OAS3 docs
@@ -252,12 +296,14 @@ func SetupApp() *chi.Mux {
 	r.Post("/users/login/reset/verify", ResetPasswordVerifyRoute)
 
 	// communities
-	r.Post("/communities", CreateCommunityRoute)                 // TODO: needs OAS3 docs
-	r.Get("/communities", GetCommunitiesForUserRoute)            // TODO: needs OAS3 docs
-	r.Get("/communities/public", GetPublicCommunitiesRoute)      // TODO: needs OAS3 docs
-	r.Patch("/communities/{communityID}", UpdateCommunityRoute)  // TODO: needs OAS3 docs
-	r.Get("/communities/{communityID}", GetCommunityByIDRoute)   // TODO: needs OAS3 docs
-	r.Delete("/communities/{communityID}", DeleteCommunityRoute) // TODO: needs OAS3 docs
+	r.Post("/communities", CreateCommunityRoute)                        // TODO: needs OAS3 docs
+	r.Get("/communities", GetCommunitiesForUserRoute)                   // TODO: needs OAS3 docs
+	r.Get("/communities/public", GetPublicCommunitiesRoute)             // TODO: needs OAS3 docs
+	r.Get("/communities/categories", GetCommunityCategoriesRoute)       // TODO: needs OAS3 docs
+	r.Patch("/communities/{communityID}", UpdateCommunityRoute)         // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}", GetCommunityByIDRoute)          // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}", DeleteCommunityRoute)        // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/restore", RestoreCommunityRoute) // TODO: needs OAS3 docs
 
 	r.Post("/communities/{communityID}/subscribe", nil)   // TODO: implement
 	r.Delete("/communities/{communityID}/subscribe", nil) // TODO: implement
@@ -268,6 +314,35 @@ func SetupApp() *chi.Mux {
 	r.Delete("/communities/{communityID}/users/{userID}", RemoveCommunityMembershipRoute) // this is for removing a request; TODO: needs OAS3 docs
 	r.Post("/communities/{communityID}/users/{userID}", ProcessCommunityMembershipRoute)  // this is for approving; TODO: needs OAS3 docs
 
+	// webhooks
+	r.Get("/webhooks/events", GetWebhookEventsRoute)                                                                   // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/webhooks", GetWebhooksRoute)                                                     // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/webhooks", CreateWebhookRoute)                                                  // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/webhooks/{webhookID}", GetWebhookRoute)                                          // TODO: needs OAS3 docs
+	r.Patch("/communities/{communityID}/webhooks/{webhookID}", UpdateWebhookRoute)                                     // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/webhooks/{webhookID}", DeleteWebhookRoute)                                    // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/webhooks/{webhookID}/deliveries", GetWebhookDeliveriesRoute)                     // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", RedeliverWebhookRoute) // TODO: needs OAS3 docs
+	r.Get("/admin/webhooks", GetWebhooksRoute)                                                                         // TODO: needs OAS3 docs
+	r.Post("/admin/webhooks", CreateWebhookRoute)                                                                      // TODO: needs OAS3 docs
+	r.Get("/admin/webhooks/{webhookID}", GetWebhookRoute)                                                              // TODO: needs OAS3 docs
+	r.Patch("/admin/webhooks/{webhookID}", UpdateWebhookRoute)                                                         // TODO: needs OAS3 docs
+	r.Delete("/admin/webhooks/{webhookID}", DeleteWebhookRoute)                                                        // TODO: needs OAS3 docs
+	r.Get("/admin/webhooks/{webhookID}/deliveries", GetWebhookDeliveriesRoute)                                         // TODO: needs OAS3 docs
+	r.Post("/admin/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", RedeliverWebhookRoute)                     // TODO: needs OAS3 docs
+
+	// join-request questionnaires
+	r.Get("/communities/{communityID}/questionnaire", GetCommunityQuestionnaireRoute)  // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/questionnaire", SaveCommunityQuestionnaireRoute) // TODO: needs OAS3 docs
+
+	// ownership
+	r.Put("/communities/{communityID}/owner/{userID}", TransferCommunityOwnershipRoute) // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/owner", AcceptCommunityOwnershipRoute)           // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/owner", CancelCommunityOwnershipTransferRoute) // TODO: needs OAS3 docs
+	r.Get("/admin/communities/orphaned", GetOrphanedCommunitiesRoute)                   // TODO: needs OAS3 docs
+	r.Get("/admin/communities/archived", GetArchivedCommunitiesRoute)                   // TODO: needs OAS3 docs
+	r.Put("/admin/communities/{communityID}/owner/{userID}", AssignCommunityOwnerRoute) // TODO: needs OAS3 docs
+
 	// prayer requests
 	r.Get("/requests", GetGlobalPrayerRequestsRoute)
 	r.Post("/requests", CreatePrayerRequestRoute)
@@ -281,11 +356,107 @@ func SetupApp() *chi.Mux {
 	r.Put("/communities/{communityID}/requests/{requestID}", AddPrayerRequestToCommunityRoute)         // TODO: needs OAS3 docs
 	r.Delete("/communities/{communityID}/requests/{requestID}", RemovePrayerRequestFromCommunityRoute) // TODO: needs OAS3 docs
 
+	// community moderation queue
+	r.Get("/communities/{communityID}/requests/pending", GetCommunityPrayerRequestsPendingReviewRoute)  // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/requests/{requestID}/review", ReviewCommunityPrayerRequestRoute) // TODO: needs OAS3 docs
+
+	// community announcements
+	r.Get("/communities/{communityID}/announcements", GetCommunityAnnouncementsRoute)                           // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/announcements", CreateCommunityAnnouncementRoute)                        // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/announcements/unread", GetCommunityAnnouncementsUnreadCountRoute)         // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/announcements/read", MarkAllCommunityAnnouncementsReadRoute)              // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/announcements/{announcementID}", GetCommunityAnnouncementRoute)           // TODO: needs OAS3 docs
+	r.Patch("/communities/{communityID}/announcements/{announcementID}", UpdateCommunityAnnouncementRoute)      // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/announcements/{announcementID}", DeleteCommunityAnnouncementRoute)     // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/announcements/{announcementID}/read", MarkCommunityAnnouncementReadRoute) // TODO: needs OAS3 docs
+
+	// community sub-groups
+	r.Get("/communities/{communityID}/groups", GetCommunitySubGroupsRoute)                                                         // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/groups", CreateCommunitySubGroupRoute)                                                      // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/groups/{subGroupID}", GetCommunitySubGroupRoute)                                             // TODO: needs OAS3 docs
+	r.Patch("/communities/{communityID}/groups/{subGroupID}", UpdateCommunitySubGroupRoute)                                        // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/groups/{subGroupID}", DeleteCommunitySubGroupRoute)                                       // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/groups/{subGroupID}/users", GetCommunitySubGroupMembersRoute)                                // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/groups/{subGroupID}/users/{userID}", SetCommunitySubGroupMemberRoute)                        // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/groups/{subGroupID}/users/{userID}", RemoveCommunitySubGroupMemberRoute)                  // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/groups/{subGroupID}/requests", GetCommunitySubGroupPrayerRequestsRoute)                      // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/groups/{subGroupID}/requests/{requestID}", AddPrayerRequestToCommunitySubGroupRoute)        // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/groups/{subGroupID}/requests/{requestID}", RemovePrayerRequestFromCommunitySubGroupRoute) // TODO: needs OAS3 docs
+
+	// prayer vigils
+	r.Get("/communities/{communityID}/vigils", GetPrayerVigilsRoute)                                                // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/vigils", CreatePrayerVigilRoute)                                             // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/vigils/{vigilID}", GetPrayerVigilRoute)                                       // TODO: needs OAS3 docs
+	r.Patch("/communities/{communityID}/vigils/{vigilID}", UpdatePrayerVigilRoute)                                  // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/vigils/{vigilID}", DeletePrayerVigilRoute)                                 // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/vigils/{vigilID}/report", GetPrayerVigilCoverageReportRoute)                  // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/vigils/{vigilID}/slots/{slotIndex}", SignUpForPrayerVigilSlotRoute)           // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/vigils/{vigilID}/slots/{slotIndex}", RemovePrayerVigilSignupRoute)         // TODO: needs OAS3 docs
+	r.Put("/communities/{communityID}/vigils/{vigilID}/requests/{requestID}", LinkPrayerRequestToVigilRoute)        // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/vigils/{vigilID}/requests/{requestID}", UnlinkPrayerRequestFromVigilRoute) // TODO: needs OAS3 docs
+	r.Get("/calendar/{token}", GetPrayerVigilCalendarRoute)                                                         // TODO: needs OAS3 docs
+
+	// community branding
+	r.Put("/communities/{communityID}/logo", UploadCommunityLogoRoute)    // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/logo", DeleteCommunityLogoRoute) // TODO: needs OAS3 docs
+	r.Get("/assets/*", GetAssetRoute)                                     // TODO: needs OAS3 docs
+
+	// invite links
+	r.Get("/communities/{communityID}/invites", GetCommunityInvitesRoute)                 // TODO: needs OAS3 docs
+	r.Post("/communities/{communityID}/invites", CreateCommunityInviteRoute)              // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/invites/{inviteID}", GetCommunityInviteRoute)       // TODO: needs OAS3 docs
+	r.Delete("/communities/{communityID}/invites/{inviteID}", RevokeCommunityInviteRoute) // TODO: needs OAS3 docs
+	r.Get("/invites/{code}", PreviewCommunityInviteRoute)                                 // does not require a user; TODO: needs OAS3 docs
+	r.Post("/invites/{code}", AcceptCommunityInviteRoute)                                 // TODO: needs OAS3 docs
+
+	// community stats
+	r.Get("/communities/{communityID}/stats", GetCommunityStatsRoute) // TODO: needs OAS3 docs
+
+	// community exports
+	r.Get("/communities/{communityID}/export", ExportCommunityRoute)                              // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/exports", GetCommunityExportsRoute)                         // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/exports/{exportID}", GetCommunityExportRoute)               // TODO: needs OAS3 docs
+	r.Get("/communities/{communityID}/exports/{exportID}/download", DownloadCommunityExportRoute) // TODO: needs OAS3 docs
+
+	// activitypub federation; these don't require a user and return 404 unless federation is enabled
+	r.Get("/.well-known/webfinger", WebFingerRoute)                                // TODO: needs OAS3 docs
+	r.Get("/ap/communities/{communityID}", GetActivityPubCommunityRoute)           // TODO: needs OAS3 docs
+	r.Get("/ap/communities/{communityID}/outbox", GetActivityPubOutboxRoute)       // TODO: needs OAS3 docs
+	r.Get("/ap/communities/{communityID}/followers", GetActivityPubFollowersRoute) // TODO: needs OAS3 docs
+	r.Post("/ap/communities/{communityID}/inbox", PostActivityPubInboxRoute)       // TODO: needs OAS3 docs
+	r.Get("/ap/requests/{requestID}", GetActivityPubRequestRoute)                  // TODO: needs OAS3 docs
+
+	// tags
+	r.Get("/tags", GetPopularTagsRoute)                                             // TODO: needs OAS3 docs
+	r.Get("/tags/autocomplete", GetTagAutocompleteRoute)                            // TODO: needs OAS3 docs
+	r.Post("/requests/{requestID}/tags", AddTagToPrayerRequestRoute)                // TODO: needs OAS3 docs
+	r.Delete("/requests/{requestID}/tags/{tagID}", RemoveTagFromPrayerRequestRoute) // TODO: needs OAS3 docs
+	r.Post("/admin/tags/{tagID}/merge", MergeTagsRoute)                             // TODO: needs OAS3 docs
+
+	// search
+	r.Get("/search", SearchRoute)                       // TODO: needs OAS3 docs
+	r.Post("/admin/search/reindex", ReindexSearchRoute) // TODO: needs OAS3 docs
+
 	// prayers made
 	r.Get("/requests/{requestID}/prayers", GetPrayersMadeOnRequestRoute)      // TODO: needs OAS3 docs
 	r.Post("/requests/{requestID}/prayers", AddPrayerToRequestRoute)          // TODO: needs OAS3 docs
 	r.Delete("/requests/{requestID}/prayers", RemovePrayerMadeOnRequestRoute) // the whenPrayed query param should be added; TODO: needs OAS3 docs
 
+	// request updates are append-only follow-ups from the author
+	r.Get("/requests/{requestID}/updates", GetPrayerRequestUpdatesRoute)    // TODO: needs OAS3 docs
+	r.Post("/requests/{requestID}/updates", CreatePrayerRequestUpdateRoute) // TODO: needs OAS3 docs
+
+	// comments are encouragement from anyone who can see the request
+	r.Get("/requests/{requestID}/comments", GetPrayerRequestCommentsRoute)                        // TODO: needs OAS3 docs
+	r.Post("/requests/{requestID}/comments", CreatePrayerRequestCommentRoute)                     // TODO: needs OAS3 docs
+	r.Delete("/requests/{requestID}/comments/{commentID}", DeletePrayerRequestCommentRoute)       // TODO: needs OAS3 docs
+	r.Post("/requests/{requestID}/comments/{commentID}/reports", ReportPrayerRequestCommentRoute) // TODO: needs OAS3 docs
+
+	// shares give single communities or people access to a request, depending on its privacy
+	r.Get("/requests/{requestID}/shares", GetPrayerRequestSharesRoute)                // TODO: needs OAS3 docs
+	r.Post("/requests/{requestID}/shares", CreatePrayerRequestShareRoute)             // TODO: needs OAS3 docs
+	r.Delete("/requests/{requestID}/shares/{shareID}", DeletePrayerRequestShareRoute) // TODO: needs OAS3 docs
+
 	// lists
 	r.Get("/lists/requests", GetPrayerListsForUserRoute)                                     // TODO: needs OAS3 docs
 	r.Post("/lists/requests", CreatePrayerListRoute)                                         // TODO: needs OAS3 docs
@@ -241,3 +241,39 @@ func CreateDateRangesForReports(input []DatePoint, startDate string, endDate str
 
 	return
 }
+
+// CreateTimeRangesForReports works like CreateDateRangesForReports but on fixed intervals, such as the slots of a prayer vigil, rather
+// than days. Every interval from start up to end is returned with gaps filled in with a zero count. The Day of each point is the
+// RFC3339 start of its interval
+func CreateTimeRangesForReports(input []DatePoint, start time.Time, end time.Time, interval time.Duration) (ret DatePointsProcessed, err error) {
+	if interval <= 0 {
+		return ret, fmt.Errorf("interval must be greater than zero, got %v", interval)
+	}
+
+	counts := map[int64]int64{}
+	for i := range input {
+		t, err := ParseTime(input[i].Day)
+		if err != nil {
+			return DatePointsProcessed{}, fmt.Errorf("unexpected date found: %s, error was %v", input[i].Day, err)
+		}
+		counts[t.UTC().Unix()] += input[i].Count
+	}
+
+	data := []DatePoint{}
+	total := int64(0)
+	for current := start.UTC(); current.Before(end); current = current.Add(interval) {
+		count := counts[current.Unix()]
+		total += count
+		data = append(data, DatePoint{
+			Day:          current.Format(time.RFC3339),
+			Count:        count,
+			RunningTotal: total,
+		})
+	}
+
+	ret = DatePointsProcessed{
+		Data:  data,
+		Total: total,
+	}
+	return
+}
@@ -178,3 +178,37 @@ func TestDurations(t *testing.T) {
 	assert.Equal(t, 30, min)
 	assert.Equal(t, 10, sec)
 }
+
+func TestTimeRanges(t *testing.T) {
+	start, _ := ParseTime("2018-07-01 22:00:00")
+	end, _ := ParseTime("2018-07-02 00:00:00")
+	input := []DatePoint{
+		DatePoint{
+			Day:   "2018-07-01 23:00:00",
+			Count: 2,
+		},
+		DatePoint{
+			Day:   "2018-07-01T22:00:00Z",
+			Count: 1,
+		},
+		DatePoint{
+			Day:   "2018-07-01 23:00:00",
+			Count: 1,
+		},
+	}
+
+	result, err := CreateTimeRangesForReports(input, start, end, 30*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), result.Total)
+	assert.Equal(t, []DatePoint{
+		DatePoint{Day: "2018-07-01T22:00:00Z", Count: 1, RunningTotal: 1},
+		DatePoint{Day: "2018-07-01T22:30:00Z", Count: 0, RunningTotal: 1},
+		DatePoint{Day: "2018-07-01T23:00:00Z", Count: 3, RunningTotal: 4},
+		DatePoint{Day: "2018-07-01T23:30:00Z", Count: 0, RunningTotal: 4},
+	}, result.Data)
+
+	_, err = CreateTimeRangesForReports(input, start, end, 0)
+	assert.NotNil(t, err)
+	_, err = CreateTimeRangesForReports([]DatePoint{DatePoint{Day: "bad"}}, start, end, time.Hour)
+	assert.NotNil(t, err)
+}
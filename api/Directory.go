@@ -0,0 +1,221 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CommunityDirectoryFilter narrows a search of the public community directory. Blank fields are not filtered on
+type CommunityDirectoryFilter struct {
+	// Query is matched against the name, description, denomination, city, and region
+	Query        string
+	Category     string
+	Denomination string
+	Language     string
+	Region       string
+	// Latitude and Longitude are the point to measure distance from; they are required to sort by distance or filter by RadiusKM
+	Latitude  *float64
+	Longitude *float64
+	RadiusKM  float64
+}
+
+const (
+	// CommunityDenominationMaxLength is the longest denomination or tradition label allowed
+	CommunityDenominationMaxLength = 64
+	// CommunityLocationMaxLength is the longest city or region allowed
+	CommunityLocationMaxLength = 128
+
+	// earthRadiusKM is used for the haversine distance
+	earthRadiusKM = 6371.0
+)
+
+var communityCategories = []string{
+	"church",
+	"ministry",
+	"family",
+	"health",
+	"grief",
+	"missions",
+	"recovery",
+	"students",
+	"youth",
+	"military",
+	"workplace",
+	"other",
+}
+
+var communityLanguageRegex = regexp.MustCompile("^[a-z]{2}$")
+
+// GetCommunityCategories gets the categories a community can be listed under
+func GetCommunityCategories() []string {
+	return communityCategories
+}
+
+// IsValidCommunityCategory checks if the category is in the list of categories
+func IsValidCommunityCategory(category string) bool {
+	for i := range communityCategories {
+		if communityCategories[i] == category {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidCommunityLanguage checks if the input is a two letter language code
+func IsValidCommunityLanguage(language string) bool {
+	return communityLanguageRegex.MatchString(language)
+}
+
+// IsValidCoordinate checks that the latitude and longitude are in range
+func IsValidCoordinate(latitude, longitude float64) bool {
+	return latitude >= -90 && latitude <= 90 && longitude >= -180 && longitude <= 180
+}
+
+// DistanceInKilometers gets the great-circle distance between two points using the haversine formula
+func DistanceInKilometers(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Pow(math.Sin(dLon/2), 2)
+	return earthRadiusKM * 2 * math.Asin(math.Sqrt(a))
+}
+
+// SetCommunityCategories replaces the categories on a community
+func SetCommunityCategories(communityID int64, categories []string) error {
+	_, err := Config.DbConn.Exec("DELETE FROM CommunityCategoryLinks WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	for i := range categories {
+		_, err = Config.DbConn.Exec("INSERT INTO CommunityCategoryLinks (communityId, category) VALUES (?, ?) ON DUPLICATE KEY UPDATE category = category",
+			communityID, categories[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommunityCategoriesForCommunity gets the categories a community is listed under
+func GetCommunityCategoriesForCommunity(communityID int64) ([]string, error) {
+	categories := []string{}
+	err := Config.DbConn.Select(&categories, "SELECT category FROM CommunityCategoryLinks WHERE communityId = ? ORDER BY category", communityID)
+	return categories, err
+}
+
+// SearchPublicCommunities searches the public directory. The sort field can be name, created, or distance; distance requires a point in
+// the filter and falls back to name otherwise
+func SearchPublicCommunities(filter CommunityDirectoryFilter, sortField, sortDir string, count, offset int) ([]Community, error) {
+	comms := []Community{}
+
+	hasPoint := filter.Latitude != nil && filter.Longitude != nil
+	where := []string{"c.privacy = 'public'", "c.archived = '1970-01-01 00:00:00'"}
+	args := []interface{}{}
+	distance := "NULL"
+	if hasPoint {
+		distance = `(? * 2 * ASIN(SQRT(POWER(SIN(RADIANS(c.latitude - ?) / 2), 2) + 
+		COS(RADIANS(?)) * COS(RADIANS(c.latitude)) * POWER(SIN(RADIANS(c.longitude - ?) / 2), 2))))`
+		args = append(args, earthRadiusKM, *filter.Latitude, *filter.Latitude, *filter.Longitude)
+	}
+
+	if filter.Query != "" {
+		like := "%" + strings.NewReplacer("%", "\\%", "_", "\\_").Replace(filter.Query) + "%"
+		where = append(where, "(c.name LIKE ? OR c.description LIKE ? OR c.denomination LIKE ? OR c.city LIKE ? OR c.region LIKE ?)")
+		args = append(args, like, like, like, like, like)
+	}
+	if filter.Category != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM CommunityCategoryLinks ccl WHERE ccl.communityId = c.id AND ccl.category = ?)")
+		args = append(args, filter.Category)
+	}
+	if filter.Denomination != "" {
+		where = append(where, "c.denomination = ?")
+		args = append(args, filter.Denomination)
+	}
+	if filter.Language != "" {
+		where = append(where, "c.language = ?")
+		args = append(args, filter.Language)
+	}
+	if filter.Region != "" {
+		where = append(where, "c.region = ?")
+		args = append(args, filter.Region)
+	}
+	having := ""
+	if hasPoint && filter.RadiusKM > 0 {
+		where = append(where, "c.latitude IS NOT NULL")
+		having = "HAVING distance <= ?"
+		args = append(args, filter.RadiusKM)
+	}
+
+	// generally, string interpolation on SQL is Very Bad, but this is white listed so there is no
+	// security concerns
+	sortDir = strings.ToUpper(sortDir)
+	if sortDir != "DESC" {
+		sortDir = "ASC"
+	}
+	sortField = strings.ToLower(sortField)
+	orderBy := ""
+	switch {
+	case sortField == "distance" && hasPoint:
+		// communities without a location are always last
+		orderBy = fmt.Sprintf("c.latitude IS NULL, distance %s, c.name", sortDir)
+	case sortField == "created":
+		orderBy = fmt.Sprintf("c.created %s", sortDir)
+	default:
+		orderBy = fmt.Sprintf("c.name %s", sortDir)
+	}
+	args = append(args, offset, count)
+
+	err := Config.DbConn.Select(&comms, fmt.Sprintf(`
+	SELECT c.*, %s AS distance, 
+	(SELECT COUNT(*) FROM CommunityUserLinks cul WHERE cul.communityId = c.id AND cul.status = 'accepted') AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = c.id AND prcl.status = 'approved') AS requestCount
+	FROM Communities c
+	WHERE %s
+	%s
+	ORDER BY %s LIMIT ?,?`, distance, strings.Join(where, " AND "), having, orderBy), args...)
+	if err != nil {
+		return comms, err
+	}
+
+	err = populateCommunityCategories(comms)
+	for i := range comms {
+		comms[i].processForAPI()
+		comms[i].clean()
+	}
+	return comms, err
+}
+
+// populateCommunityCategories loads the categories for a list of communities in one query
+func populateCommunityCategories(comms []Community) error {
+	if len(comms) == 0 {
+		return nil
+	}
+	ids := []int64{}
+	byID := map[int64]int{}
+	for i := range comms {
+		comms[i].Categories = []string{}
+		ids = append(ids, comms[i].ID)
+		byID[comms[i].ID] = i
+	}
+	query, args, err := sqlx.In("SELECT communityId, category FROM CommunityCategoryLinks WHERE communityId IN (?) ORDER BY category", ids)
+	if err != nil {
+		return err
+	}
+	links := []struct {
+		CommunityID int64  `db:"communityId"`
+		Category    string `db:"category"`
+	}{}
+	err = Config.DbConn.Select(&links, Config.DbConn.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	for i := range links {
+		if index, ok := byID[links[i].CommunityID]; ok {
+			comms[index].Categories = append(comms[index].Categories, links[i].Category)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectoryValidation(t *testing.T) {
+	assert.True(t, IsValidCommunityCategory("church"))
+	assert.False(t, IsValidCommunityCategory("Church"))
+	assert.False(t, IsValidCommunityCategory("book_club"))
+	assert.True(t, IsValidCommunityLanguage("en"))
+	assert.False(t, IsValidCommunityLanguage("eng"))
+	assert.False(t, IsValidCommunityLanguage("EN"))
+	assert.True(t, IsValidCoordinate(35.2271, -80.8431))
+	assert.False(t, IsValidCoordinate(91, 0))
+	assert.False(t, IsValidCoordinate(0, -181))
+
+	// Charlotte to Atlanta is roughly 365 kilometers
+	distance := DistanceInKilometers(35.2271, -80.8431, 33.7490, -84.3880)
+	assert.InDelta(t, 365, distance, 5)
+	assert.Equal(t, float64(0), DistanceInKilometers(10, 10, 10, 10))
+}
+
+func TestDirectorySearch(t *testing.T) {
+	ConfigSetup()
+	randID := rand.Int63n(999999999)
+	charlotteLat, charlotteLon := 35.2271, -80.8431
+	atlantaLat, atlantaLon := 33.7490, -84.3880
+
+	near := Community{
+		Name:         fmt.Sprintf("Directory Near %d", randID),
+		ShortCode:    fmt.Sprintf("dir_near_%d", randID),
+		Privacy:      CommunityPrivacyPublic,
+		Denomination: "Baptist",
+		Language:     "en",
+		City:         "Charlotte",
+		Region:       fmt.Sprintf("Region %d", randID),
+		Latitude:     &charlotteLat,
+		Longitude:    &charlotteLon,
+	}
+	far := Community{
+		Name:        fmt.Sprintf("Directory Far %d", randID),
+		ShortCode:   fmt.Sprintf("dir_far_%d", randID),
+		Privacy:     CommunityPrivacyPublic,
+		Description: "A recovery ministry",
+		Language:    "es",
+		City:        "Atlanta",
+		Region:      fmt.Sprintf("Region %d", randID),
+		Latitude:    &atlantaLat,
+		Longitude:   &atlantaLon,
+	}
+	hidden := Community{
+		Name:      fmt.Sprintf("Directory Hidden %d", randID),
+		ShortCode: fmt.Sprintf("dir_hidden_%d", randID),
+		Privacy:   CommunityPrivacyPrivate,
+		Region:    fmt.Sprintf("Region %d", randID),
+	}
+	for _, c := range []*Community{&near, &far, &hidden} {
+		err := CreateCommunity(c)
+		require.Nil(t, err)
+		defer DeleteCommunity(c.ID)
+		err = UpdateCommunity(c)
+		require.Nil(t, err)
+	}
+	err := SetCommunityCategories(far.ID, []string{"recovery", "ministry"})
+	require.Nil(t, err)
+	categories, err := GetCommunityCategoriesForCommunity(far.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"ministry", "recovery"}, categories)
+
+	region := fmt.Sprintf("Region %d", randID)
+	comms, err := SearchPublicCommunities(CommunityDirectoryFilter{Region: region}, "name", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(comms))
+	assert.Equal(t, far.ID, comms[0].ID)
+	assert.Equal(t, []string{"ministry", "recovery"}, comms[0].Categories)
+
+	comms, err = SearchPublicCommunities(CommunityDirectoryFilter{Region: region, Query: "recovery"}, "name", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comms))
+	assert.Equal(t, far.ID, comms[0].ID)
+
+	comms, err = SearchPublicCommunities(CommunityDirectoryFilter{Region: region, Category: "ministry"}, "name", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comms))
+
+	comms, err = SearchPublicCommunities(CommunityDirectoryFilter{Region: region, Language: "en", Denomination: "Baptist"}, "name", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comms))
+	assert.Equal(t, near.ID, comms[0].ID)
+
+	// sorted by distance from Charlotte, then limited to 100 kilometers
+	comms, err = SearchPublicCommunities(CommunityDirectoryFilter{Region: region, Latitude: &charlotteLat, Longitude: &charlotteLon}, "distance", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(comms))
+	assert.Equal(t, near.ID, comms[0].ID)
+	require.NotNil(t, comms[1].Distance)
+	assert.InDelta(t, 365, *comms[1].Distance, 5)
+
+	comms, err = SearchPublicCommunities(CommunityDirectoryFilter{Region: region, Latitude: &charlotteLat, Longitude: &charlotteLon, RadiusKM: 100}, "distance", "asc", 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comms))
+	assert.Equal(t, near.ID, comms[0].ID)
+}
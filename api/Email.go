@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"html"
 
 	"gopkg.in/mailgun/mailgun-go.v1"
 )
@@ -68,12 +69,37 @@ func SendEmailToGroup(to []string, subject string, body string, asBCC bool) (str
 	return resp, id, err
 }
 
-// GenerateEmail generates an email by combining the inserted body with a header and footer setup. The
-// community ID is primarily for the logo if it is present, which is a forthcoming feature
+// GenerateEmail generates an email by combining the inserted body with a header and footer setup. If the email is sent on behalf of
+// a community, its logo, accent color, and custom footer are included
 func GenerateEmail(communityID int64, body string) string {
-	// TODO: Build out the header and footer for the org
-
 	header := ""
-	footer := fmt.Sprintf(`<p>The Pregxas Team</p><p>If you believe you received this message in error, please forward this message to %s.</p>`, Config.MailFromAddress)
+	communityFooter := ""
+	if communityID > 0 {
+		community, err := GetCommunityByID(communityID)
+		if err == nil {
+			header = generateCommunityEmailHeader(community)
+			if community.EmailFooter != "" {
+				communityFooter = fmt.Sprintf(`<p style="color: #666666; font-size: 12px;">%s</p>`, community.EmailFooter)
+			}
+		}
+	}
+	footer := fmt.Sprintf(`%s<p>The Pregxas Team</p><p>If you believe you received this message in error, please forward this message to %s.</p>`, communityFooter, Config.MailFromAddress)
 	return fmt.Sprintf("%s%s%s", header, body, footer)
 }
+
+// generateCommunityEmailHeader builds the branded header for a community. The name is whatever the admins typed, so it is
+// escaped before going into the HTML
+func generateCommunityEmailHeader(community *Community) string {
+	name := html.EscapeString(community.Name)
+	style := "padding: 12px 0;"
+	titleStyle := ""
+	if community.AccentColor != "" {
+		style = fmt.Sprintf("padding: 12px 0; border-top: 4px solid %s;", community.AccentColor)
+		titleStyle = fmt.Sprintf(` style="color: %s;"`, community.AccentColor)
+	}
+	logo := ""
+	if community.LogoURL != "" {
+		logo = fmt.Sprintf(`<img src="%s" alt="%s" style="max-height: 64px;" />`, html.EscapeString(community.LogoURL), name)
+	}
+	return fmt.Sprintf(`<div style="%s">%s<h2%s>%s</h2></div>`, style, logo, titleStyle, name)
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSendEmail(t *testing.T) {
@@ -22,3 +24,34 @@ func TestSendEmail(t *testing.T) {
 	body := GenerateEmail(0, "Test")
 	assert.NotEqual(t, "", body)
 }
+
+func TestGenerateCommunityEmail(t *testing.T) {
+	ConfigSetup()
+	community := Community{
+		Name: "Branded Community",
+	}
+	err := CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	body := GenerateEmail(community.ID, "Test")
+	assert.True(t, strings.Contains(body, community.Name))
+	assert.False(t, strings.Contains(body, "<img"))
+
+	community.AccentColor = "#336699"
+	community.EmailFooter = "Sent with love from the branding team"
+	err = UpdateCommunity(&community)
+	require.Nil(t, err)
+	err = UpdateCommunityLogo(community.ID, "communities/logo.png")
+	require.Nil(t, err)
+
+	body = GenerateEmail(community.ID, "Test")
+	assert.True(t, strings.Contains(body, "#336699"))
+	assert.True(t, strings.Contains(body, community.EmailFooter))
+	assert.True(t, strings.Contains(body, Config.Storage.URL("communities/logo.png")))
+
+	// the name is escaped in the header
+	header := generateCommunityEmailHeader(&Community{Name: `<script>alert("hi")</script>`})
+	assert.False(t, strings.Contains(header, "<script>"))
+	assert.True(t, strings.Contains(header, "&lt;script&gt;"))
+}
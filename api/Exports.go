@@ -0,0 +1,372 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CommunityExport is a request to export a community's data. Small exports are sent straight back to the admin; large ones
+// are saved as a CommunityExport, generated in the background, and downloaded once ready
+type CommunityExport struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	RequestedBy int64  `json:"requestedBy" db:"requestedBy"`
+	ExportType  string `json:"exportType" db:"exportType"`
+	Format      string `json:"format" db:"format"`
+	Start       string `json:"start" db:"start"`
+	End         string `json:"end" db:"end"`
+	Status      string `json:"status" db:"status"`
+	StorageKey  string `json:"-" db:"storageKey"`
+	Error       string `json:"error,omitempty" db:"error"`
+	Created     string `json:"created" db:"created"`
+	Completed   string `json:"completed,omitempty" db:"completed"`
+	// Expires is when the file is deleted
+	Expires     string `json:"expires,omitempty" db:"expires"`
+	DownloadURL string `json:"downloadUrl,omitempty" db:"-"`
+}
+
+// CommunityExportMember is a row in a member export. Contact details such as emails are left out
+type CommunityExportMember struct {
+	UserID    int64  `json:"userId" db:"userId"`
+	Username  string `json:"username" db:"username"`
+	FirstName string `json:"firstName" db:"firstName"`
+	LastName  string `json:"lastName" db:"lastName"`
+	Role      string `json:"role" db:"role"`
+	Joined    string `json:"joined" db:"joined"`
+}
+
+// CommunityExportRequest is a row in a request export
+type CommunityExportRequest struct {
+	ID          int64  `json:"id" db:"id"`
+	Title       string `json:"title" db:"title"`
+	Body        string `json:"body" db:"body"`
+	Username    string `json:"username" db:"username"`
+	Status      string `json:"status" db:"status"`
+	Created     string `json:"created" db:"created"`
+	Answered    string `json:"answered" db:"answered"`
+	PrayerCount int64  `json:"prayerCount" db:"prayerCount"`
+}
+
+// CommunityExportPrayerActivity is a row in a prayer activity export; prayers are totaled per request per day so individual
+// members' prayers are not exposed
+type CommunityExportPrayerActivity struct {
+	Day             string `json:"day" db:"day"`
+	PrayerRequestID int64  `json:"prayerRequestId" db:"prayerRequestId"`
+	Title           string `json:"title" db:"title"`
+	Prayers         int64  `json:"prayers" db:"prayers"`
+}
+
+const (
+	// CommunityExportTypeMembers exports the accepted members
+	CommunityExportTypeMembers = "members"
+	// CommunityExportTypeRequests exports the requests shown in the community created in the range
+	CommunityExportTypeRequests = "requests"
+	// CommunityExportTypePrayers exports the prayers made in the range for requests in the community
+	CommunityExportTypePrayers = "prayers"
+
+	// CommunityExportFormatCSV is a CSV file with a header row
+	CommunityExportFormatCSV = "csv"
+	// CommunityExportFormatJSON is a JSON array
+	CommunityExportFormatJSON = "json"
+
+	// CommunityExportStatusPending is waiting to be generated
+	CommunityExportStatusPending = "pending"
+	// CommunityExportStatusProcessing is being generated
+	CommunityExportStatusProcessing = "processing"
+	// CommunityExportStatusReady can be downloaded
+	CommunityExportStatusReady = "ready"
+	// CommunityExportStatusFailed could not be generated
+	CommunityExportStatusFailed = "failed"
+
+	// CommunityExportAsyncMemberCount is the member count above which exports are generated in the background
+	CommunityExportAsyncMemberCount = 250
+	// CommunityExportRetentionDays is how long a generated export can be downloaded
+	CommunityExportRetentionDays = 7
+	// CommunityExportMaxDays is the longest range that can be exported
+	CommunityExportMaxDays = 366
+)
+
+// IsValidCommunityExportType checks if the input is a known export type
+func IsValidCommunityExportType(input string) bool {
+	return input == CommunityExportTypeMembers || input == CommunityExportTypeRequests || input == CommunityExportTypePrayers
+}
+
+// IsValidCommunityExportFormat checks if the input is a known export format
+func IsValidCommunityExportFormat(input string) bool {
+	return input == CommunityExportFormatCSV || input == CommunityExportFormatJSON
+}
+
+// GetCommunityExportContentType gets the content type for a format
+func GetCommunityExportContentType(format string) string {
+	if format == CommunityExportFormatJSON {
+		return "application/json"
+	}
+	return "text/csv"
+}
+
+// WriteCommunityExport generates the export and writes it to w. The start and end are only used for requests and prayers
+func WriteCommunityExport(w io.Writer, communityID int64, exportType, format, start, end string) error {
+	var rows interface{}
+	header := []string{}
+	records := [][]string{}
+	switch exportType {
+	case CommunityExportTypeMembers:
+		members := []CommunityExportMember{}
+		err := Config.DbConn.Select(&members, `SELECT cul.userId, u.username, u.firstName, u.lastName, cul.role, cul.joined
+			FROM CommunityUserLinks cul, Users u WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id
+			ORDER BY u.lastName, u.firstName, u.username`, communityID, CommunityUserLinkStatusAccepted)
+		if err != nil {
+			return err
+		}
+		header = []string{"userId", "username", "firstName", "lastName", "role", "joined"}
+		for i := range members {
+			members[i].Joined = exportDate(members[i].Joined)
+			records = append(records, []string{fmt.Sprintf("%d", members[i].UserID), members[i].Username, members[i].FirstName,
+				members[i].LastName, members[i].Role, members[i].Joined})
+		}
+		rows = members
+	case CommunityExportTypeRequests:
+		requests := []CommunityExportRequest{}
+		err := Config.DbConn.Select(&requests, `SELECT pr.id, COALESCE(pr.title, '') AS title, COALESCE(pr.body, '') AS body, IF(pr.anonymous = 1, '', COALESCE(u.username, '')) AS username, pr.status, pr.created, pr.answered,
+			(SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount
+			FROM PrayerRequests pr
+			INNER JOIN PrayerRequestCommunityLinks prcl ON prcl.prayerRequestId = pr.id
+			LEFT JOIN Users u ON u.id = pr.createdBy
+			WHERE prcl.communityId = ? AND prcl.status = 'approved' AND pr.created BETWEEN ? AND ? `+prayerRequestVisibleInCommunity("prcl.communityId")+`
+			ORDER BY pr.created, pr.id`, communityID, start, end)
+		if err != nil {
+			return err
+		}
+		header = []string{"id", "title", "body", "username", "status", "created", "answered", "prayerCount"}
+		for i := range requests {
+			requests[i].Created = exportDate(requests[i].Created)
+			requests[i].Answered = exportDate(requests[i].Answered)
+			records = append(records, []string{fmt.Sprintf("%d", requests[i].ID), requests[i].Title, requests[i].Body, requests[i].Username,
+				requests[i].Status, requests[i].Created, requests[i].Answered, fmt.Sprintf("%d", requests[i].PrayerCount)})
+		}
+		rows = requests
+	case CommunityExportTypePrayers:
+		activity := []CommunityExportPrayerActivity{}
+		err := Config.DbConn.Select(&activity, `SELECT DATE(p.whenPrayed) AS day, pr.id AS prayerRequestId, pr.title, COUNT(*) AS prayers
+			FROM Prayers p, PrayerRequests pr, PrayerRequestCommunityLinks prcl
+			WHERE prcl.communityId = ? AND prcl.status = 'approved' AND prcl.prayerRequestId = pr.id AND p.prayerRequestId = pr.id
+			AND p.whenPrayed BETWEEN ? AND ? `+prayerRequestVisibleInCommunity("prcl.communityId")+`
+			GROUP BY day, pr.id, pr.title ORDER BY day, pr.id`, communityID, start, end)
+		if err != nil {
+			return err
+		}
+		header = []string{"day", "prayerRequestId", "title", "prayers"}
+		for i := range activity {
+			if len(activity[i].Day) > 10 {
+				activity[i].Day = activity[i].Day[0:10]
+			}
+			records = append(records, []string{activity[i].Day, fmt.Sprintf("%d", activity[i].PrayerRequestID), activity[i].Title,
+				fmt.Sprintf("%d", activity[i].Prayers)})
+		}
+		rows = activity
+	default:
+		return fmt.Errorf("unknown export type %s", exportType)
+	}
+
+	if format == CommunityExportFormatJSON {
+		return json.NewEncoder(w).Encode(rows)
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(header)
+	for i := range records {
+		for j := range records[i] {
+			records[i][j] = exportCSVCell(records[i][j])
+		}
+	}
+	writer.WriteAll(records)
+	return writer.Error()
+}
+
+// CreateCommunityExport saves an export to be generated in the background
+func CreateCommunityExport(input *CommunityExport) error {
+	input.Status = CommunityExportStatusPending
+	res, err := Config.DbConn.NamedExec(`INSERT INTO CommunityExports (communityId, requestedBy, exportType, format, start, end, status, created)
+		VALUES (:communityId, :requestedBy, :exportType, :format, :start, :end, :status, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	exp, err := GetCommunityExport(input.CommunityID, input.ID)
+	if err != nil {
+		return err
+	}
+	*input = *exp
+	return nil
+}
+
+// GetCommunityExport gets an export in a community
+func GetCommunityExport(communityID, exportID int64) (*CommunityExport, error) {
+	exp := &CommunityExport{}
+	err := Config.DbConn.Get(exp, "SELECT * FROM CommunityExports WHERE id = ? AND communityId = ?", exportID, communityID)
+	if err != nil {
+		return nil, err
+	}
+	exp.processForAPI()
+	return exp, nil
+}
+
+// GetCommunityExports gets the exports for a community that haven't expired, newest first
+func GetCommunityExports(communityID int64) ([]CommunityExport, error) {
+	exports := []CommunityExport{}
+	err := Config.DbConn.Select(&exports, `SELECT * FROM CommunityExports WHERE communityId = ?
+		AND (expires = '1970-01-01 00:00:00' OR expires > NOW()) ORDER BY id DESC`, communityID)
+	for i := range exports {
+		exports[i].processForAPI()
+	}
+	return exports, err
+}
+
+// ProcessCommunityExport generates a pending export, saves it to storage, and emails the admin who asked for it. The export is
+// claimed first so that it is only generated once
+func ProcessCommunityExport(exportID int64) error {
+	res, err := Config.DbConn.Exec("UPDATE CommunityExports SET status = ? WHERE id = ? AND status = ?",
+		CommunityExportStatusProcessing, exportID, CommunityExportStatusPending)
+	if err != nil {
+		return err
+	}
+	if claimed, _ := res.RowsAffected(); claimed == 0 {
+		return nil
+	}
+	exp := CommunityExport{}
+	err = Config.DbConn.Get(&exp, "SELECT * FROM CommunityExports WHERE id = ?", exportID)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	err = WriteCommunityExport(buf, exp.CommunityID, exp.ExportType, exp.Format, exp.Start, exp.End)
+	key := ""
+	if err == nil {
+		key, err = generateCommunityExportKey(&exp)
+	}
+	if err == nil {
+		err = Config.Storage.Save(key, buf)
+	}
+	if err != nil {
+		Config.DbConn.Exec("UPDATE CommunityExports SET status = ?, error = ?, completed = NOW() WHERE id = ?",
+			CommunityExportStatusFailed, err.Error(), exportID)
+		return err
+	}
+	_, err = Config.DbConn.Exec(`UPDATE CommunityExports SET status = ?, storageKey = ?, completed = NOW(),
+		expires = DATE_ADD(NOW(), INTERVAL ? DAY) WHERE id = ?`, CommunityExportStatusReady, key, CommunityExportRetentionDays, exportID)
+	if err != nil {
+		return err
+	}
+
+	ready, err := GetCommunityExport(exp.CommunityID, exportID)
+	if err != nil {
+		return err
+	}
+	user, err := GetUserByID(exp.RequestedBy)
+	if err == nil && user.Email != "" {
+		content := fmt.Sprintf(`<p>Your %s export is ready.</p><p><a href="%s">Download it here</a>. The link works for %d days.</p>`,
+			exp.ExportType, ready.DownloadURL, CommunityExportRetentionDays)
+		SendEmail(user.Email, "Your Community Export Is Ready", GenerateEmail(exp.CommunityID, content))
+	}
+	return nil
+}
+
+// ProcessPendingCommunityExports generates any exports still waiting, such as ones left behind by a restart
+func ProcessPendingCommunityExports() error {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, "SELECT id FROM CommunityExports WHERE status = ? ORDER BY id", CommunityExportStatusPending)
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		ProcessCommunityExport(ids[i])
+	}
+	return nil
+}
+
+// PurgeExpiredCommunityExports deletes the files and records of exports past their expiration
+func PurgeExpiredCommunityExports() error {
+	exports := []CommunityExport{}
+	err := Config.DbConn.Select(&exports, `SELECT * FROM CommunityExports WHERE expires != '1970-01-01 00:00:00' AND expires < NOW()`)
+	if err != nil {
+		return err
+	}
+	for i := range exports {
+		err = deleteCommunityExport(&exports[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteCommunityExportsForCommunity deletes every export for a community
+func DeleteCommunityExportsForCommunity(communityID int64) error {
+	exports := []CommunityExport{}
+	err := Config.DbConn.Select(&exports, "SELECT * FROM CommunityExports WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	for i := range exports {
+		err = deleteCommunityExport(&exports[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteCommunityExport(exp *CommunityExport) error {
+	if exp.StorageKey != "" {
+		err := Config.Storage.Delete(exp.StorageKey)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := Config.DbConn.Exec("DELETE FROM CommunityExports WHERE id = ?", exp.ID)
+	return err
+}
+
+// generateCommunityExportKey creates a hard to guess key in the private part of storage
+func generateCommunityExportKey(exp *CommunityExport) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%sexports/communities/%d/%s-%s.%s", StoragePrivatePrefix, exp.CommunityID, exp.ExportType, hex.EncodeToString(b), exp.Format), nil
+}
+
+// exportDate blanks the sentinel date and converts the rest to ISO
+func exportDate(input string) string {
+	if input == "" || input == "1970-01-01 00:00:00" {
+		return ""
+	}
+	output, _ := ParseTimeToISO(input)
+	return output
+}
+
+// exportCSVCell keeps spreadsheets from running what people typed as a formula by starting those cells with a quote
+func exportCSVCell(input string) string {
+	if input != "" && strings.ContainsAny(input[0:1], "=+-@\t\r") {
+		return "'" + input
+	}
+	return input
+}
+
+func (input *CommunityExport) processForAPI() {
+	input.Created = exportDate(input.Created)
+	input.Completed = exportDate(input.Completed)
+	input.Expires = exportDate(input.Expires)
+	input.Start = exportDate(input.Start)
+	input.End = exportDate(input.End)
+	if input.Status == CommunityExportStatusReady {
+		input.DownloadURL = fmt.Sprintf("%s/communities/%d/exports/%d/download", strings.TrimSuffix(Config.RootAPIURL, "/"), input.CommunityID, input.ID)
+	}
+}
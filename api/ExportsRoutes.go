@@ -0,0 +1,207 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// ExportCommunityRoute exports a community's members, requests, or prayer activity as CSV or JSON. The start and end query
+// parameters are dates that limit requests and prayers; they default to the last year. Small communities get the file right
+// away; large ones, or any request with async=true, get a 202 with an export that is generated in the background and emailed
+// to the admin once ready
+func ExportCommunityRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, communityID, ok := checkCommunityExportPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	exportType := r.URL.Query().Get("type")
+	if exportType == "" {
+		exportType = CommunityExportTypeMembers
+	}
+	if !IsValidCommunityExportType(exportType) {
+		SendError(w, http.StatusBadRequest, "community_export_invalid_type", "type must be members, requests, or prayers", nil)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = CommunityExportFormatCSV
+	}
+	if !IsValidCommunityExportFormat(format) {
+		SendError(w, http.StatusBadRequest, "community_export_invalid_format", "format must be csv or json", nil)
+		return
+	}
+
+	end := time.Now().UTC()
+	if r.URL.Query().Get("end") != "" {
+		end, err = ParseTime(r.URL.Query().Get("end"))
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_export_invalid_date", "end must be a date such as 2019-06-09", nil)
+			return
+		}
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(-1, 0, 1)
+	if r.URL.Query().Get("start") != "" {
+		start, err = ParseTime(r.URL.Query().Get("start"))
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_export_invalid_date", "start must be a date such as 2019-06-09", nil)
+			return
+		}
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if start.After(end) {
+		SendError(w, http.StatusBadRequest, "community_export_invalid_date", "start must be on or before end", nil)
+		return
+	}
+	if end.Sub(start).Hours()/24 >= CommunityExportMaxDays {
+		SendError(w, http.StatusBadRequest, "community_export_range_too_long", fmt.Sprintf("the range can be at most %d days", CommunityExportMaxDays), nil)
+		return
+	}
+	startString := start.Format("2006-01-02 15:04:05")
+	endString := end.Format("2006-01-02") + " 23:59:59"
+
+	if r.URL.Query().Get("async") == "true" || community.MemberCount > CommunityExportAsyncMemberCount {
+		exp := CommunityExport{
+			CommunityID: communityID,
+			RequestedBy: jwtUser.ID,
+			ExportType:  exportType,
+			Format:      format,
+			Start:       startString,
+			End:         endString,
+		}
+		err = CreateCommunityExport(&exp)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "community_export_error", "could not create the export", err)
+			return
+		}
+		go ProcessCommunityExport(exp.ID)
+		Send(w, http.StatusAccepted, exp)
+		return
+	}
+
+	// the export is built first so a failure can still be sent as an error instead of a cut off file
+	buf := new(bytes.Buffer)
+	err = WriteCommunityExport(buf, communityID, exportType, format, startString, endString)
+	if err != nil {
+		Log("error", "could not generate the export", "community_export_fail", map[string]string{
+			"communityId": fmt.Sprintf("%d", communityID),
+			"exportType":  exportType,
+			"error":       err.Error(),
+		})
+		SendError(w, http.StatusInternalServerError, "community_export_error", "could not generate the export", nil)
+		return
+	}
+	w.Header().Set("Content-Type", GetCommunityExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="community-%d-%s.%s"`, communityID, exportType, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+	return
+}
+
+// GetCommunityExportsRoute gets the exports for a community that can still be downloaded or are being generated
+func GetCommunityExportsRoute(w http.ResponseWriter, r *http.Request) {
+	_, communityID, ok := checkCommunityExportPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	exports, err := GetCommunityExports(communityID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "community_export_error", "could not get the exports", err)
+		return
+	}
+	Send(w, http.StatusOK, exports)
+	return
+}
+
+// GetCommunityExportRoute gets a single export, such as to check whether it is ready
+func GetCommunityExportRoute(w http.ResponseWriter, r *http.Request) {
+	exp, ok := getCommunityExportFromRequest(w, r)
+	if !ok {
+		return
+	}
+	Send(w, http.StatusOK, exp)
+	return
+}
+
+// DownloadCommunityExportRoute sends the file for a finished export
+func DownloadCommunityExportRoute(w http.ResponseWriter, r *http.Request) {
+	exp, ok := getCommunityExportFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if exp.Status != CommunityExportStatusReady {
+		SendError(w, http.StatusConflict, "community_export_not_ready", "that export is not ready", exp)
+		return
+	}
+	file, err := Config.Storage.Open(exp.StorageKey)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_export_not_found", "that export could not be found", nil)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", GetCommunityExportContentType(exp.Format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="community-%d-%s.%s"`, exp.CommunityID, exp.ExportType, exp.Format))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, file)
+	if err != nil {
+		Log("error", "could not send the export", "community_export_download_fail", map[string]string{
+			"exportId": fmt.Sprintf("%d", exp.ID),
+			"error":    err.Error(),
+		})
+	}
+	return
+}
+
+// checkCommunityExportPermission checks that the user is an admin of the community in the url
+func checkCommunityExportPermission(r *http.Request) (JWTUser, int64, bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		return jwtUser, 0, false
+	}
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil || communityID == 0 {
+		return jwtUser, 0, false
+	}
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		return jwtUser, 0, false
+	}
+	return jwtUser, communityID, true
+}
+
+// getCommunityExportFromRequest checks permissions and loads the export in the url, sending the error if either fails
+func getCommunityExportFromRequest(w http.ResponseWriter, r *http.Request) (*CommunityExport, bool) {
+	_, communityID, ok := checkCommunityExportPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return nil, false
+	}
+	exportID, exportIDErr := strconv.ParseInt(chi.URLParam(r, "exportID"), 10, 64)
+	if exportIDErr != nil {
+		SendError(w, http.StatusNotFound, "community_export_not_found", "that export could not be found", nil)
+		return nil, false
+	}
+	exp, err := GetCommunityExport(communityID, exportID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_export_not_found", "that export could not be found", err)
+		return nil, false
+	}
+	return exp, true
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityExportRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      "Export Routes",
+		ShortCode: fmt.Sprintf("export_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// only admins can export
+	code, _, _ := TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export", community.ID), b, ExportCommunityRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export", community.ID), b, ExportCommunityRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports", community.ID), b, GetCommunityExportsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// bad input
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?type=emails", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?format=xlsx", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?type=prayers&start=2019-02-01&end=2019-01-01", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?type=prayers&start=2017-01-01&end=2019-01-01", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// small communities get the file right away
+	code, res, _ := TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	records, err := csv.NewReader(res).ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, 3, len(records))
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?type=requests&format=json", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "[]\n", res.String())
+
+	// async exports are generated in the background
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/export?async=true&format=json", community.ID), b, ExportCommunityRoute, admin.JWT, "")
+	require.Equal(t, http.StatusAccepted, code)
+	_, body, _ := UnmarshalTestMap(res)
+	exp := CommunityExport{}
+	err = mapstructure.Decode(body, &exp)
+	require.Nil(t, err)
+	require.NotEqual(t, int64(0), exp.ID)
+	assert.Equal(t, CommunityExportTypeMembers, exp.ExportType)
+
+	for i := 0; i < 50; i++ {
+		found, err := GetCommunityExport(community.ID, exp.ID)
+		require.Nil(t, err)
+		if found.Status == CommunityExportStatusReady {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports/%d", community.ID, exp.ID), b, GetCommunityExportRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, CommunityExportStatusReady, body["status"])
+	assert.Nil(t, body["storageKey"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports", community.ID), b, GetCommunityExportsRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, exports, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(exports))
+
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports/%d/download", community.ID, exp.ID), b, DownloadCommunityExportRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports/%d/download", community.ID, exp.ID), b, DownloadCommunityExportRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, strings.Contains(res.String(), member.Username))
+
+	// the file is never served publicly
+	found, err := GetCommunityExport(community.ID, exp.ID)
+	require.Nil(t, err)
+	code, _, _ = TestAPICall(http.MethodGet, "/assets/"+found.StorageKey, b, GetAssetRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/exports/%d", community.ID, exp.ID+1000), b, GetCommunityExportRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
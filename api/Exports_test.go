@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityExportValidation(t *testing.T) {
+	assert.True(t, IsValidCommunityExportType(CommunityExportTypeMembers))
+	assert.True(t, IsValidCommunityExportType(CommunityExportTypeRequests))
+	assert.True(t, IsValidCommunityExportType(CommunityExportTypePrayers))
+	assert.False(t, IsValidCommunityExportType("emails"))
+	assert.True(t, IsValidCommunityExportFormat(CommunityExportFormatCSV))
+	assert.True(t, IsValidCommunityExportFormat(CommunityExportFormatJSON))
+	assert.False(t, IsValidCommunityExportFormat("xlsx"))
+	assert.Equal(t, "text/csv", GetCommunityExportContentType(CommunityExportFormatCSV))
+	assert.Equal(t, "application/json", GetCommunityExportContentType(CommunityExportFormatJSON))
+
+	key, err := generateCommunityExportKey(&CommunityExport{CommunityID: 3, ExportType: CommunityExportTypeMembers, Format: CommunityExportFormatCSV})
+	require.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key, StoragePrivatePrefix+"exports/communities/3/members-"))
+	assert.True(t, strings.HasSuffix(key, ".csv"))
+
+	assert.Equal(t, "'=HYPERLINK(\"http://example.com\")", exportCSVCell("=HYPERLINK(\"http://example.com\")"))
+	assert.Equal(t, "'+1", exportCSVCell("+1"))
+	assert.Equal(t, "'-1", exportCSVCell("-1"))
+	assert.Equal(t, "'@SUM(A1)", exportCSVCell("@SUM(A1)"))
+	assert.Equal(t, "Please pray", exportCSVCell("Please pray"))
+	assert.Equal(t, "", exportCSVCell(""))
+}
+
+func TestCommunityExports(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	invited := User{}
+	err = CreateTestUser(&invited)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&invited)
+
+	community := Community{
+		Name:      "Exports",
+		ShortCode: fmt.Sprintf("exports_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, invited.ID, CommunityUserRoleMember, CommunityUserLinkStatusInvited, "abc")
+
+	shown := PrayerRequest{
+		Title:     "Shown",
+		Body:      "Please pray, friends",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&shown)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(shown.ID)
+	AddPrayerRequestToCommunity(shown.ID, community.ID)
+	held := PrayerRequest{
+		Title:     "Held",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&held)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(held.ID)
+	AddPrayerRequestToCommunityWithStatus(held.ID, community.ID, "pending_review")
+
+	AddPrayerMade(admin.ID, shown.ID)
+	AddPrayerMade(member.ID, shown.ID)
+	AddPrayerMade(admin.ID, held.ID)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	start := time.Now().UTC().AddDate(0, 0, -6).Format("2006-01-02") + " 00:00:00"
+	end := today + " 23:59:59"
+
+	// members only includes accepted members and never their emails
+	buf := new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypeMembers, CommunityExportFormatCSV, start, end)
+	require.Nil(t, err)
+	assert.False(t, strings.Contains(buf.String(), member.Email))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.Nil(t, err)
+	require.Equal(t, 3, len(records))
+	assert.Equal(t, []string{"userId", "username", "firstName", "lastName", "role", "joined"}, records[0])
+
+	// requests only includes the approved ones, with their prayer counts
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypeRequests, CommunityExportFormatCSV, start, end)
+	require.Nil(t, err)
+	records, err = csv.NewReader(buf).ReadAll()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(records))
+	assert.Equal(t, "Shown", records[1][1])
+	assert.Equal(t, "Please pray, friends", records[1][2])
+	assert.Equal(t, "2", records[1][7])
+
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypePrayers, CommunityExportFormatJSON, start, end)
+	require.Nil(t, err)
+	assert.True(t, strings.Contains(buf.String(), fmt.Sprintf(`{"day":"%s","prayerRequestId":%d,"title":"Shown","prayers":2}`, today, shown.ID)))
+
+	// nothing happened outside of the range
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypePrayers, CommunityExportFormatJSON, "2017-01-01 00:00:00", "2017-01-31 23:59:59")
+	require.Nil(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+
+	err = WriteCommunityExport(buf, community.ID, "emails", CommunityExportFormatCSV, start, end)
+	assert.NotNil(t, err)
+
+	// a request limited to selected communities drops out of exports and webhooks once its share is revoked
+	shown.Privacy = PrayerRequestPrivacySelectedCommunities
+	shown.Status = PrayerRequestStatusPending
+	err = UpdatePrayerRequest(&shown)
+	require.Nil(t, err)
+	share := PrayerRequestShare{
+		PrayerRequestID: shown.ID,
+		CommunityID:     community.ID,
+	}
+	err = CreatePrayerRequestShare(&share)
+	require.Nil(t, err)
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypeRequests, CommunityExportFormatCSV, start, end)
+	require.Nil(t, err)
+	records, err = csv.NewReader(buf).ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(records))
+
+	webhook := Webhook{
+		CommunityID: community.ID,
+		URL:         "https://hooks.example.com/exports",
+		Events:      []string{WebhookEventReportFiled},
+	}
+	err = CreateWebhook(&webhook)
+	require.Nil(t, err)
+	defer DeleteWebhook(webhook.ID)
+	err = QueuePrayerRequestWebhookEvent(WebhookEventReportFiled, shown.ID, map[string]int64{"requestId": shown.ID})
+	require.Nil(t, err)
+	deliveries, err := GetWebhookDeliveries(webhook.ID, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+
+	err = DeletePrayerRequestShare(shown.ID, share.ID)
+	require.Nil(t, err)
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypeRequests, CommunityExportFormatCSV, start, end)
+	require.Nil(t, err)
+	records, err = csv.NewReader(buf).ReadAll()
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	buf = new(bytes.Buffer)
+	err = WriteCommunityExport(buf, community.ID, CommunityExportTypePrayers, CommunityExportFormatJSON, start, end)
+	require.Nil(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+
+	err = QueuePrayerRequestWebhookEvent(WebhookEventReportFiled, shown.ID, map[string]int64{"requestId": shown.ID})
+	require.Nil(t, err)
+	deliveries, err = GetWebhookDeliveries(webhook.ID, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+
+	// background exports are saved privately and can be downloaded until they expire
+	exp := CommunityExport{
+		CommunityID: community.ID,
+		RequestedBy: admin.ID,
+		ExportType:  CommunityExportTypeMembers,
+		Format:      CommunityExportFormatJSON,
+		Start:       start,
+		End:         end,
+	}
+	err = CreateCommunityExport(&exp)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityExportStatusPending, exp.Status)
+	assert.Equal(t, "", exp.DownloadURL)
+
+	err = ProcessCommunityExport(exp.ID)
+	require.Nil(t, err)
+	ready, err := GetCommunityExport(community.ID, exp.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityExportStatusReady, ready.Status)
+	assert.NotEqual(t, "", ready.Expires)
+	assert.True(t, strings.HasSuffix(ready.DownloadURL, fmt.Sprintf("/communities/%d/exports/%d/download", community.ID, exp.ID)))
+	assert.True(t, strings.HasPrefix(ready.StorageKey, StoragePrivatePrefix))
+
+	file, err := Config.Storage.Open(ready.StorageKey)
+	require.Nil(t, err)
+	data, err := ioutil.ReadAll(file)
+	file.Close()
+	require.Nil(t, err)
+	assert.True(t, strings.Contains(string(data), member.Username))
+
+	// processing again does nothing since it was already claimed
+	err = ProcessCommunityExport(exp.ID)
+	assert.Nil(t, err)
+
+	// exports belong to their community
+	_, err = GetCommunityExport(community.ID+1, exp.ID)
+	assert.NotNil(t, err)
+
+	exports, err := GetCommunityExports(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(exports))
+
+	// once expired, the file and record are purged
+	_, err = Config.DbConn.Exec("UPDATE CommunityExports SET expires = DATE_SUB(NOW(), INTERVAL 1 DAY) WHERE id = ?", exp.ID)
+	require.Nil(t, err)
+	exports, err = GetCommunityExports(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(exports))
+	err = PurgeExpiredCommunityExports()
+	require.Nil(t, err)
+	_, err = GetCommunityExport(community.ID, exp.ID)
+	assert.NotNil(t, err)
+	_, err = Config.Storage.Open(ready.StorageKey)
+	assert.NotNil(t, err)
+}
@@ -24,7 +24,8 @@ const AppContextKeyFound key = "found"
 
 // PregxasAPIReturn represents a standard API return object
 type PregxasAPIReturn struct {
-	Data interface{} `json:"data,omitempty"`
+	Data interface{}     `json:"data,omitempty"`
+	Meta *PregxasAPIMeta `json:"meta,omitempty"`
 }
 
 // PregxasAPIError represents the data key of an error for the API
@@ -185,8 +186,9 @@ func CheckForUser(r *http.Request) (user JWTUser, err error) {
 // ProcessQuery parses the query string tokens for the following fields and then returns them:
 // start - The start of a date filter
 // end - The end of a date filter
-// limit - The number of entries to return
-// offset - Any offset in the pagination
+// count - The number of entries to return, between 1 and PageSizeMax
+// offset - Any offset in the pagination; an offset cursor from SendPage takes its place. A malformed cursor is ignored here, so
+// list routes reject it with CheckPageCursor first
 // sortField - Any field that should be sorted
 // sortDir - The direction of the sort
 // filterKey - The filter field
@@ -212,14 +214,21 @@ func ProcessQuery(r *http.Request) (start, end string, count, offset int, sortFi
 
 	//try to convert the limit and offset
 	count, err = strconv.Atoi(countQ)
-	if err != nil {
-		count = 500
+	if err != nil || count < 1 {
+		count = PageSizeDefault
+	}
+	if count > PageSizeMax {
+		count = PageSizeMax
 	}
 
 	offset, err = strconv.Atoi(offsetQ)
-	if err != nil {
+	if err != nil || offset < 0 {
 		offset = 0
 	}
+	cursor, err := GetPageCursor(r)
+	if err == nil && cursor != nil {
+		offset = cursor.Offset
+	}
 
 	sortDir = strings.ToUpper(sortDirQ)
 	if sortDir != "ASC" && sortDir != "DESC" {
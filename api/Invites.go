@@ -0,0 +1,239 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CommunityInvite is a shareable link that lets anyone with the code join a community
+type CommunityInvite struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	Code        string `json:"code" db:"code"`
+	CreatedBy   int64  `json:"createdBy" db:"createdBy"`
+	// Role is the role given to users who join through the link
+	Role string `json:"role" db:"role"`
+	// Approval is either auto_accept or approval_required
+	Approval string `json:"approval" db:"approval"`
+	// MaxUses is the number of times the link can be used; 0 means there is no limit
+	MaxUses int64 `json:"maxUses" db:"maxUses"`
+	Uses    int64 `json:"uses" db:"uses"`
+	// Expires is when the link stops working, stored in UTC; blank means it never expires
+	Expires string `json:"expires" db:"expires"`
+	Status  string `json:"status" db:"status"`
+	Created string `json:"created" db:"created"`
+
+	// URL is the link to share
+	URL string `json:"url" db:"-"`
+	// UsedBy is only populated when getting a single invite
+	UsedBy []CommunityInviteUse `json:"usedBy,omitempty" db:"-"`
+}
+
+// CommunityInviteUse records a user joining through an invite link
+type CommunityInviteUse struct {
+	InviteID  int64  `json:"inviteId" db:"inviteId"`
+	UserID    int64  `json:"userId" db:"userId"`
+	Used      string `json:"used" db:"used"`
+	Username  string `json:"username" db:"username"`
+	FirstName string `json:"firstName" db:"firstName"`
+	LastName  string `json:"lastName" db:"lastName"`
+}
+
+// CommunityInvitePreview is the public information shown about an invite before a user signs up or logs in
+type CommunityInvitePreview struct {
+	CommunityID      int64  `json:"communityId"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	LogoURL          string `json:"logoUrl,omitempty"`
+	MemberCount      int64  `json:"memberCount"`
+	ApprovalRequired bool   `json:"approvalRequired"`
+	Expires          string `json:"expires"`
+}
+
+const (
+	// CommunityInviteApprovalAuto adds users who use the link as accepted members
+	CommunityInviteApprovalAuto = "auto_accept"
+	// CommunityInviteApprovalRequired adds users who use the link as requested, waiting on an admin
+	CommunityInviteApprovalRequired = "approval_required"
+
+	// CommunityInviteStatusActive is a link that can be used
+	CommunityInviteStatusActive = "active"
+	// CommunityInviteStatusRevoked is a link an admin has turned off
+	CommunityInviteStatusRevoked = "revoked"
+)
+
+// ErrCommunityInviteUnavailable is returned when an invite is revoked, expired, or used up
+var ErrCommunityInviteUnavailable = errors.New("that invite is no longer available")
+
+// CreateCommunityInvite creates a new invite link with a random code. An expires that can't be read is an error rather than
+// an invite that never expires
+func CreateCommunityInvite(input *CommunityInvite) error {
+	if input.Expires != "" {
+		if _, err := ParseTime(input.Expires); err != nil {
+			return errors.New("expires is invalid")
+		}
+	}
+	code, err := generateCommunityInviteCode()
+	if err != nil {
+		return err
+	}
+	input.Code = code
+	input.Uses = 0
+	input.Status = CommunityInviteStatusActive
+	input.processForDB()
+	defer input.processForAPI()
+	res, err := Config.DbConn.NamedExec(`INSERT INTO CommunityInvites (communityId, code, createdBy, role, approval, maxUses, uses, expires, status, created) 
+		VALUES (:communityId, :code, :createdBy, :role, :approval, :maxUses, 0, :expires, :status, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetCommunityInvite gets an invite in a community along with who has used it
+func GetCommunityInvite(communityID, inviteID int64) (*CommunityInvite, error) {
+	invite := &CommunityInvite{}
+	err := Config.DbConn.Get(invite, "SELECT * FROM CommunityInvites WHERE id = ? AND communityId = ?", inviteID, communityID)
+	if err != nil {
+		return nil, err
+	}
+	invite.UsedBy = []CommunityInviteUse{}
+	err = Config.DbConn.Select(&invite.UsedBy, `SELECT ciu.*, u.username, u.firstName, u.lastName 
+		FROM CommunityInviteUses ciu, Users u WHERE ciu.inviteId = ? AND ciu.userId = u.id ORDER BY ciu.used`, inviteID)
+	for i := range invite.UsedBy {
+		invite.UsedBy[i].Used, _ = ParseTimeToISO(invite.UsedBy[i].Used)
+	}
+	invite.processForAPI()
+	return invite, err
+}
+
+// GetCommunityInviteByCode gets an invite by its code
+func GetCommunityInviteByCode(code string) (*CommunityInvite, error) {
+	invite := &CommunityInvite{}
+	err := Config.DbConn.Get(invite, "SELECT * FROM CommunityInvites WHERE code = ?", code)
+	if err != nil {
+		return nil, err
+	}
+	invite.processForAPI()
+	return invite, nil
+}
+
+// GetCommunityInvites gets the invites in a community, newest first
+func GetCommunityInvites(communityID int64) ([]CommunityInvite, error) {
+	invites := []CommunityInvite{}
+	err := Config.DbConn.Select(&invites, "SELECT * FROM CommunityInvites WHERE communityId = ? ORDER BY created DESC, id DESC", communityID)
+	for i := range invites {
+		invites[i].processForAPI()
+	}
+	return invites, err
+}
+
+// RevokeCommunityInvite turns off an invite link
+func RevokeCommunityInvite(communityID, inviteID int64) error {
+	_, err := Config.DbConn.Exec("UPDATE CommunityInvites SET status = ? WHERE id = ? AND communityId = ?", CommunityInviteStatusRevoked, inviteID, communityID)
+	return err
+}
+
+// UseCommunityInvite claims a use of the invite for the user. The claim only succeeds if the link is still active, unexpired, and has
+// uses left, so two users racing for the last use cannot both get in
+func UseCommunityInvite(inviteID, userID int64) error {
+	res, err := Config.DbConn.Exec(`UPDATE CommunityInvites SET uses = uses + 1 
+		WHERE id = ? AND status = 'active' AND (maxUses = 0 OR uses < maxUses) AND (expires = '1970-01-01 00:00:00' OR expires > UTC_TIMESTAMP())`, inviteID)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCommunityInviteUnavailable
+	}
+	_, err = Config.DbConn.Exec("INSERT INTO CommunityInviteUses (inviteId, userId, used) VALUES (?, ?, NOW())", inviteID, userID)
+	return err
+}
+
+// DeleteCommunityInvitesForCommunity deletes all of the invites in a community and their usage
+func DeleteCommunityInvitesForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE ciu FROM CommunityInviteUses ciu 
+		INNER JOIN CommunityInvites ci ON ci.id = ciu.inviteId WHERE ci.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunityInvites WHERE communityId = ?", communityID)
+	return err
+}
+
+// IsAvailable checks if the invite can still be used
+func (input *CommunityInvite) IsAvailable() bool {
+	if input.Status != CommunityInviteStatusActive {
+		return false
+	}
+	if input.MaxUses > 0 && input.Uses >= input.MaxUses {
+		return false
+	}
+	if input.Expires != "" {
+		expires, err := ParseTime(input.Expires)
+		if err != nil || !expires.After(time.Now()) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateCommunityInviteCode creates a random code; the code is the only thing protecting the link, so it comes from crypto/rand
+func generateCommunityInviteCode() (string, error) {
+	b := make([]byte, 12)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// processForDB ensures data consistency
+func (input *CommunityInvite) processForDB() {
+	if input.Role != CommunityUserRoleAdmin {
+		input.Role = CommunityUserRoleMember
+	}
+	if input.Approval != CommunityInviteApprovalRequired {
+		input.Approval = CommunityInviteApprovalAuto
+	}
+	if input.MaxUses < 0 {
+		input.MaxUses = 0
+	}
+	if input.Status != CommunityInviteStatusRevoked {
+		input.Status = CommunityInviteStatusActive
+	}
+	if input.Expires == "" {
+		input.Expires = "1970-01-01 00:00:00"
+	} else {
+		parsed, err := ParseTime(input.Expires)
+		if err == nil {
+			input.Expires = parsed.UTC().Format("2006-01-02 15:04:05")
+		}
+	}
+}
+
+// processForAPI cleans up the output
+func (input *CommunityInvite) processForAPI() {
+	if input == nil {
+		return
+	}
+	if input.Expires == "1970-01-01 00:00:00" {
+		input.Expires = ""
+	} else {
+		input.Expires, _ = ParseTimeToISO(input.Expires)
+	}
+	if input.Created == "1970-01-01 00:00:00" {
+		input.Created = ""
+	} else {
+		input.Created, _ = ParseTimeToISO(input.Created)
+	}
+	input.URL = fmt.Sprintf("%s/invites/%s", strings.TrimSuffix(Config.WebURL, "/"), input.Code)
+}
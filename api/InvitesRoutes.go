@@ -0,0 +1,286 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *CommunityInvite) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreateCommunityInviteRoute allows an admin to create a shareable invite link
+func CreateCommunityInviteRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := CommunityInvite{}
+	render.Bind(r, &input)
+	if input.Role != "" && input.Role != CommunityUserRoleMember && input.Role != CommunityUserRoleAdmin {
+		SendError(w, http.StatusBadRequest, "community_invite_invalid_role", "role must be member or admin", input)
+		return
+	}
+	if input.Approval != "" && input.Approval != CommunityInviteApprovalAuto && input.Approval != CommunityInviteApprovalRequired {
+		SendError(w, http.StatusBadRequest, "community_invite_invalid_approval", "approval must be auto_accept or approval_required", input)
+		return
+	}
+	if input.MaxUses < 0 {
+		SendError(w, http.StatusBadRequest, "community_invite_invalid_max_uses", "maxUses cannot be negative", input)
+		return
+	}
+	if input.Expires != "" {
+		expires, err := ParseTime(input.Expires)
+		if err != nil || !expires.After(time.Now()) {
+			SendError(w, http.StatusBadRequest, "community_invite_invalid_expires", "expires must be a valid date time in the future", input)
+			return
+		}
+	}
+	input.CommunityID = communityID
+	input.CreatedBy = jwtUser.ID
+
+	err = CreateCommunityInvite(&input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_invite_create_error", "could not create that invite", err)
+		return
+	}
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetCommunityInvitesRoute gets the invite links for a community
+func GetCommunityInvitesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	invites, err := GetCommunityInvites(communityID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_invites_error", "could not get the invites", err)
+		return
+	}
+	Send(w, http.StatusOK, invites)
+	return
+}
+
+// GetCommunityInviteRoute gets a single invite link and who has used it
+func GetCommunityInviteRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	inviteID, inviteIDErr := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if communityIDErr != nil || inviteIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	invite, err := GetCommunityInvite(communityID, inviteID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_invite_not_found", "that invite could not be found", nil)
+		return
+	}
+	Send(w, http.StatusOK, invite)
+	return
+}
+
+// RevokeCommunityInviteRoute turns off an invite link; users who already joined are not affected
+func RevokeCommunityInviteRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	inviteID, inviteIDErr := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if communityIDErr != nil || inviteIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	_, err = GetCommunityInvite(communityID, inviteID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_invite_not_found", "that invite could not be found", nil)
+		return
+	}
+
+	err = RevokeCommunityInvite(communityID, inviteID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_invite_revoke_error", "could not revoke that invite", err)
+		return
+	}
+	invite, _ := GetCommunityInvite(communityID, inviteID)
+	Send(w, http.StatusOK, invite)
+	return
+}
+
+// PreviewCommunityInviteRoute shows the community an invite is for. It does not require a user so that the community can be shown
+// before signing up
+func PreviewCommunityInviteRoute(w http.ResponseWriter, r *http.Request) {
+	invite, community, ok := getAvailableCommunityInvite(chi.URLParam(r, "code"))
+	if !ok {
+		SendError(w, http.StatusNotFound, "community_invite_not_found", "that invite is invalid or has expired", nil)
+		return
+	}
+
+	Send(w, http.StatusOK, CommunityInvitePreview{
+		CommunityID:      community.ID,
+		Name:             community.Name,
+		Description:      community.Description,
+		LogoURL:          community.LogoURL,
+		MemberCount:      community.MemberCount,
+		ApprovalRequired: invite.Approval == CommunityInviteApprovalRequired,
+		Expires:          invite.Expires,
+	})
+	return
+}
+
+// AcceptCommunityInviteRoute joins the user to the community through an invite link. Depending on the link, the user is either
+// accepted immediately or waits for an admin to approve the request. A full community, or one with people waiting, puts the
+// user on its waitlist instead, the same as asking to join
+func AcceptCommunityInviteRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	invite, community, ok := getAvailableCommunityInvite(chi.URLParam(r, "code"))
+	if !ok {
+		SendError(w, http.StatusNotFound, "community_invite_not_found", "that invite is invalid or has expired", nil)
+		return
+	}
+
+	status := CommunityUserLinkStatusAccepted
+	if invite.Approval == CommunityInviteApprovalRequired {
+		status = CommunityUserLinkStatusRequested
+	}
+
+	link, linkErr := GetCommunityUserLink(community.ID, jwtUser.ID)
+	if linkErr == nil && (link.Status == CommunityUserLinkStatusAccepted || link.Status == status) {
+		SendError(w, http.StatusConflict, "community_invite_already_member", "you are already a member of, or have requested to join, that community", nil)
+		return
+	}
+
+	// an invite doesn't skip the waitlist; while anyone is ahead of the user in line, they join the end of it or keep their place
+	plan := plans[community.Plan]
+	currentCount, _ := GetCountOfUsersInCommunity(community.ID)
+	ahead, _ := GetCountOfCommunityWaitlist(community.ID)
+	waitlisted := linkErr == nil && link.Status == CommunityUserLinkStatusWaitlisted
+	if waitlisted {
+		ahead = link.WaitlistPosition - 1
+	}
+	if plan.AllowedUsers <= currentCount || ahead > 0 {
+		position := link.WaitlistPosition
+		if !waitlisted {
+			position, err = JoinCommunityWaitlist(community.ID, jwtUser.ID, GenerateShortCode(community.ID, jwtUser.ID))
+			if err != nil {
+				SendError(w, http.StatusBadRequest, "community_invite_accept_error", "could not join that community", err)
+				return
+			}
+		}
+		Send(w, http.StatusOK, map[string]interface{}{
+			"communityId": community.ID,
+			"status":      CommunityUserLinkStatusWaitlisted,
+			"position":    position,
+		})
+		return
+	}
+
+	err = UseCommunityInvite(invite.ID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_invite_not_found", "that invite is invalid or has expired", nil)
+		return
+	}
+
+	if linkErr == nil {
+		// the user was already invited, had requested, or had declined, so the existing link is updated
+		err = UpdateCommunityUserLinkRole(community.ID, jwtUser.ID, invite.Role)
+		if err == nil {
+			err = UpdateCommunityUserLink(community.ID, jwtUser.ID, status)
+		}
+		if err == nil && waitlisted {
+			err = removeFromCommunityWaitlist(community.ID, jwtUser.ID, link.WaitlistPosition)
+		}
+	} else {
+		err = CreateCommunityUserLink(community.ID, jwtUser.ID, invite.Role, status, GenerateShortCode(community.ID, jwtUser.ID))
+	}
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_invite_accept_error", "could not join that community", err)
+		return
+	}
+	if status == CommunityUserLinkStatusAccepted {
+		QueueMemberJoinedWebhookEvent(community.ID, jwtUser.ID)
+	}
+
+	Send(w, http.StatusOK, map[string]interface{}{
+		"communityId": community.ID,
+		"status":      status,
+	})
+	return
+}
+
+// getAvailableCommunityInvite looks up an invite and its community, checking that both can still be used
+func getAvailableCommunityInvite(code string) (*CommunityInvite, *Community, bool) {
+	if code == "" {
+		return nil, nil, false
+	}
+	invite, err := GetCommunityInviteByCode(code)
+	if err != nil || !invite.IsAvailable() {
+		return nil, nil, false
+	}
+	community, err := GetCommunityByID(invite.CommunityID)
+	if err != nil || community.IsArchived() {
+		return nil, nil, false
+	}
+	return invite, community, true
+}
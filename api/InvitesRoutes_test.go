@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityInviteRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	joiner := User{}
+	err = CreateTestUser(&joiner)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&joiner)
+	requester := User{}
+	err = CreateTestUser(&requester)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&requester)
+
+	community := Community{
+		Name:        fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		Description: "Come pray with us",
+		ShortCode:   fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:     admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// only admins can create invites
+	b.Reset()
+	enc.Encode(map[string]interface{}{})
+	code, _, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// bad input
+	b.Reset()
+	enc.Encode(map[string]interface{}{"role": "owner"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{"expires": time.Now().Add(-1 * time.Hour).UTC().Format(time.RFC3339)})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{"expires": "next tuesday"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{"maxUses": -1})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// an auto accept link with one use
+	b.Reset()
+	enc.Encode(map[string]interface{}{"maxUses": 1, "expires": time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)})
+	code, res, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	invite := CommunityInvite{}
+	mapstructure.Decode(body, &invite)
+	invite.ID, _ = convertTestJSONFloatToInt(body["id"])
+	assert.NotEqual(t, "", invite.Code)
+	assert.NotEqual(t, "", invite.URL)
+
+	// the preview does not need a user
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/invites/%s", invite.Code), b, PreviewCommunityInviteRoute, "", "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, community.Name, body["name"])
+	assert.Equal(t, community.Description, body["description"])
+	assert.Equal(t, false, body["approvalRequired"])
+	code, _, _ = TestAPICall(http.MethodGet, "/invites/notacode", b, PreviewCommunityInviteRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// accepting needs a user
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	// existing members cannot use it
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, member.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, joiner.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, CommunityUserLinkStatusAccepted, body["status"])
+	role, err := GetUserRoleForCommunity(community.ID, joiner.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, CommunityUserRoleMember, role)
+
+	// the link is used up
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, requester.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/invites/%s", invite.Code), b, PreviewCommunityInviteRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// admins can see the usage
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/invites/%d", community.ID, invite.ID), b, GetCommunityInviteRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, float64(1), body["uses"])
+	assert.Equal(t, 1, len(body["usedBy"].([]interface{})))
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/invites/%d", community.ID, invite.ID), b, GetCommunityInviteRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// an approval required link
+	b.Reset()
+	enc.Encode(map[string]interface{}{"approval": CommunityInviteApprovalRequired})
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/invites", community.ID), b, CreateCommunityInviteRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	approvalCode := body["code"].(string)
+	approvalID, _ := convertTestJSONFloatToInt(body["id"])
+
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", approvalCode), b, AcceptCommunityInviteRoute, requester.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, CommunityUserLinkStatusRequested, body["status"])
+	link, err := GetCommunityUserLink(community.ID, requester.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityUserLinkStatusRequested, link.Status)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", approvalCode), b, AcceptCommunityInviteRoute, requester.JWT, "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/invites", community.ID), b, GetCommunityInvitesRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 2, len(bodyA))
+
+	// revoke it
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/invites/%d", community.ID, approvalID), b, RevokeCommunityInviteRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/invites/%d", community.ID, approvalID), b, RevokeCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/communities/%d/invites/0", community.ID), b, RevokeCommunityInviteRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/invites/%s", approvalCode), b, PreviewCommunityInviteRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestCommunityInviteWaitlist(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	original := plans[CommunityPlanFree]
+	plans[CommunityPlanFree] = CommunityPlan{AllowedUsers: 2, AllowedActiveRequests: original.AllowedActiveRequests, AllowedSubGroups: original.AllowedSubGroups}
+	defer func() { plans[CommunityPlanFree] = original }()
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	waiting := User{}
+	err = CreateTestUser(&waiting)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&waiting)
+	newcomer := User{}
+	err = CreateTestUser(&newcomer)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&newcomer)
+
+	community := Community{
+		Name:             fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode:        fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		Plan:             CommunityPlanFree,
+		UserSignupStatus: CommunityUserSignupStatusAccept,
+		OwnerID:          admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	_, err = JoinCommunityWaitlist(community.ID, waiting.ID, "")
+	require.Nil(t, err)
+
+	invite := CommunityInvite{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+	}
+	err = CreateCommunityInvite(&invite)
+	require.Nil(t, err)
+
+	// the community is full, so an invite joins the end of the line
+	code, res, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, newcomer.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, CommunityUserLinkStatusWaitlisted, body["status"])
+	assert.Equal(t, float64(2), body["position"])
+
+	// a seat opens without promoting anyone; the person at the front can use an invite, and the line moves up behind them
+	plans[CommunityPlanFree] = CommunityPlan{AllowedUsers: 3, AllowedActiveRequests: original.AllowedActiveRequests, AllowedSubGroups: original.AllowedSubGroups}
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, newcomer.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	link, err := GetCommunityUserLink(community.ID, newcomer.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityUserLinkStatusWaitlisted, link.Status)
+
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/invites/%s", invite.Code), b, AcceptCommunityInviteRoute, waiting.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, CommunityUserLinkStatusAccepted, body["status"])
+	link, err = GetCommunityUserLink(community.ID, waiting.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), link.WaitlistPosition)
+	link, err = GetCommunityUserLink(community.ID, newcomer.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), link.WaitlistPosition)
+
+	// leaving frees a seat for the next person in line
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/me/communities/%d", community.ID), b, LeaveCommunityRoute, member.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	link, err = GetCommunityUserLink(community.ID, newcomer.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityUserLinkStatusAccepted, link.Status)
+	assert.Equal(t, int64(0), link.WaitlistPosition)
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityInviteAvailability(t *testing.T) {
+	invite := CommunityInvite{
+		Status: CommunityInviteStatusActive,
+	}
+	assert.True(t, invite.IsAvailable())
+
+	invite.MaxUses = 2
+	invite.Uses = 1
+	assert.True(t, invite.IsAvailable())
+	invite.Uses = 2
+	assert.False(t, invite.IsAvailable())
+
+	invite.MaxUses = 0
+	invite.Expires = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	assert.True(t, invite.IsAvailable())
+	invite.Expires = time.Now().Add(-1 * time.Hour).UTC().Format(time.RFC3339)
+	assert.False(t, invite.IsAvailable())
+
+	invite.Expires = ""
+	invite.Status = CommunityInviteStatusRevoked
+	assert.False(t, invite.IsAvailable())
+}
+
+func TestCommunityInviteCRUD(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUser(admin.ID)
+	user := User{}
+	err = CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUser(user.ID)
+	user2 := User{}
+	err = CreateTestUser(&user2)
+	require.Nil(t, err)
+	defer DeleteUser(user2.ID)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	bad := CommunityInvite{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		Expires:     "next tuesday",
+	}
+	err = CreateCommunityInvite(&bad)
+	assert.NotNil(t, err)
+
+	invite := CommunityInvite{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		MaxUses:     1,
+	}
+	err = CreateCommunityInvite(&invite)
+	require.Nil(t, err)
+	assert.NotZero(t, invite.ID)
+	assert.Equal(t, 24, len(invite.Code))
+	assert.Equal(t, CommunityUserRoleMember, invite.Role)
+	assert.Equal(t, CommunityInviteApprovalAuto, invite.Approval)
+	assert.Equal(t, "", invite.Expires)
+
+	found, err := GetCommunityInviteByCode(invite.Code)
+	require.Nil(t, err)
+	assert.Equal(t, invite.ID, found.ID)
+	assert.True(t, found.IsAvailable())
+
+	// only one use is allowed
+	err = UseCommunityInvite(invite.ID, user.ID)
+	assert.Nil(t, err)
+	err = UseCommunityInvite(invite.ID, user2.ID)
+	assert.Equal(t, ErrCommunityInviteUnavailable, err)
+
+	found, err = GetCommunityInvite(community.ID, invite.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), found.Uses)
+	require.Equal(t, 1, len(found.UsedBy))
+	assert.Equal(t, user.ID, found.UsedBy[0].UserID)
+	assert.False(t, found.IsAvailable())
+
+	invites, err := GetCommunityInvites(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(invites))
+
+	err = RevokeCommunityInvite(community.ID, invite.ID)
+	assert.Nil(t, err)
+	found, err = GetCommunityInvite(community.ID, invite.ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityInviteStatusRevoked, found.Status)
+
+	err = DeleteCommunityInvitesForCommunity(community.ID)
+	assert.Nil(t, err)
+	_, err = GetCommunityInvite(community.ID, invite.ID)
+	assert.NotNil(t, err)
+}
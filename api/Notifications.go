@@ -0,0 +1,167 @@
+package api
+
+import "strings"
+
+// NotificationPreference is a user's choice of how they want to be told about a specific type of event
+type NotificationPreference struct {
+	UserID           int64  `json:"userId" db:"userId"`
+	NotificationType string `json:"notificationType" db:"notificationType"`
+	Channel          string `json:"channel" db:"channel"`
+}
+
+const (
+	// NotificationChannelEmail sends the notification by email
+	NotificationChannelEmail = "email"
+	// NotificationChannelNone means the user does not want to be notified
+	NotificationChannelNone = "none"
+
+	// NotificationTypeCommunityAnnouncement is sent when an admin posts an announcement to a community
+	NotificationTypeCommunityAnnouncement = "community_announcement"
+	// NotificationTypePrayerVigilReminder is sent before a user's prayer vigil slot starts
+	NotificationTypePrayerVigilReminder = "prayer_vigil_reminder"
+	// NotificationTypeCommunityWaitlist is sent to admins when someone joins a full community's waitlist, and to the
+	// user when they are promoted off of it
+	NotificationTypeCommunityWaitlist = "community_waitlist"
+	// NotificationTypePrayerRequestUpdate is sent when the author posts an update on a request the user prayed for or has
+	// on a prayer list
+	NotificationTypePrayerRequestUpdate = "prayer_request_update"
+	// NotificationTypePrayerRequestComment is sent to the author when someone comments on their request
+	NotificationTypePrayerRequestComment = "prayer_request_comment"
+	// NotificationTypePrayerRequestReminder is sent to the author when a pending request hasn't been updated in a while
+	NotificationTypePrayerRequestReminder = "prayer_request_reminder"
+)
+
+// notificationTypes are all of the notification types a user can set a preference for; every type defaults to email
+var notificationTypes = []string{
+	NotificationTypeCommunityAnnouncement,
+	NotificationTypePrayerVigilReminder,
+	NotificationTypeCommunityWaitlist,
+	NotificationTypePrayerRequestUpdate,
+	NotificationTypePrayerRequestComment,
+	NotificationTypePrayerRequestReminder,
+}
+var notificationChannels = []string{NotificationChannelEmail, NotificationChannelNone}
+
+// GetNotificationTypes gets the notification types
+func GetNotificationTypes() []string {
+	return notificationTypes
+}
+
+// IsValidNotificationType checks if the input is a known notification type
+func IsValidNotificationType(input string) bool {
+	for i := range notificationTypes {
+		if notificationTypes[i] == input {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidNotificationChannel checks if the input is a known notification channel
+func IsValidNotificationChannel(input string) bool {
+	for i := range notificationChannels {
+		if notificationChannels[i] == input {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNotificationPreferencesForUser gets every notification preference for the user, filling in the defaults for anything they haven't set
+func GetNotificationPreferencesForUser(userID int64) ([]NotificationPreference, error) {
+	saved := []NotificationPreference{}
+	err := Config.DbConn.Select(&saved, "SELECT * FROM UserNotificationPreferences WHERE userId = ?", userID)
+	prefs := []NotificationPreference{}
+	for i := range notificationTypes {
+		pref := NotificationPreference{
+			UserID:           userID,
+			NotificationType: notificationTypes[i],
+			Channel:          NotificationChannelEmail,
+		}
+		for j := range saved {
+			if saved[j].NotificationType == pref.NotificationType {
+				pref.Channel = saved[j].Channel
+			}
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, err
+}
+
+// SetNotificationPreference saves a user's preference for a notification type
+func SetNotificationPreference(userID int64, notificationType, channel string) error {
+	_, err := Config.DbConn.Exec(`INSERT INTO UserNotificationPreferences (userId, notificationType, channel) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE channel = VALUES(channel)`, userID, strings.ToLower(notificationType), strings.ToLower(channel))
+	return err
+}
+
+// GetEmailsToNotifyInCommunity gets the email addresses of the accepted members of a community who want to be emailed about
+// the notification type. The excluded user, usually whoever caused the notification, is left out
+func GetEmailsToNotifyInCommunity(communityID, excludeUserID int64, notificationType string) ([]string, error) {
+	emails := []string{}
+	err := Config.DbConn.Select(&emails, `SELECT u.email FROM Users u 
+		INNER JOIN CommunityUserLinks cul ON cul.userId = u.id 
+		LEFT JOIN UserNotificationPreferences unp ON unp.userId = u.id AND unp.notificationType = ?
+		WHERE cul.communityId = ? AND cul.status = 'accepted' AND u.id != ? AND u.email != '' AND (unp.channel IS NULL OR unp.channel = 'email')`,
+		notificationType, communityID, excludeUserID)
+	return emails, err
+}
+
+// GetAdminEmailsToNotifyInCommunity works like GetEmailsToNotifyInCommunity but only includes the community's admins
+func GetAdminEmailsToNotifyInCommunity(communityID int64, notificationType string) ([]string, error) {
+	emails := []string{}
+	err := Config.DbConn.Select(&emails, `SELECT u.email FROM Users u 
+		INNER JOIN CommunityUserLinks cul ON cul.userId = u.id 
+		LEFT JOIN UserNotificationPreferences unp ON unp.userId = u.id AND unp.notificationType = ?
+		WHERE cul.communityId = ? AND cul.status = 'accepted' AND cul.role = 'admin' AND u.email != '' AND (unp.channel IS NULL OR unp.channel = 'email')`,
+		notificationType, communityID)
+	return emails, err
+}
+
+// ShouldEmailUser checks if a user wants to be emailed about a notification type
+func ShouldEmailUser(userID int64, notificationType string) bool {
+	pref := NotificationPreference{}
+	err := Config.DbConn.Get(&pref, "SELECT * FROM UserNotificationPreferences WHERE userId = ? AND notificationType = ?", userID, notificationType)
+	if err != nil {
+		// nothing saved means the default, which is email
+		return true
+	}
+	return pref.Channel == NotificationChannelEmail
+}
+
+// NotifyCommunityMembers emails the members of a community who want to hear about the notification type. The email is sent as a BCC
+// so that addresses aren't shared between members
+func NotifyCommunityMembers(communityID, excludeUserID int64, notificationType, subject, content string) error {
+	emails, err := GetEmailsToNotifyInCommunity(communityID, excludeUserID, notificationType)
+	if err != nil || len(emails) == 0 {
+		return err
+	}
+	body := GenerateEmail(communityID, content)
+	_, _, err = SendEmailToGroup(emails, subject, body, true)
+	return err
+}
+
+// NotifyCommunityAdmins emails the admins of a community who want to hear about the notification type
+func NotifyCommunityAdmins(communityID int64, notificationType, subject, content string) error {
+	emails, err := GetAdminEmailsToNotifyInCommunity(communityID, notificationType)
+	if err != nil || len(emails) == 0 {
+		return err
+	}
+	body := GenerateEmail(communityID, content)
+	_, _, err = SendEmailToGroup(emails, subject, body, true)
+	return err
+}
+
+// NotifyUser emails a single user if they want to hear about the notification type
+func NotifyUser(userID, communityID int64, notificationType, subject, content string) error {
+	if !ShouldEmailUser(userID, notificationType) {
+		return nil
+	}
+	user, err := GetUserByID(userID)
+	if err != nil || user.Email == "" {
+		return err
+	}
+	body := GenerateEmail(communityID, content)
+	_, _, err = SendEmail(user.Email, subject, body)
+	return err
+}
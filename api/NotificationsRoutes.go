@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *NotificationPreference) Bind(r *http.Request) error {
+	return nil
+}
+
+// GetMyNotificationPreferencesRoute gets the current user's notification preferences
+func GetMyNotificationPreferencesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	prefs, err := GetNotificationPreferencesForUser(jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "notification_preferences_error", "could not get your notification preferences", err)
+		return
+	}
+	Send(w, http.StatusOK, prefs)
+	return
+}
+
+// UpdateMyNotificationPreferenceRoute updates a single notification preference for the current user
+func UpdateMyNotificationPreferenceRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := NotificationPreference{}
+	render.Bind(r, &input)
+	input.NotificationType = strings.ToLower(input.NotificationType)
+	input.Channel = strings.ToLower(input.Channel)
+
+	if !IsValidNotificationType(input.NotificationType) || !IsValidNotificationChannel(input.Channel) {
+		SendError(w, http.StatusBadRequest, "notification_preferences_invalid", "notificationType and channel must be valid", map[string]interface{}{
+			"notificationTypes": notificationTypes,
+			"channels":          notificationChannels,
+		})
+		return
+	}
+
+	err = SetNotificationPreference(jwtUser.ID, input.NotificationType, input.Channel)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "notification_preferences_error", "could not save that preference", err)
+		return
+	}
+
+	prefs, _ := GetNotificationPreferencesForUser(jwtUser.ID)
+	Send(w, http.StatusOK, prefs)
+	return
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreferencesRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	user := User{}
+	err := CreateTestUser(&user)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+
+	code, _, _ := TestAPICall(http.MethodGet, "/me/notifications", b, GetMyNotificationPreferencesRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, res, _ := TestAPICall(http.MethodGet, "/me/notifications", b, GetMyNotificationPreferencesRoute, user.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, len(GetNotificationTypes()), len(bodyA))
+
+	// invalid input
+	b.Reset()
+	enc.Encode(map[string]string{
+		"notificationType": "not_real",
+		"channel":          NotificationChannelNone,
+	})
+	code, _, _ = TestAPICall(http.MethodPatch, "/me/notifications", b, UpdateMyNotificationPreferenceRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"notificationType": NotificationTypeCommunityAnnouncement,
+		"channel":          "pigeon",
+	})
+	code, _, _ = TestAPICall(http.MethodPatch, "/me/notifications", b, UpdateMyNotificationPreferenceRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"notificationType": NotificationTypeCommunityAnnouncement,
+		"channel":          NotificationChannelNone,
+	})
+	code, res, _ = TestAPICall(http.MethodPatch, "/me/notifications", b, UpdateMyNotificationPreferenceRoute, user.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	for i := range bodyA {
+		pref := NotificationPreference{}
+		mapstructure.Decode(bodyA[i], &pref)
+		if pref.NotificationType == NotificationTypeCommunityAnnouncement {
+			assert.Equal(t, NotificationChannelNone, pref.Channel)
+		}
+	}
+}
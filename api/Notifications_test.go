@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferences(t *testing.T) {
+	ConfigSetup()
+	user := User{}
+	err := CreateTestUser(&user)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+
+	other := User{}
+	err = CreateTestUser(&other)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&other)
+
+	// everything defaults to email
+	prefs, err := GetNotificationPreferencesForUser(user.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, len(GetNotificationTypes()), len(prefs))
+	for i := range prefs {
+		assert.Equal(t, NotificationChannelEmail, prefs[i].Channel)
+	}
+	assert.True(t, ShouldEmailUser(user.ID, NotificationTypeCommunityAnnouncement))
+
+	err = SetNotificationPreference(user.ID, NotificationTypeCommunityAnnouncement, NotificationChannelNone)
+	assert.Nil(t, err)
+	assert.False(t, ShouldEmailUser(user.ID, NotificationTypeCommunityAnnouncement))
+	prefs, _ = GetNotificationPreferencesForUser(user.ID)
+	for i := range prefs {
+		if prefs[i].NotificationType == NotificationTypeCommunityAnnouncement {
+			assert.Equal(t, NotificationChannelNone, prefs[i].Channel)
+		}
+	}
+
+	// only members who want the email are returned
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, user.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, other.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	emails, err := GetEmailsToNotifyInCommunity(community.ID, 0, NotificationTypeCommunityAnnouncement)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(emails))
+	assert.Equal(t, other.Email, emails[0])
+
+	// the excluded user is left out
+	emails, err = GetEmailsToNotifyInCommunity(community.ID, other.ID, NotificationTypeCommunityAnnouncement)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(emails))
+
+	err = SetNotificationPreference(user.ID, NotificationTypeCommunityAnnouncement, NotificationChannelEmail)
+	assert.Nil(t, err)
+	assert.True(t, ShouldEmailUser(user.ID, NotificationTypeCommunityAnnouncement))
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// PageSizeDefault is the number of entries returned when count isn't provided
+	PageSizeDefault = 50
+	// PageSizeMax is the most entries returned in one page, no matter what count asks for
+	PageSizeMax = 200
+)
+
+// PregxasAPIMeta is the paging information sent alongside a page of a list. Next and Prev are opaque cursors to pass back as
+// the cursor query parameter; the same links are also sent in the Link header
+type PregxasAPIMeta struct {
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Total *int64 `json:"total,omitempty"`
+}
+
+// PageCursor is the decoded form of a cursor. Lists ordered by creation page by the created time and id of the entry on the
+// edge of the last page, lists ordered by a unique id or key page by just that, so entries added in the meantime don't
+// shift the pages; every other list pages by offset
+type PageCursor struct {
+	Offset  int    `json:"o,omitempty"`
+	Created string `json:"c,omitempty"`
+	ID      int64  `json:"i,omitempty"`
+	// Key is the sort value of the edge entry for lists ordered by a unique value other than an id, such as a username
+	Key string `json:"k,omitempty"`
+	// Before is set when the cursor is for the page before the entry instead of after it
+	Before bool `json:"b,omitempty"`
+}
+
+// PageInfo describes where a page sits in its list so SendPage can link to its neighbors. A nil cursor means there is no
+// page in that direction
+type PageInfo struct {
+	Next  *PageCursor
+	Prev  *PageCursor
+	Total *int64
+}
+
+// IsKeyset checks if the cursor pages from an entry rather than by offset
+func (cursor *PageCursor) IsKeyset() bool {
+	return cursor != nil && (cursor.ID != 0 || cursor.Key != "")
+}
+
+// Encode turns the cursor into the opaque string handed to clients
+func (cursor *PageCursor) Encode() string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodePageCursor parses a cursor from Encode
+func DecodePageCursor(input string) (*PageCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(input)
+	if err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+	cursor := PageCursor{}
+	err = json.Unmarshal(decoded, &cursor)
+	if err != nil || cursor.Offset < 0 || cursor.ID < 0 || (cursor.Created != "" && cursor.ID == 0) {
+		return nil, errors.New("cursor is invalid")
+	}
+	return &cursor, nil
+}
+
+// GetPageCursor gets the cursor from the query string, or nil if there isn't one
+func GetPageCursor(r *http.Request) (*PageCursor, error) {
+	input := r.URL.Query().Get("cursor")
+	if input == "" {
+		return nil, nil
+	}
+	return DecodePageCursor(input)
+}
+
+// IsTotalRequested checks if the client asked for the total number of entries with includeTotal=true. Counting can be
+// expensive on large lists, so it is only done when asked
+func IsTotalRequested(r *http.Request) bool {
+	return strings.ToLower(r.URL.Query().Get("includeTotal")) == "true"
+}
+
+// SendPage sends a page of a list with its paging meta and a Link header pointing to the next and previous pages
+func SendPage(w http.ResponseWriter, r *http.Request, code int, payload interface{}, page PageInfo) {
+	meta := PregxasAPIMeta{
+		Total: page.Total,
+	}
+	links := []string{}
+	if page.Next != nil {
+		meta.Next = page.Next.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(r, meta.Next)))
+	}
+	if page.Prev != nil {
+		meta.Prev = page.Prev.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(r, meta.Prev)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+
+	ret := PregxasAPIReturn{
+		Data: payload,
+		Meta: &meta,
+	}
+	response, _ := json.Marshal(ret)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
+
+// offsetPage builds the page info for a list paged by offset. There is assumed to be a next page whenever the page is full
+func offsetPage(count, offset, returned int) PageInfo {
+	page := PageInfo{}
+	if returned > 0 && returned >= count {
+		page.Next = &PageCursor{Offset: offset + returned}
+	}
+	if offset > 0 {
+		prev := offset - count
+		if prev < 0 {
+			prev = 0
+		}
+		page.Prev = &PageCursor{Offset: prev}
+	}
+	return page
+}
+
+// keysetPage builds the page info for a list paged by keyset. edge gets the cursor for the returned entry at i, which is
+// the page after the last entry or before the first
+func keysetPage(cursor *PageCursor, count, offset, returned int, edge func(i int) PageCursor) PageInfo {
+	page := PageInfo{}
+	if returned == 0 {
+		return page
+	}
+	first, last := edge(0), edge(returned-1)
+	first.Before = true
+	if cursor.IsKeyset() && cursor.Before {
+		page.Next = &last
+		if returned >= count {
+			page.Prev = &first
+		}
+		return page
+	}
+	if returned >= count {
+		page.Next = &last
+	}
+	if cursor.IsKeyset() || offset > 0 {
+		page.Prev = &first
+	}
+	return page
+}
+
+// keysetClause gets the condition, starting with AND, and the ORDER BY expression for a list ordered by a unique column.
+// The page before a cursor is read in reverse, so the caller flips those rows back afterward
+func keysetClause(cursor *PageCursor, column string, desc bool) (string, string, []interface{}) {
+	backward := cursor.IsKeyset() && cursor.Before
+	comparison, direction := ">", "ASC"
+	if desc != backward {
+		comparison, direction = "<", "DESC"
+	}
+	order := fmt.Sprintf("%s %s", column, direction)
+	if !cursor.IsKeyset() {
+		return " ", order, []interface{}{}
+	}
+	var value interface{} = cursor.ID
+	if cursor.Key != "" {
+		value = cursor.Key
+	}
+	return fmt.Sprintf(" AND %s %s ? ", column, comparison), order, []interface{}{value}
+}
+
+// pageURL is the request's URL with the cursor swapped in. Any offset is dropped since the cursor replaces it
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Set("cursor", cursor)
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// CheckPageCursor sends a 400 and returns false when the request has a cursor that can't be read. ProcessQuery ignores a bad
+// cursor, so list routes check it first rather than quietly sending the first page again
+func CheckPageCursor(w http.ResponseWriter, r *http.Request) bool {
+	_, err := GetPageCursor(r)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "bad_cursor", err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// GetKeysetPageCursor gets the cursor for a list ordered by a unique id, or by a unique key such as a username when byKey is
+// set. Offset cursors are still taken for older links. It sends a 400 and returns false when the cursor can't be read or is
+// from a different kind of list
+func GetKeysetPageCursor(w http.ResponseWriter, r *http.Request, byKey bool) (*PageCursor, bool) {
+	cursor, err := GetPageCursor(r)
+	if err == nil && cursor.IsKeyset() && (cursor.Created != "" || (cursor.Key != "") != byKey) {
+		err = errors.New("cursor is for a different list")
+	}
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "bad_cursor", err.Error(), nil)
+		return nil, false
+	}
+	return cursor, true
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCursorEncoding(t *testing.T) {
+	cursor := &PageCursor{
+		Created: "2026-01-01 00:00:00",
+		ID:      42,
+		Before:  true,
+	}
+	decoded, err := DecodePageCursor(cursor.Encode())
+	require.Nil(t, err)
+	assert.Equal(t, cursor, decoded)
+	assert.True(t, decoded.IsKeyset())
+
+	cursor = &PageCursor{Offset: 50}
+	decoded, err = DecodePageCursor(cursor.Encode())
+	require.Nil(t, err)
+	assert.Equal(t, 50, decoded.Offset)
+	assert.False(t, decoded.IsKeyset())
+
+	cursor = &PageCursor{ID: 7}
+	decoded, err = DecodePageCursor(cursor.Encode())
+	require.Nil(t, err)
+	assert.True(t, decoded.IsKeyset())
+	assert.True(t, (&PageCursor{Key: "bob"}).IsKeyset())
+
+	var missing *PageCursor
+	assert.False(t, missing.IsKeyset())
+
+	for _, bad := range []string{"not a cursor!", "bm9wZQ", (&PageCursor{Offset: -1}).Encode(), (&PageCursor{Created: "2026-01-01 00:00:00"}).Encode()} {
+		_, err = DecodePageCursor(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestProcessQueryPaging(t *testing.T) {
+	r := httptest.NewRequest("GET", "/requests", nil)
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	assert.Equal(t, PageSizeDefault, count)
+	assert.Equal(t, 0, offset)
+
+	r = httptest.NewRequest("GET", "/requests?count=5000&offset=-4", nil)
+	_, _, count, offset, _, _, _, _ = ProcessQuery(r)
+	assert.Equal(t, PageSizeMax, count)
+	assert.Equal(t, 0, offset)
+
+	r = httptest.NewRequest("GET", "/requests?count=0", nil)
+	_, _, count, _, _, _, _, _ = ProcessQuery(r)
+	assert.Equal(t, PageSizeDefault, count)
+
+	// an offset cursor takes the place of the offset
+	r = httptest.NewRequest("GET", "/requests?offset=3&cursor="+(&PageCursor{Offset: 20}).Encode(), nil)
+	_, _, _, offset, _, _, _, _ = ProcessQuery(r)
+	assert.Equal(t, 20, offset)
+
+	assert.False(t, IsTotalRequested(r))
+	r = httptest.NewRequest("GET", "/requests?includeTotal=true", nil)
+	assert.True(t, IsTotalRequested(r))
+}
+
+func TestCheckPageCursor(t *testing.T) {
+	r := httptest.NewRequest("GET", "/requests", nil)
+	w := httptest.NewRecorder()
+	assert.True(t, CheckPageCursor(w, r))
+
+	r = httptest.NewRequest("GET", "/requests?cursor="+(&PageCursor{Offset: 20}).Encode(), nil)
+	assert.True(t, CheckPageCursor(w, r))
+
+	r = httptest.NewRequest("GET", "/requests?cursor=nope!", nil)
+	assert.False(t, CheckPageCursor(w, r))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetKeysetPageCursor(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/reports", nil)
+	cursor, ok := GetKeysetPageCursor(w, r, false)
+	assert.True(t, ok)
+	assert.Nil(t, cursor)
+
+	r = httptest.NewRequest("GET", "/reports?cursor="+(&PageCursor{ID: 9, Before: true}).Encode(), nil)
+	cursor, ok = GetKeysetPageCursor(w, r, false)
+	require.True(t, ok)
+	assert.Equal(t, int64(9), cursor.ID)
+
+	r = httptest.NewRequest("GET", "/members?cursor="+(&PageCursor{Key: "bob"}).Encode(), nil)
+	cursor, ok = GetKeysetPageCursor(w, r, true)
+	require.True(t, ok)
+	assert.Equal(t, "bob", cursor.Key)
+
+	// older offset links still work
+	r = httptest.NewRequest("GET", "/reports?cursor="+(&PageCursor{Offset: 20}).Encode(), nil)
+	_, ok = GetKeysetPageCursor(w, r, false)
+	assert.True(t, ok)
+
+	// cursors from other kinds of lists are refused
+	for _, bad := range []*PageCursor{{Key: "bob"}, {Created: "2026-01-01 00:00:00", ID: 3}} {
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("GET", "/reports?cursor="+bad.Encode(), nil)
+		_, ok = GetKeysetPageCursor(w, r, false)
+		assert.False(t, ok)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/members?cursor="+(&PageCursor{ID: 3}).Encode(), nil)
+	_, ok = GetKeysetPageCursor(w, r, true)
+	assert.False(t, ok)
+}
+
+func TestKeysetPage(t *testing.T) {
+	ids := []int64{9, 8, 7}
+	edge := func(i int) PageCursor { return PageCursor{ID: ids[i]} }
+
+	where, order, args := keysetClause(nil, "r.id", true)
+	assert.Equal(t, " ", where)
+	assert.Equal(t, "r.id DESC", order)
+	assert.Equal(t, 0, len(args))
+	page := keysetPage(nil, 3, 0, 3, edge)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, int64(7), page.Next.ID)
+	assert.False(t, page.Next.Before)
+	assert.Nil(t, page.Prev)
+
+	next := &PageCursor{ID: 10}
+	where, order, args = keysetClause(next, "r.id", true)
+	assert.Equal(t, " AND r.id < ? ", where)
+	assert.Equal(t, "r.id DESC", order)
+	assert.Equal(t, []interface{}{int64(10)}, args)
+	page = keysetPage(next, 5, 0, 3, edge)
+	assert.Nil(t, page.Next)
+	require.NotNil(t, page.Prev)
+	assert.Equal(t, int64(9), page.Prev.ID)
+	assert.True(t, page.Prev.Before)
+
+	// the page before is read in reverse
+	before := &PageCursor{Key: "carol", Before: true}
+	where, order, args = keysetClause(before, "u.username", false)
+	assert.Equal(t, " AND u.username < ? ", where)
+	assert.Equal(t, "u.username DESC", order)
+	assert.Equal(t, []interface{}{"carol"}, args)
+	page = keysetPage(before, 3, 0, 2, edge)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, int64(8), page.Next.ID)
+	assert.Nil(t, page.Prev)
+
+	page = keysetPage(next, 3, 0, 0, edge)
+	assert.Nil(t, page.Next)
+	assert.Nil(t, page.Prev)
+}
+
+func TestOffsetPage(t *testing.T) {
+	page := offsetPage(10, 0, 10)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, 10, page.Next.Offset)
+	assert.Nil(t, page.Prev)
+
+	page = offsetPage(10, 5, 3)
+	assert.Nil(t, page.Next)
+	require.NotNil(t, page.Prev)
+	assert.Equal(t, 0, page.Prev.Offset)
+
+	page = offsetPage(10, 0, 0)
+	assert.Nil(t, page.Next)
+	assert.Nil(t, page.Prev)
+}
+
+func TestSendPage(t *testing.T) {
+	r := httptest.NewRequest("GET", "/lists?sortField=title&offset=10&count=10", nil)
+	w := httptest.NewRecorder()
+	total := int64(35)
+	page := offsetPage(10, 10, 10)
+	page.Total = &total
+	SendPage(w, r, http.StatusOK, []string{"a"}, page)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	links := strings.Split(w.Header().Get("Link"), ", ")
+	require.Equal(t, 2, len(links))
+	assert.True(t, strings.HasPrefix(links[0], "</lists?"))
+	assert.True(t, strings.HasSuffix(links[0], `>; rel="next"`))
+	assert.True(t, strings.Contains(links[0], "sortField=title"))
+	assert.False(t, strings.Contains(links[0], "offset="))
+	assert.True(t, strings.HasSuffix(links[1], `>; rel="prev"`))
+
+	ret, _, err := UnmarshalTestArray(w.Body)
+	require.Nil(t, err)
+	require.NotNil(t, ret.Meta)
+	require.NotNil(t, ret.Meta.Total)
+	assert.Equal(t, int64(35), *ret.Meta.Total)
+	next, err := DecodePageCursor(ret.Meta.Next)
+	require.Nil(t, err)
+	assert.Equal(t, 20, next.Offset)
+	assert.True(t, strings.Contains(links[0], "cursor="+ret.Meta.Next))
+	prev, err := DecodePageCursor(ret.Meta.Prev)
+	require.Nil(t, err)
+	assert.Equal(t, 0, prev.Offset)
+
+	// the last page has no links
+	w = httptest.NewRecorder()
+	SendPage(w, r, http.StatusOK, []string{}, PageInfo{})
+	assert.Equal(t, "", w.Header().Get("Link"))
+}
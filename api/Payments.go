@@ -0,0 +1,19 @@
+package api
+
+// PaymentProcessor handles the billing side of community plans
+type PaymentProcessor interface {
+	// CancelSubscription cancels a recurring subscription so the customer is not charged again
+	CancelSubscription(subscriptionID string) error
+}
+
+// ManualPaymentProcessor is used when no payment provider is configured. Rather than cancelling the subscription itself, it logs
+// the cancellation so that it can be handled by hand in the provider's dashboard
+type ManualPaymentProcessor struct{}
+
+// CancelSubscription logs the subscription that needs to be cancelled
+func (p *ManualPaymentProcessor) CancelSubscription(subscriptionID string) error {
+	Log("warning", "subscription needs to be cancelled manually", "subscription_cancel_manual", map[string]string{
+		"subscriptionId": subscriptionID,
+	})
+	return nil
+}
@@ -62,23 +62,42 @@ func GetPrayerList(listID int64) (PrayerList, error) {
 	list := PrayerList{}
 	defer list.processForAPI()
 	err := Config.DbConn.Get(&list, "SELECT * FROM PrayerLists WHERE id = ?", listID)
-	list.PrayerRequests, _ = GetPrayerRequestsOnPrayerList(listID)
+	requests, _ := GetPrayerRequestsOnPrayerList(listID)
+	// requests the owner can no longer see, such as after a share is revoked, are left out, and the owner only knows who
+	// posted their own anonymous requests
+	list.PrayerRequests = []PrayerRequest{}
+	for i := range requests {
+		if !CanUserSeePrayerRequest(list.UserID, &requests[i]) {
+			continue
+		}
+		if requests[i].CreatedBy != list.UserID {
+			requests[i].HideAnonymousAuthor()
+		}
+		list.PrayerRequests = append(list.PrayerRequests, requests[i])
+	}
 	return list, err
 }
 
-// GetPrayerListsForUser gets the liss of lists for the user, optionally sorted
-func GetPrayerListsForUser(userID int64, sortField string) ([]PrayerList, error) {
+// GetPrayerListsForUser gets a page of the lists for the user, optionally sorted
+func GetPrayerListsForUser(userID int64, sortField string, count, offset int) ([]PrayerList, error) {
 	lists := []PrayerList{}
 	var err error
 	sortField = strings.ToLower(sortField)
 	if sortField == "title" {
-		err = Config.DbConn.Select(&lists, "SELECT * FROM PrayerLists WHERE userId = ? ORDER BY title", userID)
+		err = Config.DbConn.Select(&lists, "SELECT * FROM PrayerLists WHERE userId = ? ORDER BY title, id LIMIT ?,?", userID, offset, count)
 	} else {
-		err = Config.DbConn.Select(&lists, "SELECT * FROM PrayerLists WHERE userId = ? ORDER BY created", userID)
+		err = Config.DbConn.Select(&lists, "SELECT * FROM PrayerLists WHERE userId = ? ORDER BY created, id LIMIT ?,?", userID, offset, count)
 	}
 	return lists, err
 }
 
+// GetCountOfPrayerListsForUser gets how many lists the user has
+func GetCountOfPrayerListsForUser(userID int64) (int64, error) {
+	total := int64(0)
+	err := Config.DbConn.Get(&total, "SELECT COUNT(*) FROM PrayerLists WHERE userId = ?", userID)
+	return total, err
+}
+
 // GetPrayerRequestsOnPrayerList gets all of the requests on a list
 func GetPrayerRequestsOnPrayerList(listID int64) ([]PrayerRequest, error) {
 	requests := []PrayerRequest{}
@@ -90,6 +109,7 @@ func GetPrayerRequestsOnPrayerList(listID int64) ([]PrayerRequest, error) {
 	for i := range requests {
 		requests[i].processForAPI()
 	}
+	populatePrayerRequestTags(requests)
 	return requests, err
 }
 
@@ -49,7 +49,7 @@ func CreatePrayerListRoute(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-// GetPrayerListsForUserRoute gets all of the lists for a user
+// GetPrayerListsForUserRoute gets a page of the lists for a user
 func GetPrayerListsForUserRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -57,15 +57,23 @@ func GetPrayerListsForUserRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, _, _, _, sortField, _, _, _ := ProcessQuery(r)
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, sortField, _, _, _ := ProcessQuery(r)
 
-	lists, err := GetPrayerListsForUser(jwtUser.ID, sortField)
+	lists, err := GetPrayerListsForUser(jwtUser.ID, sortField, count, offset)
 	if err != nil {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
 
-	Send(w, http.StatusOK, lists)
+	page := offsetPage(count, offset, len(lists))
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfPrayerListsForUser(jwtUser.ID)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, lists, page)
 	return
 }
 
@@ -196,15 +204,8 @@ func AddPrayerRequestToPrayerListRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ok, so there's some interesting logic here
-	// first, if the request belongs to the user or is public, it's good
-	// if not, if the request is in a group that the user belongs to, then it too is good
-	canAdd := false
-	if request.Privacy == "public" || request.CreatedBy == jwtUser.ID || IsUserAndRequestInSameGroup(jwtUser.ID, requestID) {
-		canAdd = true
-	}
-
-	if !canAdd {
+	// users can only add requests they can see
+	if !CanUserSeePrayerRequest(jwtUser.ID, request) {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
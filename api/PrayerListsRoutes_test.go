@@ -149,6 +149,13 @@ func TestPrayerRequestListCRUDRoutes(t *testing.T) {
 	require.NotZero(t, len(foundLists))
 	assert.Equal(t, update.Title, foundLists[0].Title)
 	assert.Equal(t, PrayerListUpdateFrequencyDaily, foundLists[0].UpdateFrequency)
+	code, res, _ = TestAPICall(http.MethodGet, "/lists/requests/?count=1&includeTotal=true", b, GetPrayerListsForUserRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	ret, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+	require.NotNil(t, ret.Meta)
+	require.NotNil(t, ret.Meta.Total)
+	assert.NotZero(t, *ret.Meta.Total)
 
 	// make some bad remove calls
 	code, res, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/lists/requests/%d/%d", list.ID, request.ID), b, RemovePrayerRequestFromPrayerListRoute, "", "")
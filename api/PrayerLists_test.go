@@ -58,14 +58,21 @@ func TestPrayerListCRUD(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotZero(t, len(requests))
 	assert.Equal(t, request.ID, requests[0].ID)
+	assert.NotNil(t, requests[0].Tags)
 
 	// get for the users
-	lists, err := GetPrayerListsForUser(randID, "title")
+	lists, err := GetPrayerListsForUser(randID, "title", PageSizeDefault, 0)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(lists))
-	lists, err = GetPrayerListsForUser(randID, "createdOn")
+	lists, err = GetPrayerListsForUser(randID, "createdOn", PageSizeDefault, 0)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(lists))
+	lists, err = GetPrayerListsForUser(randID, "title", PageSizeDefault, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(lists))
+	total, err := GetCountOfPrayerListsForUser(randID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), total)
 
 	// remove and verify
 	err = RemoveRequestFromPrayerList(request.ID, list.ID)
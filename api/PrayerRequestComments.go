@@ -0,0 +1,88 @@
+package api
+
+import "fmt"
+
+// PrayerRequestComment is a short note of encouragement left on a request. Comments can't be edited, only deleted by the
+// commenter or the request's author
+type PrayerRequestComment struct {
+	ID              int64  `json:"id" db:"id"`
+	PrayerRequestID int64  `json:"prayerRequestId" db:"prayerRequestId"`
+	UserID          int64  `json:"userId" db:"userId"`
+	Username        string `json:"username" db:"username"`
+	Body            string `json:"body" db:"body"`
+	Created         string `json:"created" db:"created"`
+}
+
+// PrayerRequestCommentBodyMaxLength is the longest a comment's body can be
+const PrayerRequestCommentBodyMaxLength = 512
+
+// CreatePrayerRequestComment adds a comment to a request
+func CreatePrayerRequestComment(input *PrayerRequestComment) error {
+	res, err := Config.DbConn.NamedExec(`INSERT INTO PrayerRequestComments (prayerRequestId, userId, body, created)
+		VALUES (:prayerRequestId, :userId, :body, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	found, err := GetPrayerRequestComment(input.PrayerRequestID, input.ID)
+	if err != nil {
+		return err
+	}
+	*input = *found
+	return nil
+}
+
+// GetPrayerRequestComment gets a single comment on a request
+func GetPrayerRequestComment(requestID, commentID int64) (*PrayerRequestComment, error) {
+	comment := &PrayerRequestComment{}
+	err := Config.DbConn.Get(comment, `SELECT c.*, u.username FROM PrayerRequestComments c, Users u
+		WHERE c.id = ? AND c.prayerRequestId = ? AND c.userId = u.id`, commentID, requestID)
+	comment.processForAPI()
+	return comment, err
+}
+
+// GetPrayerRequestComments gets the comments on a request, oldest first so they read as a conversation. A keyset cursor
+// starts the page from a comment id instead of the offset
+func GetPrayerRequestComments(requestID int64, cursor *PageCursor, count, offset int) ([]PrayerRequestComment, error) {
+	comments := []PrayerRequestComment{}
+	where, order, args := keysetClause(cursor, "c.id", false)
+	args = append([]interface{}{requestID}, args...)
+	args = append(args, offset, count)
+	err := Config.DbConn.Select(&comments, fmt.Sprintf(`SELECT c.*, u.username FROM PrayerRequestComments c, Users u
+		WHERE c.prayerRequestId = ? AND c.userId = u.id %s ORDER BY %s LIMIT ?,?`, where, order), args...)
+	if cursor.IsKeyset() && cursor.Before {
+		for i, j := 0, len(comments)-1; i < j; i, j = i+1, j-1 {
+			comments[i], comments[j] = comments[j], comments[i]
+		}
+	}
+	for i := range comments {
+		comments[i].processForAPI()
+	}
+	return comments, err
+}
+
+// DeletePrayerRequestComment deletes a comment on a request
+func DeletePrayerRequestComment(requestID, commentID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestComments WHERE id = ? AND prayerRequestId = ?", commentID, requestID)
+	return err
+}
+
+// DeletePrayerRequestCommentsForRequest deletes every comment on a request
+func DeletePrayerRequestCommentsForRequest(requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestComments WHERE prayerRequestId = ?", requestID)
+	return err
+}
+
+// NotifyPrayerRequestComment lets the author know someone commented on their request. Authors aren't notified of their
+// own comments
+func NotifyPrayerRequestComment(request *PrayerRequest, comment *PrayerRequestComment) error {
+	if comment.UserID == request.CreatedBy {
+		return nil
+	}
+	content := fmt.Sprintf("<p><strong>%s</strong> commented on your prayer request <strong>%s</strong>:</p><p>%s</p>", comment.Username, request.Title, comment.Body)
+	return NotifyUser(request.CreatedBy, 0, NotificationTypePrayerRequestComment, fmt.Sprintf("New comment on %s", request.Title), content)
+}
+
+func (input *PrayerRequestComment) processForAPI() {
+	input.Created, _ = ParseTimeToISO(input.Created)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *PrayerRequestComment) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreatePrayerRequestCommentRoute adds a comment to a request. Anyone who can see the request can comment unless the author
+// has disabled comments. The author is notified
+func CreatePrayerRequestCommentRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, request, ok := getVisiblePrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if request.CommentStatus == PrayerRequestCommentStatusDisabled {
+		SendError(w, http.StatusForbidden, "prayer_request_comments_disabled", "the author has disabled comments on this request", nil)
+		return
+	}
+
+	input := PrayerRequestComment{}
+	render.Bind(r, &input)
+	input.Body, _ = sanitize(input.Body)
+	if input.Body == "" || len(input.Body) > PrayerRequestCommentBodyMaxLength {
+		SendError(w, http.StatusBadRequest, "prayer_request_comment_bad_data", fmt.Sprintf("body is required and can be at most %d characters", PrayerRequestCommentBodyMaxLength), input)
+		return
+	}
+	input.PrayerRequestID = request.ID
+	input.UserID = jwtUser.ID
+
+	err := CreatePrayerRequestComment(&input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_comment_bad_data", "the comment could not be saved", err)
+		return
+	}
+	go NotifyPrayerRequestComment(request, &input)
+
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetPrayerRequestCommentsRoute gets the comments on a request, oldest first. Comments are still shown after the author
+// disables new ones
+func GetPrayerRequestCommentsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, request, ok := getVisiblePrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+	cursor, ok := GetKeysetPageCursor(w, r, false)
+	if !ok {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+
+	comments, err := GetPrayerRequestComments(request.ID, cursor, count, offset)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "prayer_request_comment_error", "could not get the comments", err)
+		return
+	}
+	// the author's own replies on an anonymous request would give them away
+	if !canUserSeePrayerRequestAuthor(jwtUser, request) {
+		for i := range comments {
+			if comments[i].UserID == request.CreatedBy {
+				comments[i].UserID = 0
+				comments[i].Username = ""
+			}
+		}
+	}
+	page := keysetPage(cursor, count, offset, len(comments), func(i int) PageCursor { return PageCursor{ID: comments[i].ID} })
+	SendPage(w, r, http.StatusOK, comments, page)
+	return
+}
+
+// DeletePrayerRequestCommentRoute deletes a comment. The commenter, the request's author, and platform admins can delete it
+func DeletePrayerRequestCommentRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, request, comment, ok := getPrayerRequestCommentFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if comment.UserID != jwtUser.ID && request.CreatedBy != jwtUser.ID && jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err := DeletePrayerRequestComment(request.ID, comment.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "prayer_request_comment_error", "could not delete the comment", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// ReportPrayerRequestCommentRoute reports a comment. The report goes into the same queue as reports on requests, with the
+// comment's id set
+func ReportPrayerRequestCommentRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, request, comment, ok := getPrayerRequestCommentFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report := Report{}
+	render.Bind(r, &report)
+
+	report.ReporterID = jwtUser.ID
+	report.RequestID = request.ID
+	report.CommentID = comment.ID
+	report.Reason = strings.ToLower(report.Reason)
+	report.ReasonText, _ = sanitize(report.ReasonText)
+	report.Status = ReportStatusOpen
+
+	if !IsValidReportReason(report.Reason) {
+		SendError(w, http.StatusBadRequest, "report_reason_invalid", "report reason is invalid", report)
+		return
+	}
+
+	err := CreateReport(&report)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "report_create_failed", "report could not be created", err)
+		return
+	}
+	// the reporter is left out so that reports stay anonymous to the communities
+	QueuePrayerRequestWebhookEvent(WebhookEventReportFiled, request.ID, map[string]interface{}{
+		"id":         report.ID,
+		"requestId":  report.RequestID,
+		"commentId":  report.CommentID,
+		"reason":     report.Reason,
+		"reasonText": report.ReasonText,
+		"status":     report.Status,
+		"reported":   report.Reported,
+	})
+
+	Send(w, http.StatusCreated, report)
+	return
+}
+
+// getVisiblePrayerRequestFromRequest loads the request in the url if the user can see it, sending the error if not
+func getVisiblePrayerRequestFromRequest(w http.ResponseWriter, r *http.Request) (JWTUser, *PrayerRequest, bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || !CanUserSeePrayerRequest(jwtUser.ID, request) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+	return jwtUser, request, true
+}
+
+// getPrayerRequestCommentFromRequest loads the visible request and the comment in the url, sending the error if either fails
+func getPrayerRequestCommentFromRequest(w http.ResponseWriter, r *http.Request) (JWTUser, *PrayerRequest, *PrayerRequestComment, bool) {
+	jwtUser, request, ok := getVisiblePrayerRequestFromRequest(w, r)
+	if !ok {
+		return jwtUser, nil, nil, false
+	}
+	commentID, commentIDErr := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if commentIDErr != nil {
+		SendError(w, http.StatusNotFound, "prayer_request_comment_not_found", "that comment could not be found", nil)
+		return jwtUser, nil, nil, false
+	}
+	comment, err := GetPrayerRequestComment(request.ID, commentID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_request_comment_not_found", "that comment could not be found", nil)
+		return jwtUser, nil, nil, false
+	}
+	return jwtUser, request, comment, true
+}
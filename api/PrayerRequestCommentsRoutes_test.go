@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestCommentRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&author)
+	commenter := User{}
+	err = CreateTestUser(&commenter)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&commenter)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&other)
+
+	request := PrayerRequest{
+		Title:     "Move",
+		Body:      "Please pray for our move",
+		CreatedBy: author.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	b.Reset()
+	enc := json.NewEncoder(b)
+	enc.Encode(map[string]string{})
+	code, _, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/comments", request.ID), b, CreatePrayerRequestCommentRoute, commenter.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// comments are sanitized
+	b.Reset()
+	enc.Encode(map[string]string{
+		"body": "Praying for a smooth move<script>alert('hi')</script>",
+	})
+	code, res, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/comments", request.ID), b, CreatePrayerRequestCommentRoute, commenter.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	comment := PrayerRequestComment{}
+	mapstructure.Decode(body, &comment)
+	assert.NotEqual(t, int64(0), comment.ID)
+	assert.NotContains(t, comment.Body, "<script>")
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/comments", request.ID), b, GetPrayerRequestCommentsRoute, other.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(list))
+
+	// comments can be reported
+	b.Reset()
+	enc.Encode(map[string]string{
+		"reason": "bad",
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/comments/%d/reports", request.ID, comment.ID), b, ReportPrayerRequestCommentRoute, other.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"reason": ReportReasonOffensive,
+	})
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/comments/%d/reports", request.ID, comment.ID), b, ReportPrayerRequestCommentRoute, other.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	report := Report{}
+	mapstructure.Decode(body, &report)
+	defer DeleteReportForTest(report.ID)
+	found, err := GetReport(report.ID)
+	require.Nil(t, err)
+	assert.Equal(t, comment.ID, found.CommentID)
+	assert.Equal(t, request.ID, found.RequestID)
+
+	// only the commenter, the author, or an admin can delete a comment
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/comments/%d", request.ID, comment.ID), b, DeletePrayerRequestCommentRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/comments/%d", request.ID, comment.ID), b, DeletePrayerRequestCommentRoute, author.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/comments/%d", request.ID, comment.ID), b, DeletePrayerRequestCommentRoute, author.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// the author can turn comments off
+	b.Reset()
+	enc.Encode(map[string]string{
+		"commentStatus": "maybe",
+	})
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/requests/%d", request.ID), b, UpdatePrayerRequestRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"commentStatus": PrayerRequestCommentStatusDisabled,
+	})
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("/requests/%d", request.ID), b, UpdatePrayerRequestRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"body": "Praying",
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/comments", request.ID), b, CreatePrayerRequestCommentRoute, commenter.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// private requests hide their comments from users who can't see the request
+	request.Privacy = "private"
+	err = UpdatePrayerRequest(&request)
+	require.Nil(t, err)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/comments", request.ID), b, GetPrayerRequestCommentsRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+}
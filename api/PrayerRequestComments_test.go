@@ -0,0 +1,98 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestComments(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	commenter := User{}
+	err = CreateTestUser(&commenter)
+	require.Nil(t, err)
+	defer DeleteUser(commenter.ID)
+
+	request := PrayerRequest{
+		Title:     "Exams",
+		Body:      "Please pray for my exams",
+		CreatedBy: author.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	assert.Equal(t, PrayerRequestCommentStatusEnabled, request.CommentStatus)
+
+	first := PrayerRequestComment{
+		PrayerRequestID: request.ID,
+		UserID:          commenter.ID,
+		Body:            "Praying for you!",
+	}
+	err = CreatePrayerRequestComment(&first)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), first.ID)
+	assert.Equal(t, commenter.Username, first.Username)
+	assert.NotEqual(t, "", first.Created)
+
+	second := PrayerRequestComment{
+		PrayerRequestID: request.ID,
+		UserID:          author.ID,
+		Body:            "Thank you",
+	}
+	err = CreatePrayerRequestComment(&second)
+	require.Nil(t, err)
+
+	comments, err := GetPrayerRequestComments(request.ID, nil, 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(comments))
+	assert.Equal(t, first.ID, comments[0].ID)
+	assert.Equal(t, second.ID, comments[1].ID)
+
+	comments, err = GetPrayerRequestComments(request.ID, nil, 1, 1)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comments))
+	assert.Equal(t, second.ID, comments[0].ID)
+
+	// keyset cursors page from a comment id either way
+	comments, err = GetPrayerRequestComments(request.ID, &PageCursor{ID: first.ID}, 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comments))
+	assert.Equal(t, second.ID, comments[0].ID)
+	comments, err = GetPrayerRequestComments(request.ID, &PageCursor{ID: second.ID, Before: true}, 10, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(comments))
+	assert.Equal(t, first.ID, comments[0].ID)
+
+	// comments are only found on their own request
+	_, err = GetPrayerRequestComment(request.ID+1, first.ID)
+	assert.NotNil(t, err)
+
+	// disabling comments leaves the status alone and the comment status can be left blank to keep it
+	request.CommentStatus = PrayerRequestCommentStatusDisabled
+	err = UpdatePrayerRequest(&request)
+	require.Nil(t, err)
+	request.CommentStatus = ""
+	err = UpdatePrayerRequest(&request)
+	require.Nil(t, err)
+	found, err := GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, PrayerRequestCommentStatusDisabled, found.CommentStatus)
+
+	err = DeletePrayerRequestComment(request.ID, first.ID)
+	require.Nil(t, err)
+	comments, err = GetPrayerRequestComments(request.ID, nil, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(comments))
+
+	err = DeletePrayerRequestCommentsForRequest(request.ID)
+	require.Nil(t, err)
+	comments, err = GetPrayerRequestComments(request.ID, nil, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(comments))
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrayerRequestFeedFilter narrows and orders a feed of requests. Blank fields are not filtered on
+type PrayerRequestFeedFilter struct {
+	// Status is one of the request statuses; anything else is ignored
+	Status string
+	// Tag must already be normalized with NormalizeTag
+	Tag string
+	// AuthorID limits the feed to one author. Anonymous requests only match when IncludeAnonymous is set, which should only be
+	// done for the author and moderators so that filtering by author can't reveal who posted them
+	AuthorID         int64
+	IncludeAnonymous bool
+	// Start and End limit the feed to requests created between them, in DB time
+	Start string
+	End   string
+	// SortField is created, prayers, activity, or needs_prayer, and defaults to created
+	SortField string
+	SortDir   string
+	// Cursor is the page being fetched. A keyset cursor is only honored in the created order; the other orders change as people
+	// pray, so they page by offset
+	Cursor *PageCursor
+}
+
+const (
+	// PrayerRequestFeedSortCreated orders by when the request was created
+	PrayerRequestFeedSortCreated = "created"
+	// PrayerRequestFeedSortPrayers orders by how many prayers have been made for the request
+	PrayerRequestFeedSortPrayers = "prayers"
+	// PrayerRequestFeedSortActivity orders by the last time the request was updated, prayed for, or commented on
+	PrayerRequestFeedSortActivity = "activity"
+	// PrayerRequestFeedSortNeedsPrayer puts the requests with the fewest recent prayers first, and the oldest first among those,
+	// so nothing goes unprayed. The sort direction is ignored
+	PrayerRequestFeedSortNeedsPrayer = "needs_prayer"
+
+	// PrayerRequestNeedsPrayerDays is how far back prayers count toward the needs_prayer order
+	PrayerRequestNeedsPrayerDays = 7
+)
+
+// prayerRequestLastActivity is the query expression for the last time anything happened on a request
+const prayerRequestLastActivity = `GREATEST(pr.created,
+	COALESCE((SELECT MAX(pru.created) FROM PrayerRequestUpdates pru WHERE pru.prayerRequestId = pr.id), pr.created),
+	COALESCE((SELECT MAX(pa.whenPrayed) FROM Prayers pa WHERE pa.prayerRequestId = pr.id), pr.created),
+	COALESCE((SELECT MAX(prc.created) FROM PrayerRequestComments prc WHERE prc.prayerRequestId = pr.id), pr.created))`
+
+// IsValidPrayerRequestFeedSort checks if the input is a sort field feeds understand; blank uses the default
+func IsValidPrayerRequestFeedSort(input string) bool {
+	return input == "" || input == PrayerRequestFeedSortCreated || input == PrayerRequestFeedSortPrayers ||
+		input == PrayerRequestFeedSortActivity || input == PrayerRequestFeedSortNeedsPrayer
+}
+
+// clause gets the conditions for the filter, starting with AND, along with their args
+func (filter *PrayerRequestFeedFilter) clause() (string, []interface{}) {
+	where := []string{}
+	args := []interface{}{}
+	if filter.Status == PrayerRequestStatusPending || filter.Status == "answered" || filter.Status == "not_answered" || filter.Status == "unknown" {
+		where = append(where, "pr.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Tag != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM PrayerRequestTagLinks prtl, PrayerRequestTags prt
+			WHERE prtl.prayerRequestId = pr.id AND prtl.tagId = prt.id AND prt.tag = ?)`)
+		args = append(args, filter.Tag)
+	}
+	if filter.AuthorID != 0 {
+		where = append(where, "pr.createdBy = ?")
+		args = append(args, filter.AuthorID)
+		if !filter.IncludeAnonymous {
+			where = append(where, "pr.anonymous = 0")
+		}
+	}
+	if filter.Start != "" {
+		where = append(where, "pr.created >= ?")
+		args = append(args, filter.Start)
+	}
+	if filter.End != "" {
+		where = append(where, "pr.created <= ?")
+		args = append(args, filter.End)
+	}
+	if len(where) == 0 {
+		return " ", args
+	}
+	return " AND " + strings.Join(where, " AND ") + " ", args
+}
+
+// isKeyset checks if the feed pages by created time and id instead of by offset
+func (filter *PrayerRequestFeedFilter) isKeyset() bool {
+	return filter.SortField == "" || filter.SortField == PrayerRequestFeedSortCreated
+}
+
+// isBackward checks if the page before a keyset cursor is being fetched, in which case the feed is queried in reverse and
+// flipped back afterward
+func (filter *PrayerRequestFeedFilter) isBackward() bool {
+	return filter.isKeyset() && filter.Cursor.IsKeyset() && filter.Cursor.Before
+}
+
+// cursorClause gets the condition, starting with AND, that starts the page after or before a keyset cursor. It is kept
+// apart from clause so totals count the whole feed
+func (filter *PrayerRequestFeedFilter) cursorClause() (string, []interface{}) {
+	if !filter.isKeyset() || !filter.Cursor.IsKeyset() {
+		return " ", []interface{}{}
+	}
+	comparison := "<"
+	if (strings.ToUpper(filter.SortDir) == "ASC") != filter.Cursor.Before {
+		comparison = ">"
+	}
+	return fmt.Sprintf(" AND (pr.created %s ? OR (pr.created = ? AND pr.id %s ?)) ", comparison, comparison),
+		[]interface{}{filter.Cursor.Created, filter.Cursor.Created, filter.Cursor.ID}
+}
+
+// orderBy gets the ORDER BY expression for the filter. The feeds select the prayer count as prayerCount
+func (filter *PrayerRequestFeedFilter) orderBy() string {
+	// generally, string interpolation on SQL is Very Bad, but this is white listed so there is no
+	// security concerns
+	sortDir := strings.ToUpper(filter.SortDir)
+	if sortDir != "ASC" {
+		sortDir = "DESC"
+	}
+	if filter.isBackward() {
+		if sortDir == "ASC" {
+			sortDir = "DESC"
+		} else {
+			sortDir = "ASC"
+		}
+	}
+	switch filter.SortField {
+	case PrayerRequestFeedSortPrayers:
+		return fmt.Sprintf("prayerCount %s, pr.created DESC", sortDir)
+	case PrayerRequestFeedSortActivity:
+		return fmt.Sprintf("%s %s, pr.created DESC", prayerRequestLastActivity, sortDir)
+	case PrayerRequestFeedSortNeedsPrayer:
+		return fmt.Sprintf(`(SELECT COUNT(*) FROM Prayers pn WHERE pn.prayerRequestId = pr.id AND pn.whenPrayed > DATE_SUB(NOW(), INTERVAL %d DAY)) ASC,
+			pr.created ASC, pr.id ASC`, PrayerRequestNeedsPrayerDays)
+	}
+	return fmt.Sprintf("pr.created %s, pr.id %s", sortDir, sortDir)
+}
+
+// arrange puts a page fetched in reverse for a backward cursor back in feed order
+func (filter *PrayerRequestFeedFilter) arrange(requests []PrayerRequest) {
+	if !filter.isBackward() {
+		return
+	}
+	for i, j := 0, len(requests)-1; i < j; i, j = i+1, j-1 {
+		requests[i], requests[j] = requests[j], requests[i]
+	}
+}
+
+// page builds the page info for a page of the feed. In the created order, the cursors point just past the first and last
+// requests on the page; otherwise they hold offsets
+func (filter *PrayerRequestFeedFilter) page(requests []PrayerRequest, count, offset int) PageInfo {
+	if !filter.isKeyset() {
+		return offsetPage(count, offset, len(requests))
+	}
+	page := PageInfo{}
+	if len(requests) == 0 {
+		return page
+	}
+	full := len(requests) >= count
+	started := filter.Cursor.IsKeyset() || offset > 0
+	if filter.isBackward() {
+		page.Next = prayerRequestCursor(requests[len(requests)-1], false)
+		if full {
+			page.Prev = prayerRequestCursor(requests[0], true)
+		}
+		return page
+	}
+	if full {
+		page.Next = prayerRequestCursor(requests[len(requests)-1], false)
+	}
+	if started {
+		page.Prev = prayerRequestCursor(requests[0], true)
+	}
+	return page
+}
+
+// prayerRequestCursor gets a keyset cursor for the page after, or before, the request
+func prayerRequestCursor(request PrayerRequest, before bool) *PageCursor {
+	created, err := ParseISOTimeToDBTime(request.Created)
+	if err != nil {
+		created = request.Created
+	}
+	return &PageCursor{
+		Created: created,
+		ID:      request.ID,
+		Before:  before,
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestFeedFilterClause(t *testing.T) {
+	filter := PrayerRequestFeedFilter{}
+	clause, args := filter.clause()
+	assert.Equal(t, " ", clause)
+	assert.Zero(t, len(args))
+	assert.Equal(t, "pr.created DESC, pr.id DESC", filter.orderBy())
+
+	filter = PrayerRequestFeedFilter{
+		Status:   "answered",
+		Tag:      "healing",
+		AuthorID: 4,
+		Start:    "2026-01-01 00:00:00",
+		End:      "2026-02-01 00:00:00",
+	}
+	clause, args = filter.clause()
+	assert.True(t, strings.HasPrefix(clause, " AND "))
+	assert.True(t, strings.Contains(clause, "pr.anonymous = 0"))
+	assert.Equal(t, []interface{}{"answered", "healing", int64(4), "2026-01-01 00:00:00", "2026-02-01 00:00:00"}, args)
+
+	// unknown statuses are ignored, and anonymous requests can match the author when allowed
+	filter = PrayerRequestFeedFilter{
+		Status:           "everything",
+		AuthorID:         4,
+		IncludeAnonymous: true,
+	}
+	clause, args = filter.clause()
+	assert.False(t, strings.Contains(clause, "pr.anonymous"))
+	assert.Equal(t, []interface{}{int64(4)}, args)
+
+	filter = PrayerRequestFeedFilter{
+		SortField: PrayerRequestFeedSortPrayers,
+		SortDir:   "asc",
+	}
+	assert.Equal(t, "prayerCount ASC, pr.created DESC", filter.orderBy())
+	filter.SortField = PrayerRequestFeedSortNeedsPrayer
+	assert.True(t, strings.HasSuffix(filter.orderBy(), "pr.created ASC, pr.id ASC"))
+	filter.SortDir = "; DROP TABLE Users"
+	filter.SortField = PrayerRequestFeedSortCreated
+	assert.Equal(t, "pr.created DESC, pr.id DESC", filter.orderBy())
+
+	assert.True(t, IsValidPrayerRequestFeedSort(""))
+	assert.True(t, IsValidPrayerRequestFeedSort(PrayerRequestFeedSortActivity))
+	assert.False(t, IsValidPrayerRequestFeedSort("title"))
+}
+
+func TestPrayerRequestFeedFilterCursor(t *testing.T) {
+	filter := PrayerRequestFeedFilter{}
+	clause, args := filter.cursorClause()
+	assert.Equal(t, " ", clause)
+	assert.Zero(t, len(args))
+
+	// the page after a cursor continues down the feed
+	filter.Cursor = &PageCursor{Created: "2026-01-01 00:00:00", ID: 5}
+	clause, args = filter.cursorClause()
+	assert.Equal(t, " AND (pr.created < ? OR (pr.created = ? AND pr.id < ?)) ", clause)
+	assert.Equal(t, []interface{}{"2026-01-01 00:00:00", "2026-01-01 00:00:00", int64(5)}, args)
+	assert.Equal(t, "pr.created DESC, pr.id DESC", filter.orderBy())
+
+	// the page before it is fetched in reverse and flipped back
+	filter.Cursor.Before = true
+	clause, _ = filter.cursorClause()
+	assert.True(t, strings.Contains(clause, "pr.created > ?"))
+	assert.Equal(t, "pr.created ASC, pr.id ASC", filter.orderBy())
+	requests := []PrayerRequest{{ID: 1}, {ID: 2}, {ID: 3}}
+	filter.arrange(requests)
+	assert.Equal(t, int64(3), requests[0].ID)
+	assert.Equal(t, int64(1), requests[2].ID)
+
+	filter.SortDir = "asc"
+	clause, _ = filter.cursorClause()
+	assert.True(t, strings.Contains(clause, "pr.created < ?"))
+
+	// other sorts ignore keyset cursors and page by offset
+	filter.SortField = PrayerRequestFeedSortPrayers
+	clause, _ = filter.cursorClause()
+	assert.Equal(t, " ", clause)
+	page := filter.page(requests, 3, 3)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, 6, page.Next.Offset)
+	require.NotNil(t, page.Prev)
+	assert.Equal(t, 0, page.Prev.Offset)
+
+	// in the created order, the cursors point past the edges of the page
+	filter = PrayerRequestFeedFilter{}
+	requests = []PrayerRequest{{ID: 9, Created: "2026-01-02T00:00:00Z"}, {ID: 8, Created: "2026-01-01T00:00:00Z"}}
+	page = filter.page(requests, 2, 0)
+	require.NotNil(t, page.Next)
+	assert.Equal(t, int64(8), page.Next.ID)
+	assert.Equal(t, "2026-01-01 00:00:00", page.Next.Created)
+	assert.False(t, page.Next.Before)
+	assert.Nil(t, page.Prev)
+	page = filter.page(requests, 3, 0)
+	assert.Nil(t, page.Next)
+}
+
+func TestPrayerRequestFeedFilters(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUser(other.ID)
+
+	community := Community{
+		Name:      "Feed filters",
+		ShortCode: fmt.Sprintf("feed_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, author.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, other.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	requests := []PrayerRequest{}
+	for i, createdBy := range []int64{author.ID, author.ID, other.ID} {
+		request := PrayerRequest{
+			Title:     fmt.Sprintf("Feed %d", i),
+			Body:      "Please pray",
+			CreatedBy: createdBy,
+			Privacy:   PrayerRequestPrivacyPrivate,
+			Anonymous: i == 1,
+		}
+		err = CreatePrayerRequest(&request)
+		require.Nil(t, err)
+		defer DeletePrayerRequest(request.ID)
+		AddPrayerRequestToCommunity(request.ID, community.ID)
+		requests = append(requests, request)
+	}
+	// the first request gets the most prayers, the last gets one
+	AddPrayerMade(other.ID, requests[0].ID)
+	AddPrayerMade(author.ID, requests[0].ID)
+	AddPrayerMade(author.ID, requests[2].ID)
+
+	feed := GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		SortField: PrayerRequestFeedSortPrayers,
+	}, false, 100, 0)
+	require.Equal(t, 3, len(feed))
+	assert.Equal(t, requests[0].ID, feed[0].ID)
+	assert.Equal(t, requests[1].ID, feed[2].ID)
+
+	// needs prayer puts the unprayed request first
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		SortField: PrayerRequestFeedSortNeedsPrayer,
+	}, false, 100, 0)
+	require.Equal(t, 3, len(feed))
+	assert.Equal(t, requests[1].ID, feed[0].ID)
+	assert.Equal(t, requests[2].ID, feed[1].ID)
+
+	// the anonymous request only matches its author when allowed
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		AuthorID: author.ID,
+	}, false, 100, 0)
+	require.Equal(t, 1, len(feed))
+	assert.Equal(t, requests[0].ID, feed[0].ID)
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		AuthorID:         author.ID,
+		IncludeAnonymous: true,
+	}, false, 100, 0)
+	assert.Equal(t, 2, len(feed))
+
+	requests[2].Status = "answered"
+	err = UpdatePrayerRequest(&requests[2])
+	require.Nil(t, err)
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		Status: "answered",
+	}, false, 100, 0)
+	require.Equal(t, 1, len(feed))
+	assert.Equal(t, requests[2].ID, feed[0].ID)
+
+	// nothing was created in the future
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		Start: time.Now().UTC().Add(time.Hour).Format("2006-01-02 15:04:05"),
+	}, false, 100, 0)
+	assert.Zero(t, len(feed))
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{
+		SortField: PrayerRequestFeedSortActivity,
+		Start:     time.Now().UTC().Add(-1 * time.Hour).Format("2006-01-02 15:04:05"),
+	}, false, 100, 0)
+	assert.Equal(t, 3, len(feed))
+
+	// pages from a cursor don't shift when a new request is added
+	filter := PrayerRequestFeedFilter{}
+	first := GetPrayerRequestsForCommunity(community.ID, filter, false, 2, 0)
+	require.Equal(t, 2, len(first))
+	page := filter.page(first, 2, 0)
+	require.NotNil(t, page.Next)
+
+	added := PrayerRequest{
+		Title:     "Feed added",
+		Body:      "Please pray",
+		CreatedBy: other.ID,
+		Privacy:   PrayerRequestPrivacyPrivate,
+	}
+	err = CreatePrayerRequest(&added)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(added.ID)
+	AddPrayerRequestToCommunity(added.ID, community.ID)
+
+	filter.Cursor = page.Next
+	second := GetPrayerRequestsForCommunity(community.ID, filter, false, 2, 0)
+	require.Equal(t, 1, len(second))
+	assert.Equal(t, requests[0].ID, second[0].ID)
+	page = filter.page(second, 2, 0)
+	assert.Nil(t, page.Next)
+	require.NotNil(t, page.Prev)
+
+	filter.Cursor = page.Prev
+	back := GetPrayerRequestsForCommunity(community.ID, filter, false, 2, 0)
+	require.Equal(t, 2, len(back))
+	assert.Equal(t, first[0].ID, back[0].ID)
+	assert.Equal(t, first[1].ID, back[1].ID)
+
+	total, err := GetCountOfPrayerRequestsForCommunity(community.ID, filter, false)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), total)
+}
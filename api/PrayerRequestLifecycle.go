@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// PrayerRequestArchiveGraceDays is how long an author has to respond to a reminder before the request is archived
+	PrayerRequestArchiveGraceDays = 14
+	// PrayerRequestExpiryMaxDays is the longest expiry a site or community can set
+	PrayerRequestExpiryMaxDays = 3650
+)
+
+// prayerRequestActivity is a pending request along with how many days it has been since the author created, edited, or updated it
+type prayerRequestActivity struct {
+	ID        int64  `db:"id"`
+	Title     string `db:"title"`
+	CreatedBy int64  `db:"createdBy"`
+	IdleDays  int64  `db:"idleDays"`
+}
+
+// GetPrayerRequestExpiryDays gets how many days a pending request can go without an update before its author is reminded. A
+// community's policy overrides the site's, and when a request is shared with several communities the shortest policy wins.
+// 0 means the request never expires
+func GetPrayerRequestExpiryDays(requestID int64) (int64, error) {
+	LoadSite()
+	policies := []int64{}
+	err := Config.DbConn.Select(&policies, `SELECT c.requestExpiryDays FROM Communities c WHERE c.id IN (
+		SELECT prcl.communityId FROM PrayerRequestCommunityLinks prcl WHERE prcl.prayerRequestId = ? AND prcl.status != 'rejected'
+		UNION SELECT sg.communityId FROM PrayerRequestSubGroupLinks prsl, CommunitySubGroups sg WHERE prsl.prayerRequestId = ? AND prsl.subGroupId = sg.id)`,
+		requestID, requestID)
+	if err != nil {
+		return 0, err
+	}
+	return getEffectivePrayerRequestExpiryDays(Site.RequestExpiryDays, policies), nil
+}
+
+// getEffectivePrayerRequestExpiryDays picks the shortest policy, with communities that have no policy of their own using the site's
+func getEffectivePrayerRequestExpiryDays(siteDays int64, communityDays []int64) int64 {
+	if len(communityDays) == 0 {
+		return siteDays
+	}
+	days := int64(0)
+	for i := range communityDays {
+		policy := communityDays[i]
+		if policy == 0 {
+			policy = siteDays
+		}
+		if policy > 0 && (days == 0 || policy < days) {
+			days = policy
+		}
+	}
+	return days
+}
+
+// SendPrayerRequestReminders emails the authors of pending requests that have gone past their expiry without an update, asking
+// them to post an update or mark the request answered or not answered. Each request is only reminded once until the author
+// responds
+func SendPrayerRequestReminders() error {
+	LoadSite()
+	shortest := int64(0)
+	err := Config.DbConn.Get(&shortest, "SELECT COALESCE(MIN(requestExpiryDays), 0) FROM Communities WHERE requestExpiryDays > 0")
+	if err != nil {
+		return err
+	}
+	if Site.RequestExpiryDays > 0 && (shortest == 0 || Site.RequestExpiryDays < shortest) {
+		shortest = Site.RequestExpiryDays
+	}
+	if shortest == 0 {
+		return nil
+	}
+
+	requests := []prayerRequestActivity{}
+	err = Config.DbConn.Select(&requests, `SELECT id, title, createdBy, idleDays FROM (
+		SELECT pr.id, COALESCE(pr.title, '') AS title, pr.createdBy, TIMESTAMPDIFF(DAY,
+		GREATEST(pr.created, pr.lastActive, COALESCE((SELECT MAX(pru.created) FROM PrayerRequestUpdates pru WHERE pru.prayerRequestId = pr.id), pr.created)), NOW()) AS idleDays
+		FROM PrayerRequests pr WHERE pr.status = ? AND pr.archived = '1970-01-01 00:00:00' AND pr.reminded = '1970-01-01 00:00:00') activity
+		WHERE idleDays >= ?`, PrayerRequestStatusPending, shortest)
+	if err != nil {
+		return err
+	}
+	for i := range requests {
+		days, err := GetPrayerRequestExpiryDays(requests[i].ID)
+		if err != nil || days == 0 || requests[i].IdleDays < days {
+			continue
+		}
+		_, err = Config.DbConn.Exec("UPDATE PrayerRequests SET reminded = NOW() WHERE id = ?", requests[i].ID)
+		if err != nil {
+			return err
+		}
+		content := fmt.Sprintf(`<p>It has been a while since your prayer request <strong>%s</strong> was updated.</p>
+	<p>Let everyone praying for you know how things are going by posting an update, or mark the request as answered or not answered.</p>
+	<p>If we don't hear from you in the next %d days, the request will be archived. It will still be in your history, and you can bring it
+	back at any time by posting an update.</p>
+	<p><a href="%s/requests/%d">View your request</a></p>`, requests[i].Title, PrayerRequestArchiveGraceDays, strings.TrimSuffix(Config.WebURL, "/"), requests[i].ID)
+		NotifyUser(requests[i].CreatedBy, 0, NotificationTypePrayerRequestReminder, "How Is Your Prayer Request Going?", content)
+	}
+	return nil
+}
+
+// ArchiveUnansweredPrayerRequests archives the pending requests whose authors haven't responded to a reminder within the grace
+// period. Archived requests are left out of feeds and don't count toward a community's active requests
+func ArchiveUnansweredPrayerRequests() error {
+	_, err := Config.DbConn.Exec(`UPDATE PrayerRequests SET archived = NOW() WHERE status = ? AND archived = '1970-01-01 00:00:00'
+		AND reminded != '1970-01-01 00:00:00' AND reminded < DATE_SUB(NOW(), INTERVAL ? DAY)`, PrayerRequestStatusPending, PrayerRequestArchiveGraceDays)
+	return err
+}
+
+// MarkPrayerRequestActive records that the author responded, which clears any reminder and brings an archived request back
+func MarkPrayerRequestActive(requestID int64) error {
+	_, err := Config.DbConn.Exec("UPDATE PrayerRequests SET reminded = '1970-01-01 00:00:00', archived = '1970-01-01 00:00:00', lastActive = NOW() WHERE id = ?", requestID)
+	return err
+}
+
+// IsValidPrayerRequestExpiryDays checks if the days can be used as an expiry policy; 0 means no policy
+func IsValidPrayerRequestExpiryDays(days int64) bool {
+	return days >= 0 && days <= PrayerRequestExpiryMaxDays
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestExpiryPolicy(t *testing.T) {
+	// no communities uses the site's policy
+	assert.Equal(t, int64(30), getEffectivePrayerRequestExpiryDays(30, []int64{}))
+	assert.Equal(t, int64(0), getEffectivePrayerRequestExpiryDays(0, []int64{}))
+	// communities without a policy use the site's and the shortest wins
+	assert.Equal(t, int64(30), getEffectivePrayerRequestExpiryDays(30, []int64{0, 60}))
+	assert.Equal(t, int64(10), getEffectivePrayerRequestExpiryDays(30, []int64{0, 10}))
+	assert.Equal(t, int64(60), getEffectivePrayerRequestExpiryDays(0, []int64{0, 60}))
+	assert.Equal(t, int64(0), getEffectivePrayerRequestExpiryDays(0, []int64{0}))
+
+	assert.True(t, IsValidPrayerRequestExpiryDays(0))
+	assert.True(t, IsValidPrayerRequestExpiryDays(PrayerRequestExpiryMaxDays))
+	assert.False(t, IsValidPrayerRequestExpiryDays(-1))
+	assert.False(t, IsValidPrayerRequestExpiryDays(PrayerRequestExpiryMaxDays+1))
+}
+
+func TestPrayerRequestLifecycle(t *testing.T) {
+	ConfigSetup()
+	LoadSite()
+	siteDays := Site.RequestExpiryDays
+	Site.RequestExpiryDays = 0
+	defer func() {
+		Site.RequestExpiryDays = siteDays
+	}()
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+
+	community := Community{
+		Name:      "Lifecycle",
+		ShortCode: fmt.Sprintf("lifecycle_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	community.RequestExpiryDays = 30
+	err = UpdateCommunity(&community)
+	require.Nil(t, err)
+
+	request := PrayerRequest{
+		Title:     "Old request",
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   "public",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+	_, err = Config.DbConn.Exec("UPDATE PrayerRequests SET created = DATE_SUB(NOW(), INTERVAL 45 DAY) WHERE id = ?", request.ID)
+	require.Nil(t, err)
+
+	days, err := GetPrayerRequestExpiryDays(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(30), days)
+	activeBefore, err := GetCountOfActiveRequestsInCommunity(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), activeBefore)
+
+	err = SendPrayerRequestReminders()
+	require.Nil(t, err)
+	found, err := GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.NotEqual(t, "1970-01-01 00:00:00", found.Reminded)
+	assert.Equal(t, "", found.Archived)
+
+	// nothing happens until the grace period is over
+	err = ArchiveUnansweredPrayerRequests()
+	require.Nil(t, err)
+	found, err = GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "", found.Archived)
+
+	_, err = Config.DbConn.Exec("UPDATE PrayerRequests SET reminded = DATE_SUB(NOW(), INTERVAL ? DAY) WHERE id = ?", PrayerRequestArchiveGraceDays+1, request.ID)
+	require.Nil(t, err)
+	err = ArchiveUnansweredPrayerRequests()
+	require.Nil(t, err)
+	found, err = GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.NotEqual(t, "", found.Archived)
+
+	// archived requests leave the feeds and the active count but stay in the author's history
+	assert.Zero(t, len(GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+	active, err := GetCountOfActiveRequestsInCommunity(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), active)
+	history, err := GetUserPrayerRequests(author.ID, "", "", "", 100, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(history))
+
+	// posting an update brings it back
+	update := PrayerRequestUpdate{
+		CreatedBy: author.ID,
+		Body:      "Still praying",
+	}
+	err = CreatePrayerRequestUpdate(found, &update)
+	require.Nil(t, err)
+	found, err = GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "", found.Archived)
+	assert.Equal(t, "1970-01-01 00:00:00", found.Reminded)
+	assert.Equal(t, 1, len(GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+
+	// editing the request restarts the expiry, so the author isn't reminded again right away
+	_, err = Config.DbConn.Exec("UPDATE PrayerRequestUpdates SET created = DATE_SUB(NOW(), INTERVAL 45 DAY) WHERE prayerRequestId = ?", request.ID)
+	require.Nil(t, err)
+	found.Privacy = PrayerRequestPrivacyPrivate
+	err = UpdatePrayerRequest(found)
+	require.Nil(t, err)
+	err = SendPrayerRequestReminders()
+	require.Nil(t, err)
+	found, err = GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "1970-01-01 00:00:00", found.Reminded)
+}
@@ -0,0 +1,139 @@
+package api
+
+import "fmt"
+
+// PrayerRequestShare gives a single community or person access to a request. Either CommunityID or UserID is set, never both
+type PrayerRequestShare struct {
+	ID              int64  `json:"id" db:"id"`
+	PrayerRequestID int64  `json:"prayerRequestId" db:"prayerRequestId"`
+	CommunityID     int64  `json:"communityId,omitempty" db:"communityId"`
+	CommunityName   string `json:"communityName,omitempty" db:"communityName"`
+	UserID          int64  `json:"userId,omitempty" db:"userId"`
+	Username        string `json:"username,omitempty" db:"username"`
+	Created         string `json:"created" db:"created"`
+}
+
+const (
+	// PrayerRequestPrivacyPublic requests can be seen by anyone and are shown in the global feed
+	PrayerRequestPrivacyPublic = "public"
+	// PrayerRequestPrivacyPrivate requests are communities-only: they can be seen by the members of every community and
+	// sub-group they are added to
+	PrayerRequestPrivacyPrivate = "private"
+	// PrayerRequestPrivacySelectedCommunities requests can only be seen by the members of the communities they are shared with;
+	// adding one to another community's feed doesn't make it visible there
+	PrayerRequestPrivacySelectedCommunities = "selected_communities"
+	// PrayerRequestPrivacyPeople requests can only be seen by the people they are shared with and never show up in feeds
+	PrayerRequestPrivacyPeople = "people"
+)
+
+// IsValidPrayerRequestPrivacy checks if the input is a known privacy level
+func IsValidPrayerRequestPrivacy(input string) bool {
+	return input == PrayerRequestPrivacyPublic || input == PrayerRequestPrivacyPrivate ||
+		input == PrayerRequestPrivacySelectedCommunities || input == PrayerRequestPrivacyPeople
+}
+
+// CanUserSeePrayerRequest is the single visibility rule for requests. The author can always see their request, and people it
+// is shared with can see it at any privacy level. Otherwise, the request's privacy decides
+func CanUserSeePrayerRequest(userID int64, request *PrayerRequest) bool {
+	if request.Privacy == PrayerRequestPrivacyPublic || (userID != 0 && request.CreatedBy == userID) {
+		return true
+	}
+	if userID == 0 {
+		return false
+	}
+	if IsPrayerRequestSharedWithUser(request.ID, userID) {
+		return true
+	}
+	switch request.Privacy {
+	case PrayerRequestPrivacyPrivate:
+		return IsUserAndRequestInSameGroup(userID, request.ID)
+	case PrayerRequestPrivacySelectedCommunities:
+		return IsUserInCommunitySharedWithPrayerRequest(request.ID, userID)
+	}
+	return false
+}
+
+// prayerRequestVisibleInCommunity is a query clause for feeds that limits the requests to the ones the members of the community
+// in the given column can see. Requests shared with people are never shown in feeds
+func prayerRequestVisibleInCommunity(communityColumn string) string {
+	return fmt.Sprintf(` AND (pr.privacy IN ('%s', '%s') OR (pr.privacy = '%s' AND EXISTS
+		(SELECT 1 FROM PrayerRequestShares prs WHERE prs.prayerRequestId = pr.id AND prs.communityId = %s))) `,
+		PrayerRequestPrivacyPublic, PrayerRequestPrivacyPrivate, PrayerRequestPrivacySelectedCommunities, communityColumn)
+}
+
+// IsPrayerRequestSharedWithUser checks if the request has been shared directly with the user
+func IsPrayerRequestSharedWithUser(requestID, userID int64) bool {
+	count := 0
+	err := Config.DbConn.Get(&count, "SELECT COUNT(*) FROM PrayerRequestShares WHERE prayerRequestId = ? AND userId = ?", requestID, userID)
+	return err == nil && count > 0
+}
+
+// IsUserInCommunitySharedWithPrayerRequest checks if the user is an accepted member of a community the request is shared with
+func IsUserInCommunitySharedWithPrayerRequest(requestID, userID int64) bool {
+	count := 0
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) FROM PrayerRequestShares prs, CommunityUserLinks cul, Communities c
+		WHERE prs.prayerRequestId = ? AND prs.communityId = cul.communityId AND cul.userId = ? AND cul.status = 'accepted'
+		AND c.id = cul.communityId AND c.archived = '1970-01-01 00:00:00'`, requestID, userID)
+	return err == nil && count > 0
+}
+
+// CreatePrayerRequestShare shares the request with a community or a person. Sharing again with the same community or person
+// returns the existing share
+func CreatePrayerRequestShare(input *PrayerRequestShare) error {
+	res, err := Config.DbConn.NamedExec(`INSERT INTO PrayerRequestShares (prayerRequestId, communityId, userId, created)
+		VALUES (:prayerRequestId, :communityId, :userId, NOW()) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id)`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	found, err := GetPrayerRequestShare(input.PrayerRequestID, input.ID)
+	if err != nil {
+		return err
+	}
+	*input = *found
+	return nil
+}
+
+// GetPrayerRequestShare gets a single share on a request
+func GetPrayerRequestShare(requestID, shareID int64) (*PrayerRequestShare, error) {
+	share := &PrayerRequestShare{}
+	err := Config.DbConn.Get(share, `SELECT prs.*, COALESCE(c.name, '') AS communityName, COALESCE(u.username, '') AS username
+		FROM PrayerRequestShares prs LEFT JOIN Communities c ON c.id = prs.communityId LEFT JOIN Users u ON u.id = prs.userId
+		WHERE prs.id = ? AND prs.prayerRequestId = ?`, shareID, requestID)
+	share.processForAPI()
+	return share, err
+}
+
+// GetPrayerRequestShares gets everyone a request has been shared with, in the order they were shared
+func GetPrayerRequestShares(requestID int64) ([]PrayerRequestShare, error) {
+	shares := []PrayerRequestShare{}
+	err := Config.DbConn.Select(&shares, `SELECT prs.*, COALESCE(c.name, '') AS communityName, COALESCE(u.username, '') AS username
+		FROM PrayerRequestShares prs LEFT JOIN Communities c ON c.id = prs.communityId LEFT JOIN Users u ON u.id = prs.userId
+		WHERE prs.prayerRequestId = ? ORDER BY prs.created, prs.id`, requestID)
+	for i := range shares {
+		shares[i].processForAPI()
+	}
+	return shares, err
+}
+
+// DeletePrayerRequestShare revokes a single share
+func DeletePrayerRequestShare(requestID, shareID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestShares WHERE id = ? AND prayerRequestId = ?", shareID, requestID)
+	return err
+}
+
+// DeletePrayerRequestSharesForRequest revokes every share on a request
+func DeletePrayerRequestSharesForRequest(requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestShares WHERE prayerRequestId = ?", requestID)
+	return err
+}
+
+// DeletePrayerRequestSharesForCommunity revokes every share with a community
+func DeletePrayerRequestSharesForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestShares WHERE communityId = ?", communityID)
+	return err
+}
+
+func (input *PrayerRequestShare) processForAPI() {
+	input.Created, _ = ParseTimeToISO(input.Created)
+}
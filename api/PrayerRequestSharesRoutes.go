@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *PrayerRequestShare) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreatePrayerRequestShareRoute shares a request with a person by username or with a community by id. Only the author can share
+// their request, and they can only share it with communities they belong to
+func CreatePrayerRequestShareRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, request, ok := getOwnPrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	input := PrayerRequestShare{}
+	render.Bind(r, &input)
+	input.Username = strings.TrimSpace(input.Username)
+	if (input.Username == "") == (input.CommunityID == 0) {
+		SendError(w, http.StatusBadRequest, "prayer_request_share_bad_data", "either username or communityId is required", input)
+		return
+	}
+
+	share := PrayerRequestShare{
+		PrayerRequestID: request.ID,
+	}
+	if input.Username != "" {
+		user, err := GetUserByUsername(input.Username)
+		if err != nil || user.ID == 0 {
+			SendError(w, http.StatusNotFound, "prayer_request_share_user_not_found", "that user could not be found", nil)
+			return
+		}
+		if user.ID == jwtUser.ID {
+			SendError(w, http.StatusBadRequest, "prayer_request_share_bad_data", "you can't share a request with yourself", nil)
+			return
+		}
+		share.UserID = user.ID
+	} else {
+		role, err := GetUserRoleForCommunity(input.CommunityID, jwtUser.ID)
+		if err != nil || (role != "admin" && role != "member") {
+			SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+			return
+		}
+		share.CommunityID = input.CommunityID
+	}
+
+	err := CreatePrayerRequestShare(&share)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_share_error", "the request could not be shared", err)
+		return
+	}
+	Send(w, http.StatusCreated, share)
+	return
+}
+
+// GetPrayerRequestSharesRoute gets everyone the author has shared their request with
+func GetPrayerRequestSharesRoute(w http.ResponseWriter, r *http.Request) {
+	_, request, ok := getOwnPrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+	shares, err := GetPrayerRequestShares(request.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "prayer_request_share_error", "could not get the shares", err)
+		return
+	}
+	Send(w, http.StatusOK, shares)
+	return
+}
+
+// DeletePrayerRequestShareRoute revokes a single share
+func DeletePrayerRequestShareRoute(w http.ResponseWriter, r *http.Request) {
+	_, request, ok := getOwnPrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+	shareID, shareIDErr := strconv.ParseInt(chi.URLParam(r, "shareID"), 10, 64)
+	if shareIDErr != nil {
+		SendError(w, http.StatusNotFound, "prayer_request_share_not_found", "that share could not be found", nil)
+		return
+	}
+	if _, err := GetPrayerRequestShare(request.ID, shareID); err != nil {
+		SendError(w, http.StatusNotFound, "prayer_request_share_not_found", "that share could not be found", nil)
+		return
+	}
+
+	err := DeletePrayerRequestShare(request.ID, shareID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "prayer_request_share_error", "could not revoke the share", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// getOwnPrayerRequestFromRequest loads the request in the url if the user is its author, sending the error if not
+func getOwnPrayerRequestFromRequest(w http.ResponseWriter, r *http.Request) (JWTUser, *PrayerRequest, bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || request.CreatedBy != jwtUser.ID {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return jwtUser, nil, false
+	}
+	return jwtUser, request, true
+}
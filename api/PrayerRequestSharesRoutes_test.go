@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestShareRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&author)
+	friend := User{}
+	err = CreateTestUser(&friend)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&friend)
+
+	community := Community{
+		Name:      "Sharing",
+		ShortCode: fmt.Sprintf("sharing_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	b.Reset()
+	enc := json.NewEncoder(b)
+	enc.Encode(map[string]string{
+		"title":   "Just for friends",
+		"body":    "Please pray",
+		"privacy": "friends",
+	})
+	code, _, _ := TestAPICall(http.MethodPost, "/requests", b, CreatePrayerRequestRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"title":   "Just for friends",
+		"body":    "Please pray",
+		"privacy": PrayerRequestPrivacyPeople,
+	})
+	code, res, _ := TestAPICall(http.MethodPost, "/requests", b, CreatePrayerRequestRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	request := PrayerRequest{}
+	mapstructure.Decode(body, &request)
+	defer DeletePrayerRequest(request.ID)
+
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// only the author can share, with a username or a community they belong to
+	b.Reset()
+	enc.Encode(map[string]string{
+		"username": author.Username,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/shares", request.ID), b, CreatePrayerRequestShareRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/shares", request.ID), b, CreatePrayerRequestShareRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"communityId": community.ID,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/shares", request.ID), b, CreatePrayerRequestShareRoute, author.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"username": fmt.Sprintf("nobody_%d", rand.Int63n(99999999)),
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/shares", request.ID), b, CreatePrayerRequestShareRoute, author.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"username": friend.Username,
+	})
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/shares", request.ID), b, CreatePrayerRequestShareRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	share := PrayerRequestShare{}
+	mapstructure.Decode(body, &share)
+	assert.Equal(t, friend.ID, share.UserID)
+
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/prayers", request.ID), b, AddPrayerToRequestRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/prayers", request.ID), b, GetPrayersMadeOnRequestRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/shares", request.ID), b, GetPrayerRequestSharesRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(list))
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/shares", request.ID), b, GetPrayerRequestSharesRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// revoking the share takes the access away
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/shares/%d", request.ID, share.ID), b, DeletePrayerRequestShareRoute, author.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/shares/%d", request.ID, share.ID), b, DeletePrayerRequestShareRoute, author.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/prayers", request.ID), b, AddPrayerToRequestRoute, friend.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestPrivacyValidation(t *testing.T) {
+	assert.True(t, IsValidPrayerRequestPrivacy(PrayerRequestPrivacyPublic))
+	assert.True(t, IsValidPrayerRequestPrivacy(PrayerRequestPrivacyPrivate))
+	assert.True(t, IsValidPrayerRequestPrivacy(PrayerRequestPrivacySelectedCommunities))
+	assert.True(t, IsValidPrayerRequestPrivacy(PrayerRequestPrivacyPeople))
+	assert.False(t, IsValidPrayerRequestPrivacy("friends"))
+	assert.False(t, IsValidPrayerRequestPrivacy(""))
+}
+
+func TestPrayerRequestVisibility(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	memberA := User{}
+	err = CreateTestUser(&memberA)
+	require.Nil(t, err)
+	defer DeleteUser(memberA.ID)
+	memberB := User{}
+	err = CreateTestUser(&memberB)
+	require.Nil(t, err)
+	defer DeleteUser(memberB.ID)
+	friend := User{}
+	err = CreateTestUser(&friend)
+	require.Nil(t, err)
+	defer DeleteUser(friend.ID)
+
+	invited := User{}
+	err = CreateTestUser(&invited)
+	require.Nil(t, err)
+	defer DeleteUser(invited.ID)
+	waiting := User{}
+	err = CreateTestUser(&waiting)
+	require.Nil(t, err)
+	defer DeleteUser(waiting.ID)
+	adminA := User{}
+	err = CreateTestUser(&adminA)
+	require.Nil(t, err)
+	defer DeleteUser(adminA.ID)
+
+	communityA := Community{
+		Name:      "Visibility A",
+		ShortCode: fmt.Sprintf("vis_a_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&communityA)
+	require.Nil(t, err)
+	defer DeleteCommunity(communityA.ID)
+	communityB := Community{
+		Name:      "Visibility B",
+		ShortCode: fmt.Sprintf("vis_b_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&communityB)
+	require.Nil(t, err)
+	defer DeleteCommunity(communityB.ID)
+	CreateCommunityUserLink(communityA.ID, author.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(communityB.ID, author.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(communityA.ID, memberA.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(communityB.ID, memberB.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(communityA.ID, invited.ID, CommunityUserRoleMember, CommunityUserLinkStatusInvited, "")
+	CreateCommunityUserLink(communityA.ID, adminA.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	_, err = JoinCommunityWaitlist(communityA.ID, waiting.ID, "")
+	require.Nil(t, err)
+
+	request := PrayerRequest{
+		Title:     "Visibility",
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   PrayerRequestPrivacyPrivate,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	AddPrayerRequestToCommunity(request.ID, communityA.ID)
+	AddPrayerRequestToCommunity(request.ID, communityB.ID)
+
+	// communities-only is every community the request was added to
+	assert.True(t, CanUserSeePrayerRequest(author.ID, &request))
+	assert.True(t, CanUserSeePrayerRequest(memberA.ID, &request))
+	assert.True(t, CanUserSeePrayerRequest(memberB.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(friend.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(0, &request))
+	// admins count as members, but people who haven't joined yet don't
+	assert.True(t, CanUserSeePrayerRequest(adminA.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(invited.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(waiting.ID, &request))
+
+	// selected communities need a share even if the request is in the feed
+	request.Privacy = PrayerRequestPrivacySelectedCommunities
+	err = UpdatePrayerRequest(&request)
+	require.Nil(t, err)
+	shareA := PrayerRequestShare{
+		PrayerRequestID: request.ID,
+		CommunityID:     communityA.ID,
+	}
+	err = CreatePrayerRequestShare(&shareA)
+	require.Nil(t, err)
+	assert.Equal(t, communityA.Name, shareA.CommunityName)
+	assert.True(t, CanUserSeePrayerRequest(memberA.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(memberB.ID, &request))
+	assert.Equal(t, 1, len(GetPrayerRequestsForCommunity(communityA.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+	assert.Zero(t, len(GetPrayerRequestsForCommunity(communityB.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+
+	// sharing again gives back the same share
+	again := PrayerRequestShare{
+		PrayerRequestID: request.ID,
+		CommunityID:     communityA.ID,
+	}
+	err = CreatePrayerRequestShare(&again)
+	require.Nil(t, err)
+	assert.Equal(t, shareA.ID, again.ID)
+
+	// people shares work at any level, and people-only requests never show in feeds
+	request.Privacy = PrayerRequestPrivacyPeople
+	err = UpdatePrayerRequest(&request)
+	require.Nil(t, err)
+	shareFriend := PrayerRequestShare{
+		PrayerRequestID: request.ID,
+		UserID:          friend.ID,
+	}
+	err = CreatePrayerRequestShare(&shareFriend)
+	require.Nil(t, err)
+	assert.Equal(t, friend.Username, shareFriend.Username)
+	assert.True(t, CanUserSeePrayerRequest(friend.ID, &request))
+	assert.False(t, CanUserSeePrayerRequest(memberA.ID, &request))
+	assert.Zero(t, len(GetPrayerRequestsForCommunity(communityA.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+
+	shares, err := GetPrayerRequestShares(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(shares))
+
+	// revoking a share removes access
+	err = DeletePrayerRequestShare(request.ID, shareFriend.ID)
+	require.Nil(t, err)
+	assert.False(t, CanUserSeePrayerRequest(friend.ID, &request))
+
+	err = DeletePrayerRequestSharesForRequest(request.ID)
+	require.Nil(t, err)
+	shares, err = GetPrayerRequestShares(request.ID)
+	require.Nil(t, err)
+	assert.Zero(t, len(shares))
+}
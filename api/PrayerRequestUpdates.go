@@ -0,0 +1,116 @@
+package api
+
+import "fmt"
+
+// PrayerRequestUpdate is a follow-up the author posts on their request, such as "surgery went well". Updates can't be edited
+// once posted. An update can also change the request's status, such as to answered
+type PrayerRequestUpdate struct {
+	ID              int64  `json:"id" db:"id"`
+	PrayerRequestID int64  `json:"prayerRequestId" db:"prayerRequestId"`
+	CreatedBy       int64  `json:"createdBy" db:"createdBy"`
+	Body            string `json:"body" db:"body"`
+	// Status is the status the request was changed to; it is blank if the update didn't change it
+	Status         string `json:"status,omitempty" db:"status"`
+	PreviousStatus string `json:"previousStatus,omitempty" db:"previousStatus"`
+	Created        string `json:"created" db:"created"`
+}
+
+// PrayerRequestUpdateBodyMaxLength is the longest an update's body can be
+const PrayerRequestUpdateBodyMaxLength = 2048
+
+// IsValidPrayerRequestStatus checks if the input is a known request status
+func IsValidPrayerRequestStatus(input string) bool {
+	return input == PrayerRequestStatusPending || input == PrayerRequestStatusAnswered ||
+		input == PrayerRequestStatusNotAnswered || input == PrayerRequestStatusUnknown
+}
+
+// CreatePrayerRequestUpdate adds an update to the request, changing the request's status if the update has one
+func CreatePrayerRequestUpdate(request *PrayerRequest, input *PrayerRequestUpdate) error {
+	input.PrayerRequestID = request.ID
+	input.PreviousStatus = ""
+	if input.Status == request.Status {
+		input.Status = ""
+	}
+	if input.Status != "" {
+		input.PreviousStatus = request.Status
+		request.Status = input.Status
+		err := UpdatePrayerRequest(request)
+		if err != nil {
+			return err
+		}
+	}
+	res, err := Config.DbConn.NamedExec(`INSERT INTO PrayerRequestUpdates (prayerRequestId, createdBy, body, status, previousStatus, created)
+		VALUES (:prayerRequestId, :createdBy, :body, :status, :previousStatus, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	err = MarkPrayerRequestActive(request.ID)
+	if err != nil {
+		return err
+	}
+	found := PrayerRequestUpdate{}
+	err = Config.DbConn.Get(&found, "SELECT * FROM PrayerRequestUpdates WHERE id = ?", input.ID)
+	if err != nil {
+		return err
+	}
+	found.processForAPI()
+	*input = found
+	return nil
+}
+
+// GetPrayerRequestUpdates gets the updates on a request, oldest first, so they read as a timeline
+func GetPrayerRequestUpdates(requestID int64) ([]PrayerRequestUpdate, error) {
+	updates := []PrayerRequestUpdate{}
+	err := Config.DbConn.Select(&updates, "SELECT * FROM PrayerRequestUpdates WHERE prayerRequestId = ? ORDER BY created, id", requestID)
+	for i := range updates {
+		updates[i].processForAPI()
+	}
+	return updates, err
+}
+
+// DeletePrayerRequestUpdatesForRequest deletes every update on a request
+func DeletePrayerRequestUpdatesForRequest(requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestUpdates WHERE prayerRequestId = ?", requestID)
+	return err
+}
+
+// GetUserIDsFollowingPrayerRequest gets the users who have prayed for a request or have it on one of their prayer lists,
+// leaving out the excluded user, usually the author. For requests that aren't public, only users who can still see the
+// request are included
+func GetUserIDsFollowingPrayerRequest(request *PrayerRequest, excludeUserID int64) ([]int64, error) {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, `SELECT p.userId FROM Prayers p WHERE p.prayerRequestId = ? AND p.userId != 0 AND p.userId != ?
+		UNION SELECT pl.userId FROM PrayerLists pl, PrayerRequestPrayerListLinks prpll
+		WHERE prpll.prayerRequestId = ? AND prpll.listId = pl.id AND pl.userId != ?`, request.ID, excludeUserID, request.ID, excludeUserID)
+	if err != nil || request.Privacy == PrayerRequestPrivacyPublic {
+		return ids, err
+	}
+	visible := []int64{}
+	for i := range ids {
+		if CanUserSeePrayerRequest(ids[i], request) {
+			visible = append(visible, ids[i])
+		}
+	}
+	return visible, nil
+}
+
+// NotifyPrayerRequestUpdate emails everyone following the request about a new update
+func NotifyPrayerRequestUpdate(request *PrayerRequest, update *PrayerRequestUpdate) error {
+	ids, err := GetUserIDsFollowingPrayerRequest(request, update.CreatedBy)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("<p>There is an update on the prayer request <strong>%s</strong>:</p><p>%s</p>", request.Title, update.Body)
+	if update.Status == PrayerRequestStatusAnswered {
+		content += "<p>The request has been marked as answered.</p>"
+	}
+	for i := range ids {
+		NotifyUser(ids[i], 0, NotificationTypePrayerRequestUpdate, fmt.Sprintf("Update on %s", request.Title), content)
+	}
+	return nil
+}
+
+func (input *PrayerRequestUpdate) processForAPI() {
+	input.Created, _ = ParseTimeToISO(input.Created)
+}
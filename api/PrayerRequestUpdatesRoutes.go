@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *PrayerRequestUpdate) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreatePrayerRequestUpdateRoute lets the author post an update on their request, optionally changing its status. Everyone
+// who prayed for the request or has it on a prayer list is notified
+func CreatePrayerRequestUpdateRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	// only the author can post updates
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || request.CreatedBy != jwtUser.ID {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := PrayerRequestUpdate{}
+	render.Bind(r, &input)
+	input.Body, _ = sanitize(input.Body)
+	if input.Body == "" || len(input.Body) > PrayerRequestUpdateBodyMaxLength {
+		SendError(w, http.StatusBadRequest, "prayer_request_update_bad_data", fmt.Sprintf("body is required and can be at most %d characters", PrayerRequestUpdateBodyMaxLength), input)
+		return
+	}
+	if input.Status != "" && !IsValidPrayerRequestStatus(input.Status) {
+		SendError(w, http.StatusBadRequest, "prayer_request_update_bad_data", "status must be pending, answered, not_answered, or unknown", input)
+		return
+	}
+	input.CreatedBy = jwtUser.ID
+
+	wasAnswered := request.Status == PrayerRequestStatusAnswered
+	err = CreatePrayerRequestUpdate(request, &input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_update_bad_data", "the update could not be saved", err)
+		return
+	}
+	if !wasAnswered && request.Status == PrayerRequestStatusAnswered {
+		QueuePrayerRequestWebhookEvent(WebhookEventRequestAnswered, request.ID, request)
+	}
+	go NotifyPrayerRequestUpdate(request, &input)
+
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetPrayerRequestUpdatesRoute gets the timeline of updates on a request
+func GetPrayerRequestUpdatesRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || !CanUserSeePrayerRequest(jwtUser.ID, request) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	updates, err := GetPrayerRequestUpdates(requestID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "prayer_request_update_error", "could not get the updates", err)
+		return
+	}
+	if !canUserSeePrayerRequestAuthor(jwtUser, request) {
+		for i := range updates {
+			updates[i].CreatedBy = 0
+		}
+	}
+	Send(w, http.StatusOK, updates)
+	return
+}
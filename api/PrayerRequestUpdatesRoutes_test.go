@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestUpdateRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&author)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&other)
+
+	request := PrayerRequest{
+		Title:     "Job",
+		Body:      "Please pray for my interview",
+		CreatedBy: author.ID,
+		Privacy:   "private",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	// only the author can post updates
+	b.Reset()
+	enc := json.NewEncoder(b)
+	enc.Encode(map[string]string{
+		"body": "Interview went well",
+	})
+	code, _, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/updates", request.ID), b, CreatePrayerRequestUpdateRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/updates", request.ID), b, CreatePrayerRequestUpdateRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"body":   "Interview went well",
+		"status": "deleted",
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/updates", request.ID), b, CreatePrayerRequestUpdateRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"body": "Interview went well",
+	})
+	code, res, _ := TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/updates", request.ID), b, CreatePrayerRequestUpdateRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	update := PrayerRequestUpdate{}
+	mapstructure.Decode(body, &update)
+	assert.Equal(t, "Interview went well", update.Body)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"body":   "I got the job!",
+		"status": PrayerRequestStatusAnswered,
+	})
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/updates", request.ID), b, CreatePrayerRequestUpdateRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, PrayerRequestStatusAnswered, body["status"])
+
+	// the request includes its timeline
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, PrayerRequestStatusAnswered, body["status"])
+	updates := body["updates"].([]interface{})
+	require.Equal(t, 2, len(updates))
+	assert.Equal(t, "Interview went well", updates[0].(map[string]interface{})["body"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/updates", request.ID), b, GetPrayerRequestUpdatesRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 2, len(list))
+
+	// the request is private, so others can't see the updates
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d/updates", request.ID), b, GetPrayerRequestUpdatesRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+}
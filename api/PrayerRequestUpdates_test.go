@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerRequestStatusValidation(t *testing.T) {
+	assert.True(t, IsValidPrayerRequestStatus(PrayerRequestStatusPending))
+	assert.True(t, IsValidPrayerRequestStatus(PrayerRequestStatusAnswered))
+	assert.True(t, IsValidPrayerRequestStatus(PrayerRequestStatusNotAnswered))
+	assert.True(t, IsValidPrayerRequestStatus(PrayerRequestStatusUnknown))
+	assert.False(t, IsValidPrayerRequestStatus("deleted"))
+	assert.False(t, IsValidPrayerRequestStatus(""))
+}
+
+func TestPrayerRequestUpdates(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	prayer := User{}
+	err = CreateTestUser(&prayer)
+	require.Nil(t, err)
+	defer DeleteUser(prayer.ID)
+	lister := User{}
+	err = CreateTestUser(&lister)
+	require.Nil(t, err)
+	defer DeleteUser(lister.ID)
+	outsider := User{}
+	err = CreateTestUser(&outsider)
+	require.Nil(t, err)
+	defer DeleteUser(outsider.ID)
+
+	community := Community{
+		Name:      "Updates",
+		ShortCode: fmt.Sprintf("updates_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, author.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, prayer.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, lister.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	request := PrayerRequest{
+		Title:     "Surgery",
+		Body:      "Please pray for my surgery",
+		CreatedBy: author.ID,
+		Privacy:   "private",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+
+	AddPrayerMade(author.ID, request.ID)
+	AddPrayerMade(prayer.ID, request.ID)
+	AddPrayerMade(outsider.ID, request.ID)
+	list := PrayerList{
+		UserID: lister.ID,
+		Title:  "Mine",
+	}
+	err = CreatePrayerList(&list)
+	require.Nil(t, err)
+	defer DeletePrayerList(list.ID)
+	AddRequestToPrayerList(request.ID, list.ID)
+
+	// the author isn't notified of their own update, and the outsider can no longer see the private request
+	found, err := GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	ids, err := GetUserIDsFollowingPrayerRequest(found, author.ID)
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []int64{prayer.ID, lister.ID}, ids)
+
+	found.Privacy = "public"
+	ids, err = GetUserIDsFollowingPrayerRequest(found, author.ID)
+	require.Nil(t, err)
+	assert.ElementsMatch(t, []int64{prayer.ID, lister.ID, outsider.ID}, ids)
+	found.Privacy = "private"
+
+	update := PrayerRequestUpdate{
+		CreatedBy: author.ID,
+		Body:      "Still waiting",
+	}
+	err = CreatePrayerRequestUpdate(found, &update)
+	require.Nil(t, err)
+	assert.NotEqual(t, int64(0), update.ID)
+	assert.Equal(t, "", update.Status)
+	assert.NotEqual(t, "", update.Created)
+
+	// a status the request already has isn't recorded as a change
+	update = PrayerRequestUpdate{
+		CreatedBy: author.ID,
+		Body:      "Still pending",
+		Status:    PrayerRequestStatusPending,
+	}
+	err = CreatePrayerRequestUpdate(found, &update)
+	require.Nil(t, err)
+	assert.Equal(t, "", update.Status)
+
+	update = PrayerRequestUpdate{
+		CreatedBy: author.ID,
+		Body:      "Surgery went well",
+		Status:    PrayerRequestStatusAnswered,
+	}
+	err = CreatePrayerRequestUpdate(found, &update)
+	require.Nil(t, err)
+	assert.Equal(t, PrayerRequestStatusAnswered, update.Status)
+	assert.Equal(t, PrayerRequestStatusPending, update.PreviousStatus)
+
+	found, err = GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, PrayerRequestStatusAnswered, found.Status)
+	assert.NotEqual(t, "", found.Answered)
+
+	updates, err := GetPrayerRequestUpdates(request.ID)
+	require.Nil(t, err)
+	require.Equal(t, 3, len(updates))
+	assert.Equal(t, "Still waiting", updates[0].Body)
+	assert.Equal(t, "Surgery went well", updates[2].Body)
+
+	err = DeletePrayerRequestUpdatesForRequest(request.ID)
+	require.Nil(t, err)
+	updates, err = GetPrayerRequestUpdates(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(updates))
+}
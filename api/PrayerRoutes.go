@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
@@ -13,6 +16,11 @@ func (data *Prayer) Bind(r *http.Request) error {
 	return nil
 }
 
+// Bind binds data
+func (data *PrayerRequestCommunityLink) Bind(r *http.Request) error {
+	return nil
+}
+
 // Bind binds data
 func (data *PrayerRequest) Bind(r *http.Request) error {
 	return nil
@@ -34,7 +42,11 @@ func CreatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 	input.CreatedBy = jwtUser.ID
 	input.Status = PrayerRequestStatusPending
 	if input.Privacy == "" {
-		input.Privacy = "private"
+		input.Privacy = PrayerRequestPrivacyPrivate
+	}
+	if !IsValidPrayerRequestPrivacy(input.Privacy) {
+		SendError(w, http.StatusBadRequest, "prayer_request_bad_data", "privacy must be public, private, selected_communities, or people", input)
+		return
 	}
 
 	if input.Title == "" || input.Body == "" {
@@ -42,6 +54,22 @@ func CreatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	tags := []string{}
+	for i := range input.Tags {
+		tag, ok := NormalizeTag(input.Tags[i])
+		if !ok {
+			SendError(w, http.StatusBadRequest, "prayer_request_bad_data", fmt.Sprintf("tags can be at most %d characters", PrayerRequestTagMaxLength), input)
+			return
+		}
+		if !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > PrayerRequestTagsMaxPerRequest {
+		SendError(w, http.StatusBadRequest, "prayer_request_bad_data", fmt.Sprintf("a request can have at most %d tags", PrayerRequestTagsMaxPerRequest), input)
+		return
+	}
+
 	err = CreatePrayerRequest(&input)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, "prayer_request_bad_data", "prayer request could not be created", err)
@@ -49,15 +77,19 @@ func CreatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// now process the tags
-	for i := range input.Tags {
-		AddTagToPrayerRequest(input.ID, input.Tags[i])
+	input.Tags = []string{}
+	for i := range tags {
+		if _, err := AddTagToPrayerRequest(input.ID, tags[i]); err == nil {
+			input.Tags = append(input.Tags, tags[i])
+		}
 	}
+	QueuePrayerRequestWebhookEvent(WebhookEventRequestCreated, input.ID, input)
 
 	Send(w, http.StatusCreated, input)
 	return
 }
 
-// UpdatePrayerRequestRoute updates the status or privacy of a prayer request. Note that once created, we do not allow for changing of titles, bodies, etc
+// UpdatePrayerRequestRoute updates the status, privacy, or comment status of a prayer request. Note that once created, we do not allow for changing of titles, bodies, etc
 func UpdatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -82,9 +114,22 @@ func UpdatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 	render.Bind(r, &input)
 
 	if input.Privacy != "" {
+		if !IsValidPrayerRequestPrivacy(input.Privacy) {
+			SendError(w, http.StatusBadRequest, "prayer_request_bad_data", "privacy must be public, private, selected_communities, or people", input)
+			return
+		}
 		request.Privacy = input.Privacy
 	}
 
+	if input.CommentStatus != "" {
+		if input.CommentStatus != PrayerRequestCommentStatusEnabled && input.CommentStatus != PrayerRequestCommentStatusDisabled {
+			SendError(w, http.StatusBadRequest, "prayer_request_bad_data", "commentStatus must be enabled or disabled", input)
+			return
+		}
+		request.CommentStatus = input.CommentStatus
+	}
+
+	wasAnswered := request.Status == PrayerRequestStatusAnswered
 	if input.Status != "" {
 		request.Status = input.Status
 	}
@@ -93,6 +138,9 @@ func UpdatePrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
 		SendError(w, http.StatusBadRequest, "prayer_request_bad_data", "prayer request could not be updated", err)
 		return
 	}
+	if !wasAnswered && request.Status == PrayerRequestStatusAnswered {
+		QueuePrayerRequestWebhookEvent(WebhookEventRequestAnswered, request.ID, request)
+	}
 
 	Send(w, http.StatusOK, request)
 	return
@@ -151,31 +199,38 @@ func GetPrayerRequestByIDRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ok, so there's some interesting logic here
-	// first, if the request belongs to the user or is public, it's good
-	// if not, if the request is in a group that the user belongs to, then it too is good
-
-	if request.Privacy == "public" || request.CreatedBy == jwtUser.ID {
-		Send(w, http.StatusOK, request)
+	if !CanUserSeePrayerRequest(jwtUser.ID, request) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
 
-	// check the groups
-	if IsUserAndRequestInSameGroup(jwtUser.ID, requestID) {
-		Send(w, http.StatusOK, request)
-		return
+	request.Updates, _ = GetPrayerRequestUpdates(requestID)
+	if !canUserSeePrayerRequestAuthor(jwtUser, request) {
+		request.HideAnonymousAuthor()
 	}
-
-	SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+	Send(w, http.StatusOK, request)
 	return
 }
 
-// GetGlobalPrayerRequestsRoute gets the global request list
+// GetGlobalPrayerRequestsRoute gets the global request list. It takes the feed filters and sorts described in
+// getPrayerRequestFeedFilterFromRequest
 func GetGlobalPrayerRequestsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, _ := CheckForUser(r)
 	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
-	requests := GetGlobalPrayerRequests(count, offset)
+	filter, err := getPrayerRequestFeedFilterFromRequest(r, jwtUser, false)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_feed_bad_filter", err.Error(), nil)
+		return
+	}
+	requests := GetGlobalPrayerRequests(filter, count, offset)
+	hideAnonymousAuthors(jwtUser, false, requests)
 
-	Send(w, http.StatusOK, requests)
+	page := filter.page(requests, count, offset)
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfGlobalPrayerRequests(filter)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, requests, page)
 	return
 }
 
@@ -194,29 +249,36 @@ func GetUserPrayerRequestsRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	status := r.URL.Query().Get("status")
+	if !CheckPageCursor(w, r) {
+		return
+	}
 	start, end, count, offset, _, _, _, _ := ProcessQuery(r)
 	requests, _ := GetUserPrayerRequests(userID, status, start, end, count, offset)
+	// the page is judged before filtering so that filtering out requests doesn't end the list early
+	page := offsetPage(count, offset, len(requests))
 
 	// if the userID and the jwt user are the same, show all
 	if userID == jwtUser.ID {
-		Send(w, http.StatusOK, requests)
+		SendPage(w, r, http.StatusOK, requests, page)
 		return
 	}
 
 	// since it is a different user, we only want to return requests that are public
 	// in the future, we can modify the SQL to get the communities each prayer is in and filter on that
+	// anonymous requests are left out entirely since listing them here would reveal who posted them
 	processed := []PrayerRequest{}
 	for i := range requests {
-		if requests[i].Privacy == "public" {
+		if requests[i].Privacy == "public" && (!requests[i].Anonymous || jwtUser.PlatformRole == "admin") {
 			processed = append(processed, requests[i])
 		}
 	}
 
-	Send(w, http.StatusOK, processed)
+	SendPage(w, r, http.StatusOK, processed, page)
 	return
 }
 
-// GetCommunityPrayerRequestsRoute gets the requests for a user
+// GetCommunityPrayerRequestsRoute gets the requests in a community. It takes the feed filters and sorts described in
+// getPrayerRequestFeedFilterFromRequest
 func GetCommunityPrayerRequestsRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 {
@@ -230,18 +292,29 @@ func GetCommunityPrayerRequestsRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// make sure the user is in the community
-	link, err := GetCommunityUserLink(communityID, jwtUser.ID)
-	if err != nil || link.Status != "accepted" || (link.Role != "admin" && link.Role != "member") {
+	// make sure the user is in the community and it hasn't been archived
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || (role != "admin" && role != "member") {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
 
-	status := r.URL.Query().Get("status")
 	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	filter, err := getPrayerRequestFeedFilterFromRequest(r, jwtUser, role == "admin")
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_feed_bad_filter", err.Error(), nil)
+		return
+	}
 
-	requests := GetPrayerRequestsForCommunity(communityID, status, count, offset)
-	Send(w, http.StatusOK, requests)
+	requests := GetPrayerRequestsForCommunity(communityID, filter, role == "admin", count, offset)
+	hideAnonymousAuthors(jwtUser, role == "admin", requests)
+
+	page := filter.page(requests, count, offset)
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfPrayerRequestsForCommunity(communityID, filter, role == "admin")
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, requests, page)
 	return
 }
 
@@ -271,20 +344,157 @@ func AddPrayerRequestToCommunityRoute(w http.ResponseWriter, r *http.Request) {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	// requests shared with sub-groups count toward the same limit
+	limitReached, _ := IsCommunityActiveRequestLimitReached(community, requestID)
+	if limitReached {
+		SendError(w, http.StatusForbidden, "community_request_limit_reached", "this community cannot accept anymore active requests", map[string]interface{}{
+			"allowed": plans[community.Plan].AllowedActiveRequests,
+		})
+		return
+	}
+
+	// if the community moderates requests, anything added by a non-admin waits in the queue
+	linkStatus := PrayerRequestCommunityLinkStatusApproved
+	if community.RequestModeration == CommunityRequestModerationReview && role != "admin" {
+		linkStatus = PrayerRequestCommunityLinkStatusPendingReview
+	}
 
 	// alrite, add it
-	err = AddPrayerRequestToCommunity(requestID, communityID)
+	existing, existingErr := GetPrayerRequestCommunityLink(requestID, communityID)
+	err = AddPrayerRequestToCommunityWithStatus(requestID, communityID, linkStatus)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, "prayer_request_community_add_error", "could not add that request to that community", err)
 		return
 	}
-	Send(w, http.StatusOK, map[string]bool{
-		"added": true,
+	link, _ := GetPrayerRequestCommunityLink(requestID, communityID)
+	if link.Status == PrayerRequestCommunityLinkStatusApproved && (existingErr != nil || existing.Status != PrayerRequestCommunityLinkStatusApproved) {
+		QueueCommunityWebhookEvent(WebhookEventRequestCreated, communityID, request)
+		QueueActivityPubRequestCreated(requestID, communityID)
+	}
+	Send(w, http.StatusOK, map[string]interface{}{
+		"added":  true,
+		"status": link.Status,
 	})
 	return
 
 }
 
+// GetCommunityPrayerRequestsPendingReviewRoute gets the moderation queue for a community; it is only available to admins
+func GetCommunityPrayerRequestsPendingReviewRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	requests := GetPrayerRequestsPendingReviewForCommunity(communityID, count, offset)
+	SendPage(w, r, http.StatusOK, requests, offsetPage(count, offset, len(requests)))
+	return
+}
+
+// ReviewCommunityPrayerRequestRoute allows an admin to approve or reject a request that is pending review. The reason
+// for a rejection is emailed to the author
+func ReviewCommunityPrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if communityIDErr != nil || requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	link, err := GetPrayerRequestCommunityLink(requestID, communityID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_request_community_link_not_found", "that request is not in that community", nil)
+		return
+	}
+	if link.Status != PrayerRequestCommunityLinkStatusPendingReview {
+		SendError(w, http.StatusBadRequest, "prayer_request_community_link_not_pending", "that request is not pending review", link)
+		return
+	}
+
+	input := PrayerRequestCommunityLink{}
+	render.Bind(r, &input)
+	input.RejectionReason, _ = sanitize(input.RejectionReason)
+	if input.Status != PrayerRequestCommunityLinkStatusApproved && input.Status != PrayerRequestCommunityLinkStatusRejected {
+		SendError(w, http.StatusBadRequest, "prayer_request_community_review_invalid", "status must be either 'approved' or 'rejected'", input)
+		return
+	}
+	if input.Status == PrayerRequestCommunityLinkStatusRejected && input.RejectionReason == "" {
+		SendError(w, http.StatusBadRequest, "prayer_request_community_review_invalid", "a rejectionReason is required when rejecting a request", input)
+		return
+	}
+	if input.Status == PrayerRequestCommunityLinkStatusApproved {
+		input.RejectionReason = ""
+	}
+
+	err = ReviewPrayerRequestCommunityLink(requestID, communityID, jwtUser.ID, input.Status, input.RejectionReason)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_community_review_error", "could not review that request", err)
+		return
+	}
+
+	if input.Status == PrayerRequestCommunityLinkStatusApproved {
+		request, reqErr := GetPrayerRequest(requestID)
+		if reqErr == nil {
+			QueueCommunityWebhookEvent(WebhookEventRequestCreated, communityID, request)
+			QueueActivityPubRequestCreated(requestID, communityID)
+		}
+	}
+
+	if input.Status == PrayerRequestCommunityLinkStatusRejected {
+		request, reqErr := GetPrayerRequest(requestID)
+		community, commErr := GetCommunityByID(communityID)
+		author, userErr := GetUserByID(request.CreatedBy)
+		if reqErr == nil && commErr == nil && userErr == nil {
+			emailContent := fmt.Sprintf(`<p>Your prayer request "%s" was not added to the %s community.</p>
+	<p>The community's administrators gave the following reason:</p>
+	<p>%s</p>
+	<p>Your request has not been deleted and is still available to you and any other communities you shared it with.</p>
+	`, request.Title, community.Name, input.RejectionReason)
+			emailBody := GenerateEmail(communityID, emailContent)
+			SendEmail(author.Email, "Your Prayer Request Was Not Approved", emailBody)
+		}
+	}
+
+	link, _ = GetPrayerRequestCommunityLink(requestID, communityID)
+	Send(w, http.StatusOK, link)
+	return
+}
+
 // RemovePrayerRequestFromCommunityRoute removes an existing prayer request from a community
 func RemovePrayerRequestFromCommunityRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
@@ -346,12 +556,7 @@ func AddPrayerToRequestRoute(w http.ResponseWriter, r *http.Request) {
 	}
 
 	canSubmit, minutesUntilNext := CanUserMakeNewPrayer(jwtUser.ID, requestID)
-	allowed := false
-	if request.Privacy == "public" || request.CreatedBy == jwtUser.ID || IsUserAndRequestInSameGroup(jwtUser.ID, requestID) {
-		allowed = true
-	}
-
-	if !allowed {
+	if !CanUserSeePrayerRequest(jwtUser.ID, request) {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
@@ -369,6 +574,12 @@ func AddPrayerToRequestRoute(w http.ResponseWriter, r *http.Request) {
 		SendError(w, http.StatusBadRequest, "prayer_add_cannot_submit", "cannot add prayer", err)
 		return
 	}
+	QueuePrayerRequestWebhookEvent(WebhookEventPrayerMade, request.ID, map[string]interface{}{
+		"prayerRequestId": request.ID,
+		"userId":          jwtUser.ID,
+		"username":        jwtUser.Username,
+		"totalPrayers":    request.PrayerCount + 1,
+	})
 
 	Send(w, http.StatusOK, map[string]interface{}{
 		"prayerAdded":            true,
@@ -399,12 +610,7 @@ func GetPrayersMadeOnRequestRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allowed := false
-	if request.Privacy == "public" || request.CreatedBy == jwtUser.ID || IsUserAndRequestInSameGroup(jwtUser.ID, requestID) {
-		allowed = true
-	}
-
-	if !allowed {
+	if !CanUserSeePrayerRequest(jwtUser.ID, request) {
 		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
 		return
 	}
@@ -455,3 +661,83 @@ func RemovePrayerMadeOnRequestRoute(w http.ResponseWriter, r *http.Request) {
 	})
 	return
 }
+
+// canUserSeePrayerRequestAuthor checks if the user can know who posted a request. Anonymous requests only show their author to
+// the author, platform admins, and admins of a community the request is shared with
+func canUserSeePrayerRequestAuthor(jwtUser JWTUser, request *PrayerRequest) bool {
+	if !request.Anonymous || request.CreatedBy == jwtUser.ID || jwtUser.PlatformRole == "admin" {
+		return true
+	}
+	return IsUserPrayerRequestModerator(jwtUser.ID, request.ID)
+}
+
+// hideAnonymousAuthors hides the authors of anonymous requests in a feed, other than the user's own. Moderators of the feed,
+// such as community admins, see every author
+func hideAnonymousAuthors(jwtUser JWTUser, isModerator bool, requests []PrayerRequest) {
+	if isModerator || jwtUser.PlatformRole == "admin" {
+		return
+	}
+	for i := range requests {
+		if jwtUser.ID == 0 || requests[i].CreatedBy != jwtUser.ID {
+			requests[i].HideAnonymousAuthor()
+		}
+	}
+}
+
+// getPrayerRequestFeedFilterFromRequest reads the feed filters from the query:
+// status - only requests with the status
+// tag - only requests with the tag
+// authorId - only requests by the user; anonymous requests only match for the author and moderators
+// start, end - only requests created in the range, as ISO times
+// sortField - created, prayers, activity, or needs_prayer, with sortDir for all but needs_prayer
+// cursor - a cursor from a previous page, which must have been made with the same sort
+func getPrayerRequestFeedFilterFromRequest(r *http.Request, jwtUser JWTUser, isModerator bool) (PrayerRequestFeedFilter, error) {
+	query := r.URL.Query()
+	filter := PrayerRequestFeedFilter{
+		Status:    strings.ToLower(query.Get("status")),
+		SortField: strings.ToLower(query.Get("sortField")),
+		SortDir:   query.Get("sortDir"),
+	}
+	if !IsValidPrayerRequestFeedSort(filter.SortField) {
+		return filter, errors.New("sortField must be created, prayers, activity, or needs_prayer")
+	}
+	if query.Get("tag") != "" {
+		tag, ok := NormalizeTag(query.Get("tag"))
+		if !ok {
+			return filter, errors.New("tag is invalid")
+		}
+		filter.Tag = tag
+	}
+	if query.Get("authorId") != "" {
+		authorID, err := strconv.ParseInt(query.Get("authorId"), 10, 64)
+		if err != nil || authorID < 1 {
+			return filter, errors.New("authorId must be a user id")
+		}
+		filter.AuthorID = authorID
+		filter.IncludeAnonymous = isModerator || authorID == jwtUser.ID || jwtUser.PlatformRole == "admin"
+	}
+	var err error
+	if query.Get("start") != "" {
+		filter.Start, err = ParseISOTimeToDBTime(query.Get("start"))
+		if err != nil {
+			return filter, errors.New("start must be an ISO time")
+		}
+	}
+	if query.Get("end") != "" {
+		filter.End, err = ParseISOTimeToDBTime(query.Get("end"))
+		if err != nil {
+			return filter, errors.New("end must be an ISO time")
+		}
+	}
+	filter.Cursor, err = GetPageCursor(r)
+	if err != nil {
+		return filter, err
+	}
+	if filter.Cursor.IsKeyset() && !filter.isKeyset() {
+		return filter, errors.New("cursor is for the created sort")
+	}
+	if filter.Cursor.IsKeyset() && filter.Cursor.Created == "" {
+		return filter, errors.New("cursor is for a different list")
+	}
+	return filter, nil
+}
@@ -444,3 +444,199 @@ func TestPrayerRoutes(t *testing.T) {
 	mapstructure.Decode(body, &foundRequest)
 	assert.Equal(t, 1, foundRequest.PrayerCount)
 }
+
+func TestPrayerRequestModerationRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:              fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode:         fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		RequestModeration: CommunityRequestModerationReview,
+		OwnerID:           admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, "admin", "accepted", "")
+	CreateCommunityUserLink(community.ID, member.ID, "member", "accepted", "")
+
+	request := PrayerRequest{
+		Title:     "Moderated",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	// the member adds it and it goes into the queue
+	code, res, _ := TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/requests/%d", community.ID, request.ID), b, AddPrayerRequestToCommunityRoute, member.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, PrayerRequestCommunityLinkStatusPendingReview, body["status"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/requests", community.ID), b, GetCommunityPrayerRequestsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Zero(t, len(bodyA))
+
+	// only admins see the queue
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/requests/pending", community.ID), b, GetCommunityPrayerRequestsPendingReviewRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/requests/pending", community.ID), b, GetCommunityPrayerRequestsPendingReviewRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+
+	// bad reviews
+	b.Reset()
+	enc.Encode(map[string]string{"status": "approved"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/requests/%d/review", community.ID, request.ID), b, ReviewCommunityPrayerRequestRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	b.Reset()
+	enc.Encode(map[string]string{"status": "rejected"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/requests/%d/review", community.ID, request.ID), b, ReviewCommunityPrayerRequestRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]string{"status": "maybe"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/requests/%d/review", community.ID, request.ID), b, ReviewCommunityPrayerRequestRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// approve it
+	b.Reset()
+	enc.Encode(map[string]string{"status": "approved"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/requests/%d/review", community.ID, request.ID), b, ReviewCommunityPrayerRequestRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/requests", community.ID), b, GetCommunityPrayerRequestsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+
+	// it can't be reviewed twice
+	b.Reset()
+	enc.Encode(map[string]string{"status": "rejected", "rejectionReason": "Changed our minds"})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/communities/%d/requests/%d/review", community.ID, request.ID), b, ReviewCommunityPrayerRequestRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestAnonymousPrayerRequestRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&author)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&other)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":     "Recovery",
+		"body":      "Please pray for my recovery",
+		"privacy":   "public",
+		"anonymous": true,
+	})
+	code, res, _ := TestAPICall(http.MethodPost, "/requests", b, CreatePrayerRequestRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	request := PrayerRequest{}
+	mapstructure.Decode(body, &request)
+	defer DeletePrayerRequest(request.ID)
+	assert.True(t, request.Anonymous)
+	assert.Equal(t, author.ID, request.CreatedBy)
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, float64(author.ID), body["createdBy"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests/%d", request.ID), b, GetPrayerRequestByIDRoute, other.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, float64(0), body["createdBy"])
+	assert.Equal(t, "", body["username"])
+
+	code, res, _ = TestAPICall(http.MethodGet, "/requests", b, GetGlobalPrayerRequestsRoute, other.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	for i := range bodyA {
+		found := bodyA[i].(map[string]interface{})
+		if found["id"] == float64(request.ID) {
+			assert.Equal(t, "", found["username"])
+			assert.Equal(t, float64(0), found["createdBy"])
+		}
+	}
+
+	// listing another user's requests would give away who posted it
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/users/%d/requests", author.ID), b, GetUserPrayerRequestsRoute, other.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Zero(t, len(bodyA))
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/users/%d/requests", author.ID), b, GetUserPrayerRequestsRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+}
+
+func TestPrayerRequestFeedFilterRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	user := User{}
+	err := CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+
+	code, _, _ := TestAPICall(http.MethodGet, "/requests?sortField=title", b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/requests?authorId=someone", b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/requests?tag=%23%23%23", b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/requests?start=yesterday", b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/requests?cursor=nope", b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	keyset := &PageCursor{Created: "2026-01-01 00:00:00", ID: 1}
+	code, _, _ = TestAPICall(http.MethodGet, "/requests?sortField=prayers&cursor="+keyset.Encode(), b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	request := PrayerRequest{
+		Title:     "Filtered",
+		Body:      "Please pray",
+		CreatedBy: user.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	code, res, _ := TestAPICall(http.MethodGet, fmt.Sprintf("/requests?authorId=%d&sortField=needs_prayer", user.ID), b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(list))
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/requests?authorId=%d&includeTotal=true", user.ID), b, GetGlobalPrayerRequestsRoute, user.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	ret, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(list))
+	require.NotNil(t, ret.Meta)
+	require.NotNil(t, ret.Meta.Total)
+	assert.Equal(t, int64(1), *ret.Meta.Total)
+	assert.Equal(t, "", ret.Meta.Next)
+}
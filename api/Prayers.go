@@ -15,23 +15,52 @@ const (
 	// PrayerRequestStatusUnknown represents a prayer that is unknown
 	PrayerRequestStatusUnknown = "unknown"
 
+	// PrayerRequestCommunityLinkStatusApproved represents a request that is visible to the community
+	PrayerRequestCommunityLinkStatusApproved = "approved"
+	// PrayerRequestCommunityLinkStatusPendingReview represents a request waiting on an admin to review it
+	PrayerRequestCommunityLinkStatusPendingReview = "pending_review"
+	// PrayerRequestCommunityLinkStatusRejected represents a request an admin decided not to show in the community
+	PrayerRequestCommunityLinkStatusRejected = "rejected"
+
+	// PrayerRequestCommentStatusEnabled lets anyone who can see the request comment on it
+	PrayerRequestCommentStatusEnabled = "enabled"
+	// PrayerRequestCommentStatusDisabled stops new comments; existing comments are still shown
+	PrayerRequestCommentStatusDisabled = "disabled"
+
 	// PrayerTimeoutInMinutes is the number of minutes between times a user is allowed to submit a prayer made towards a prayer request
 	PrayerTimeoutInMinutes = 60 * 6
 )
 
 // PrayerRequest represents a single prayer request
 type PrayerRequest struct {
-	ID          int64    `json:"id" db:"id"`
-	Title       string   `json:"title" db:"title"`
-	Body        string   `json:"body" db:"body"`
-	CreatedBy   int64    `json:"createdBy" db:"createdBy"`
-	Privacy     string   `json:"privacy" db:"privacy"`
-	Created     string   `json:"created" db:"created"`
-	Status      string   `json:"status" db:"status"`
+	ID        int64  `json:"id" db:"id"`
+	Title     string `json:"title" db:"title"`
+	Body      string `json:"body" db:"body"`
+	CreatedBy int64  `json:"createdBy" db:"createdBy"`
+	Privacy   string `json:"privacy" db:"privacy"`
+	Created   string `json:"created" db:"created"`
+	Status    string `json:"status" db:"status"`
+	// Answered is when the request was marked as answered
+	Answered    string   `json:"answered,omitempty" db:"answered"`
 	Tags        []string `json:"tags" db:"-"`
 	PrayerCount int      `json:"prayerCount" db:"prayerCount"`
 	Username    string   `json:"username" db:"username"`
 	Added       string   `json:"added,omitempty" db:"added,omitempty"`
+	// CommunityLinkStatus is only populated in community queries shown to admins
+	CommunityLinkStatus string `json:"communityLinkStatus,omitempty" db:"communityLinkStatus"`
+	// CommentStatus is whether the author allows new comments
+	CommentStatus string `json:"commentStatus" db:"commentStatus"`
+	// Anonymous requests hide their author from everyone except the author and moderators
+	Anonymous bool `json:"anonymous" db:"anonymous"`
+	// Archived is when a pending request was archived after its author didn't respond to a reminder; archived requests are
+	// left out of feeds but stay in the author's history
+	Archived string `json:"archived,omitempty" db:"archived"`
+	// Reminded is when the author was last asked for an update
+	Reminded string `json:"-" db:"reminded"`
+	// LastActive is when the author last edited the request or responded to a reminder
+	LastActive string `json:"-" db:"lastActive"`
+	// Updates are the author's follow-ups, oldest first; they are only populated when getting a single request
+	Updates []PrayerRequestUpdate `json:"updates,omitempty" db:"-"`
 }
 
 // Prayer represents a prayer made towards a request
@@ -43,36 +72,52 @@ type Prayer struct {
 
 // PrayerRequestCommunityLink joins a prayer request to a community
 type PrayerRequestCommunityLink struct {
-	PrayerRequestID int64 `json:"prayerRequestId" db:"prayerRequestId"`
-	CommunityID     int64 `json:"communityId" db:"communityId"`
+	PrayerRequestID int64  `json:"prayerRequestId" db:"prayerRequestId"`
+	CommunityID     int64  `json:"communityId" db:"communityId"`
+	Status          string `json:"status" db:"status"`
+	Added           string `json:"added" db:"added"`
+	ReviewedBy      int64  `json:"reviewedBy" db:"reviewedBy"`
+	Reviewed        string `json:"reviewed" db:"reviewed"`
+	RejectionReason string `json:"rejectionReason" db:"rejectionReason"`
 }
 
 // CreatePrayerRequest creates a new prayer request
 func CreatePrayerRequest(input *PrayerRequest) error {
 	input.processForDB()
 	defer input.processForAPI()
-	query := `INSERT INTO PrayerRequests (title, body, createdBy, privacy, status, created) 
-		VALUES (:title, :body, :createdBy, :privacy, :status, NOW())`
+	query := `INSERT INTO PrayerRequests (title, body, createdBy, privacy, status, commentStatus, anonymous, created) 
+		VALUES (:title, :body, :createdBy, :privacy, :status, :commentStatus, :anonymous, NOW())`
 	res, err := Config.DbConn.NamedExec(query, &input)
 	if err != nil {
 		return err
 	}
 	input.ID, _ = res.LastInsertId()
+	IndexPrayerRequest(input.ID)
 	return nil
 }
 
-// UpdatePrayerRequest updates only the privacy or status of the prayer request to avoid things like request editing to make things look awkward
+// UpdatePrayerRequest updates only the privacy, status, or comment status of the prayer request to avoid things like request editing to
+// make things look awkward. The answered time is recorded when the status first changes to answered. Since only the author updates
+// a request, any update counts as activity: it clears a pending reminder, brings an archived request back, and restarts the expiry
 func UpdatePrayerRequest(input *PrayerRequest) error {
-	_, err := Config.DbConn.NamedExec("UPDATE PrayerRequests SET privacy = :privacy, status = :status WHERE id = :id LIMIT 1", input)
-	return err
+	_, err := Config.DbConn.NamedExec(`UPDATE PrayerRequests SET 
+		answered = IF(:status = 'answered', IF(status = 'answered', answered, NOW()), '1970-01-01 00:00:00'), 
+		privacy = :privacy, status = :status, commentStatus = IF(:commentStatus = '', commentStatus, :commentStatus), 
+		reminded = '1970-01-01 00:00:00', archived = '1970-01-01 00:00:00', lastActive = NOW() WHERE id = :id LIMIT 1`, input)
+	if err != nil {
+		return err
+	}
+	IndexPrayerRequest(input.ID)
+	return nil
 }
 
-// DeletePrayerRequest deletes a prayer request, any prayers made for it, and any tag links; a cron will cleanup orphaned tags
+// DeletePrayerRequest deletes a prayer request, any prayers made for it, and any tag links; PurgeOrphanedTags will cleanup orphaned tags
 func DeletePrayerRequest(id int64) error {
 	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequests WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
+	RemoveFromSearch(SearchCollectionRequests, id)
 	_, err = Config.DbConn.Exec("DELETE FROM Prayers WHERE prayerRequestId = ?", id)
 	if err != nil {
 		return err
@@ -81,6 +126,10 @@ func DeletePrayerRequest(id int64) error {
 	if err != nil {
 		return err
 	}
+	_, err = Config.DbConn.Exec("DELETE FROM PrayerRequestSubGroupLinks WHERE prayerRequestId = ?", id)
+	if err != nil {
+		return err
+	}
 	_, err = Config.DbConn.Exec("DELETE FROM PrayerRequestTagLinks WHERE prayerRequestId = ?", id)
 	if err != nil {
 		return err
@@ -89,6 +138,26 @@ func DeletePrayerRequest(id int64) error {
 	if err != nil {
 		return err
 	}
+	err = DeleteActivityPubPrayersForRequest(id)
+	if err != nil {
+		return err
+	}
+	err = DeletePrayerRequestUpdatesForRequest(id)
+	if err != nil {
+		return err
+	}
+	err = DeletePrayerRequestCommentsForRequest(id)
+	if err != nil {
+		return err
+	}
+	err = DeletePrayerRequestSharesForRequest(id)
+	if err != nil {
+		return err
+	}
+	err = DeletePrayerVigilRequestLinksForRequest(id)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -99,36 +168,102 @@ func GetPrayerRequest(id int64) (*PrayerRequest, error) {
 	err := Config.DbConn.Get(&request, `SELECT pr.*, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
 		FROM PrayerRequests pr WHERE pr.id = ? LIMIT 1`, id)
 	request.processForAPI()
+	if err == nil {
+		tags, _ := GetTagsOnRequest(id)
+		for i := range tags {
+			request.Tags = append(request.Tags, tags[i].Tag)
+		}
+	}
 	return &request, err
 }
 
-// GetGlobalPrayerRequests gets the public prayer request feed
-func GetGlobalPrayerRequests(count, offset int) []PrayerRequest {
+// GetGlobalPrayerRequests gets the public prayer request feed, narrowed and ordered by the filter
+func GetGlobalPrayerRequests(filter PrayerRequestFeedFilter, count, offset int) []PrayerRequest {
 	requests := []PrayerRequest{}
+	clause, args := filter.clause()
+	cursorClause, cursorArgs := filter.cursorClause()
+	args = append(args, cursorArgs...)
+	args = append(args, offset, count)
 	Config.DbConn.Select(&requests, `SELECT pr.*, u.username, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
-		FROM PrayerRequests pr, Users u WHERE pr.privacy = 'public' AND pr.createdBy = u.id ORDER BY pr.created DESC LIMIT ?,?`, offset, count)
+		`+globalPrayerRequestsFrom+clause+cursorClause+`
+		ORDER BY `+filter.orderBy()+` LIMIT ?,?`, args...)
 	for i := range requests {
 		requests[i].processForAPI()
 	}
+	filter.arrange(requests)
+	populatePrayerRequestTags(requests)
 	return requests
 }
 
-// GetPrayerRequestsForCommunity gets the requests in a community
-func GetPrayerRequestsForCommunity(communityID int64, status string, count, offset int) []PrayerRequest {
+// GetCountOfGlobalPrayerRequests gets how many requests are in the public feed with the filter, ignoring any cursor
+func GetCountOfGlobalPrayerRequests(filter PrayerRequestFeedFilter) (int64, error) {
+	total := int64(0)
+	clause, args := filter.clause()
+	err := Config.DbConn.Get(&total, `SELECT COUNT(*) `+globalPrayerRequestsFrom+clause, args...)
+	return total, err
+}
+
+// globalPrayerRequestsFrom is where the public feed comes from, ready for the filter's conditions
+const globalPrayerRequestsFrom = `FROM PrayerRequests pr, Users u WHERE pr.privacy = 'public' AND pr.archived = '1970-01-01 00:00:00' AND pr.createdBy = u.id `
+
+// GetPrayerRequestsForCommunity gets the requests in a community, narrowed and ordered by the filter. Regular members only see approved
+// requests; passing includeUnapproved also returns requests that are pending review, which should only be done for admins
+func GetPrayerRequestsForCommunity(communityID int64, filter PrayerRequestFeedFilter, includeUnapproved bool, count, offset int) []PrayerRequest {
 	requests := []PrayerRequest{}
+	from, args := communityPrayerRequestsFrom(communityID, includeUnapproved)
+	clause, filterArgs := filter.clause()
+	cursorClause, cursorArgs := filter.cursorClause()
+	args = append(args, filterArgs...)
+	args = append(args, cursorArgs...)
+	args = append(args, offset, count)
+	Config.DbConn.Select(&requests, `SELECT pr.*, u.username, prcl.status AS communityLinkStatus, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
+		`+from+clause+cursorClause+`
+		ORDER BY `+filter.orderBy()+` LIMIT ?,?`, args...)
+	for i := range requests {
+		requests[i].processForAPI()
+		if !includeUnapproved {
+			requests[i].CommunityLinkStatus = ""
+		}
+	}
+	filter.arrange(requests)
+	populatePrayerRequestTags(requests)
+	return requests
+}
 
-	if status == "pending" || status == "answered" || status == "not_answered" || status == "unknown" {
-		Config.DbConn.Select(&requests, `SELECT pr.*, u.username, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
-			FROM PrayerRequests pr, Users u, PrayerRequestCommunityLinks prcl 
-			WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND pr.createdBy = u.id AND pr.status = ? ORDER BY pr.created DESC LIMIT ?,?`, communityID, status, offset, count)
-	} else {
-		Config.DbConn.Select(&requests, `SELECT pr.*, u.username, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
-			FROM PrayerRequests pr, Users u, PrayerRequestCommunityLinks prcl 
-			WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND pr.createdBy = u.id ORDER BY pr.created DESC LIMIT ?,?`, communityID, offset, count)
+// GetCountOfPrayerRequestsForCommunity gets how many requests are in the community's feed with the filter, ignoring any cursor
+func GetCountOfPrayerRequestsForCommunity(communityID int64, filter PrayerRequestFeedFilter, includeUnapproved bool) (int64, error) {
+	total := int64(0)
+	from, args := communityPrayerRequestsFrom(communityID, includeUnapproved)
+	clause, filterArgs := filter.clause()
+	args = append(args, filterArgs...)
+	err := Config.DbConn.Get(&total, `SELECT COUNT(*) `+from+clause, args...)
+	return total, err
+}
+
+// communityPrayerRequestsFrom gets where a community's feed comes from, ready for the filter's conditions, along with its args
+func communityPrayerRequestsFrom(communityID int64, includeUnapproved bool) (string, []interface{}) {
+	// approved requests are always shown; admins also see the ones waiting on review
+	alsoShown := PrayerRequestCommunityLinkStatusApproved
+	if includeUnapproved {
+		alsoShown = PrayerRequestCommunityLinkStatusPendingReview
 	}
+	return `FROM PrayerRequests pr, Users u, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status IN ('approved', ?) AND pr.archived = '1970-01-01 00:00:00' AND pr.createdBy = u.id ` +
+		prayerRequestVisibleInCommunity("prcl.communityId"), []interface{}{communityID, alsoShown}
+}
+
+// GetPrayerRequestsPendingReviewForCommunity gets the moderation queue for a community, oldest first
+func GetPrayerRequestsPendingReviewForCommunity(communityID int64, count, offset int) []PrayerRequest {
+	requests := []PrayerRequest{}
+	Config.DbConn.Select(&requests, `SELECT pr.*, u.username, prcl.status AS communityLinkStatus, prcl.added, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
+		FROM PrayerRequests pr, Users u, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = ? AND pr.archived = '1970-01-01 00:00:00' AND pr.createdBy = u.id `+prayerRequestVisibleInCommunity("prcl.communityId")+`
+		ORDER BY prcl.added LIMIT ?,?`,
+		communityID, PrayerRequestCommunityLinkStatusPendingReview, offset, count)
 	for i := range requests {
 		requests[i].processForAPI()
 	}
+	populatePrayerRequestTags(requests)
 	return requests
 }
 
@@ -160,9 +295,40 @@ func GetUserPrayerRequests(userID int64, status string, startDate, endDate strin
 	for i := range requests {
 		requests[i].processForAPI()
 	}
+	populatePrayerRequestTags(requests)
 	return requests, err
 }
 
+// HideAnonymousAuthor clears the author of an anonymous request, including on its updates, so it can be shown to someone who
+// isn't allowed to know who posted it
+func (input *PrayerRequest) HideAnonymousAuthor() {
+	if !input.Anonymous {
+		return
+	}
+	input.CreatedBy = 0
+	input.Username = ""
+	if input.Updates == nil {
+		return
+	}
+	// the updates are copied since copies of the request share them
+	updates := make([]PrayerRequestUpdate, len(input.Updates))
+	copy(updates, input.Updates)
+	for i := range updates {
+		updates[i].CreatedBy = 0
+	}
+	input.Updates = updates
+}
+
+// IsUserPrayerRequestModerator checks if the user is an admin of a community the request is shared with, including while it
+// is pending review
+func IsUserPrayerRequestModerator(userID, requestID int64) bool {
+	count := 0
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) FROM PrayerRequestCommunityLinks prcl, CommunityUserLinks cul
+		WHERE prcl.prayerRequestId = ? AND prcl.communityId = cul.communityId AND cul.userId = ? AND cul.role = ? AND cul.status = ?`,
+		requestID, userID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted)
+	return err == nil && count > 0
+}
+
 // AddPrayerMade adds a prayer made towards a request
 func AddPrayerMade(userID, prayerRequestID int64) error {
 	when := time.Now().Format("2006-01-02 15:04:05")
@@ -213,7 +379,30 @@ func CanUserMakeNewPrayer(userID, prayerRequestID int64) (canSubmitPrayer bool,
 
 // AddPrayerRequestToCommunity adds a request to a community; 0 means it is on the public feed
 func AddPrayerRequestToCommunity(requestID, communityID int64) error {
-	_, err := Config.DbConn.Exec(`INSERT INTO PrayerRequestCommunityLinks (prayerRequestId, communityId) VALUES (?,?) ON DUPLICATE KEY UPDATE communityId = ?`, requestID, communityID, communityID)
+	return AddPrayerRequestToCommunityWithStatus(requestID, communityID, PrayerRequestCommunityLinkStatusApproved)
+}
+
+// AddPrayerRequestToCommunityWithStatus adds a request to a community with a specific link status, such as when the community
+// requires review. Re-adding a rejected request resubmits it; otherwise an existing link is left alone
+func AddPrayerRequestToCommunityWithStatus(requestID, communityID int64, status string) error {
+	_, err := Config.DbConn.Exec(`INSERT INTO PrayerRequestCommunityLinks (prayerRequestId, communityId, status, added) VALUES (?,?,?,NOW()) 
+		ON DUPLICATE KEY UPDATE status = IF(status = 'rejected', VALUES(status), status), rejectionReason = IF(status = 'rejected', rejectionReason, '')`,
+		requestID, communityID, status)
+	return err
+}
+
+// GetPrayerRequestCommunityLink gets a single link between a request and a community
+func GetPrayerRequestCommunityLink(requestID, communityID int64) (PrayerRequestCommunityLink, error) {
+	link := PrayerRequestCommunityLink{}
+	err := Config.DbConn.Get(&link, `SELECT * FROM PrayerRequestCommunityLinks WHERE prayerRequestId = ? AND communityId = ?`, requestID, communityID)
+	link.processForAPI()
+	return link, err
+}
+
+// ReviewPrayerRequestCommunityLink approves or rejects a request that is pending review in a community
+func ReviewPrayerRequestCommunityLink(requestID, communityID, reviewerID int64, status, reason string) error {
+	_, err := Config.DbConn.Exec(`UPDATE PrayerRequestCommunityLinks SET status = ?, rejectionReason = ?, reviewedBy = ?, reviewed = NOW() 
+		WHERE prayerRequestId = ? AND communityId = ?`, status, reason, reviewerID, requestID, communityID)
 	return err
 }
 
@@ -223,14 +412,24 @@ func RemovePrayerRequestFromCommunity(requestID, communityID int64) error {
 	return err
 }
 
-// GetCommunitiesPrayerRequestIsIn gets a list of communities a prayer request has been added to
+// RemoveUsersPrayerRequestsFromCommunity removes all of the requests created by a user from a community, such as when they leave it
+func RemoveUsersPrayerRequestsFromCommunity(userID, communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE prcl FROM PrayerRequestCommunityLinks prcl 
+		INNER JOIN PrayerRequests pr ON pr.id = prcl.prayerRequestId 
+		WHERE prcl.communityId = ? AND pr.createdBy = ?`, communityID, userID)
+	return err
+}
+
+// GetCommunitiesPrayerRequestIsIn gets a list of communities a prayer request has been added to and can be seen in
 func GetCommunitiesPrayerRequestIsIn(requestID int64) ([]Community, error) {
 	comms := []Community{}
-	err := Config.DbConn.Select(&comms, `SELECT c.* FROM Communities c, PrayerRequestCommunityLinks prcl WHERE prcl.prayerRequestId = ? AND prcl.communityId = c.id ORDER BY c.name`, requestID)
+	err := Config.DbConn.Select(&comms, `SELECT c.* FROM Communities c, PrayerRequestCommunityLinks prcl, PrayerRequests pr
+		WHERE prcl.prayerRequestId = ? AND prcl.communityId = c.id AND prcl.status = 'approved' AND pr.id = prcl.prayerRequestId `+
+		prayerRequestVisibleInCommunity("prcl.communityId")+` ORDER BY c.name`, requestID)
 	return comms, err
 }
 
-// GetCountOfRequestsInCommunity gets the number of requests in a community within a time frame
+// GetCountOfRequestsInCommunity gets the number of approved requests in a community within a time frame
 func GetCountOfRequestsInCommunity(communityID int64, start, end string) (int64, error) {
 	count := struct {
 		Count int64 `db:"count"`
@@ -241,7 +440,7 @@ func GetCountOfRequestsInCommunity(communityID int64, start, end string) (int64,
 	if end == "" {
 		end = "2100-01-01 00:00:00"
 	}
-	err := Config.DbConn.Get(&count, `SELECT COUNT(*) AS count FROM PrayerRequestCommunityLinks prcl, PrayerRequests pr WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND pr.created BETWEEN ? AND ?`,
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) AS count FROM PrayerRequestCommunityLinks prcl, PrayerRequests pr WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = 'approved' AND pr.created BETWEEN ? AND ?`,
 		communityID, start, end)
 	return count.Count, err
 }
@@ -260,7 +459,11 @@ func GetCountOfPrayersMadeForRequest(requestID int64, start, end string) (count
 }
 
 // IsUserAndRequestInSameGroup is a healper to see if a user can view a private prayer request due to being in the same community as the request
+// or in a sub-group the request was shared with. Only accepted members and admins count; pending links don't give access
 func IsUserAndRequestInSameGroup(userID, requestID int64) bool {
+	if IsUserAndRequestInSameSubGroup(userID, requestID) {
+		return true
+	}
 	// first, get the groups for the request and the groups for the user
 	prComms, err := GetCommunitiesPrayerRequestIsIn(requestID)
 	if err != nil {
@@ -274,7 +477,8 @@ func IsUserAndRequestInSameGroup(userID, requestID int64) bool {
 	// since the lists aren't all that large, this shouldn't be too painful
 	for i := range prComms {
 		for j := range userComms {
-			if prComms[i].ID == userComms[j].ID && userComms[j].UserRole == "member" {
+			if prComms[i].ID == userComms[j].ID && userComms[j].UserStatus == CommunityUserLinkStatusAccepted &&
+				(userComms[j].UserRole == CommunityUserRoleMember || userComms[j].UserRole == CommunityUserRoleAdmin) {
 				return true
 			}
 		}
@@ -282,6 +486,21 @@ func IsUserAndRequestInSameGroup(userID, requestID int64) bool {
 	return false
 }
 
+// processForAPI ensures data consistency
+func (u *PrayerRequestCommunityLink) processForAPI() {
+	if u.Added == "1970-01-01 00:00:00" {
+		u.Added = ""
+	} else {
+		u.Added, _ = ParseTimeToISO(u.Added)
+	}
+
+	if u.Reviewed == "1970-01-01 00:00:00" {
+		u.Reviewed = ""
+	} else {
+		u.Reviewed, _ = ParseTimeToISO(u.Reviewed)
+	}
+}
+
 // processForDB ensures data consistency
 func (u *PrayerRequest) processForDB() {
 
@@ -309,7 +528,11 @@ func (u *PrayerRequest) processForDB() {
 	}
 
 	if u.Privacy == "" {
-		u.Privacy = "private"
+		u.Privacy = PrayerRequestPrivacyPrivate
+	}
+
+	if u.CommentStatus == "" {
+		u.CommentStatus = PrayerRequestCommentStatusEnabled
 	}
 }
 
@@ -331,6 +554,18 @@ func (u *PrayerRequest) processForAPI() {
 		u.Added, _ = ParseTimeToISO(u.Added)
 	}
 
+	if u.Answered == "1970-01-01 00:00:00" {
+		u.Answered = ""
+	} else {
+		u.Answered, _ = ParseTimeToISO(u.Answered)
+	}
+
+	if u.Archived == "1970-01-01 00:00:00" {
+		u.Archived = ""
+	} else {
+		u.Archived, _ = ParseTimeToISO(u.Archived)
+	}
+
 	if u.Status == "" {
 		u.Status = "pending"
 	}
@@ -142,3 +142,165 @@ func TestPrayerCRUD(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+func TestPrayerRequestModeration(t *testing.T) {
+	ConfigSetup()
+	randID := rand.Int63n(99999999)
+	user := User{}
+	err := CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUser(user.ID)
+
+	community := Community{
+		Name:              fmt.Sprintf("Test_%d", randID),
+		ShortCode:         fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		RequestModeration: CommunityRequestModerationReview,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	request := PrayerRequest{
+		Title:     fmt.Sprintf("Test Prayer %d", randID),
+		Body:      "Test Prayer Request Body",
+		CreatedBy: user.ID,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	err = AddPrayerRequestToCommunityWithStatus(request.ID, community.ID, PrayerRequestCommunityLinkStatusPendingReview)
+	assert.Nil(t, err)
+	link, err := GetPrayerRequestCommunityLink(request.ID, community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, PrayerRequestCommunityLinkStatusPendingReview, link.Status)
+	assert.NotEqual(t, "", link.Added)
+
+	// members don't see it, admins and the queue do
+	assert.Zero(t, len(GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+	adminFeed := GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, true, 100, 0)
+	require.Equal(t, 1, len(adminFeed))
+	assert.Equal(t, PrayerRequestCommunityLinkStatusPendingReview, adminFeed[0].CommunityLinkStatus)
+	queue := GetPrayerRequestsPendingReviewForCommunity(community.ID, 100, 0)
+	require.Equal(t, 1, len(queue))
+	assert.Equal(t, request.ID, queue[0].ID)
+	comms, _ := GetCommunitiesPrayerRequestIsIn(request.ID)
+	assert.Zero(t, len(comms))
+	pendingCount, err := GetCountOfRequestsInCommunity(community.ID, "", "")
+	assert.Nil(t, err)
+	assert.Zero(t, pendingCount)
+
+	// reject it, then resubmit it
+	err = ReviewPrayerRequestCommunityLink(request.ID, community.ID, user.ID, PrayerRequestCommunityLinkStatusRejected, "Off topic")
+	assert.Nil(t, err)
+	link, _ = GetPrayerRequestCommunityLink(request.ID, community.ID)
+	assert.Equal(t, PrayerRequestCommunityLinkStatusRejected, link.Status)
+	assert.Equal(t, "Off topic", link.RejectionReason)
+	assert.Equal(t, user.ID, link.ReviewedBy)
+	assert.Zero(t, len(GetPrayerRequestsPendingReviewForCommunity(community.ID, 100, 0)))
+
+	err = AddPrayerRequestToCommunityWithStatus(request.ID, community.ID, PrayerRequestCommunityLinkStatusPendingReview)
+	assert.Nil(t, err)
+	link, _ = GetPrayerRequestCommunityLink(request.ID, community.ID)
+	assert.Equal(t, PrayerRequestCommunityLinkStatusPendingReview, link.Status)
+	assert.Equal(t, "", link.RejectionReason)
+
+	// approve it
+	err = ReviewPrayerRequestCommunityLink(request.ID, community.ID, user.ID, PrayerRequestCommunityLinkStatusApproved, "")
+	assert.Nil(t, err)
+	memberFeed := GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)
+	require.Equal(t, 1, len(memberFeed))
+	assert.Equal(t, "", memberFeed[0].CommunityLinkStatus)
+	comms, _ = GetCommunitiesPrayerRequestIsIn(request.ID)
+	assert.Equal(t, 1, len(comms))
+}
+
+func TestPrayerRequestHideAnonymousAuthor(t *testing.T) {
+	request := PrayerRequest{
+		ID:        1,
+		CreatedBy: 42,
+		Username:  "someone",
+		Updates: []PrayerRequestUpdate{
+			{ID: 1, CreatedBy: 42},
+		},
+	}
+	request.HideAnonymousAuthor()
+	assert.Equal(t, int64(42), request.CreatedBy)
+	assert.Equal(t, "someone", request.Username)
+
+	// copies of the request share its updates, so hiding the author on one must not change the other
+	request.Anonymous = true
+	hidden := request
+	hidden.HideAnonymousAuthor()
+	assert.Equal(t, int64(0), hidden.CreatedBy)
+	assert.Equal(t, "", hidden.Username)
+	assert.Equal(t, int64(0), hidden.Updates[0].CreatedBy)
+	assert.Equal(t, int64(42), request.Updates[0].CreatedBy)
+
+	data := hideAnonymousAuthorInWebhookData(&request)
+	assert.Equal(t, int64(0), data.(*PrayerRequest).CreatedBy)
+	assert.Equal(t, int64(42), request.CreatedBy)
+	data = hideAnonymousAuthorInWebhookData(request)
+	assert.Equal(t, "", data.(PrayerRequest).Username)
+	data = hideAnonymousAuthorInWebhookData(map[string]interface{}{"userId": 42})
+	assert.Equal(t, 42, data.(map[string]interface{})["userId"])
+}
+
+func TestAnonymousPrayerRequestModerators(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	admin := User{}
+	err = CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUser(admin.ID)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUser(member.ID)
+
+	community := Community{
+		Name:      "Anonymous",
+		ShortCode: fmt.Sprintf("anon_%d", rand.Int63n(99999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	request := PrayerRequest{
+		Title:     "Private struggle",
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   "public",
+		Anonymous: true,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	found, err := GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.True(t, found.Anonymous)
+
+	assert.False(t, IsUserPrayerRequestModerator(admin.ID, request.ID))
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+	assert.True(t, IsUserPrayerRequestModerator(admin.ID, request.ID))
+	assert.False(t, IsUserPrayerRequestModerator(member.ID, request.ID))
+
+	// the request still shows up in the feeds, just without the author
+	feed := GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)
+	require.Equal(t, 1, len(feed))
+	hideAnonymousAuthors(JWTUser{ID: member.ID}, false, feed)
+	assert.Equal(t, int64(0), feed[0].CreatedBy)
+	assert.Equal(t, "", feed[0].Username)
+
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, true, 100, 0)
+	hideAnonymousAuthors(JWTUser{ID: admin.ID}, true, feed)
+	assert.Equal(t, author.ID, feed[0].CreatedBy)
+	feed = GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)
+	hideAnonymousAuthors(JWTUser{ID: author.ID}, false, feed)
+	assert.Equal(t, author.ID, feed[0].CreatedBy)
+}
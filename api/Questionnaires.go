@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CommunityQuestion is a single question on a community's join-request questionnaire. Questions are never edited in
+// place; saving a questionnaire creates a new version so answers given to older versions stay readable
+type CommunityQuestion struct {
+	ID           int64  `json:"id" db:"id"`
+	CommunityID  int64  `json:"communityId" db:"communityId"`
+	Version      int64  `json:"version" db:"version"`
+	Position     int64  `json:"position" db:"position"`
+	QuestionType string `json:"questionType" db:"questionType"`
+	Prompt       string `json:"prompt" db:"prompt"`
+	// Choices are only used for choice questions
+	Choices    []string `json:"choices,omitempty" db:"-"`
+	ChoicesRaw string   `json:"-" db:"choices"`
+	Required   bool     `json:"required" db:"required"`
+	Created    string   `json:"created" db:"created"`
+}
+
+// CommunityQuestionnaire is the current version of a community's questions
+type CommunityQuestionnaire struct {
+	CommunityID int64               `json:"communityId"`
+	Version     int64               `json:"version"`
+	Questions   []CommunityQuestion `json:"questions"`
+}
+
+// CommunityQuestionAnswer is a user's answer to a question when requesting to join a community. The prompt and version
+// are joined from the question that was answered, not the current questionnaire
+type CommunityQuestionAnswer struct {
+	QuestionID   int64  `json:"questionId" db:"questionId"`
+	CommunityID  int64  `json:"communityId" db:"communityId"`
+	UserID       int64  `json:"userId" db:"userId"`
+	Answer       string `json:"answer" db:"answer"`
+	Created      string `json:"created" db:"created"`
+	Version      int64  `json:"version" db:"version"`
+	QuestionType string `json:"questionType" db:"questionType"`
+	Prompt       string `json:"prompt" db:"prompt"`
+}
+
+const (
+	// CommunityQuestionTypeText is a free text answer
+	CommunityQuestionTypeText = "text"
+	// CommunityQuestionTypeChoice must be answered with one of the question's choices
+	CommunityQuestionTypeChoice = "choice"
+	// CommunityQuestionTypeYesNo must be answered with yes or no
+	CommunityQuestionTypeYesNo = "yes_no"
+
+	// CommunityQuestionnaireMaxQuestions is the most questions a questionnaire can have
+	CommunityQuestionnaireMaxQuestions = 10
+	// CommunityQuestionPromptMaxLength is the longest a prompt can be
+	CommunityQuestionPromptMaxLength = 512
+	// CommunityQuestionMaxChoices is the most choices a choice question can have
+	CommunityQuestionMaxChoices = 20
+	// CommunityQuestionAnswerMaxLength is the longest an answer or choice can be
+	CommunityQuestionAnswerMaxLength = 1024
+)
+
+// GetCommunityQuestionnaire gets the current version of a community's questionnaire. A community without a questionnaire
+// returns version 0 and no questions
+func GetCommunityQuestionnaire(communityID int64) (CommunityQuestionnaire, error) {
+	questionnaire := CommunityQuestionnaire{
+		CommunityID: communityID,
+		Questions:   []CommunityQuestion{},
+	}
+	err := Config.DbConn.Get(&questionnaire.Version, "SELECT questionnaireVersion FROM Communities WHERE id = ?", communityID)
+	if err != nil {
+		return questionnaire, err
+	}
+	if questionnaire.Version == 0 {
+		return questionnaire, nil
+	}
+	err = Config.DbConn.Select(&questionnaire.Questions, `SELECT * FROM CommunityQuestions
+		WHERE communityId = ? AND version = ? ORDER BY position`, communityID, questionnaire.Version)
+	for i := range questionnaire.Questions {
+		questionnaire.Questions[i].processForAPI()
+	}
+	return questionnaire, err
+}
+
+// SaveCommunityQuestionnaire saves the questions as a new version of the community's questionnaire. Passing no questions
+// clears the questionnaire. Questions should be validated with Validate first
+func SaveCommunityQuestionnaire(communityID int64, questions []CommunityQuestion) (CommunityQuestionnaire, error) {
+	_, err := Config.DbConn.Exec("UPDATE Communities SET questionnaireVersion = questionnaireVersion + 1 WHERE id = ?", communityID)
+	if err != nil {
+		return CommunityQuestionnaire{}, err
+	}
+	version := int64(0)
+	err = Config.DbConn.Get(&version, "SELECT questionnaireVersion FROM Communities WHERE id = ?", communityID)
+	if err != nil {
+		return CommunityQuestionnaire{}, err
+	}
+	for i := range questions {
+		questions[i].CommunityID = communityID
+		questions[i].Version = version
+		questions[i].Position = int64(i)
+		questions[i].processForDB()
+		_, err = Config.DbConn.Exec(`INSERT INTO CommunityQuestions
+			(communityId, version, position, questionType, prompt, choices, required, created)
+			VALUES (?, ?, ?, ?, ?, ?, ?, NOW())`,
+			communityID, version, questions[i].Position, questions[i].QuestionType, questions[i].Prompt, questions[i].ChoicesRaw, questions[i].Required)
+		if err != nil {
+			return CommunityQuestionnaire{}, err
+		}
+	}
+	return GetCommunityQuestionnaire(communityID)
+}
+
+// Validate checks that a question is well formed and cleans up its prompt and choices
+func (input *CommunityQuestion) Validate() error {
+	input.Prompt, _ = sanitize(input.Prompt)
+	input.Prompt = strings.TrimSpace(input.Prompt)
+	if input.Prompt == "" || len(input.Prompt) > CommunityQuestionPromptMaxLength {
+		return fmt.Errorf("prompt is required and must be at most %d characters", CommunityQuestionPromptMaxLength)
+	}
+	switch input.QuestionType {
+	case CommunityQuestionTypeText, CommunityQuestionTypeYesNo:
+		input.Choices = nil
+	case CommunityQuestionTypeChoice:
+		if len(input.Choices) < 2 || len(input.Choices) > CommunityQuestionMaxChoices {
+			return fmt.Errorf("choice questions need between 2 and %d choices", CommunityQuestionMaxChoices)
+		}
+		seen := map[string]bool{}
+		for i := range input.Choices {
+			input.Choices[i], _ = sanitize(input.Choices[i])
+			input.Choices[i] = strings.TrimSpace(input.Choices[i])
+			if input.Choices[i] == "" || len(input.Choices[i]) > CommunityQuestionAnswerMaxLength {
+				return fmt.Errorf("choices cannot be blank and must be at most %d characters", CommunityQuestionAnswerMaxLength)
+			}
+			if seen[input.Choices[i]] {
+				return fmt.Errorf("choice %s is listed more than once", input.Choices[i])
+			}
+			seen[input.Choices[i]] = true
+		}
+	default:
+		return fmt.Errorf("questionType must be text, choice, or yes_no")
+	}
+	return nil
+}
+
+// ValidateAnswers checks the answers against the questionnaire and returns the cleaned answers to save. Every required
+// question must be answered and answers to questions not on the current version are rejected
+func (questionnaire *CommunityQuestionnaire) ValidateAnswers(answers []CommunityQuestionAnswer) ([]CommunityQuestionAnswer, error) {
+	given := map[int64]string{}
+	for i := range answers {
+		answer, _ := sanitize(answers[i].Answer)
+		given[answers[i].QuestionID] = strings.TrimSpace(answer)
+	}
+
+	ret := []CommunityQuestionAnswer{}
+	for _, question := range questionnaire.Questions {
+		answer, found := given[question.ID]
+		delete(given, question.ID)
+		if !found || answer == "" {
+			if question.Required {
+				return nil, fmt.Errorf("question %d is required", question.ID)
+			}
+			continue
+		}
+		switch question.QuestionType {
+		case CommunityQuestionTypeText:
+			if len(answer) > CommunityQuestionAnswerMaxLength {
+				return nil, fmt.Errorf("the answer to question %d must be at most %d characters", question.ID, CommunityQuestionAnswerMaxLength)
+			}
+		case CommunityQuestionTypeYesNo:
+			answer = strings.ToLower(answer)
+			if answer != "yes" && answer != "no" {
+				return nil, fmt.Errorf("the answer to question %d must be yes or no", question.ID)
+			}
+		case CommunityQuestionTypeChoice:
+			valid := false
+			for _, choice := range question.Choices {
+				if choice == answer {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("the answer to question %d must be one of its choices", question.ID)
+			}
+		}
+		ret = append(ret, CommunityQuestionAnswer{
+			QuestionID:   question.ID,
+			CommunityID:  questionnaire.CommunityID,
+			Answer:       answer,
+			Version:      question.Version,
+			QuestionType: question.QuestionType,
+			Prompt:       question.Prompt,
+		})
+	}
+	for questionID := range given {
+		return nil, fmt.Errorf("question %d is not on the current questionnaire", questionID)
+	}
+	return ret, nil
+}
+
+// SaveCommunityQuestionAnswers replaces a user's answers for a community
+func SaveCommunityQuestionAnswers(communityID, userID int64, answers []CommunityQuestionAnswer) error {
+	err := DeleteCommunityQuestionAnswers(communityID, userID)
+	if err != nil {
+		return err
+	}
+	for i := range answers {
+		_, err = Config.DbConn.Exec(`INSERT INTO CommunityQuestionAnswers (questionId, communityId, userId, answer, created)
+			VALUES (?, ?, ?, ?, NOW())`, answers[i].QuestionID, communityID, userID, answers[i].Answer)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCommunityQuestionAnswers gets the answers the users in a community gave, keyed by user id
+func GetCommunityQuestionAnswers(communityID int64) (map[int64][]CommunityQuestionAnswer, error) {
+	answers := []CommunityQuestionAnswer{}
+	err := Config.DbConn.Select(&answers, `SELECT a.*, q.version, q.questionType, q.prompt
+		FROM CommunityQuestionAnswers a, CommunityQuestions q
+		WHERE a.communityId = ? AND a.questionId = q.id
+		ORDER BY a.userId, q.version, q.position`, communityID)
+	ret := map[int64][]CommunityQuestionAnswer{}
+	for i := range answers {
+		ret[answers[i].UserID] = append(ret[answers[i].UserID], answers[i])
+	}
+	return ret, err
+}
+
+// DeleteCommunityQuestionAnswers removes a user's answers for a community
+func DeleteCommunityQuestionAnswers(communityID, userID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM CommunityQuestionAnswers WHERE communityId = ? AND userId = ?", communityID, userID)
+	return err
+}
+
+// DeleteCommunityQuestionnaireForCommunity removes every version of a community's questionnaire and its answers
+func DeleteCommunityQuestionnaireForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM CommunityQuestionAnswers WHERE communityId = ?", communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunityQuestions WHERE communityId = ?", communityID)
+	return err
+}
+
+func (input *CommunityQuestion) processForDB() {
+	input.ChoicesRaw = ""
+	if len(input.Choices) > 0 {
+		raw, _ := json.Marshal(input.Choices)
+		input.ChoicesRaw = string(raw)
+	}
+}
+
+func (input *CommunityQuestion) processForAPI() {
+	input.Choices = nil
+	if input.ChoicesRaw != "" {
+		json.Unmarshal([]byte(input.ChoicesRaw), &input.Choices)
+	}
+	if input.Created == "1970-01-01 00:00:00" {
+		input.Created = ""
+	}
+}
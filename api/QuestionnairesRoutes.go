@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// communityQuestionnaireInput is the body for saving a questionnaire
+type communityQuestionnaireInput struct {
+	Questions []CommunityQuestion `json:"questions"`
+}
+
+// Bind binds data
+func (data *communityQuestionnaireInput) Bind(r *http.Request) error {
+	return nil
+}
+
+// communityMembershipRequestInput is the optional body when requesting to join a community
+type communityMembershipRequestInput struct {
+	Answers []CommunityQuestionAnswer `json:"answers"`
+}
+
+// Bind binds data
+func (data *communityMembershipRequestInput) Bind(r *http.Request) error {
+	return nil
+}
+
+// GetCommunityQuestionnaireRoute gets the current questionnaire for a community. Anyone who could request to join a public
+// community can see it, as can the community's members
+func GetCommunityQuestionnaireRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil || community.IsArchived() {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
+		return
+	}
+
+	if community.Privacy != CommunityPrivacyPublic {
+		role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+		if err != nil || (role != "admin" && role != "member") {
+			SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
+			return
+		}
+	}
+
+	questionnaire, err := GetCommunityQuestionnaire(communityID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "community_questionnaire_error", "could not get that questionnaire", err)
+		return
+	}
+	Send(w, http.StatusOK, questionnaire)
+	return
+}
+
+// SaveCommunityQuestionnaireRoute allows an admin to replace the questionnaire. The previous questions are kept as an older
+// version so existing answers can still be read
+func SaveCommunityQuestionnaireRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", err)
+		return
+	}
+
+	input := communityQuestionnaireInput{}
+	render.Bind(r, &input)
+	if len(input.Questions) > CommunityQuestionnaireMaxQuestions {
+		SendError(w, http.StatusBadRequest, "community_questionnaire_too_many_questions", "a questionnaire can have at most 10 questions", input)
+		return
+	}
+	for i := range input.Questions {
+		err = input.Questions[i].Validate()
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_questionnaire_invalid_question", err.Error(), input.Questions[i])
+			return
+		}
+	}
+
+	questionnaire, err := SaveCommunityQuestionnaire(communityID, input.Questions)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "community_questionnaire_error", "could not save that questionnaire", err)
+		return
+	}
+	Send(w, http.StatusOK, questionnaire)
+	return
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityQuestionnaireRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	user := User{}
+	err = CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+
+	community := Community{
+		Name:             fmt.Sprintf("Questionnaire_%d", rand.Int63n(999999999)),
+		ShortCode:        fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		Privacy:          CommunityPrivacyPublic,
+		UserSignupStatus: CommunityUserSignupStatusApproval,
+		OwnerID:          admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+
+	// a non-admin cannot save a questionnaire
+	b.Reset()
+	enc.Encode(map[string]interface{}{"questions": []map[string]interface{}{}})
+	code, _, _ := TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/questionnaire", community.ID), b, SaveCommunityQuestionnaireRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{"questions": []map[string]interface{}{
+		{"questionType": "choice", "prompt": "Campus", "choices": []string{"North"}},
+	}})
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/questionnaire", community.ID), b, SaveCommunityQuestionnaireRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{"questions": []map[string]interface{}{
+		{"questionType": "text", "prompt": "Who referred you?", "required": true},
+		{"questionType": "yes_no", "prompt": "Do you live nearby?"},
+	}})
+	code, res, _ := TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/questionnaire", community.ID), b, SaveCommunityQuestionnaireRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+
+	// anyone can read the questionnaire of a public community
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/questionnaire", community.ID), b, GetCommunityQuestionnaireRoute, user.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	questionnaire := CommunityQuestionnaire{}
+	_, body, _ := UnmarshalTestMap(res)
+	mapstructure.Decode(body, &questionnaire)
+	assert.Equal(t, int64(1), questionnaire.Version)
+	require.Equal(t, 2, len(questionnaire.Questions))
+
+	// requesting without the required answer fails
+	b.Reset()
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, user.ID), b, RequestCommunityMembershipRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{"answers": []map[string]interface{}{
+		{"questionId": questionnaire.Questions[0].ID, "answer": "Pat"},
+		{"questionId": questionnaire.Questions[1].ID, "answer": "yes"},
+	}})
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/users/%d", community.ID, user.ID), b, RequestCommunityMembershipRoute, user.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+
+	// the admin sees the answers with the link
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/users?status=requested", community.ID), b, GetCommunityLinksRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	links := []CommunityUserLink{}
+	_, bodyA, _ := UnmarshalTestArray(res)
+	mapstructure.Decode(bodyA, &links)
+	require.Equal(t, 1, len(links))
+	require.Equal(t, 2, len(links[0].Answers))
+	assert.Equal(t, "Who referred you?", links[0].Answers[0].Prompt)
+	assert.Equal(t, "Pat", links[0].Answers[0].Answer)
+
+	// a new version keeps the old answers readable
+	b.Reset()
+	enc.Encode(map[string]interface{}{"questions": []map[string]interface{}{
+		{"questionType": "text", "prompt": "Anything else?"},
+	}})
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("/communities/%d/questionnaire", community.ID), b, SaveCommunityQuestionnaireRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/users?status=requested", community.ID), b, GetCommunityLinksRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	links = []CommunityUserLink{}
+	mapstructure.Decode(bodyA, &links)
+	require.Equal(t, 1, len(links))
+	require.Equal(t, 2, len(links[0].Answers))
+	assert.Equal(t, int64(1), links[0].Answers[0].Version)
+}
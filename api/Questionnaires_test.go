@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityQuestionValidate(t *testing.T) {
+	bad := []CommunityQuestion{
+		{QuestionType: CommunityQuestionTypeText, Prompt: "   "},
+		{QuestionType: CommunityQuestionTypeText, Prompt: strings.Repeat("a", CommunityQuestionPromptMaxLength+1)},
+		{QuestionType: "essay", Prompt: "Tell us about yourself"},
+		{QuestionType: CommunityQuestionTypeChoice, Prompt: "Pick one", Choices: []string{"only"}},
+		{QuestionType: CommunityQuestionTypeChoice, Prompt: "Pick one", Choices: []string{"a", "a"}},
+		{QuestionType: CommunityQuestionTypeChoice, Prompt: "Pick one", Choices: []string{"a", " "}},
+	}
+	for i := range bad {
+		assert.NotNil(t, bad[i].Validate(), fmt.Sprintf("%+v", bad[i]))
+	}
+
+	good := CommunityQuestion{QuestionType: CommunityQuestionTypeYesNo, Prompt: " Do you attend? ", Choices: []string{"a"}}
+	assert.Nil(t, good.Validate())
+	assert.Equal(t, "Do you attend?", good.Prompt)
+	assert.Nil(t, good.Choices)
+}
+
+func TestCommunityQuestionnaireValidateAnswers(t *testing.T) {
+	questionnaire := CommunityQuestionnaire{
+		CommunityID: 1,
+		Version:     2,
+		Questions: []CommunityQuestion{
+			{ID: 10, Version: 2, QuestionType: CommunityQuestionTypeText, Prompt: "Who referred you?", Required: true},
+			{ID: 11, Version: 2, QuestionType: CommunityQuestionTypeChoice, Prompt: "Campus", Choices: []string{"North", "South"}},
+			{ID: 12, Version: 2, QuestionType: CommunityQuestionTypeYesNo, Prompt: "Are you a member?", Required: true},
+		},
+	}
+
+	_, err := questionnaire.ValidateAnswers([]CommunityQuestionAnswer{{QuestionID: 12, Answer: "yes"}})
+	assert.NotNil(t, err, "required text question was not answered")
+	_, err = questionnaire.ValidateAnswers([]CommunityQuestionAnswer{{QuestionID: 10, Answer: "Pat"}, {QuestionID: 12, Answer: "maybe"}})
+	assert.NotNil(t, err)
+	_, err = questionnaire.ValidateAnswers([]CommunityQuestionAnswer{{QuestionID: 10, Answer: "Pat"}, {QuestionID: 11, Answer: "East"}, {QuestionID: 12, Answer: "no"}})
+	assert.NotNil(t, err)
+	_, err = questionnaire.ValidateAnswers([]CommunityQuestionAnswer{{QuestionID: 10, Answer: "Pat"}, {QuestionID: 12, Answer: "no"}, {QuestionID: 9, Answer: "old"}})
+	assert.NotNil(t, err, "question from an older version")
+
+	answers, err := questionnaire.ValidateAnswers([]CommunityQuestionAnswer{{QuestionID: 12, Answer: "YES"}, {QuestionID: 10, Answer: " Pat "}, {QuestionID: 11, Answer: ""}})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(answers))
+	assert.Equal(t, int64(10), answers[0].QuestionID)
+	assert.Equal(t, "Pat", answers[0].Answer)
+	assert.Equal(t, "yes", answers[1].Answer)
+	assert.Equal(t, "Are you a member?", answers[1].Prompt)
+
+	empty := CommunityQuestionnaire{}
+	answers, err = empty.ValidateAnswers(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(answers))
+}
+
+func TestCommunityQuestionnaireVersions(t *testing.T) {
+	ConfigSetup()
+	user := User{}
+	err := CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUser(user.ID)
+
+	community := Community{
+		Name:      fmt.Sprintf("Questionnaire %d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("q_%d", rand.Int63n(999999999)),
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	questionnaire, err := GetCommunityQuestionnaire(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), questionnaire.Version)
+	assert.Equal(t, 0, len(questionnaire.Questions))
+
+	questionnaire, err = SaveCommunityQuestionnaire(community.ID, []CommunityQuestion{
+		{QuestionType: CommunityQuestionTypeText, Prompt: "Who referred you?", Required: true},
+		{QuestionType: CommunityQuestionTypeChoice, Prompt: "Campus", Choices: []string{"North", "South"}},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(1), questionnaire.Version)
+	require.Equal(t, 2, len(questionnaire.Questions))
+	assert.Equal(t, []string{"North", "South"}, questionnaire.Questions[1].Choices)
+	assert.True(t, questionnaire.Questions[0].Required)
+
+	answers, err := questionnaire.ValidateAnswers([]CommunityQuestionAnswer{
+		{QuestionID: questionnaire.Questions[0].ID, Answer: "Pat"},
+		{QuestionID: questionnaire.Questions[1].ID, Answer: "South"},
+	})
+	require.Nil(t, err)
+	err = SaveCommunityQuestionAnswers(community.ID, user.ID, answers)
+	require.Nil(t, err)
+
+	// replacing the questionnaire keeps the old answers readable
+	questionnaire, err = SaveCommunityQuestionnaire(community.ID, []CommunityQuestion{
+		{QuestionType: CommunityQuestionTypeYesNo, Prompt: "Are you a member?"},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), questionnaire.Version)
+	require.Equal(t, 1, len(questionnaire.Questions))
+
+	saved, err := GetCommunityQuestionAnswers(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(saved[user.ID]))
+	assert.Equal(t, "Who referred you?", saved[user.ID][0].Prompt)
+	assert.Equal(t, int64(1), saved[user.ID][0].Version)
+	assert.Equal(t, "South", saved[user.ID][1].Answer)
+
+	// clearing
+	questionnaire, err = SaveCommunityQuestionnaire(community.ID, []CommunityQuestion{})
+	require.Nil(t, err)
+	assert.Equal(t, int64(3), questionnaire.Version)
+	assert.Equal(t, 0, len(questionnaire.Questions))
+
+	err = DeleteCommunityQuestionAnswers(community.ID, user.ID)
+	require.Nil(t, err)
+	saved, err = GetCommunityQuestionAnswers(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(saved[user.ID]))
+}
@@ -1,8 +1,11 @@
 package api
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
-// Report is a report on a request
+// Report is a report on a request or on a comment on a request
 type Report struct {
 	ID           int64  `json:"id" db:"id"`
 	RequestID    int64  `json:"requestId" db:"requestId"`
@@ -13,6 +16,8 @@ type Report struct {
 	Updated      string `json:"updated" db:"updated"`
 	Status       string `json:"status" db:"status"`
 	RequestTitle string `json:"requestTitle" db:"requestTitle"`
+	// CommentID is the comment being reported, or 0 if the report is on the request itself
+	CommentID int64 `json:"commentId,omitempty" db:"commentId"`
 }
 
 const (
@@ -48,12 +53,22 @@ func GetReportReasons() []string {
 	return reportReasons
 }
 
+// IsValidReportReason checks if the input is a known report reason
+func IsValidReportReason(input string) bool {
+	for i := range reportReasons {
+		if input == reportReasons[i] {
+			return true
+		}
+	}
+	return false
+}
+
 // CreateReport adds a report for a request
 func CreateReport(input *Report) error {
 	input.processForDB()
 	defer input.processForAPI()
-	res, err := Config.DbConn.NamedExec(`INSERT INTO Reports (requestId, reporterId, reason, reasonText, reported, updated, status) 
-		VALUES (:requestId, :reporterId, :reason, :reasonText, NOW(), NOW(), :status)`, input)
+	res, err := Config.DbConn.NamedExec(`INSERT INTO Reports (requestId, commentId, reporterId, reason, reasonText, reported, updated, status) 
+		VALUES (:requestId, :commentId, :reporterId, :reason, :reasonText, NOW(), NOW(), :status)`, input)
 	if err != nil {
 		return err
 	}
@@ -83,26 +98,51 @@ func GetReport(reportID int64) (Report, error) {
 	return report, err
 }
 
-// GetReportsForRequest gets all reports for a request
-func GetReportsForRequest(requestID int64) ([]Report, error) {
-	reports := []Report{}
-	err := Config.DbConn.Select(&reports, "SELECT r.*, pr.title AS requestTitle FROM Reports r, PrayerRequests pr WHERE r.requestId = ? AND r.requestId = pr.id", requestID)
-	for i := range reports {
-		reports[i].processForAPI()
-	}
-	return reports, err
+// GetReportsForRequest gets a page of the reports for a request, newest first. A keyset cursor starts the page from a
+// report id instead of the offset
+func GetReportsForRequest(requestID int64, cursor *PageCursor, count, offset int) ([]Report, error) {
+	return getReports("r.requestId = ?", requestID, cursor, count, offset)
+}
+
+// GetCountOfReportsForRequest gets how many reports there are for a request
+func GetCountOfReportsForRequest(requestID int64) (int64, error) {
+	total := int64(0)
+	err := Config.DbConn.Get(&total, "SELECT COUNT(*) FROM Reports r, PrayerRequests pr WHERE r.requestId = ? AND r.requestId = pr.id", requestID)
+	return total, err
+}
+
+// GetReportsForPlatform gets a page of the reports for the platform with the status, newest first. A keyset cursor starts
+// the page from a report id instead of the offset
+func GetReportsForPlatform(status string, cursor *PageCursor, count, offset int) ([]Report, error) {
+	return getReports("r.status = ?", status, cursor, count, offset)
 }
 
-// GetReportsForPlatform gets all of the reports for the platform
-func GetReportsForPlatform(status string) ([]Report, error) {
+// getReports gets a page of the reports matching the condition, newest first
+func getReports(condition string, value interface{}, cursor *PageCursor, count, offset int) ([]Report, error) {
 	reports := []Report{}
-	err := Config.DbConn.Select(&reports, "SELECT r.*, pr.title AS requestTitle FROM Reports r, PrayerRequests pr WHERE r.status = ? AND r.requestId = pr.id", status)
+	where, order, args := keysetClause(cursor, "r.id", true)
+	args = append([]interface{}{value}, args...)
+	args = append(args, offset, count)
+	err := Config.DbConn.Select(&reports, fmt.Sprintf(`SELECT r.*, pr.title AS requestTitle FROM Reports r, PrayerRequests pr WHERE %s AND r.requestId = pr.id
+		%s ORDER BY %s LIMIT ?,?`, condition, where, order), args...)
+	if cursor.IsKeyset() && cursor.Before {
+		for i, j := 0, len(reports)-1; i < j; i, j = i+1, j-1 {
+			reports[i], reports[j] = reports[j], reports[i]
+		}
+	}
 	for i := range reports {
 		reports[i].processForAPI()
 	}
 	return reports, err
 }
 
+// GetCountOfReportsForPlatform gets how many reports for the platform have the status
+func GetCountOfReportsForPlatform(status string) (int64, error) {
+	total := int64(0)
+	err := Config.DbConn.Get(&total, "SELECT COUNT(*) FROM Reports r, PrayerRequests pr WHERE r.status = ? AND r.requestId = pr.id", status)
+	return total, err
+}
+
 func (u *Report) processForDB() {
 	if u.Reported == "" {
 		u.Reported = time.Now().Format("2006-01-02 15:04:05")
@@ -42,18 +42,12 @@ func ReportRequestRoute(w http.ResponseWriter, r *http.Request) {
 
 	report.ReporterID = jwtUser.ID
 	report.RequestID = requestID
+	report.CommentID = 0
 	report.Reason = strings.ToLower(report.Reason)
 	report.ReasonText, _ = sanitize(report.ReasonText)
 	report.Status = ReportStatusOpen
 
-	validReason := false
-	for i := range reportReasons {
-		if report.Reason == reportReasons[i] {
-			validReason = true
-		}
-	}
-
-	if !validReason {
+	if !IsValidReportReason(report.Reason) {
 		SendError(w, http.StatusBadRequest, "report_reason_invalid", "report reason is invalid", report)
 		return
 	}
@@ -64,12 +58,21 @@ func ReportRequestRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// TODO: send an email to the administrator
+	// the reporter is left out so that reports stay anonymous to the communities
+	QueuePrayerRequestWebhookEvent(WebhookEventReportFiled, requestID, map[string]interface{}{
+		"id":         report.ID,
+		"requestId":  report.RequestID,
+		"reason":     report.Reason,
+		"reasonText": report.ReasonText,
+		"status":     report.Status,
+		"reported":   report.Reported,
+	})
 
 	Send(w, http.StatusCreated, report)
 	return
 }
 
-// GetReportsOnRequestRoute gets the reports for a single request
+// GetReportsOnRequestRoute gets a page of the reports for a single request
 func GetReportsOnRequestRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
@@ -83,17 +86,27 @@ func GetReportsOnRequestRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reports, err := GetReportsForRequest(requestID)
+	cursor, ok := GetKeysetPageCursor(w, r, false)
+	if !ok {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	reports, err := GetReportsForRequest(requestID, cursor, count, offset)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, "reports_admin_get_failure", "could not fetch reports for that request", err)
 		return
 	}
 
-	Send(w, http.StatusOK, reports)
+	page := keysetPage(cursor, count, offset, len(reports), func(i int) PageCursor { return PageCursor{ID: reports[i].ID} })
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfReportsForRequest(requestID)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, reports, page)
 	return
 }
 
-// GetReportsOnPlatformRoute gets the reports for the platform by status
+// GetReportsOnPlatformRoute gets a page of the reports for the platform by status
 func GetReportsOnPlatformRoute(w http.ResponseWriter, r *http.Request) {
 	jwtUser, err := CheckForUser(r)
 	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
@@ -104,8 +117,19 @@ func GetReportsOnPlatformRoute(w http.ResponseWriter, r *http.Request) {
 	if status == "" {
 		status = ReportStatusOpen
 	}
-	reports, _ := GetReportsForPlatform(status)
-	Send(w, http.StatusOK, reports)
+	cursor, ok := GetKeysetPageCursor(w, r, false)
+	if !ok {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	reports, _ := GetReportsForPlatform(status, cursor, count, offset)
+
+	page := keysetPage(cursor, count, offset, len(reports), func(i int) PageCursor { return PageCursor{ID: reports[i].ID} })
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfReportsForPlatform(status)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, reports, page)
 	return
 }
 
@@ -55,7 +55,7 @@ func TestReportsCrud(t *testing.T) {
 	assert.Equal(t, report.ReasonText, found.ReasonText)
 	assert.Equal(t, request.Title, found.RequestTitle)
 
-	foundForRequest, err := GetReportsForRequest(request.ID)
+	foundForRequest, err := GetReportsForRequest(request.ID, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(foundForRequest))
 	found = foundForRequest[0]
@@ -67,7 +67,7 @@ func TestReportsCrud(t *testing.T) {
 	assert.Equal(t, report.ReasonText, found.ReasonText)
 	assert.Equal(t, request.Title, found.RequestTitle)
 
-	foundOnPlatform, err := GetReportsForPlatform(ReportStatusClosedNoAction)
+	foundOnPlatform, err := GetReportsForPlatform(ReportStatusClosedNoAction, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	foundInLoop := false
 	for i := range foundOnPlatform {
@@ -77,7 +77,7 @@ func TestReportsCrud(t *testing.T) {
 	}
 	assert.False(t, foundInLoop)
 
-	foundOnPlatform, err = GetReportsForPlatform(ReportStatusOpen)
+	foundOnPlatform, err = GetReportsForPlatform(ReportStatusOpen, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	foundInLoop = false
 	for i := range foundOnPlatform {
@@ -102,7 +102,7 @@ func TestReportsCrud(t *testing.T) {
 	assert.Equal(t, report.ReasonText, found.ReasonText)
 	assert.Equal(t, request.Title, found.RequestTitle)
 
-	foundForRequest, err = GetReportsForRequest(request.ID)
+	foundForRequest, err = GetReportsForRequest(request.ID, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(foundForRequest))
 	found = foundForRequest[0]
@@ -114,7 +114,7 @@ func TestReportsCrud(t *testing.T) {
 	assert.Equal(t, report.ReasonText, found.ReasonText)
 	assert.Equal(t, request.Title, found.RequestTitle)
 
-	foundOnPlatform, err = GetReportsForPlatform(ReportStatusClosedNoAction)
+	foundOnPlatform, err = GetReportsForPlatform(ReportStatusClosedNoAction, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	foundInLoop = false
 	for i := range foundOnPlatform {
@@ -124,7 +124,7 @@ func TestReportsCrud(t *testing.T) {
 	}
 	assert.True(t, foundInLoop)
 
-	foundOnPlatform, err = GetReportsForPlatform(ReportStatusOpen)
+	foundOnPlatform, err = GetReportsForPlatform(ReportStatusOpen, nil, PageSizeMax, 0)
 	assert.Nil(t, err)
 	foundInLoop = false
 	for i := range foundOnPlatform {
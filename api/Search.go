@@ -0,0 +1,393 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SearchBackend indexes text for requests, communities, and users and finds the ids that match a search. Backends only
+// know about ids and words; visibility is always checked against the database after a search
+type SearchBackend interface {
+	// Index replaces any text already indexed for the object with the new text
+	Index(collection string, id int64, text string) error
+	// Remove removes the object from the index; removing an object that isn't indexed is not an error
+	Remove(collection string, id int64) error
+	// Query gets the ids of up to limit objects that match every word in the terms, best match first
+	Query(collection string, terms string, limit int) ([]int64, error)
+	// Clear removes every object in the collection
+	Clear(collection string) error
+}
+
+const (
+	// SearchCollectionRequests holds the title, body, and tags of requests
+	SearchCollectionRequests = "requests"
+	// SearchCollectionCommunities holds the name, description, and location of communities
+	SearchCollectionCommunities = "communities"
+	// SearchCollectionUsers holds usernames
+	SearchCollectionUsers = "users"
+
+	// SearchMaxResults is the most ids fetched from the backend for each collection in a search, before visibility is checked.
+	// Paging happens on what is left, so matches past the first SearchMaxResults can't be reached with offset
+	SearchMaxResults = 100
+)
+
+// SearchResults are the matches from each collection the user can see
+type SearchResults struct {
+	Requests    []PrayerRequest `json:"requests"`
+	Communities []Community     `json:"communities"`
+	Users       []SearchUser    `json:"users"`
+}
+
+// SearchUser is the public part of a user found in a search
+type SearchUser struct {
+	ID       int64  `json:"id" db:"id"`
+	Username string `json:"username" db:"username"`
+}
+
+// IsValidSearchCollection checks if the input is a collection that can be searched
+func IsValidSearchCollection(input string) bool {
+	return input == SearchCollectionRequests || input == SearchCollectionCommunities || input == SearchCollectionUsers
+}
+
+// IndexPrayerRequest indexes a request's title, body, and tags, replacing anything already indexed for it
+func IndexPrayerRequest(requestID int64) error {
+	request, err := GetPrayerRequest(requestID)
+	if err != nil {
+		return err
+	}
+	text := strings.Join(append([]string{request.Title, request.Body}, request.Tags...), " ")
+	return indexForSearch(SearchCollectionRequests, requestID, text)
+}
+
+// IndexCommunity indexes a community's name, description, and location, replacing anything already indexed for it
+func IndexCommunity(communityID int64) error {
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		return err
+	}
+	return indexForSearch(SearchCollectionCommunities, communityID, community.searchText())
+}
+
+// IndexUser indexes a user's username. Names and emails are never indexed
+func IndexUser(userID int64) error {
+	user, err := GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	return indexForSearch(SearchCollectionUsers, userID, user.Username)
+}
+
+// RemoveFromSearch removes an object from the search index, logging any failure
+func RemoveFromSearch(collection string, id int64) error {
+	err := Config.Search.Remove(collection, id)
+	if err != nil {
+		Log("error", "could not remove from the search index", "search_remove_fail", map[string]string{
+			"collection": collection,
+			"id":         fmt.Sprintf("%d", id),
+			"error":      err.Error(),
+		})
+	}
+	return err
+}
+
+// ReindexSearch clears the search index and indexes every request, community, and user again. It is meant to be run by an
+// admin after switching backends or if the index gets out of sync, not on a schedule
+func ReindexSearch() error {
+	for _, collection := range []string{SearchCollectionRequests, SearchCollectionCommunities, SearchCollectionUsers} {
+		err := Config.Search.Clear(collection)
+		if err != nil {
+			return err
+		}
+	}
+
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, "SELECT id FROM PrayerRequests")
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		IndexPrayerRequest(ids[i])
+	}
+
+	communities := []Community{}
+	err = Config.DbConn.Select(&communities, "SELECT * FROM Communities")
+	if err != nil {
+		return err
+	}
+	for i := range communities {
+		indexForSearch(SearchCollectionCommunities, communities[i].ID, communities[i].searchText())
+	}
+
+	users := []SearchUser{}
+	err = Config.DbConn.Select(&users, "SELECT id, username FROM Users")
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		indexForSearch(SearchCollectionUsers, users[i].ID, users[i].Username)
+	}
+	return nil
+}
+
+// StartSearch prepares the search backend when the server starts. The in-process index isn't kept between restarts, so it is
+// rebuilt in the background
+func StartSearch() {
+	if _, ok := Config.Search.(*MemorySearch); ok {
+		go func() {
+			err := ReindexSearch()
+			if err != nil {
+				Log("error", "could not build the search index", "search_reindex_fail", map[string]string{
+					"error": err.Error(),
+				})
+			}
+		}()
+	}
+}
+
+// SearchPrayerRequests finds the requests matching the terms that the user can see. Archived requests are only found by their
+// authors
+func SearchPrayerRequests(jwtUser JWTUser, terms string) ([]PrayerRequest, error) {
+	requests := []PrayerRequest{}
+	ids, err := Config.Search.Query(SearchCollectionRequests, terms, SearchMaxResults)
+	if err != nil {
+		return requests, err
+	}
+	for i := range ids {
+		request, err := GetPrayerRequest(ids[i])
+		if err != nil || (request.Archived != "" && request.CreatedBy != jwtUser.ID) || !CanUserSeePrayerRequest(jwtUser.ID, request) {
+			continue
+		}
+		if !canUserSeePrayerRequestAuthor(jwtUser, request) {
+			request.HideAnonymousAuthor()
+		}
+		requests = append(requests, *request)
+	}
+	return requests, nil
+}
+
+// SearchCommunities finds the communities matching the terms that the user can see, which are the public ones and the ones
+// the user belongs to. Archived communities are never found
+func SearchCommunities(userID int64, terms string) ([]Community, error) {
+	communities := []Community{}
+	ids, err := Config.Search.Query(SearchCollectionCommunities, terms, SearchMaxResults)
+	if err != nil {
+		return communities, err
+	}
+	for i := range ids {
+		community, err := GetCommunityByID(ids[i])
+		if err != nil || community.IsArchived() {
+			continue
+		}
+		if community.Privacy != CommunityPrivacyPublic {
+			role, err := GetUserRoleForCommunity(community.ID, userID)
+			if err != nil || (role != "admin" && role != "member") {
+				continue
+			}
+		}
+		community.clean()
+		communities = append(communities, *community)
+	}
+	return communities, nil
+}
+
+// SearchUsers finds the users whose usernames match the terms
+func SearchUsers(terms string) ([]SearchUser, error) {
+	users := []SearchUser{}
+	ids, err := Config.Search.Query(SearchCollectionUsers, terms, SearchMaxResults)
+	if err != nil || len(ids) == 0 {
+		return users, err
+	}
+	query, args, err := sqlx.In("SELECT id, username FROM Users WHERE id IN (?)", ids)
+	if err != nil {
+		return users, err
+	}
+	found := []SearchUser{}
+	err = Config.DbConn.Select(&found, Config.DbConn.Rebind(query), args...)
+	if err != nil {
+		return users, err
+	}
+	// keep the backend's order
+	byID := map[int64]SearchUser{}
+	for i := range found {
+		byID[found[i].ID] = found[i]
+	}
+	for i := range ids {
+		if user, ok := byID[ids[i]]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// indexForSearch sends the text to the backend, logging any failure. Indexing failures never stop the change being indexed
+func indexForSearch(collection string, id int64, text string) error {
+	err := Config.Search.Index(collection, id, text)
+	if err != nil {
+		Log("error", "could not update the search index", "search_index_fail", map[string]string{
+			"collection": collection,
+			"id":         fmt.Sprintf("%d", id),
+			"error":      err.Error(),
+		})
+	}
+	return err
+}
+
+// searchText is the text indexed for a community
+func (input *Community) searchText() string {
+	return strings.Join([]string{input.Name, input.ShortCode, input.Description, input.Denomination, input.City, input.Region}, " ")
+}
+
+// searchWords splits text into the lowercase words that are indexed and searched
+func searchWords(text string) []string {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// MemorySearch is a SearchBackend that keeps an inverted index in memory. It is meant for small installs and tests; the index
+// is lost when the server stops
+type MemorySearch struct {
+	lock        sync.RWMutex
+	collections map[string]*memorySearchCollection
+}
+
+type memorySearchCollection struct {
+	// words maps each indexed word to the objects that have it
+	words map[string]map[int64]bool
+	// objects maps each object to its words and when it was last indexed, so newer objects can be ranked first
+	objects map[int64]memorySearchObject
+	indexed int64
+}
+
+type memorySearchObject struct {
+	words   []string
+	indexed int64
+}
+
+// NewMemorySearch creates a new, empty in-process search index
+func NewMemorySearch() *MemorySearch {
+	return &MemorySearch{
+		collections: map[string]*memorySearchCollection{},
+	}
+}
+
+// Index replaces the words indexed for the object
+func (s *MemorySearch) Index(collection string, id int64, text string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.collection(collection)
+	c.remove(id)
+	words := searchWords(text)
+	if len(words) == 0 {
+		return nil
+	}
+	c.indexed++
+	c.objects[id] = memorySearchObject{
+		words:   words,
+		indexed: c.indexed,
+	}
+	for _, word := range words {
+		if c.words[word] == nil {
+			c.words[word] = map[int64]bool{}
+		}
+		c.words[word][id] = true
+	}
+	return nil
+}
+
+// Remove removes the object's words from the index
+func (s *MemorySearch) Remove(collection string, id int64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.collection(collection).remove(id)
+	return nil
+}
+
+// Query finds the objects where every search word starts one of their words, most recently indexed first
+func (s *MemorySearch) Query(collection string, terms string, limit int) ([]int64, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	ids := []int64{}
+	c, ok := s.collections[collection]
+	words := searchWords(terms)
+	if !ok || len(words) == 0 {
+		return ids, nil
+	}
+
+	var matches map[int64]bool
+	for _, word := range words {
+		found := map[int64]bool{}
+		for indexed, objects := range c.words {
+			if !strings.HasPrefix(indexed, word) {
+				continue
+			}
+			for id := range objects {
+				if matches == nil || matches[id] {
+					found[id] = true
+				}
+			}
+		}
+		matches = found
+		if len(matches) == 0 {
+			return ids, nil
+		}
+	}
+
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return c.objects[ids[i]].indexed > c.objects[ids[j]].indexed
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+// Clear removes every object in the collection
+func (s *MemorySearch) Clear(collection string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.collections, collection)
+	return nil
+}
+
+func (s *MemorySearch) collection(name string) *memorySearchCollection {
+	c, ok := s.collections[name]
+	if !ok {
+		c = &memorySearchCollection{
+			words:   map[string]map[int64]bool{},
+			objects: map[int64]memorySearchObject{},
+		}
+		s.collections[name] = c
+	}
+	return c
+}
+
+func (c *memorySearchCollection) remove(id int64) {
+	object, ok := c.objects[id]
+	if !ok {
+		return
+	}
+	for _, word := range object.words {
+		delete(c.words[word], id)
+		if len(c.words[word]) == 0 {
+			delete(c.words, word)
+		}
+	}
+	delete(c.objects, id)
+}
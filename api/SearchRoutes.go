@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SearchRoute searches requests, communities, and users with the q query param. The type query param limits the search to one
+// of requests, communities, or users. Only results the user can see are returned, and each list is paged on its own. Only the
+// first SearchMaxResults matches of each type are checked, so the pages end there
+func SearchRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	terms := strings.TrimSpace(r.URL.Query().Get("q"))
+	if terms == "" {
+		SendError(w, http.StatusBadRequest, "search_bad_data", "q is required", nil)
+		return
+	}
+	collection := strings.ToLower(r.URL.Query().Get("type"))
+	if collection != "" && !IsValidSearchCollection(collection) {
+		SendError(w, http.StatusBadRequest, "search_bad_data", "type must be requests, communities, or users", nil)
+		return
+	}
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+
+	results := SearchResults{
+		Requests:    []PrayerRequest{},
+		Communities: []Community{},
+		Users:       []SearchUser{},
+	}
+	if collection == "" || collection == SearchCollectionRequests {
+		requests, err := SearchPrayerRequests(jwtUser, terms)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "search_error", "the search could not be completed", err)
+			return
+		}
+		start, end := searchPage(len(requests), count, offset)
+		results.Requests = requests[start:end]
+	}
+	if collection == "" || collection == SearchCollectionCommunities {
+		communities, err := SearchCommunities(jwtUser.ID, terms)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "search_error", "the search could not be completed", err)
+			return
+		}
+		start, end := searchPage(len(communities), count, offset)
+		results.Communities = communities[start:end]
+	}
+	if collection == "" || collection == SearchCollectionUsers {
+		users, err := SearchUsers(terms)
+		if err != nil {
+			SendError(w, http.StatusInternalServerError, "search_error", "the search could not be completed", err)
+			return
+		}
+		start, end := searchPage(len(users), count, offset)
+		results.Users = users[start:end]
+	}
+
+	// every type shares the same offset, so there is a next page as long as any of them filled this one
+	returned := len(results.Requests)
+	if len(results.Communities) > returned {
+		returned = len(results.Communities)
+	}
+	if len(results.Users) > returned {
+		returned = len(results.Users)
+	}
+	SendPage(w, r, http.StatusOK, results, offsetPage(count, offset, returned))
+	return
+}
+
+// ReindexSearchRoute rebuilds the search index in the background. It is only for platform admins
+func ReindexSearchRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	go func() {
+		err := ReindexSearch()
+		if err != nil {
+			Log("error", "could not rebuild the search index", "search_reindex_fail", map[string]string{
+				"error": err.Error(),
+			})
+		}
+	}()
+	Send(w, http.StatusAccepted, map[string]bool{
+		"reindexing": true,
+	})
+	return
+}
+
+// searchPage gets the bounds of a page of results, since visibility is checked after the search
+func searchPage(total, count, offset int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + count
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	user := User{}
+	err := CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&user)
+	admin := User{
+		PlatformRole: "admin",
+	}
+	err = CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	word := fmt.Sprintf("findme%d", rand.Int63n(99999999))
+	request := PrayerRequest{
+		Title:     "Route " + word,
+		Body:      "Please pray",
+		CreatedBy: user.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	code, _, _ := TestAPICall(http.MethodGet, "/search?q="+word, b, SearchRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/search", b, SearchRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/search?type=prayers&q="+word, b, SearchRoute, user.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, res, _ := TestAPICall(http.MethodGet, "/search?q="+word, b, SearchRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.Equal(t, 1, len(body["requests"].([]interface{})))
+	assert.Equal(t, 0, len(body["communities"].([]interface{})))
+
+	code, res, _ = TestAPICall(http.MethodGet, "/search?type=users&q="+user.Username, b, SearchRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, 0, len(body["requests"].([]interface{})))
+	assert.NotZero(t, len(body["users"].([]interface{})))
+
+	// the reindex itself isn't run here since it clears the index the other tests are using
+	code, _, _ = TestAPICall(http.MethodPost, "/admin/search/reindex", b, ReindexSearchRoute, user.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestSearchPage(t *testing.T) {
+	start, end := searchPage(5, 2, 0)
+	assert.Equal(t, 0, start)
+	assert.Equal(t, 2, end)
+	start, end = searchPage(5, 10, 4)
+	assert.Equal(t, 4, start)
+	assert.Equal(t, 5, end)
+	start, end = searchPage(5, 10, 10)
+	assert.Equal(t, 5, start)
+	assert.Equal(t, 5, end)
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// sonicBucket is the single bucket used in each collection; visibility is checked against the database, not by bucket
+	sonicBucket = "default"
+	// sonicDefaultBufferSize is the longest command Sonic accepts when it doesn't say otherwise
+	sonicDefaultBufferSize = 20000
+)
+
+// SonicSearch is a SearchBackend that talks to a Sonic server over its channel protocol. It keeps one connection open for
+// ingesting and one for searching, and reconnects if Sonic closes them
+type SonicSearch struct {
+	Address  string
+	Password string
+	Timeout  time.Duration
+
+	lock   sync.Mutex
+	ingest *sonicChannel
+	search *sonicChannel
+}
+
+// sonicError is an ERR response from Sonic. Unlike a network error, the connection is still good after one
+type sonicError string
+
+func (e sonicError) Error() string {
+	return fmt.Sprintf("sonic: %s", string(e))
+}
+
+type sonicChannel struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	timeout    time.Duration
+	bufferSize int
+}
+
+// NewSonicSearch creates a new Sonic backend for the server at the address, such as localhost:1491
+func NewSonicSearch(address, password string) *SonicSearch {
+	return &SonicSearch{
+		Address:  address,
+		Password: password,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Index flushes the object and pushes the new text, split across as many pushes as Sonic's buffer needs
+func (s *SonicSearch) Index(collection string, id int64, text string) error {
+	return s.run("ingest", func(c *sonicChannel) error {
+		object := strconv.FormatInt(id, 10)
+		_, err := c.command(fmt.Sprintf("FLUSHO %s %s %s", collection, sonicBucket, object))
+		if err != nil {
+			return err
+		}
+		prefix := fmt.Sprintf("PUSH %s %s %s ", collection, sonicBucket, object)
+		for _, chunk := range sonicChunks(text, c.bufferSize-len(prefix)-2) {
+			_, err = c.command(fmt.Sprintf(`%s"%s"`, prefix, chunk))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove flushes the object
+func (s *SonicSearch) Remove(collection string, id int64) error {
+	return s.run("ingest", func(c *sonicChannel) error {
+		_, err := c.command(fmt.Sprintf("FLUSHO %s %s %d", collection, sonicBucket, id))
+		return err
+	})
+}
+
+// Query runs a search and waits for its results
+func (s *SonicSearch) Query(collection string, terms string, limit int) ([]int64, error) {
+	ids := []int64{}
+	terms = strings.Join(strings.Fields(sonicText(terms)), " ")
+	if terms == "" {
+		return ids, nil
+	}
+	err := s.run("search", func(c *sonicChannel) error {
+		line, err := c.command(fmt.Sprintf(`QUERY %s %s "%s" LIMIT(%d)`, collection, sonicBucket, terms, limit))
+		if err != nil {
+			return err
+		}
+		// Sonic answers with a marker and then sends the results as an event with the same marker
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != "PENDING" {
+			return sonicError(fmt.Sprintf("unexpected response %q", line))
+		}
+		for {
+			line, err = c.read()
+			if err != nil {
+				return err
+			}
+			event := strings.Fields(line)
+			if len(event) >= 3 && event[0] == "EVENT" && event[1] == "QUERY" && event[2] == fields[1] {
+				for _, object := range event[3:] {
+					if id, err := strconv.ParseInt(object, 10, 64); err == nil {
+						ids = append(ids, id)
+					}
+				}
+				return nil
+			}
+		}
+	})
+	return ids, err
+}
+
+// Clear flushes the collection
+func (s *SonicSearch) Clear(collection string) error {
+	return s.run("ingest", func(c *sonicChannel) error {
+		_, err := c.command(fmt.Sprintf("FLUSHC %s", collection))
+		return err
+	})
+}
+
+// run calls fn with the channel for the mode, connecting if needed. Sonic closes idle connections, so if the connection fails
+// fn is tried once more on a new one
+func (s *SonicSearch) run(mode string, fn func(c *sonicChannel) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	channel := &s.ingest
+	if mode == "search" {
+		channel = &s.search
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if *channel == nil {
+			*channel, err = dialSonicChannel(s.Address, s.Password, mode, s.Timeout)
+			if err != nil {
+				return err
+			}
+		}
+		err = fn(*channel)
+		if _, ok := err.(sonicError); err == nil || ok {
+			return err
+		}
+		(*channel).conn.Close()
+		*channel = nil
+	}
+	return err
+}
+
+// dialSonicChannel connects to Sonic and starts a channel in the mode, either ingest or search
+func dialSonicChannel(address, password, mode string, timeout time.Duration) (*sonicChannel, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &sonicChannel{
+		conn:       conn,
+		reader:     bufio.NewReader(conn),
+		timeout:    timeout,
+		bufferSize: sonicDefaultBufferSize,
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	line, err := c.read()
+	if err == nil && !strings.HasPrefix(line, "CONNECTED") {
+		err = sonicError(fmt.Sprintf("unexpected greeting %q", line))
+	}
+	if err == nil {
+		line, err = c.command(fmt.Sprintf("START %s %s", mode, password))
+	}
+	if err == nil && !strings.HasPrefix(line, "STARTED") {
+		err = sonicError(fmt.Sprintf("unexpected response %q", line))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// STARTED ingest protocol(1) buffer(20000)
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, "buffer(") {
+			if size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(field, "buffer("), ")")); err == nil && size > 0 {
+				c.bufferSize = size
+			}
+		}
+	}
+	return c, nil
+}
+
+// command sends a command and reads the first line of the response
+func (c *sonicChannel) command(command string) (string, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	_, err := c.conn.Write([]byte(command + "\r\n"))
+	if err != nil {
+		return "", err
+	}
+	return c.read()
+}
+
+// read reads a line, turning an ERR response into a sonicError
+func (c *sonicChannel) read() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "ERR ") {
+		return line, sonicError(strings.TrimPrefix(line, "ERR "))
+	}
+	return line, nil
+}
+
+// sonicText removes the characters that would break out of a quoted value in a command. None of them matter for search
+func sonicText(text string) string {
+	return strings.NewReplacer(`"`, " ", `\`, " ", "\r", " ", "\n", " ").Replace(text)
+}
+
+// sonicChunks splits the text into pieces of at most size bytes, breaking between words
+func sonicChunks(text string, size int) []string {
+	if size < 1 {
+		size = 1
+	}
+	chunks := []string{}
+	chunk := ""
+	for _, word := range strings.Fields(sonicText(text)) {
+		if len(word) > size {
+			word = word[:size]
+		}
+		if chunk != "" && len(chunk)+1+len(word) > size {
+			chunks = append(chunks, chunk)
+			chunk = ""
+		}
+		if chunk != "" {
+			chunk += " "
+		}
+		chunk += word
+	}
+	if chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
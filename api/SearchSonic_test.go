@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSonic is a small stand-in for a Sonic server that records the commands it gets
+type fakeSonic struct {
+	listener net.Listener
+	lock     sync.Mutex
+	commands []string
+}
+
+func newFakeSonic(t *testing.T) *fakeSonic {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	s := &fakeSonic{
+		listener: listener,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSonic) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	conn.Write([]byte("CONNECTED <sonic-server v1.3.0>\r\n"))
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.lock.Lock()
+		s.commands = append(s.commands, line)
+		s.lock.Unlock()
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "START":
+			if fields[2] != "secret" {
+				conn.Write([]byte("ENDED authentication_failed\r\n"))
+				return
+			}
+			conn.Write([]byte("STARTED " + fields[1] + " protocol(1) buffer(64)\r\n"))
+		case "PUSH":
+			conn.Write([]byte("OK\r\n"))
+		case "FLUSHO", "FLUSHC":
+			conn.Write([]byte("RESULT 1\r\n"))
+		case "QUERY":
+			if strings.Contains(line, "broken") {
+				conn.Write([]byte("ERR invalid_format(QUERY <collection> <bucket> \"<terms>\")\r\n"))
+				continue
+			}
+			conn.Write([]byte("PENDING Bt2m2gYa\r\nEVENT QUERY Bt2m2gYa 3 1\r\n"))
+		case "DROP":
+			// lets the tests act like Sonic closing an idle connection
+			return
+		}
+	}
+}
+
+func (s *fakeSonic) received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+func TestSonicSearch(t *testing.T) {
+	server := newFakeSonic(t)
+	defer server.listener.Close()
+	search := NewSonicSearch(server.listener.Addr().String(), "secret")
+
+	// text is flushed first, and pushes are split to fit in the buffer Sonic asked for
+	err := search.Index(SearchCollectionRequests, 7, `Please pray for "my mom" and
+her surgery tomorrow at the hospital`)
+	require.Nil(t, err)
+	commands := server.received()
+	require.True(t, len(commands) > 3)
+	assert.Equal(t, "START ingest secret", commands[0])
+	assert.Equal(t, "FLUSHO requests default 7", commands[1])
+	for _, command := range commands[2:] {
+		assert.True(t, strings.HasPrefix(command, `PUSH requests default 7 "`))
+		assert.True(t, len(command) <= 64)
+		assert.Equal(t, 2, strings.Count(command, `"`))
+	}
+
+	ids, err := search.Query(SearchCollectionRequests, "surgery", 10)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 1}, ids)
+	commands = server.received()
+	assert.Equal(t, `QUERY requests default "surgery" LIMIT(10)`, commands[len(commands)-1])
+
+	// an error from Sonic is returned without reconnecting
+	_, err = search.Query(SearchCollectionRequests, "broken", 10)
+	assert.NotNil(t, err)
+	_, ok := err.(sonicError)
+	assert.True(t, ok)
+
+	// a dropped connection is replaced
+	search.run("search", func(c *sonicChannel) error {
+		c.conn.Write([]byte("DROP\r\n"))
+		return nil
+	})
+	ids, err = search.Query(SearchCollectionRequests, "surgery", 10)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{3, 1}, ids)
+
+	err = search.Remove(SearchCollectionUsers, 4)
+	assert.Nil(t, err)
+	err = search.Clear(SearchCollectionCommunities)
+	assert.Nil(t, err)
+	commands = server.received()
+	assert.Equal(t, "FLUSHC communities", commands[len(commands)-1])
+	assert.Equal(t, "FLUSHO users default 4", commands[len(commands)-2])
+
+	wrong := NewSonicSearch(server.listener.Addr().String(), "wrong")
+	err = wrong.Clear(SearchCollectionCommunities)
+	assert.NotNil(t, err)
+}
+
+func TestSonicChunks(t *testing.T) {
+	assert.Equal(t, []string{}, sonicChunks("  ", 10))
+	assert.Equal(t, []string{"one two", "three"}, sonicChunks("one\ntwo \"three\"", 7))
+	assert.Equal(t, []string{"abcd", "ef"}, sonicChunks("abcdefgh ef", 4))
+}
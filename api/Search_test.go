@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemorySearch(t *testing.T) {
+	search := NewMemorySearch()
+	search.Index(SearchCollectionRequests, 1, "Pray for my mother's surgery")
+	search.Index(SearchCollectionRequests, 2, "Surgery recovery and healing")
+	search.Index(SearchCollectionRequests, 3, "A new job")
+	search.Index(SearchCollectionUsers, 1, "surgeon")
+
+	// newest first, and words match by prefix
+	ids, err := search.Query(SearchCollectionRequests, "SURG", 10)
+	require.Nil(t, err)
+	assert.Equal(t, []int64{2, 1}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "surgery mother", 10)
+	assert.Equal(t, []int64{1}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "surgery job", 10)
+	assert.Equal(t, []int64{}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "surgery", 1)
+	assert.Equal(t, []int64{2}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "  !! ", 10)
+	assert.Equal(t, []int64{}, ids)
+
+	// indexing again replaces the old words
+	search.Index(SearchCollectionRequests, 2, "Answered, thank you")
+	ids, _ = search.Query(SearchCollectionRequests, "surgery", 10)
+	assert.Equal(t, []int64{1}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "answered", 10)
+	assert.Equal(t, []int64{2}, ids)
+
+	search.Remove(SearchCollectionRequests, 1)
+	ids, _ = search.Query(SearchCollectionRequests, "surgery", 10)
+	assert.Equal(t, []int64{}, ids)
+
+	// collections are separate
+	ids, _ = search.Query(SearchCollectionUsers, "surg", 10)
+	assert.Equal(t, []int64{1}, ids)
+	search.Clear(SearchCollectionUsers)
+	ids, _ = search.Query(SearchCollectionUsers, "surg", 10)
+	assert.Equal(t, []int64{}, ids)
+	ids, _ = search.Query(SearchCollectionRequests, "job", 10)
+	assert.Equal(t, []int64{3}, ids)
+}
+
+func TestSearchVisibility(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUser(other.ID)
+
+	word := fmt.Sprintf("searchable%d", rand.Int63n(99999999))
+	public := PrayerRequest{
+		Title:     "Public " + word,
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+		Anonymous: true,
+	}
+	err = CreatePrayerRequest(&public)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(public.ID)
+	private := PrayerRequest{
+		Title:     "Private",
+		Body:      "Please pray about " + word,
+		CreatedBy: author.ID,
+		Privacy:   PrayerRequestPrivacyPrivate,
+	}
+	err = CreatePrayerRequest(&private)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(private.ID)
+
+	found, err := SearchPrayerRequests(JWTUser{ID: author.ID}, word)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(found))
+	found, err = SearchPrayerRequests(JWTUser{ID: other.ID}, word)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(found))
+	assert.Equal(t, public.ID, found[0].ID)
+	assert.Zero(t, found[0].CreatedBy)
+
+	// tags are searchable too
+	tag := fmt.Sprintf("tagged%d", rand.Int63n(99999999))
+	created, err := AddTagToPrayerRequest(private.ID, tag)
+	require.Nil(t, err)
+	defer DeleteTag(created.ID)
+	found, _ = SearchPrayerRequests(JWTUser{ID: author.ID}, tag)
+	assert.Equal(t, 1, len(found))
+
+	community := Community{
+		Name:        "Search " + word,
+		ShortCode:   fmt.Sprintf("search_%d", rand.Int63n(99999)),
+		Description: "A private group",
+		Privacy:     CommunityPrivacyPrivate,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, author.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	communities, err := SearchCommunities(author.ID, word)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(communities))
+	communities, _ = SearchCommunities(other.ID, word)
+	assert.Zero(t, len(communities))
+
+	users, err := SearchUsers(other.Username)
+	require.Nil(t, err)
+	require.NotZero(t, len(users))
+	assert.Equal(t, other.ID, users[0].ID)
+
+	// deleting removes it from the index
+	DeletePrayerRequest(public.ID)
+	found, _ = SearchPrayerRequests(JWTUser{ID: author.ID}, word)
+	assert.Equal(t, 1, len(found))
+}
@@ -141,6 +141,17 @@ func UpdateSiteRoute(w http.ResponseWriter, r *http.Request) {
 
 	Site.LogoLocation = input.LogoLocation
 
+	// the request expiry can be turned off by passing -1
+	if input.RequestExpiryDays < 0 {
+		Site.RequestExpiryDays = 0
+	} else if input.RequestExpiryDays > 0 {
+		if !IsValidPrayerRequestExpiryDays(input.RequestExpiryDays) {
+			SendError(w, http.StatusBadRequest, "site_update_invalid_request_expiry", fmt.Sprintf("requestExpiryDays must be at most %d", PrayerRequestExpiryMaxDays), input)
+			return
+		}
+		Site.RequestExpiryDays = input.RequestExpiryDays
+	}
+
 	err = UpdateSiteSettings(&Site)
 	if err != nil {
 		SendError(w, http.StatusBadRequest, "site_update_err", "could not save site settings", err)
@@ -7,7 +7,9 @@ type SiteStruct struct {
 	SecretKey    string `json:"secretKey,omitempty" db:"secretKey"`
 	Status       string `json:"status" db:"status"`
 	LogoLocation string `json:"logoLocation" db:"logoLocation"`
-	Loaded       bool   `json:"-"`
+	// RequestExpiryDays is how long a pending request can go without an update before its author is reminded; 0 turns it off
+	RequestExpiryDays int64 `json:"requestExpiryDays" db:"requestExpiryDays"`
+	Loaded            bool  `json:"-"`
 }
 
 // Site is the global Site variable with global configuration options from the DB
@@ -51,7 +53,7 @@ func DeleteSiteForTest() error {
 
 // UpdateSiteSettings updates the settings for a site
 func UpdateSiteSettings(input *SiteStruct) error {
-	_, err := Config.DbConn.NamedExec("UPDATE Site SET name = :name, description = :description, secretKey = :secretKey, status = :status, logoLocation = :logoLocation", input)
+	_, err := Config.DbConn.NamedExec("UPDATE Site SET name = :name, description = :description, secretKey = :secretKey, status = :status, logoLocation = :logoLocation, requestExpiryDays = :requestExpiryDays", input)
 	if err != nil {
 		return err
 	}
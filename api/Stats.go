@@ -0,0 +1,171 @@
+package api
+
+import (
+	"sort"
+)
+
+const (
+	// CommunityStatsDefaultDays is the number of days reported on when no start date is provided
+	CommunityStatsDefaultDays = 30
+	// CommunityStatsMaxDays is the longest range that can be reported on in a single call
+	CommunityStatsMaxDays = 366
+)
+
+// CommunityStats is the set of daily time series and summary figures for a community over a date range
+type CommunityStats struct {
+	CommunityID int64  `json:"communityId"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	// NewRequests are the requests shared with the community, by the day they were created
+	NewRequests DatePointsProcessed `json:"newRequests"`
+	// PrayersMade are the prayers made for the community's requests, by anyone
+	PrayersMade DatePointsProcessed `json:"prayersMade"`
+	// ActivePrayingMembers is the number of members who prayed for a community request each day; the total is the
+	// number of distinct members over the whole range rather than the sum of the days
+	ActivePrayingMembers DatePointsProcessed `json:"activePrayingMembers"`
+	// NewMembers are the members accepted into the community
+	NewMembers DatePointsProcessed `json:"newMembers"`
+	// AnsweredRequests are the community's requests marked as answered, by the day they were marked
+	AnsweredRequests DatePointsProcessed   `json:"answeredRequests"`
+	Summary          CommunityStatsSummary `json:"summary"`
+}
+
+// CommunityStatsSummary holds the summary figures for the requests created in the range
+type CommunityStatsSummary struct {
+	RequestCount               int64   `json:"requestCount"`
+	AveragePrayersPerRequest   float64 `json:"averagePrayersPerRequest"`
+	MedianMinutesToFirstPrayer float64 `json:"medianMinutesToFirstPrayer"`
+	// AnsweredShare is the fraction, from 0 to 1, of the requests that have been marked as answered
+	AnsweredShare float64 `json:"answeredShare"`
+}
+
+// GetCommunityStats builds the stats for a community between two DB-formatted times
+func GetCommunityStats(communityID int64, start, end string) (*CommunityStats, error) {
+	stats := &CommunityStats{
+		CommunityID: communityID,
+	}
+	stats.Start, _ = ParseTimeToISO(start)
+	stats.End, _ = ParseTimeToISO(end)
+
+	var err error
+	stats.NewRequests, err = getCommunityStatsSeries(`SELECT DATE(pr.created) AS day, COUNT(*) AS count 
+		FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = 'approved' AND pr.created BETWEEN ? AND ? 
+		GROUP BY day ORDER BY day`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.PrayersMade, err = getCommunityStatsSeries(`SELECT DATE(p.whenPrayed) AS day, COUNT(*) AS count 
+		FROM Prayers p, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = p.prayerRequestId AND prcl.status = 'approved' AND p.whenPrayed BETWEEN ? AND ? 
+		GROUP BY day ORDER BY day`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ActivePrayingMembers, err = getCommunityStatsSeries(`SELECT DATE(p.whenPrayed) AS day, COUNT(DISTINCT p.userId) AS count 
+		FROM Prayers p, PrayerRequestCommunityLinks prcl, CommunityUserLinks cul 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = p.prayerRequestId AND prcl.status = 'approved' 
+		AND cul.communityId = prcl.communityId AND cul.userId = p.userId AND cul.status = 'accepted' AND p.whenPrayed BETWEEN ? AND ? 
+		GROUP BY day ORDER BY day`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	err = Config.DbConn.Get(&stats.ActivePrayingMembers.Total, `SELECT COUNT(DISTINCT p.userId) 
+		FROM Prayers p, PrayerRequestCommunityLinks prcl, CommunityUserLinks cul 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = p.prayerRequestId AND prcl.status = 'approved' 
+		AND cul.communityId = prcl.communityId AND cul.userId = p.userId AND cul.status = 'accepted' AND p.whenPrayed BETWEEN ? AND ?`,
+		communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.NewMembers, err = getCommunityStatsSeries(`SELECT DATE(cul.joined) AS day, COUNT(*) AS count 
+		FROM CommunityUserLinks cul 
+		WHERE cul.communityId = ? AND cul.status = 'accepted' AND cul.joined BETWEEN ? AND ? 
+		GROUP BY day ORDER BY day`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.AnsweredRequests, err = getCommunityStatsSeries(`SELECT DATE(pr.answered) AS day, COUNT(*) AS count 
+		FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = 'approved' AND pr.status = 'answered' 
+		AND pr.answered BETWEEN ? AND ? 
+		GROUP BY day ORDER BY day`, communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Summary, err = getCommunityStatsSummary(communityID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// getCommunityStatsSeries runs a query that returns day and count columns and fills in the gaps across the whole range
+func getCommunityStatsSeries(query string, communityID int64, start, end string) (DatePointsProcessed, error) {
+	points := []DatePoint{}
+	err := Config.DbConn.Select(&points, query, communityID, start, end)
+	if err != nil {
+		return DatePointsProcessed{}, err
+	}
+	return CreateDateRangesForReports(points, start, end)
+}
+
+// getCommunityStatsSummary calculates the summary figures for the community's requests created in the range
+func getCommunityStatsSummary(communityID int64, start, end string) (CommunityStatsSummary, error) {
+	summary := CommunityStatsSummary{}
+	requests := []struct {
+		Status               string `db:"status"`
+		PrayerCount          int64  `db:"prayerCount"`
+		MinutesToFirstPrayer *int64 `db:"minutesToFirstPrayer"`
+	}{}
+	err := Config.DbConn.Select(&requests, `SELECT pr.status, 
+		(SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount, 
+		(SELECT TIMESTAMPDIFF(MINUTE, pr.created, MIN(p.whenPrayed)) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS minutesToFirstPrayer 
+		FROM PrayerRequests pr, PrayerRequestCommunityLinks prcl 
+		WHERE prcl.communityId = ? AND prcl.prayerRequestId = pr.id AND prcl.status = 'approved' AND pr.created BETWEEN ? AND ?`,
+		communityID, start, end)
+	if err != nil {
+		return summary, err
+	}
+	summary.RequestCount = int64(len(requests))
+	if summary.RequestCount == 0 {
+		return summary, nil
+	}
+
+	prayers := int64(0)
+	answered := int64(0)
+	minutes := []int64{}
+	for i := range requests {
+		prayers += requests[i].PrayerCount
+		if requests[i].Status == PrayerRequestStatusAnswered {
+			answered++
+		}
+		if requests[i].MinutesToFirstPrayer != nil {
+			minutes = append(minutes, *requests[i].MinutesToFirstPrayer)
+		}
+	}
+	summary.AveragePrayersPerRequest = float64(prayers) / float64(summary.RequestCount)
+	summary.AnsweredShare = float64(answered) / float64(summary.RequestCount)
+	summary.MedianMinutesToFirstPrayer = median(minutes)
+	return summary, nil
+}
+
+// median finds the median of the values, or 0 if there are none
+func median(values []int64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+	middle := len(values) / 2
+	if len(values)%2 == 0 {
+		return float64(values[middle-1]+values[middle]) / 2
+	}
+	return float64(values[middle])
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// GetCommunityStatsRoute gets the daily time series and summary figures for a community. The start and end query parameters are
+// dates; the end defaults to today and the start defaults to CommunityStatsDefaultDays before the end
+func GetCommunityStatsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	end := time.Now().UTC()
+	if r.URL.Query().Get("end") != "" {
+		end, err = ParseTime(r.URL.Query().Get("end"))
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_stats_invalid_date", "end must be a date such as 2019-06-09", nil)
+			return
+		}
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -1*(CommunityStatsDefaultDays-1))
+	if r.URL.Query().Get("start") != "" {
+		start, err = ParseTime(r.URL.Query().Get("start"))
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "community_stats_invalid_date", "start must be a date such as 2019-06-09", nil)
+			return
+		}
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if start.After(end) {
+		SendError(w, http.StatusBadRequest, "community_stats_invalid_date", "start must be on or before end", nil)
+		return
+	}
+	if end.Sub(start).Hours()/24 >= CommunityStatsMaxDays {
+		SendError(w, http.StatusBadRequest, "community_stats_range_too_long", fmt.Sprintf("the range can be at most %d days", CommunityStatsMaxDays), nil)
+		return
+	}
+
+	stats, err := GetCommunityStats(communityID, start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02")+" 23:59:59")
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_stats_error", "could not generate the stats", err)
+		return
+	}
+	Send(w, http.StatusOK, stats)
+	return
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityStatsRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      "Stats Routes",
+		ShortCode: fmt.Sprintf("stats_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	// only admins can see the stats
+	code, _, _ := TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats", community.ID), b, GetCommunityStatsRoute, "", "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats", community.ID), b, GetCommunityStatsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// bad ranges
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats?start=yesterday", community.ID), b, GetCommunityStatsRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats?start=2019-02-01&end=2019-01-01", community.ID), b, GetCommunityStatsRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats?start=2017-01-01&end=2019-01-01", community.ID), b, GetCommunityStatsRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// the default is the last 30 days
+	code, res, _ := TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats", community.ID), b, GetCommunityStatsRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ := UnmarshalTestMap(res)
+	newMembers := body["newMembers"].(map[string]interface{})
+	assert.Equal(t, CommunityStatsDefaultDays, len(newMembers["data"].([]interface{})))
+	assert.Equal(t, float64(2), newMembers["total"])
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/communities/%d/stats?start=2019-01-01&end=2019-01-10", community.ID), b, GetCommunityStatsRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	prayersMade := body["prayersMade"].(map[string]interface{})
+	assert.Equal(t, 10, len(prayersMade["data"].([]interface{})))
+	assert.Equal(t, "2019-01-01T00:00:00Z", body["start"])
+}
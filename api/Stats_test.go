@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, float64(0), median([]int64{}))
+	assert.Equal(t, float64(5), median([]int64{5}))
+	assert.Equal(t, float64(3), median([]int64{9, 1, 3}))
+	assert.Equal(t, float64(2.5), median([]int64{4, 1, 3, 2}))
+}
+
+func TestCommunityStats(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      "Stats",
+		ShortCode: fmt.Sprintf("stats_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusInvited, "abc")
+
+	link, err := GetCommunityUserLink(community.ID, member.ID)
+	require.Nil(t, err)
+	assert.Equal(t, "", link.Joined)
+	err = UpdateCommunityUserLink(community.ID, member.ID, CommunityUserLinkStatusAccepted)
+	require.Nil(t, err)
+	link, err = GetCommunityUserLink(community.ID, member.ID)
+	require.Nil(t, err)
+	assert.NotEqual(t, "", link.Joined)
+
+	answered := PrayerRequest{
+		Title:     "Answered",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&answered)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(answered.ID)
+	AddPrayerRequestToCommunity(answered.ID, community.ID)
+	pending := PrayerRequest{
+		Title:     "Pending",
+		Body:      "Please pray",
+		CreatedBy: admin.ID,
+	}
+	err = CreatePrayerRequest(&pending)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(pending.ID)
+	AddPrayerRequestToCommunity(pending.ID, community.ID)
+
+	AddPrayerMade(admin.ID, answered.ID)
+	AddPrayerMade(member.ID, answered.ID)
+	AddPrayerMade(admin.ID, pending.ID)
+
+	answered.Status = PrayerRequestStatusAnswered
+	err = UpdatePrayerRequest(&answered)
+	require.Nil(t, err)
+	found, err := GetPrayerRequest(answered.ID)
+	require.Nil(t, err)
+	assert.NotEqual(t, "", found.Answered)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	start := time.Now().UTC().AddDate(0, 0, -6).Format("2006-01-02")
+	stats, err := GetCommunityStats(community.ID, start+" 00:00:00", today+" 23:59:59")
+	require.Nil(t, err)
+	assert.Equal(t, 7, len(stats.NewRequests.Data))
+	assert.Equal(t, int64(2), stats.NewRequests.Total)
+	assert.Equal(t, int64(3), stats.PrayersMade.Total)
+	assert.Equal(t, int64(2), stats.ActivePrayingMembers.Total)
+	assert.Equal(t, int64(2), stats.NewMembers.Total)
+	assert.Equal(t, int64(1), stats.AnsweredRequests.Total)
+	assert.Equal(t, today, stats.PrayersMade.Data[6].Day[0:10])
+	assert.Equal(t, int64(3), stats.PrayersMade.Data[6].Count)
+
+	assert.Equal(t, int64(2), stats.Summary.RequestCount)
+	assert.Equal(t, 1.5, stats.Summary.AveragePrayersPerRequest)
+	assert.Equal(t, 0.5, stats.Summary.AnsweredShare)
+	assert.True(t, stats.Summary.MedianMinutesToFirstPrayer >= 0)
+
+	// an empty range has no requests to summarize
+	stats, err = GetCommunityStats(community.ID, "2017-01-01 00:00:00", "2017-01-31 23:59:59")
+	require.Nil(t, err)
+	assert.Equal(t, 31, len(stats.NewMembers.Data))
+	assert.Equal(t, int64(0), stats.Summary.RequestCount)
+	assert.Equal(t, float64(0), stats.Summary.AnsweredShare)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StoragePrivatePrefix starts keys that must never be served publicly, such as community exports; they can only be read
+// through routes that check permissions
+const StoragePrivatePrefix = "private/"
+
+// StorageBackend stores uploaded files, such as community logos. Keys are slash-separated paths, such as communities/1/logo.png
+type StorageBackend interface {
+	// Save writes the data to the key, replacing anything already there
+	Save(key string, data io.Reader) error
+	// Open opens the file at the key for reading
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the file at the key; deleting a key that does not exist is not an error
+	Delete(key string) error
+	// URL gets the public URL for the key
+	URL(key string) string
+}
+
+// LocalStorage is a StorageBackend that keeps files on the local filesystem and serves them through the API's /assets route
+type LocalStorage struct {
+	BasePath string
+	BaseURL  string
+}
+
+// NewLocalStorage creates a new local storage backend rooted at basePath
+func NewLocalStorage(basePath, baseURL string) *LocalStorage {
+	return &LocalStorage{
+		BasePath: basePath,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Save writes the data to the key, creating any needed directories
+func (s *LocalStorage) Save(key string, data io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, data)
+	return err
+}
+
+// Open opens the file at the key for reading
+func (s *LocalStorage) Open(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes the file at the key
+func (s *LocalStorage) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL gets the public URL for the key
+func (s *LocalStorage) URL(key string) string {
+	return fmt.Sprintf("%s/%s", s.BaseURL, key)
+}
+
+// path converts a key to a path on disk, refusing any key that would escape the base path
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+	if key == "" || cleaned == string(filepath.Separator) || strings.Contains(key, "..") {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.BasePath, cleaned), nil
+}
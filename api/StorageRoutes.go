@@ -0,0 +1,37 @@
+package api
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi"
+)
+
+// GetAssetRoute serves a file from the local storage backend, such as a community logo
+func GetAssetRoute(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+	if strings.HasPrefix(path.Clean("/"+key), "/"+StoragePrivatePrefix) {
+		SendError(w, http.StatusNotFound, "asset_not_found", "that file could not be found", nil)
+		return
+	}
+	file, err := Config.Storage.Open(key)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "asset_not_found", "that file could not be found", nil)
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	// keys change whenever the file does, so they can be cached for a long time
+	w.Header().Set("Cache-Control", "public, max-age=31536000")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+	return
+}
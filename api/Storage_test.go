@@ -0,0 +1,47 @@
+package api
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pregxas-storage")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	storage := NewLocalStorage(dir, "http://localhost:8080/assets/")
+	assert.Equal(t, "http://localhost:8080/assets/communities/1/logo.png", storage.URL("communities/1/logo.png"))
+
+	err = storage.Save("communities/1/logo.png", strings.NewReader("logo"))
+	require.Nil(t, err)
+
+	file, err := storage.Open("communities/1/logo.png")
+	require.Nil(t, err)
+	data, err := ioutil.ReadAll(file)
+	file.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, "logo", string(data))
+
+	err = storage.Delete("communities/1/logo.png")
+	assert.Nil(t, err)
+	_, err = storage.Open("communities/1/logo.png")
+	assert.NotNil(t, err)
+
+	// deleting again is fine
+	err = storage.Delete("communities/1/logo.png")
+	assert.Nil(t, err)
+
+	// keys cannot escape the base path
+	err = storage.Save("../escape.png", strings.NewReader("nope"))
+	assert.NotNil(t, err)
+	_, err = storage.Open("communities/../../escape.png")
+	assert.NotNil(t, err)
+	err = storage.Save("", strings.NewReader("nope"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,267 @@
+package api
+
+// CommunitySubGroup is a smaller group of members inside of a community, such as a small group or a ministry team. Sub-groups
+// are not billed separately; their members must be members of the parent community and their requests count toward the
+// parent's plan limits
+type CommunitySubGroup struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	Created     string `json:"created" db:"created"`
+
+	// UserRole is only populated in queries in which a user is joined to the sub-group
+	UserRole     string `json:"userRole,omitempty" db:"userRole"`
+	MemberCount  int64  `json:"memberCount" db:"memberCount"`
+	RequestCount int64  `json:"requestCount" db:"requestCount"`
+}
+
+// CommunitySubGroupUserLink is a link between a user and a sub-group
+type CommunitySubGroupUserLink struct {
+	SubGroupID int64  `json:"subGroupId" db:"subGroupId"`
+	UserID     int64  `json:"userId" db:"userId"`
+	Role       string `json:"role" db:"role"`
+	FirstName  string `json:"firstName" db:"firstName"`
+	LastName   string `json:"lastName" db:"lastName"`
+	Username   string `json:"username" db:"username"`
+}
+
+const (
+	// CommunitySubGroupRoleMember is a regular member of a sub-group
+	CommunitySubGroupRoleMember = "member"
+	// CommunitySubGroupRoleLeader can manage the sub-group's members and requests
+	CommunitySubGroupRoleLeader = "leader"
+)
+
+// CreateCommunitySubGroup creates a new sub-group in a community
+func CreateCommunitySubGroup(input *CommunitySubGroup) error {
+	defer input.processForAPI()
+	res, err := Config.DbConn.NamedExec(`INSERT INTO CommunitySubGroups (communityId, name, description, created) 
+		VALUES (:communityId, :name, :description, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// UpdateCommunitySubGroup updates the name and description of a sub-group
+func UpdateCommunitySubGroup(input *CommunitySubGroup) error {
+	_, err := Config.DbConn.NamedExec("UPDATE CommunitySubGroups SET name = :name, description = :description WHERE id = :id AND communityId = :communityId", input)
+	return err
+}
+
+// GetCommunitySubGroup gets a single sub-group in a community
+func GetCommunitySubGroup(communityID, subGroupID int64) (*CommunitySubGroup, error) {
+	found := &CommunitySubGroup{}
+	err := Config.DbConn.Get(found, `SELECT sg.*,
+	(SELECT COUNT(*) FROM CommunitySubGroupUserLinks sgul WHERE sgul.subGroupId = sg.id) AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestSubGroupLinks prsl WHERE prsl.subGroupId = sg.id) AS requestCount
+	FROM CommunitySubGroups sg WHERE sg.id = ? AND sg.communityId = ?`, subGroupID, communityID)
+	found.processForAPI()
+	return found, err
+}
+
+// GetCommunitySubGroups gets all of the sub-groups in a community along with the user's role in each, if any
+func GetCommunitySubGroups(communityID, userID int64) ([]CommunitySubGroup, error) {
+	groups := []CommunitySubGroup{}
+	err := Config.DbConn.Select(&groups, `SELECT sg.*, IFNULL(sgul.role, '') AS userRole,
+	(SELECT COUNT(*) FROM CommunitySubGroupUserLinks sgul2 WHERE sgul2.subGroupId = sg.id) AS memberCount,
+	(SELECT COUNT(*) FROM PrayerRequestSubGroupLinks prsl WHERE prsl.subGroupId = sg.id) AS requestCount
+	FROM CommunitySubGroups sg LEFT JOIN CommunitySubGroupUserLinks sgul ON sgul.subGroupId = sg.id AND sgul.userId = ?
+	WHERE sg.communityId = ? ORDER BY sg.name`, userID, communityID)
+	for i := range groups {
+		groups[i].processForAPI()
+	}
+	return groups, err
+}
+
+// GetCountOfSubGroupsInCommunity gets the number of sub-groups in a community
+func GetCountOfSubGroupsInCommunity(communityID int64) (int64, error) {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, "SELECT COUNT(*) FROM CommunitySubGroups WHERE communityId = ?", communityID)
+	return count, err
+}
+
+// DeleteCommunitySubGroup deletes a sub-group and all of its links
+func DeleteCommunitySubGroup(communityID, subGroupID int64) error {
+	res, err := Config.DbConn.Exec("DELETE FROM CommunitySubGroups WHERE id = ? AND communityId = ?", subGroupID, communityID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunitySubGroupUserLinks WHERE subGroupId = ?", subGroupID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM PrayerRequestSubGroupLinks WHERE subGroupId = ?", subGroupID)
+	return err
+}
+
+// DeleteCommunitySubGroupsForCommunity deletes all of the sub-groups in a community
+func DeleteCommunitySubGroupsForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE sgul FROM CommunitySubGroupUserLinks sgul 
+		INNER JOIN CommunitySubGroups sg ON sg.id = sgul.subGroupId WHERE sg.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`DELETE prsl FROM PrayerRequestSubGroupLinks prsl 
+		INNER JOIN CommunitySubGroups sg ON sg.id = prsl.subGroupId WHERE sg.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM CommunitySubGroups WHERE communityId = ?", communityID)
+	return err
+}
+
+// SetCommunitySubGroupUserLink adds a user to a sub-group or changes their role if they are already in it
+func SetCommunitySubGroupUserLink(subGroupID, userID int64, role string) error {
+	if role != CommunitySubGroupRoleLeader {
+		role = CommunitySubGroupRoleMember
+	}
+	_, err := Config.DbConn.Exec(`INSERT INTO CommunitySubGroupUserLinks (subGroupId, userId, role) VALUES (?, ?, ?) 
+		ON DUPLICATE KEY UPDATE role = VALUES(role)`, subGroupID, userID, role)
+	return err
+}
+
+// GetCommunitySubGroupUserLinks gets the members of a sub-group, leaders first
+func GetCommunitySubGroupUserLinks(subGroupID int64) ([]CommunitySubGroupUserLink, error) {
+	links := []CommunitySubGroupUserLink{}
+	err := Config.DbConn.Select(&links, `SELECT sgul.*, u.firstName, u.lastName, u.username FROM CommunitySubGroupUserLinks sgul, Users u 
+		WHERE sgul.subGroupId = ? AND sgul.userId = u.id ORDER BY sgul.role = 'leader' DESC, u.username`, subGroupID)
+	return links, err
+}
+
+// GetUserRoleForCommunitySubGroup gets the role the user has in a sub-group
+func GetUserRoleForCommunitySubGroup(subGroupID, userID int64) (string, error) {
+	role := ""
+	err := Config.DbConn.Get(&role, "SELECT role FROM CommunitySubGroupUserLinks WHERE subGroupId = ? AND userId = ?", subGroupID, userID)
+	return role, err
+}
+
+// DeleteCommunitySubGroupUserLink removes a user from a sub-group
+func DeleteCommunitySubGroupUserLink(subGroupID, userID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM CommunitySubGroupUserLinks WHERE subGroupId = ? AND userId = ?", subGroupID, userID)
+	return err
+}
+
+// RemoveUserFromCommunitySubGroups removes the user from every sub-group in a community; this should be called whenever
+// they leave the parent community
+func RemoveUserFromCommunitySubGroups(communityID, userID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE sgul FROM CommunitySubGroupUserLinks sgul 
+		INNER JOIN CommunitySubGroups sg ON sg.id = sgul.subGroupId WHERE sg.communityId = ? AND sgul.userId = ?`, communityID, userID)
+	return err
+}
+
+// AddPrayerRequestToCommunitySubGroup shares a request with a sub-group
+func AddPrayerRequestToCommunitySubGroup(requestID, subGroupID int64) error {
+	_, err := Config.DbConn.Exec(`INSERT INTO PrayerRequestSubGroupLinks (prayerRequestId, subGroupId, added) VALUES (?, ?, NOW()) 
+		ON DUPLICATE KEY UPDATE prayerRequestId = prayerRequestId`, requestID, subGroupID)
+	return err
+}
+
+// RemovePrayerRequestFromCommunitySubGroup stops sharing a request with a sub-group
+func RemovePrayerRequestFromCommunitySubGroup(requestID, subGroupID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestSubGroupLinks WHERE prayerRequestId = ? AND subGroupId = ?", requestID, subGroupID)
+	return err
+}
+
+// IsPrayerRequestInCommunitySubGroup checks if a request has been shared with a sub-group
+func IsPrayerRequestInCommunitySubGroup(requestID, subGroupID int64) bool {
+	count := int64(0)
+	Config.DbConn.Get(&count, "SELECT COUNT(*) FROM PrayerRequestSubGroupLinks WHERE prayerRequestId = ? AND subGroupId = ?", requestID, subGroupID)
+	return count > 0
+}
+
+// GetPrayerRequestsForCommunitySubGroup gets the requests shared with a sub-group, narrowed and ordered by the filter
+func GetPrayerRequestsForCommunitySubGroup(subGroupID int64, filter PrayerRequestFeedFilter, count, offset int) []PrayerRequest {
+	requests := []PrayerRequest{}
+	clause, filterArgs := filter.clause()
+	cursorClause, cursorArgs := filter.cursorClause()
+	args := append([]interface{}{subGroupID}, filterArgs...)
+	args = append(args, cursorArgs...)
+	args = append(args, offset, count)
+	Config.DbConn.Select(&requests, `SELECT pr.*, u.username, prsl.added, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
+		`+subGroupPrayerRequestsFrom+clause+cursorClause+`
+		ORDER BY `+filter.orderBy()+` LIMIT ?,?`, args...)
+	for i := range requests {
+		requests[i].processForAPI()
+	}
+	filter.arrange(requests)
+	populatePrayerRequestTags(requests)
+	return requests
+}
+
+// GetCountOfPrayerRequestsForCommunitySubGroup gets how many requests are in the sub-group's feed with the filter, ignoring any cursor
+func GetCountOfPrayerRequestsForCommunitySubGroup(subGroupID int64, filter PrayerRequestFeedFilter) (int64, error) {
+	total := int64(0)
+	clause, filterArgs := filter.clause()
+	args := append([]interface{}{subGroupID}, filterArgs...)
+	err := Config.DbConn.Get(&total, `SELECT COUNT(*) `+subGroupPrayerRequestsFrom+clause, args...)
+	return total, err
+}
+
+// subGroupPrayerRequestsFrom is where a sub-group's feed comes from, ready for the filter's conditions
+var subGroupPrayerRequestsFrom = `FROM PrayerRequests pr, Users u, PrayerRequestSubGroupLinks prsl, CommunitySubGroups sg 
+		WHERE prsl.subGroupId = ? AND prsl.prayerRequestId = pr.id AND pr.archived = '1970-01-01 00:00:00' AND pr.createdBy = u.id 
+		AND sg.id = prsl.subGroupId ` + prayerRequestVisibleInCommunity("sg.communityId")
+
+// IsUserAndRequestInSameSubGroup checks if the request has been shared with a sub-group the user belongs to. The user must still be
+// an accepted member of the parent community
+func IsUserAndRequestInSameSubGroup(userID, requestID int64) bool {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, `SELECT COUNT(*) FROM PrayerRequestSubGroupLinks prsl
+		INNER JOIN CommunitySubGroupUserLinks sgul ON sgul.subGroupId = prsl.subGroupId
+		INNER JOIN CommunitySubGroups sg ON sg.id = prsl.subGroupId
+		INNER JOIN CommunityUserLinks cul ON cul.communityId = sg.communityId AND cul.userId = sgul.userId
+		INNER JOIN Communities c ON c.id = sg.communityId
+		WHERE prsl.prayerRequestId = ? AND sgul.userId = ? AND cul.status = 'accepted' AND c.archived = '1970-01-01 00:00:00'`, requestID, userID)
+	if err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// GetCountOfActiveRequestsInCommunity gets the number of pending, unarchived requests shared with a community or any of its sub-groups, which
+// is what the plan's AllowedActiveRequests is checked against. A request shared in more than one place only counts once
+func GetCountOfActiveRequestsInCommunity(communityID int64) (int64, error) {
+	return getCountOfActiveRequestsInCommunityWith(communityID, 0)
+}
+
+// IsCommunityActiveRequestLimitReached checks if sharing the request with the community or one of its sub-groups would put the
+// community over its plan's AllowedActiveRequests. A request that is already counted never goes over the limit
+func IsCommunityActiveRequestLimitReached(community *Community, requestID int64) (bool, error) {
+	plan, ok := plans[community.Plan]
+	if !ok {
+		plan = plans[CommunityPlanFree]
+	}
+	count, err := getCountOfActiveRequestsInCommunityWith(community.ID, requestID)
+	if err != nil {
+		return false, err
+	}
+	return count > plan.AllowedActiveRequests, nil
+}
+
+func getCountOfActiveRequestsInCommunityWith(communityID, requestID int64) (int64, error) {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, `SELECT COUNT(DISTINCT pr.id) FROM PrayerRequests pr WHERE pr.status = 'pending' AND pr.archived = '1970-01-01 00:00:00' AND (
+		pr.id = ? OR
+		pr.id IN (SELECT prcl.prayerRequestId FROM PrayerRequestCommunityLinks prcl WHERE prcl.communityId = ? AND prcl.status != 'rejected') OR
+		pr.id IN (SELECT prsl.prayerRequestId FROM PrayerRequestSubGroupLinks prsl, CommunitySubGroups sg WHERE prsl.subGroupId = sg.id AND sg.communityId = ?))`,
+		requestID, communityID, communityID)
+	return count, err
+}
+
+// processForAPI cleans up the output
+func (input *CommunitySubGroup) processForAPI() {
+	if input == nil {
+		return
+	}
+	if input.Created == "1970-01-01 00:00:00" {
+		input.Created = ""
+	} else {
+		input.Created, _ = ParseTimeToISO(input.Created)
+	}
+}
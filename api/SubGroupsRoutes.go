@@ -0,0 +1,501 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *CommunitySubGroup) Bind(r *http.Request) error {
+	return nil
+}
+
+// Bind binds data
+func (data *CommunitySubGroupUserLink) Bind(r *http.Request) error {
+	return nil
+}
+
+// canManageCommunitySubGroup checks if the user is an admin of the parent community or a leader of the sub-group
+func canManageCommunitySubGroup(communityRole string, subGroupID, userID int64) bool {
+	if communityRole == "admin" {
+		return true
+	}
+	subGroupRole, _ := GetUserRoleForCommunitySubGroup(subGroupID, userID)
+	return subGroupRole == CommunitySubGroupRoleLeader
+}
+
+// CreateCommunitySubGroupRoute allows a community admin to create a sub-group, up to the number allowed by the community's plan
+func CreateCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	plan := plans[community.Plan]
+	currentCount, _ := GetCountOfSubGroupsInCommunity(communityID)
+	if plan.AllowedSubGroups <= currentCount {
+		SendError(w, http.StatusForbidden, "community_sub_group_limit_reached", "this community cannot create anymore sub-groups", map[string]interface{}{
+			"currentCount": currentCount,
+			"allowed":      plan.AllowedSubGroups,
+		})
+		return
+	}
+
+	input := CommunitySubGroup{}
+	render.Bind(r, &input)
+	input.Name, _ = sanitize(input.Name)
+	input.Description, _ = sanitize(input.Description)
+	if input.Name == "" {
+		SendError(w, http.StatusBadRequest, "community_sub_group_missing_data", "name is required", input)
+		return
+	}
+	input.CommunityID = communityID
+
+	err = CreateCommunitySubGroup(&input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_create_error", "could not create that sub-group", err)
+		return
+	}
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetCommunitySubGroupsRoute gets the sub-groups in a community for any member of the community
+func GetCommunitySubGroupsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	groups, err := GetCommunitySubGroups(communityID, jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_groups_error", "could not get the sub-groups", err)
+		return
+	}
+	Send(w, http.StatusOK, groups)
+	return
+}
+
+// GetCommunitySubGroupRoute gets a single sub-group
+func GetCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	group, err := GetCommunitySubGroup(communityID, subGroupID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+	group.UserRole, _ = GetUserRoleForCommunitySubGroup(subGroupID, jwtUser.ID)
+	Send(w, http.StatusOK, group)
+	return
+}
+
+// UpdateCommunitySubGroupRoute allows a community admin or sub-group leader to update the sub-group
+func UpdateCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || !canManageCommunitySubGroup(role, subGroupID, jwtUser.ID) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	group, err := GetCommunitySubGroup(communityID, subGroupID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	input := CommunitySubGroup{}
+	render.Bind(r, &input)
+	if input.Name != "" {
+		group.Name, _ = sanitize(input.Name)
+	}
+	if input.Description != "" {
+		group.Description, _ = sanitize(input.Description)
+	}
+
+	err = UpdateCommunitySubGroup(group)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_update_error", "could not update that sub-group", err)
+		return
+	}
+	Send(w, http.StatusOK, group)
+	return
+}
+
+// DeleteCommunitySubGroupRoute allows a community admin to delete a sub-group. Requests shared with it are not deleted
+func DeleteCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = DeleteCommunitySubGroup(communityID, subGroupID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_delete_error", "could not delete that sub-group", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// GetCommunitySubGroupMembersRoute gets the members of a sub-group; it is available to the sub-group's members and the community's admins
+func GetCommunitySubGroupMembersRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	subGroupRole, _ := GetUserRoleForCommunitySubGroup(subGroupID, jwtUser.ID)
+	if err != nil || (role != "admin" && subGroupRole == "") {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	links, err := GetCommunitySubGroupUserLinks(subGroupID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_members_error", "could not get the members", err)
+		return
+	}
+	Send(w, http.StatusOK, links)
+	return
+}
+
+// SetCommunitySubGroupMemberRoute adds a member of the community to a sub-group or changes their role. Only community admins and
+// sub-group leaders can do this
+func SetCommunitySubGroupMemberRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	userID, userIDErr := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil || userIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || !canManageCommunitySubGroup(role, subGroupID, jwtUser.ID) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	// sub-group members must be members of the parent so that they count toward its plan
+	memberRole, err := GetUserRoleForCommunity(communityID, userID)
+	if err != nil || memberRole == "" {
+		SendError(w, http.StatusBadRequest, "community_sub_group_not_community_member", "that user must be a member of the community first", nil)
+		return
+	}
+
+	input := CommunitySubGroupUserLink{}
+	render.Bind(r, &input)
+	if input.Role == "" {
+		input.Role = CommunitySubGroupRoleMember
+	}
+	if input.Role != CommunitySubGroupRoleMember && input.Role != CommunitySubGroupRoleLeader {
+		SendError(w, http.StatusBadRequest, "community_sub_group_invalid_role", "role must be either member or leader", input)
+		return
+	}
+
+	err = SetCommunitySubGroupUserLink(subGroupID, userID, input.Role)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_member_error", "could not add that member", err)
+		return
+	}
+
+	links, _ := GetCommunitySubGroupUserLinks(subGroupID)
+	Send(w, http.StatusOK, links)
+	return
+}
+
+// RemoveCommunitySubGroupMemberRoute removes a member from a sub-group. Community admins and sub-group leaders can remove anyone and
+// members can remove themselves
+func RemoveCommunitySubGroupMemberRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	userID, userIDErr := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil || userIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || (userID != jwtUser.ID && !canManageCommunitySubGroup(role, subGroupID, jwtUser.ID)) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	err = DeleteCommunitySubGroupUserLink(subGroupID, userID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "community_sub_group_member_error", "could not remove that member", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"removed": true,
+	})
+	return
+}
+
+// GetCommunitySubGroupPrayerRequestsRoute gets the requests shared with a sub-group; it is available to the sub-group's members
+// and the community's admins
+func GetCommunitySubGroupPrayerRequestsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	subGroupRole, _ := GetUserRoleForCommunitySubGroup(subGroupID, jwtUser.ID)
+	if err != nil || (role != "admin" && subGroupRole == "") {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	filter, err := getPrayerRequestFeedFilterFromRequest(r, jwtUser, role == "admin")
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_feed_bad_filter", err.Error(), nil)
+		return
+	}
+	requests := GetPrayerRequestsForCommunitySubGroup(subGroupID, filter, count, offset)
+	hideAnonymousAuthors(jwtUser, role == "admin", requests)
+
+	page := filter.page(requests, count, offset)
+	if IsTotalRequested(r) {
+		total, _ := GetCountOfPrayerRequestsForCommunitySubGroup(subGroupID, filter)
+		page.Total = &total
+	}
+	SendPage(w, r, http.StatusOK, requests, page)
+	return
+}
+
+// AddPrayerRequestToCommunitySubGroupRoute shares a request with a sub-group the author belongs to, without sharing it with the
+// rest of the community
+func AddPrayerRequestToCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil || requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	request, err := GetPrayerRequest(requestID)
+	if err != nil || request.CreatedBy != jwtUser.ID {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	subGroupRole, _ := GetUserRoleForCommunitySubGroup(subGroupID, jwtUser.ID)
+	if err != nil || role == "" || subGroupRole == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	community, err := GetCommunityByID(communityID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+	limitReached, _ := IsCommunityActiveRequestLimitReached(community, requestID)
+	if limitReached {
+		SendError(w, http.StatusForbidden, "community_request_limit_reached", "this community cannot accept anymore active requests", map[string]interface{}{
+			"allowed": plans[community.Plan].AllowedActiveRequests,
+		})
+		return
+	}
+
+	err = AddPrayerRequestToCommunitySubGroup(requestID, subGroupID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_sub_group_add_error", "could not add that request to that sub-group", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"added": true,
+	})
+	return
+}
+
+// RemovePrayerRequestFromCommunitySubGroupRoute stops sharing a request with a sub-group. The author, the sub-group's leaders, and the
+// community's admins can do this
+func RemovePrayerRequestFromCommunitySubGroupRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	subGroupID, subGroupIDErr := strconv.ParseInt(chi.URLParam(r, "subGroupID"), 10, 64)
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if communityIDErr != nil || subGroupIDErr != nil || requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	request, err := GetPrayerRequest(requestID)
+	if err != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, _ := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if request.CreatedBy != jwtUser.ID && !canManageCommunitySubGroup(role, subGroupID, jwtUser.ID) {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetCommunitySubGroup(communityID, subGroupID); err != nil {
+		SendError(w, http.StatusNotFound, "community_sub_group_not_found", "that sub-group could not be found", nil)
+		return
+	}
+
+	err = RemovePrayerRequestFromCommunitySubGroup(requestID, subGroupID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_request_sub_group_remove_error", "could not remove that request from that sub-group", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"removed": true,
+	})
+	return
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunitySubGroupsRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	leader := User{}
+	err = CreateTestUser(&leader)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&leader)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	outsider := User{}
+	err = CreateTestUser(&outsider)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&outsider)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, leader.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	url := fmt.Sprintf("/communities/%d/groups", community.ID)
+
+	// only admins can create
+	b.Reset()
+	enc.Encode(map[string]string{
+		"name": "Tuesday Night",
+	})
+	code, _, _ := TestAPICall(http.MethodPost, url, b, CreateCommunitySubGroupRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"name": "Tuesday Night",
+	})
+	code, res, _ := TestAPICall(http.MethodPost, url, b, CreateCommunitySubGroupRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	group := CommunitySubGroup{}
+	mapstructure.Decode(body, &group)
+	require.NotZero(t, group.ID)
+	groupURL := fmt.Sprintf("%s/%d", url, group.ID)
+
+	code, res, _ = TestAPICall(http.MethodGet, url, b, GetCommunitySubGroupsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+	code, _, _ = TestAPICall(http.MethodGet, url, b, GetCommunitySubGroupsRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// the admin makes a leader, and the leader adds a member
+	b.Reset()
+	enc.Encode(map[string]string{
+		"role": CommunitySubGroupRoleLeader,
+	})
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/users/%d", groupURL, leader.ID), b, SetCommunitySubGroupMemberRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/users/%d", groupURL, member.ID), b, SetCommunitySubGroupMemberRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/users/%d", groupURL, member.ID), b, SetCommunitySubGroupMemberRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// outsiders of the parent cannot be added
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/users/%d", groupURL, outsider.ID), b, SetCommunitySubGroupMemberRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, groupURL+"/users", b, GetCommunitySubGroupMembersRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 2, len(bodyA))
+
+	// the member shares a request with just the sub-group
+	request := PrayerRequest{
+		Title:     "Small group",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+		Privacy:   "private",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("%s/requests/%d", groupURL, request.ID), b, AddPrayerRequestToCommunitySubGroupRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("%s/requests/%d", groupURL, request.ID), b, AddPrayerRequestToCommunitySubGroupRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, groupURL+"/requests", b, GetCommunitySubGroupPrayerRequestsRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+	code, _, _ = TestAPICall(http.MethodGet, groupURL+"/requests", b, GetCommunitySubGroupPrayerRequestsRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// the leader can take it down and the member can leave
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("%s/requests/%d", groupURL, request.ID), b, RemovePrayerRequestFromCommunitySubGroupRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("%s/users/%d", groupURL, member.ID), b, RemoveCommunitySubGroupMemberRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// update and delete
+	b.Reset()
+	enc.Encode(map[string]string{
+		"description": "We meet on Tuesdays",
+	})
+	code, res, _ = TestAPICall(http.MethodPatch, groupURL, b, UpdateCommunitySubGroupRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	mapstructure.Decode(body, &group)
+	assert.Equal(t, "We meet on Tuesdays", group.Description)
+
+	code, _, _ = TestAPICall(http.MethodDelete, groupURL, b, DeleteCommunitySubGroupRoute, leader.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, groupURL, b, DeleteCommunitySubGroupRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodGet, groupURL, b, GetCommunitySubGroupRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
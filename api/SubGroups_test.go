@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunitySubGroups(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	outsider := User{}
+	err = CreateTestUser(&outsider)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&outsider)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	group := CommunitySubGroup{
+		CommunityID: community.ID,
+		Name:        "Tuesday Night",
+	}
+	err = CreateCommunitySubGroup(&group)
+	require.Nil(t, err)
+	assert.NotZero(t, group.ID)
+
+	count, err := GetCountOfSubGroupsInCommunity(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	err = SetCommunitySubGroupUserLink(group.ID, admin.ID, CommunitySubGroupRoleLeader)
+	assert.Nil(t, err)
+	err = SetCommunitySubGroupUserLink(group.ID, member.ID, "")
+	assert.Nil(t, err)
+	links, err := GetCommunitySubGroupUserLinks(group.ID)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(links))
+	assert.Equal(t, admin.ID, links[0].UserID)
+	assert.Equal(t, CommunitySubGroupRoleLeader, links[0].Role)
+
+	groups, err := GetCommunitySubGroups(community.ID, member.ID)
+	assert.Nil(t, err)
+	require.Equal(t, 1, len(groups))
+	assert.Equal(t, CommunitySubGroupRoleMember, groups[0].UserRole)
+	assert.Equal(t, int64(2), groups[0].MemberCount)
+
+	// a private request shared only with the sub-group is visible to its members but not the rest of the community
+	request := PrayerRequest{
+		Title:     "Small group",
+		Body:      "Please pray",
+		CreatedBy: admin.ID,
+		Privacy:   "private",
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+
+	assert.False(t, IsUserAndRequestInSameGroup(member.ID, request.ID))
+	err = AddPrayerRequestToCommunitySubGroup(request.ID, group.ID)
+	assert.Nil(t, err)
+	assert.True(t, IsPrayerRequestInCommunitySubGroup(request.ID, group.ID))
+	assert.True(t, IsUserAndRequestInSameGroup(member.ID, request.ID))
+	assert.False(t, IsUserAndRequestInSameGroup(outsider.ID, request.ID))
+	assert.Zero(t, len(GetPrayerRequestsForCommunity(community.ID, PrayerRequestFeedFilter{}, false, 100, 0)))
+	assert.Equal(t, 1, len(GetPrayerRequestsForCommunitySubGroup(group.ID, PrayerRequestFeedFilter{}, 100, 0)))
+
+	// sub-group requests count toward the parent's active requests
+	active, err := GetCountOfActiveRequestsInCommunity(community.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), active)
+	reached, err := IsCommunityActiveRequestLimitReached(&community, request.ID)
+	assert.Nil(t, err)
+	assert.False(t, reached)
+
+	// leaving the community removes the user from its sub-groups
+	err = DeleteCommunityUserLink(community.ID, member.ID)
+	assert.Nil(t, err)
+	role, _ := GetUserRoleForCommunitySubGroup(group.ID, member.ID)
+	assert.Equal(t, "", role)
+	assert.False(t, IsUserAndRequestInSameGroup(member.ID, request.ID))
+
+	err = RemovePrayerRequestFromCommunitySubGroup(request.ID, group.ID)
+	assert.Nil(t, err)
+	assert.False(t, IsPrayerRequestInCommunitySubGroup(request.ID, group.ID))
+
+	err = DeleteCommunitySubGroup(community.ID, group.ID)
+	assert.Nil(t, err)
+	_, err = GetCommunitySubGroup(community.ID, group.ID)
+	assert.NotNil(t, err)
+	links, _ = GetCommunitySubGroupUserLinks(group.ID)
+	assert.Zero(t, len(links))
+}
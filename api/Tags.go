@@ -1,6 +1,11 @@
 package api
 
-import "strings"
+import (
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
 
 // PrayerRequestTag is a tag applied to a prayer request
 type PrayerRequestTag struct {
@@ -14,9 +19,39 @@ type PrayerRequestTagLinks struct {
 	PrayerRequestID int64 `json:"prayerRequestId" db:"prayerRequestId"`
 }
 
-// AddTagToPrayerRequest adds a tag to a request; if the tag doesn't exist it will be created
+// PrayerRequestTagCount is a tag along with how many public requests use it
+type PrayerRequestTagCount struct {
+	ID    int64  `json:"id" db:"id"`
+	Tag   string `json:"tag" db:"tag"`
+	Count int64  `json:"count" db:"count"`
+}
+
+const (
+	// PrayerRequestTagMaxLength is the longest a tag can be
+	PrayerRequestTagMaxLength = 32
+	// PrayerRequestTagsMaxPerRequest is the most tags a single request can have
+	PrayerRequestTagsMaxPerRequest = 10
+)
+
+// NormalizeTag cleans up a tag so that the same tag typed differently is stored once. Tags are lowercase, can't have a
+// leading #, and use dashes instead of spaces. The second return is false if nothing usable is left or the tag is too long
+func NormalizeTag(tag string) (string, bool) {
+	tag, _ = sanitize(tag)
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+	if tag == "" || len(tag) > PrayerRequestTagMaxLength {
+		return tag, false
+	}
+	return tag, true
+}
+
+// AddTagToPrayerRequest adds a tag to a request; if the tag doesn't exist it will be created. Adding a tag the request already
+// has is not an error
 func AddTagToPrayerRequest(prayerRequestID int64, tag string) (PrayerRequestTag, error) {
-	tag = strings.ToLower(tag)
+	tag, ok := NormalizeTag(tag)
+	if !ok {
+		return PrayerRequestTag{}, errors.New("tag is invalid")
+	}
 	// first, find out if that tag exists already; if it does, link it to the existing
 	// if not, create it first
 	found, err := GetTagIDByTag(tag)
@@ -28,10 +63,21 @@ func AddTagToPrayerRequest(prayerRequestID int64, tag string) (PrayerRequestTag,
 		}
 		found.ID, _ = res.LastInsertId()
 		found.Tag = tag
+		if found.ID == 0 {
+			// someone else created it first
+			found, err = GetTagIDByTag(tag)
+			if err != nil {
+				return found, err
+			}
+		}
 	}
 	// link it
-	_, err = Config.DbConn.Exec("INSERT INTO PrayerRequestTagLinks (prayerRequestId, tagId) VALUES (?, ?)", prayerRequestID, found.ID)
-	return found, err
+	_, err = Config.DbConn.Exec("INSERT IGNORE INTO PrayerRequestTagLinks (prayerRequestId, tagId) VALUES (?, ?)", prayerRequestID, found.ID)
+	if err != nil {
+		return found, err
+	}
+	IndexPrayerRequest(prayerRequestID)
+	return found, nil
 }
 
 // GetTagIDByTag gets a tag by its name
@@ -42,6 +88,13 @@ func GetTagIDByTag(tag string) (PrayerRequestTag, error) {
 	return existingTag, err
 }
 
+// GetTagByID gets a tag by its id
+func GetTagByID(tagID int64) (PrayerRequestTag, error) {
+	tag := PrayerRequestTag{}
+	err := Config.DbConn.Get(&tag, "SELECT * FROM PrayerRequestTags WHERE id = ?", tagID)
+	return tag, err
+}
+
 // GetTagsOnRequest gets the tags on a request
 func GetTagsOnRequest(prayerRequestID int64) ([]PrayerRequestTag, error) {
 	tags := []PrayerRequestTag{}
@@ -49,10 +102,75 @@ func GetTagsOnRequest(prayerRequestID int64) ([]PrayerRequestTag, error) {
 	return tags, err
 }
 
-// RemoveTagFromRequest unlinks a tag and a request; a cron will clean up any orphaned tags we don't want to keep around
+// GetCountOfTagsOnRequest gets how many tags a request has
+func GetCountOfTagsOnRequest(prayerRequestID int64) (int64, error) {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, "SELECT COUNT(*) FROM PrayerRequestTagLinks WHERE prayerRequestId = ?", prayerRequestID)
+	return count, err
+}
+
+// GetPopularTags gets the tags used on the most public requests. Tags only used on private requests are left out so that
+// browsing tags can't reveal anything about requests the user can't see
+func GetPopularTags(count, offset int) ([]PrayerRequestTagCount, error) {
+	tags := []PrayerRequestTagCount{}
+	err := Config.DbConn.Select(&tags, `SELECT t.id, t.tag, COUNT(*) AS count FROM PrayerRequestTags t, PrayerRequestTagLinks l, PrayerRequests pr
+		WHERE t.id = l.tagId AND l.prayerRequestId = pr.id AND pr.privacy = 'public' AND pr.archived = '1970-01-01 00:00:00'
+		GROUP BY t.id, t.tag ORDER BY count DESC, t.tag LIMIT ?,?`, offset, count)
+	return tags, err
+}
+
+// GetTagsStartingWith autocompletes a tag for the user. Like GetPopularTags, only tags on public requests or on the user's own
+// requests are suggested, and archived requests are left out
+func GetTagsStartingWith(userID int64, prefix string, count int) ([]PrayerRequestTagCount, error) {
+	tags := []PrayerRequestTagCount{}
+	prefix = strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(prefix)
+	err := Config.DbConn.Select(&tags, `SELECT t.id, t.tag, COUNT(*) AS count FROM PrayerRequestTags t, PrayerRequestTagLinks l, PrayerRequests pr
+		WHERE t.tag LIKE ? AND t.id = l.tagId AND l.prayerRequestId = pr.id AND (pr.privacy = 'public' OR pr.createdBy = ?)
+		AND pr.archived = '1970-01-01 00:00:00' GROUP BY t.id, t.tag ORDER BY count DESC, t.tag LIMIT ?`, prefix+"%", userID, count)
+	return tags, err
+}
+
+// populatePrayerRequestTags loads the tags for a list of requests in one query
+func populatePrayerRequestTags(requests []PrayerRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	ids := []int64{}
+	byID := map[int64][]int{}
+	for i := range requests {
+		requests[i].Tags = []string{}
+		ids = append(ids, requests[i].ID)
+		byID[requests[i].ID] = append(byID[requests[i].ID], i)
+	}
+	query, args, err := sqlx.In(`SELECT l.prayerRequestId, t.tag FROM PrayerRequestTagLinks l, PrayerRequestTags t
+		WHERE l.prayerRequestId IN (?) AND l.tagId = t.id ORDER BY t.tag`, ids)
+	if err != nil {
+		return err
+	}
+	links := []struct {
+		PrayerRequestID int64  `db:"prayerRequestId"`
+		Tag             string `db:"tag"`
+	}{}
+	err = Config.DbConn.Select(&links, Config.DbConn.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	for i := range links {
+		for _, index := range byID[links[i].PrayerRequestID] {
+			requests[index].Tags = append(requests[index].Tags, links[i].Tag)
+		}
+	}
+	return nil
+}
+
+// RemoveTagFromRequest unlinks a tag and a request; PurgeOrphanedTags will clean up any orphaned tags we don't want to keep around
 func RemoveTagFromRequest(prayerRequestID, tagID int64) error {
 	_, err := Config.DbConn.Exec("DELETE FROM PrayerRequestTagLinks WHERE prayerRequestId = ? AND tagId = ? LIMIT 1", prayerRequestID, tagID)
-	return err
+	if err != nil {
+		return err
+	}
+	IndexPrayerRequest(prayerRequestID)
+	return nil
 }
 
 // DeleteTag deletes a tag from the database and should only be used by admins or tests
@@ -67,3 +185,39 @@ func DeleteTag(tagID int64) error {
 	}
 	return nil
 }
+
+// MergeTags moves every request tagged with one tag to another and deletes the first, for admins cleaning up duplicates and
+// misspellings. Requests that already have both tags end up with just the one
+func MergeTags(fromTagID, intoTagID int64) error {
+	requestIDs := []int64{}
+	err := Config.DbConn.Select(&requestIDs, "SELECT prayerRequestId FROM PrayerRequestTagLinks WHERE tagId = ?", fromTagID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`INSERT IGNORE INTO PrayerRequestTagLinks (prayerRequestId, tagId)
+		SELECT prayerRequestId, ? FROM PrayerRequestTagLinks WHERE tagId = ?`, intoTagID, fromTagID)
+	if err != nil {
+		return err
+	}
+	err = DeleteTag(fromTagID)
+	if err != nil {
+		return err
+	}
+	for i := range requestIDs {
+		IndexPrayerRequest(requestIDs[i])
+	}
+	return nil
+}
+
+// PurgeOrphanedTags removes links to requests that no longer exist and then the tags that no request uses. It runs as a
+// scheduled task
+func PurgeOrphanedTags() error {
+	_, err := Config.DbConn.Exec(`DELETE l FROM PrayerRequestTagLinks l LEFT JOIN PrayerRequests pr ON pr.id = l.prayerRequestId
+		WHERE pr.id IS NULL`)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`DELETE t FROM PrayerRequestTags t LEFT JOIN PrayerRequestTagLinks l ON l.tagId = t.id
+		WHERE l.tagId IS NULL`)
+	return err
+}
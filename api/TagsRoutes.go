@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *PrayerRequestTag) Bind(r *http.Request) error {
+	return nil
+}
+
+type tagMergeInput struct {
+	IntoTagID int64 `json:"intoTagId"`
+}
+
+// Bind binds the data for the HTTP
+func (data *tagMergeInput) Bind(r *http.Request) error {
+	return nil
+}
+
+// GetPopularTagsRoute gets the tags used on the most public requests
+func GetPopularTagsRoute(w http.ResponseWriter, r *http.Request) {
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	tags, err := GetPopularTags(count, offset)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "tag_error", "could not get the tags", err)
+		return
+	}
+	SendPage(w, r, http.StatusOK, tags, offsetPage(count, offset, len(tags)))
+	return
+}
+
+// GetTagAutocompleteRoute suggests existing tags that start with the q query param, most used first
+func GetTagAutocompleteRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	prefix, ok := NormalizeTag(r.URL.Query().Get("q"))
+	if !ok {
+		Send(w, http.StatusOK, []PrayerRequestTagCount{})
+		return
+	}
+	_, _, count, _, _, _, _, _ := ProcessQuery(r)
+	tags, err := GetTagsStartingWith(jwtUser.ID, prefix, count)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "tag_error", "could not get the tags", err)
+		return
+	}
+	Send(w, http.StatusOK, tags)
+	return
+}
+
+// AddTagToPrayerRequestRoute tags a request. Only the author can tag their request
+func AddTagToPrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
+	_, request, ok := getOwnPrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	input := PrayerRequestTag{}
+	render.Bind(r, &input)
+	tag, ok := NormalizeTag(input.Tag)
+	if !ok {
+		SendError(w, http.StatusBadRequest, "tag_bad_data", fmt.Sprintf("tag is required and can be at most %d characters", PrayerRequestTagMaxLength), input)
+		return
+	}
+	tagCount, err := GetCountOfTagsOnRequest(request.ID)
+	if err != nil || (tagCount >= PrayerRequestTagsMaxPerRequest && !containsString(request.Tags, tag)) {
+		SendError(w, http.StatusBadRequest, "tag_limit_reached", fmt.Sprintf("a request can have at most %d tags", PrayerRequestTagsMaxPerRequest), nil)
+		return
+	}
+
+	added, err := AddTagToPrayerRequest(request.ID, tag)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "tag_bad_data", "the tag could not be added", err)
+		return
+	}
+	Send(w, http.StatusCreated, added)
+	return
+}
+
+// RemoveTagFromPrayerRequestRoute removes a tag from a request. Only the author can untag their request
+func RemoveTagFromPrayerRequestRoute(w http.ResponseWriter, r *http.Request) {
+	_, request, ok := getOwnPrayerRequestFromRequest(w, r)
+	if !ok {
+		return
+	}
+	tagID, tagIDErr := strconv.ParseInt(chi.URLParam(r, "tagID"), 10, 64)
+	if tagIDErr != nil {
+		SendError(w, http.StatusNotFound, "tag_not_found", "that tag could not be found", nil)
+		return
+	}
+	tag, err := GetTagByID(tagID)
+	if err != nil || !containsString(request.Tags, tag.Tag) {
+		SendError(w, http.StatusNotFound, "tag_not_found", "that tag could not be found", nil)
+		return
+	}
+
+	err = RemoveTagFromRequest(request.ID, tagID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "tag_error", "could not remove the tag", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// MergeTagsRoute moves every request with the tag in the url to the tag in the body and deletes the first. It is only for
+// platform admins
+func MergeTagsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 || jwtUser.PlatformRole != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+	tagID, tagIDErr := strconv.ParseInt(chi.URLParam(r, "tagID"), 10, 64)
+	if tagIDErr != nil {
+		SendError(w, http.StatusNotFound, "tag_not_found", "that tag could not be found", nil)
+		return
+	}
+	if _, err := GetTagByID(tagID); err != nil {
+		SendError(w, http.StatusNotFound, "tag_not_found", "that tag could not be found", nil)
+		return
+	}
+
+	input := tagMergeInput{}
+	render.Bind(r, &input)
+	into, err := GetTagByID(input.IntoTagID)
+	if err != nil || into.ID == tagID {
+		SendError(w, http.StatusBadRequest, "tag_bad_data", "intoTagId must be a different, existing tag", input)
+		return
+	}
+
+	err = MergeTags(tagID, into.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "tag_error", "could not merge the tags", err)
+		return
+	}
+	Send(w, http.StatusOK, into)
+	return
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&author)
+	other := User{}
+	err = CreateTestUser(&other)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&other)
+	admin := User{
+		PlatformRole: "admin",
+	}
+	err = CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	randID := rand.Int63n(99999999)
+	tag := fmt.Sprintf("route-%d", randID)
+
+	b.Reset()
+	enc := json.NewEncoder(b)
+	enc.Encode(map[string]interface{}{
+		"title":   "Tagged on create",
+		"body":    "Please pray",
+		"privacy": PrayerRequestPrivacyPublic,
+		"tags":    []string{"#" + tag, tag},
+	})
+	code, res, _ := TestAPICall(http.MethodPost, "/requests", b, CreatePrayerRequestRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	request := PrayerRequest{}
+	mapstructure.Decode(body, &request)
+	defer DeletePrayerRequest(request.ID)
+	assert.Equal(t, []string{tag}, request.Tags)
+	created, err := GetTagIDByTag(tag)
+	require.Nil(t, err)
+	defer DeleteTag(created.ID)
+
+	// the feed can be filtered by tag
+	code, res, _ = TestAPICall(http.MethodGet, "/requests?tag="+tag, b, GetGlobalPrayerRequestsRoute, author.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(list))
+
+	code, res, _ = TestAPICall(http.MethodGet, "/tags/autocomplete?q=route-", b, GetTagAutocompleteRoute, other.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, list, _ = UnmarshalTestArray(res)
+	assert.NotZero(t, len(list))
+	code, _, _ = TestAPICall(http.MethodGet, "/tags", b, GetPopularTagsRoute, "", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// only the author can add or remove tags
+	second := fmt.Sprintf("second-%d", randID)
+	b.Reset()
+	enc.Encode(map[string]string{
+		"tag": second,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/tags", request.ID), b, AddTagToPrayerRequestRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"tag": "",
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/tags", request.ID), b, AddTagToPrayerRequestRoute, author.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]string{
+		"tag": second,
+	})
+	code, res, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/requests/%d/tags", request.ID), b, AddTagToPrayerRequestRoute, author.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	secondTag := PrayerRequestTag{}
+	mapstructure.Decode(body, &secondTag)
+	defer DeleteTag(secondTag.ID)
+	assert.Equal(t, second, secondTag.Tag)
+
+	// admins can merge the second tag into the first
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"intoTagId": created.ID,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/admin/tags/%d/merge", secondTag.ID), b, MergeTagsRoute, author.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"intoTagId": secondTag.ID,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/admin/tags/%d/merge", secondTag.ID), b, MergeTagsRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"intoTagId": created.ID,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("/admin/tags/%d/merge", secondTag.ID), b, MergeTagsRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	found, err := GetPrayerRequest(request.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []string{tag}, found.Tags)
+
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/tags/%d", request.ID, created.ID), b, RemoveTagFromPrayerRequestRoute, other.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/tags/%d", request.ID, created.ID), b, RemoveTagFromPrayerRequestRoute, author.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/requests/%d/tags/%d", request.ID, created.ID), b, RemoveTagFromPrayerRequestRoute, author.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
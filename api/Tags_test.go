@@ -71,3 +71,109 @@ func TestTagCRD(t *testing.T) {
 	require.Equal(t, 1, len(found2))
 	assert.Equal(t, strings.ToLower(tag1), found2[0].Tag)
 }
+
+func TestNormalizeTag(t *testing.T) {
+	tag, ok := NormalizeTag("  #Healing  ")
+	assert.True(t, ok)
+	assert.Equal(t, "healing", tag)
+
+	tag, ok = NormalizeTag("Job  Search")
+	assert.True(t, ok)
+	assert.Equal(t, "job-search", tag)
+
+	_, ok = NormalizeTag("   ")
+	assert.False(t, ok)
+	_, ok = NormalizeTag("#")
+	assert.False(t, ok)
+	_, ok = NormalizeTag(strings.Repeat("a", PrayerRequestTagMaxLength+1))
+	assert.False(t, ok)
+}
+
+func TestTagFeedsMergeAndPurge(t *testing.T) {
+	ConfigSetup()
+	user := User{}
+	err := CreateTestUser(&user)
+	require.Nil(t, err)
+	defer DeleteUser(user.ID)
+
+	randID := rand.Int63n(99999999)
+	tag := fmt.Sprintf("feed-%d", randID)
+	misspelled := fmt.Sprintf("fead-%d", randID)
+
+	tagged := PrayerRequest{
+		Title:     "Tagged",
+		Body:      "Please pray",
+		CreatedBy: user.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+	}
+	err = CreatePrayerRequest(&tagged)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(tagged.ID)
+	untagged := PrayerRequest{
+		Title:     "Untagged",
+		Body:      "Please pray",
+		CreatedBy: user.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+	}
+	err = CreatePrayerRequest(&untagged)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(untagged.ID)
+
+	created, err := AddTagToPrayerRequest(tagged.ID, tag)
+	require.Nil(t, err)
+	defer DeleteTag(created.ID)
+	// adding it twice is fine
+	_, err = AddTagToPrayerRequest(tagged.ID, tag)
+	assert.Nil(t, err)
+	wrong, err := AddTagToPrayerRequest(untagged.ID, misspelled)
+	require.Nil(t, err)
+	defer DeleteTag(wrong.ID)
+
+	found, err := GetPrayerRequest(tagged.ID)
+	require.Nil(t, err)
+	assert.Equal(t, []string{tag}, found.Tags)
+
+	feed := GetGlobalPrayerRequests(PrayerRequestFeedFilter{Tag: tag}, 100, 0)
+	require.Equal(t, 1, len(feed))
+	assert.Equal(t, tagged.ID, feed[0].ID)
+	assert.Equal(t, []string{tag}, feed[0].Tags)
+
+	suggested, err := GetTagsStartingWith(0, "feed-", 100)
+	require.Nil(t, err)
+	foundSuggestion := false
+	for i := range suggested {
+		if suggested[i].Tag == tag {
+			foundSuggestion = true
+			assert.Equal(t, int64(1), suggested[i].Count)
+		}
+	}
+	assert.True(t, foundSuggestion)
+
+	// archived requests don't suggest their tags
+	_, err = Config.DbConn.Exec("UPDATE PrayerRequests SET archived = NOW() WHERE id = ?", tagged.ID)
+	require.Nil(t, err)
+	suggested, err = GetTagsStartingWith(0, "feed-", 100)
+	require.Nil(t, err)
+	for i := range suggested {
+		assert.NotEqual(t, tag, suggested[i].Tag)
+	}
+	err = MarkPrayerRequestActive(tagged.ID)
+	require.Nil(t, err)
+
+	// merging moves the misspelled tag's requests over
+	err = MergeTags(wrong.ID, created.ID)
+	require.Nil(t, err)
+	_, err = GetTagByID(wrong.ID)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, len(GetGlobalPrayerRequests(PrayerRequestFeedFilter{Tag: tag}, 100, 0)))
+
+	// once no request uses it, the purge removes it
+	err = RemoveTagFromRequest(tagged.ID, created.ID)
+	require.Nil(t, err)
+	err = RemoveTagFromRequest(untagged.ID, created.ID)
+	require.Nil(t, err)
+	err = PurgeOrphanedTags()
+	require.Nil(t, err)
+	_, err = GetTagByID(created.ID)
+	assert.NotNil(t, err)
+}
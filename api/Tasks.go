@@ -0,0 +1,105 @@
+package api
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduledTask is a job that runs in the background on an interval, such as sending reminders. Tasks are started
+// by the server with StartScheduledTasks and never run during tests unless called directly
+type ScheduledTask struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// scheduledTasks are all of the tasks the server runs; new tasks should be added here
+var scheduledTasks = []ScheduledTask{
+	{
+		Name:     "prayer_vigil_reminders",
+		Interval: time.Minute,
+		Run:      SendPrayerVigilReminders,
+	},
+	{
+		Name:     "archived_community_purge",
+		Interval: time.Hour,
+		Run:      PurgeArchivedCommunities,
+	},
+	{
+		Name:     "community_waitlist_promotion",
+		Interval: time.Hour,
+		Run:      PromoteCommunityWaitlists,
+	},
+	{
+		Name:     "webhook_deliveries",
+		Interval: time.Minute,
+		Run:      DeliverPendingWebhooks,
+	},
+	{
+		Name:     "community_exports",
+		Interval: time.Minute,
+		Run:      ProcessPendingCommunityExports,
+	},
+	{
+		Name:     "community_export_purge",
+		Interval: time.Hour,
+		Run:      PurgeExpiredCommunityExports,
+	},
+	{
+		Name:     "activitypub_deliveries",
+		Interval: time.Minute,
+		Run:      DeliverPendingActivityPubActivities,
+	},
+	{
+		Name:     "prayer_request_reminders",
+		Interval: time.Hour,
+		Run:      SendPrayerRequestReminders,
+	},
+	{
+		Name:     "prayer_request_archive",
+		Interval: time.Hour,
+		Run:      ArchiveUnansweredPrayerRequests,
+	},
+	{
+		Name:     "orphaned_tag_purge",
+		Interval: time.Hour,
+		Run:      PurgeOrphanedTags,
+	},
+}
+
+// GetScheduledTasks gets the registered tasks
+func GetScheduledTasks() []ScheduledTask {
+	return scheduledTasks
+}
+
+// StartScheduledTasks starts each scheduled task in its own goroutine
+func StartScheduledTasks() {
+	for i := range scheduledTasks {
+		go runScheduledTask(scheduledTasks[i])
+	}
+}
+
+func runScheduledTask(task ScheduledTask) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		RunScheduledTask(task)
+	}
+}
+
+// RunScheduledTask runs a task once, logging any errors or panics so that one bad run does not stop the server
+func RunScheduledTask(task ScheduledTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+		if err != nil {
+			Log("error", "scheduled task failed", "scheduled_task_error", map[string]string{
+				"task":  task.Name,
+				"error": err.Error(),
+			})
+		}
+	}()
+	err = task.Run()
+	return
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledTasks(t *testing.T) {
+	ConfigSetup()
+	for _, task := range GetScheduledTasks() {
+		assert.NotEqual(t, "", task.Name)
+		assert.True(t, task.Interval > 0)
+		assert.NotNil(t, task.Run)
+	}
+
+	ran := false
+	err := RunScheduledTask(ScheduledTask{
+		Name:     "test",
+		Interval: time.Minute,
+		Run: func() error {
+			ran = true
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.True(t, ran)
+
+	err = RunScheduledTask(ScheduledTask{
+		Name: "test_error",
+		Run: func() error {
+			return errors.New("failed")
+		},
+	})
+	assert.NotNil(t, err)
+
+	// a panic is recovered and returned as an error
+	err = RunScheduledTask(ScheduledTask{
+		Name: "test_panic",
+		Run: func() error {
+			panic("oh no")
+		},
+	})
+	assert.NotNil(t, err)
+}
@@ -15,6 +15,8 @@ const (
 	TokenPasswordReset = "password_reset"
 	//TokenRefresh is a refresh token used for refreshing a new access token, such as during expiration
 	TokenRefresh = "refresh"
+	// TokenCalendar is a long-lived token used in calendar feed URLs, since calendar apps cannot send a JWT
+	TokenCalendar = "calendar"
 )
 
 // Token represents a stringified token, such as for a password or email verification
@@ -37,6 +39,11 @@ func GenerateToken(userID int64, tokenType string) (token string, err error) {
 		hasher.Write([]byte(str))
 		hash := hex.EncodeToString(hasher.Sum(nil))
 		token = fmt.Sprintf("r%d_%s", userID, hash[0:20])
+	} else if tokenType == TokenCalendar {
+		str := fmt.Sprintf("c_%d%d-%d %s", userID, rand.Intn(100000000), r, tokenType)
+		hasher.Write([]byte(str))
+		hash := hex.EncodeToString(hasher.Sum(nil))
+		token = fmt.Sprintf("c%s", hash)
 	} else {
 		str := fmt.Sprintf("%d%d-%d %s", userID, rand.Intn(100000000), r, tokenType)
 		hasher.Write([]byte(str))
@@ -70,6 +77,20 @@ func VerifyToken(token, tokenType string) (userID int64, valid bool, err error)
 	return
 }
 
+// GetTokenForUser gets the existing token of a type for a user, if there is one
+func GetTokenForUser(userID int64, tokenType string) (string, error) {
+	token := Token{}
+	err := Config.DbConn.Get(&token, "SELECT * FROM UserTokens WHERE userId = ? AND tokenType = ? LIMIT 1", userID, tokenType)
+	return token.Token, err
+}
+
+// LookupToken finds the user for a token without removing it, which is needed for long-lived tokens such as calendar tokens
+func LookupToken(token, tokenType string) (userID int64, err error) {
+	found := Token{}
+	err = Config.DbConn.Get(&found, "SELECT * FROM UserTokens WHERE token = ? AND tokenType = ?", token, tokenType)
+	return found.UserID, err
+}
+
 // DeleteTokensCreatedBeforeTime deletes tokens created before a specific time
 func DeleteTokensCreatedBeforeTime(time string) error {
 	_, err := Config.DbConn.Exec("DELETE FROM UserTokens WHERE created < ?", time)
@@ -59,6 +59,7 @@ func CreateUser(input *User) error {
 		return err
 	}
 	input.ID, _ = res.LastInsertId()
+	IndexUser(input.ID)
 	return nil
 }
 
@@ -110,7 +111,11 @@ func UpdateUser(input *User) error {
 	query := `UPDATE Users SET firstName = :firstName, lastName = :lastName, email = :email, username = :username, password = :password, status = :status, updated = NOW() WHERE id = :id LIMIT 1`
 	_, err := Config.DbConn.NamedExec(query, input)
 	input.processForAPI()
-	return err
+	if err != nil {
+		return err
+	}
+	IndexUser(input.ID)
+	return nil
 }
 
 // GetUserByID gets a user by its ID
@@ -143,6 +148,15 @@ func DeleteUser(userID int64) {
 	Config.DbConn.Exec("DELETE FROM Users where id = ?", userID)
 	Config.DbConn.Exec("DELETE FROM CommunityUserLinks where userId = ?", userID)
 	Config.DbConn.Exec("DELETE FROM Prayers where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM UserNotificationPreferences where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM CommunityAnnouncementReads where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM CommunitySubGroupUserLinks where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM PrayerVigilSignups where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM CommunityInviteUses where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM CommunityQuestionAnswers where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM PrayerRequestComments where userId = ?", userID)
+	Config.DbConn.Exec("DELETE FROM PrayerRequestShares where userId = ?", userID)
+	RemoveFromSearch(SearchCollectionUsers, userID)
 }
 
 // LoginUser attempts to login a user
@@ -0,0 +1,517 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PrayerVigil is a community event, such as a 24-hour prayer chain, that is split into time slots members sign up to cover
+type PrayerVigil struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	CreatedBy   int64  `json:"createdBy" db:"createdBy"`
+	Title       string `json:"title" db:"title"`
+	Description string `json:"description" db:"description"`
+	StartTime   string `json:"startTime" db:"startTime"`
+	EndTime     string `json:"endTime" db:"endTime"`
+	SlotMinutes int64  `json:"slotMinutes" db:"slotMinutes"`
+	// ReminderMinutes is how long before a slot starts that the people signed up for it are reminded
+	ReminderMinutes int64  `json:"reminderMinutes" db:"reminderMinutes"`
+	Created         string `json:"created" db:"created"`
+
+	SlotCount        int64 `json:"slotCount" db:"-"`
+	CoveredSlotCount int64 `json:"coveredSlotCount" db:"coveredSlotCount"`
+
+	// Slots, Gaps, and Requests are only populated when getting a single vigil
+	Slots    []PrayerVigilSlot `json:"slots,omitempty" db:"-"`
+	Gaps     []PrayerVigilGap  `json:"gaps,omitempty" db:"-"`
+	Requests []PrayerRequest   `json:"requests,omitempty" db:"-"`
+}
+
+// PrayerVigilSlot is a single block of time in a vigil
+type PrayerVigilSlot struct {
+	Index   int64               `json:"index"`
+	Start   string              `json:"start"`
+	End     string              `json:"end"`
+	Signups []PrayerVigilSignup `json:"signups"`
+}
+
+// PrayerVigilGap is a run of one or more consecutive slots that nobody has signed up for
+type PrayerVigilGap struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	Slots int64  `json:"slots"`
+}
+
+// PrayerVigilSignup is a user committing to pray during a slot
+type PrayerVigilSignup struct {
+	VigilID      int64  `json:"vigilId" db:"vigilId"`
+	UserID       int64  `json:"userId" db:"userId"`
+	SlotStart    string `json:"slotStart" db:"slotStart"`
+	ReminderSent string `json:"-" db:"reminderSent"`
+	Created      string `json:"created" db:"created"`
+	Username     string `json:"username" db:"username"`
+
+	// these are only populated when getting the signups for a user
+	VigilTitle    string `json:"vigilTitle,omitempty" db:"vigilTitle"`
+	SlotMinutes   int64  `json:"slotMinutes,omitempty" db:"slotMinutes"`
+	CommunityID   int64  `json:"communityId,omitempty" db:"communityId"`
+	CommunityName string `json:"communityName,omitempty" db:"communityName"`
+	// ReminderMinutes is the vigil's reminder, used for the alarm in the calendar feed
+	ReminderMinutes int64 `json:"reminderMinutes,omitempty" db:"reminderMinutes"`
+}
+
+// PrayerVigilCoverageReport summarizes how well a vigil is covered. Signups is one point per slot, with uncovered slots filled in as zero
+type PrayerVigilCoverageReport struct {
+	VigilID         int64               `json:"vigilId"`
+	TotalSlots      int64               `json:"totalSlots"`
+	CoveredSlots    int64               `json:"coveredSlots"`
+	CoveragePercent float64             `json:"coveragePercent"`
+	Participants    int64               `json:"participants"`
+	Signups         DatePointsProcessed `json:"signups"`
+	Gaps            []PrayerVigilGap    `json:"gaps"`
+}
+
+const (
+	// PrayerVigilMinSlotMinutes is the shortest slot allowed
+	PrayerVigilMinSlotMinutes = 5
+	// PrayerVigilMaxSlotMinutes is the longest slot allowed
+	PrayerVigilMaxSlotMinutes = 24 * 60
+	// PrayerVigilMaxSlots keeps vigils to a reasonable size
+	PrayerVigilMaxSlots = 1000
+	// PrayerVigilDefaultReminderMinutes is used if the admin does not set a reminder
+	PrayerVigilDefaultReminderMinutes = 30
+)
+
+// CreatePrayerVigil creates a new vigil
+func CreatePrayerVigil(input *PrayerVigil) error {
+	input.processForDB()
+	defer input.processForAPI()
+	res, err := Config.DbConn.NamedExec(`INSERT INTO PrayerVigils (communityId, createdBy, title, description, startTime, endTime, slotMinutes, reminderMinutes, created) 
+		VALUES (:communityId, :createdBy, :title, :description, :startTime, :endTime, :slotMinutes, :reminderMinutes, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// UpdatePrayerVigil updates the title, description, and reminder of a vigil. The timing cannot be changed since members have signed up for slots
+func UpdatePrayerVigil(input *PrayerVigil) error {
+	input.processForDB()
+	defer input.processForAPI()
+	_, err := Config.DbConn.NamedExec(`UPDATE PrayerVigils SET title = :title, description = :description, reminderMinutes = :reminderMinutes 
+		WHERE id = :id AND communityId = :communityId`, input)
+	return err
+}
+
+// GetPrayerVigil gets a single vigil in a community
+func GetPrayerVigil(communityID, vigilID int64) (*PrayerVigil, error) {
+	vigil := &PrayerVigil{}
+	err := Config.DbConn.Get(vigil, `SELECT pv.*, 
+		(SELECT COUNT(DISTINCT pvs.slotStart) FROM PrayerVigilSignups pvs WHERE pvs.vigilId = pv.id) AS coveredSlotCount
+		FROM PrayerVigils pv WHERE pv.id = ? AND pv.communityId = ?`, vigilID, communityID)
+	vigil.processForAPI()
+	return vigil, err
+}
+
+// GetPrayerVigilsForCommunity gets the vigils in a community, soonest first. Vigils that have ended are only included if includePast is true
+func GetPrayerVigilsForCommunity(communityID int64, includePast bool, count, offset int) ([]PrayerVigil, error) {
+	vigils := []PrayerVigil{}
+	pastClause := " AND pv.endTime > NOW() "
+	if includePast {
+		pastClause = ""
+	}
+	err := Config.DbConn.Select(&vigils, `SELECT pv.*, 
+		(SELECT COUNT(DISTINCT pvs.slotStart) FROM PrayerVigilSignups pvs WHERE pvs.vigilId = pv.id) AS coveredSlotCount
+		FROM PrayerVigils pv WHERE pv.communityId = ? `+pastClause+` ORDER BY pv.startTime LIMIT ?,?`, communityID, offset, count)
+	for i := range vigils {
+		vigils[i].processForAPI()
+	}
+	return vigils, err
+}
+
+// DeletePrayerVigil deletes a vigil along with its signups and request links
+func DeletePrayerVigil(communityID, vigilID int64) error {
+	res, err := Config.DbConn.Exec("DELETE FROM PrayerVigils WHERE id = ? AND communityId = ?", vigilID, communityID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return nil
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM PrayerVigilSignups WHERE vigilId = ?", vigilID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM PrayerVigilRequestLinks WHERE vigilId = ?", vigilID)
+	return err
+}
+
+// DeletePrayerVigilsForCommunity deletes all of the vigils in a community
+func DeletePrayerVigilsForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE pvs FROM PrayerVigilSignups pvs 
+		INNER JOIN PrayerVigils pv ON pv.id = pvs.vigilId WHERE pv.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`DELETE pvrl FROM PrayerVigilRequestLinks pvrl 
+		INNER JOIN PrayerVigils pv ON pv.id = pvrl.vigilId WHERE pv.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM PrayerVigils WHERE communityId = ?", communityID)
+	return err
+}
+
+// SignUpForPrayerVigilSlot signs a user up for the slot starting at slotStart; signing up twice is not an error
+func SignUpForPrayerVigilSlot(vigilID, userID int64, slotStart time.Time) error {
+	_, err := Config.DbConn.Exec(`INSERT INTO PrayerVigilSignups (vigilId, userId, slotStart, reminderSent, created) VALUES (?, ?, ?, 'no', NOW()) 
+		ON DUPLICATE KEY UPDATE userId = userId`, vigilID, userID, slotStart.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// RemovePrayerVigilSignup removes a user from a slot
+func RemovePrayerVigilSignup(vigilID, userID int64, slotStart time.Time) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerVigilSignups WHERE vigilId = ? AND userId = ? AND slotStart = ?",
+		vigilID, userID, slotStart.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetPrayerVigilSignups gets all of the signups for a vigil
+func GetPrayerVigilSignups(vigilID int64) ([]PrayerVigilSignup, error) {
+	signups := []PrayerVigilSignup{}
+	err := Config.DbConn.Select(&signups, `SELECT pvs.*, u.username FROM PrayerVigilSignups pvs, Users u 
+		WHERE pvs.vigilId = ? AND pvs.userId = u.id ORDER BY pvs.slotStart, u.username`, vigilID)
+	for i := range signups {
+		signups[i].processForAPI()
+	}
+	return signups, err
+}
+
+// GetUpcomingPrayerVigilSignupsForUser gets the slots a user has signed up for that have not yet ended, across all communities
+func GetUpcomingPrayerVigilSignupsForUser(userID int64) ([]PrayerVigilSignup, error) {
+	signups := []PrayerVigilSignup{}
+	err := Config.DbConn.Select(&signups, `SELECT pvs.*, u.username, pv.title AS vigilTitle, pv.slotMinutes, pv.reminderMinutes, pv.communityId, c.name AS communityName 
+		FROM PrayerVigilSignups pvs, PrayerVigils pv, Communities c, Users u 
+		WHERE pvs.userId = ? AND pvs.vigilId = pv.id AND pv.communityId = c.id AND pvs.userId = u.id AND c.archived = '1970-01-01 00:00:00' 
+		AND DATE_ADD(pvs.slotStart, INTERVAL pv.slotMinutes MINUTE) > NOW() ORDER BY pvs.slotStart`, userID)
+	for i := range signups {
+		signups[i].processForAPI()
+	}
+	return signups, err
+}
+
+// LinkPrayerRequestToVigil links a request to a vigil so people know what to pray for
+func LinkPrayerRequestToVigil(vigilID, requestID int64) error {
+	_, err := Config.DbConn.Exec("INSERT INTO PrayerVigilRequestLinks (vigilId, prayerRequestId) VALUES (?, ?) ON DUPLICATE KEY UPDATE vigilId = vigilId", vigilID, requestID)
+	return err
+}
+
+// UnlinkPrayerRequestFromVigil removes a request from a vigil
+func UnlinkPrayerRequestFromVigil(vigilID, requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerVigilRequestLinks WHERE vigilId = ? AND prayerRequestId = ?", vigilID, requestID)
+	return err
+}
+
+// DeletePrayerVigilRequestLinksForRequest removes a request from every vigil it is linked to
+func DeletePrayerVigilRequestLinksForRequest(requestID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM PrayerVigilRequestLinks WHERE prayerRequestId = ?", requestID)
+	return err
+}
+
+// GetPrayerRequestsForVigil gets the requests linked to a vigil. Vigils are shown to the whole community, so the authors of
+// anonymous requests are always hidden
+func GetPrayerRequestsForVigil(vigilID int64) ([]PrayerRequest, error) {
+	requests := []PrayerRequest{}
+	err := Config.DbConn.Select(&requests, `SELECT pr.*, u.username, (SELECT COUNT(*) FROM Prayers p WHERE p.prayerRequestId = pr.id) AS prayerCount 
+		FROM PrayerRequests pr, Users u, PrayerVigilRequestLinks pvrl, PrayerVigils pv 
+		WHERE pvrl.vigilId = ? AND pvrl.prayerRequestId = pr.id AND pr.createdBy = u.id AND pv.id = pvrl.vigilId `+prayerRequestVisibleInCommunity("pv.communityId")+`
+		ORDER BY pr.created DESC`, vigilID)
+	for i := range requests {
+		requests[i].processForAPI()
+		requests[i].HideAnonymousAuthor()
+	}
+	return requests, err
+}
+
+// GetSlotTimes gets the start of the vigil, the length of each slot, and the number of slots. The final slot is
+// shortened if the vigil does not divide evenly
+func (input *PrayerVigil) GetSlotTimes() (start time.Time, end time.Time, length time.Duration, count int64, err error) {
+	start, err = ParseTime(input.StartTime)
+	if err != nil {
+		return
+	}
+	end, err = ParseTime(input.EndTime)
+	if err != nil {
+		return
+	}
+	if input.SlotMinutes <= 0 {
+		err = fmt.Errorf("slotMinutes must be greater than zero")
+		return
+	}
+	start = start.UTC()
+	end = end.UTC()
+	length = time.Duration(input.SlotMinutes) * time.Minute
+	duration := end.Sub(start)
+	count = int64(duration / length)
+	if duration%length != 0 {
+		count++
+	}
+	return
+}
+
+// GetSlotStart gets the start time of the slot at index, ensuring it is part of the vigil
+func (input *PrayerVigil) GetSlotStart(index int64) (time.Time, error) {
+	start, _, length, count, err := input.GetSlotTimes()
+	if err != nil {
+		return start, err
+	}
+	if index < 0 || index >= count {
+		return start, fmt.Errorf("slot %d is not in this vigil", index)
+	}
+	return start.Add(time.Duration(index) * length), nil
+}
+
+// BuildSlots fills in the Slots and Gaps for the vigil from its signups
+func (input *PrayerVigil) BuildSlots(signups []PrayerVigilSignup) error {
+	start, end, length, count, err := input.GetSlotTimes()
+	if err != nil {
+		return err
+	}
+	bySlot := map[int64][]PrayerVigilSignup{}
+	for i := range signups {
+		slotStart, err := ParseTime(signups[i].SlotStart)
+		if err != nil {
+			continue
+		}
+		bySlot[slotStart.UTC().Unix()] = append(bySlot[slotStart.UTC().Unix()], signups[i])
+	}
+
+	input.Slots = []PrayerVigilSlot{}
+	input.Gaps = []PrayerVigilGap{}
+	var gap *PrayerVigilGap
+	for i := int64(0); i < count; i++ {
+		slotStart := start.Add(time.Duration(i) * length)
+		slotEnd := slotStart.Add(length)
+		if slotEnd.After(end) {
+			slotEnd = end
+		}
+		slot := PrayerVigilSlot{
+			Index:   i,
+			Start:   slotStart.Format(time.RFC3339),
+			End:     slotEnd.Format(time.RFC3339),
+			Signups: bySlot[slotStart.Unix()],
+		}
+		if slot.Signups == nil {
+			slot.Signups = []PrayerVigilSignup{}
+		}
+		input.Slots = append(input.Slots, slot)
+
+		// consecutive empty slots are merged into a single gap
+		if len(slot.Signups) == 0 {
+			if gap == nil {
+				gap = &PrayerVigilGap{
+					Start: slot.Start,
+				}
+			}
+			gap.End = slot.End
+			gap.Slots++
+		} else if gap != nil {
+			input.Gaps = append(input.Gaps, *gap)
+			gap = nil
+		}
+	}
+	if gap != nil {
+		input.Gaps = append(input.Gaps, *gap)
+	}
+	input.SlotCount = count
+	input.CoveredSlotCount = count - countGapSlots(input.Gaps)
+	return nil
+}
+
+func countGapSlots(gaps []PrayerVigilGap) int64 {
+	total := int64(0)
+	for i := range gaps {
+		total += gaps[i].Slots
+	}
+	return total
+}
+
+// GetPrayerVigilCoverageReport builds the coverage report for a vigil
+func GetPrayerVigilCoverageReport(vigil *PrayerVigil) (*PrayerVigilCoverageReport, error) {
+	points := []DatePoint{}
+	err := Config.DbConn.Select(&points, `SELECT pvs.slotStart AS day, COUNT(*) AS count FROM PrayerVigilSignups pvs 
+		WHERE pvs.vigilId = ? GROUP BY pvs.slotStart ORDER BY pvs.slotStart`, vigil.ID)
+	if err != nil {
+		return nil, err
+	}
+	participants := int64(0)
+	err = Config.DbConn.Get(&participants, "SELECT COUNT(DISTINCT userId) FROM PrayerVigilSignups WHERE vigilId = ?", vigil.ID)
+	if err != nil {
+		return nil, err
+	}
+	signups, err := GetPrayerVigilSignups(vigil.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = vigil.BuildSlots(signups)
+	if err != nil {
+		return nil, err
+	}
+	start, end, length, _, _ := vigil.GetSlotTimes()
+	processed, err := CreateTimeRangesForReports(points, start, end, length)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PrayerVigilCoverageReport{
+		VigilID:      vigil.ID,
+		TotalSlots:   vigil.SlotCount,
+		CoveredSlots: vigil.CoveredSlotCount,
+		Participants: participants,
+		Signups:      processed,
+		Gaps:         vigil.Gaps,
+	}
+	if report.TotalSlots > 0 {
+		report.CoveragePercent = float64(report.CoveredSlots) / float64(report.TotalSlots) * 100
+	}
+	return report, nil
+}
+
+// SendPrayerVigilReminders emails everyone whose slot starts within its vigil's reminder window. Each signup is only reminded once. This
+// is run as a scheduled task
+func SendPrayerVigilReminders() error {
+	signups := []PrayerVigilSignup{}
+	err := Config.DbConn.Select(&signups, `SELECT pvs.*, u.username, pv.title AS vigilTitle, pv.slotMinutes, pv.reminderMinutes, pv.communityId, c.name AS communityName 
+		FROM PrayerVigilSignups pvs, PrayerVigils pv, Communities c, Users u 
+		WHERE pvs.reminderSent = 'no' AND pvs.vigilId = pv.id AND pv.communityId = c.id AND pvs.userId = u.id AND c.archived = '1970-01-01 00:00:00' 
+		AND pvs.slotStart > NOW() AND pvs.slotStart <= DATE_ADD(NOW(), INTERVAL pv.reminderMinutes MINUTE)`)
+	if err != nil {
+		return err
+	}
+	for i := range signups {
+		signup := signups[i]
+		// mark it first so a failed email is not sent over and over
+		_, err = Config.DbConn.Exec("UPDATE PrayerVigilSignups SET reminderSent = 'yes' WHERE vigilId = ? AND userId = ? AND slotStart = ?",
+			signup.VigilID, signup.UserID, signup.SlotStart)
+		if err != nil {
+			return err
+		}
+		slotStart, _ := ParseTimeToISO(signup.SlotStart)
+		content := fmt.Sprintf(`<p>This is a reminder that you signed up to pray during the %s vigil in the %s community.</p>
+	<p>Your slot starts at %s and lasts %d minutes.</p>
+	`, signup.VigilTitle, signup.CommunityName, slotStart, signup.SlotMinutes)
+		NotifyUser(signup.UserID, signup.CommunityID, NotificationTypePrayerVigilReminder, "Your Prayer Vigil Slot Is Coming Up", content)
+	}
+	return nil
+}
+
+// GeneratePrayerVigilCalendar builds an iCalendar feed from a user's signups
+func GeneratePrayerVigilCalendar(signups []PrayerVigilSignup) string {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Pregxas//Prayer Vigils//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Prayer Vigils",
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for i := range signups {
+		start, err := ParseTime(signups[i].SlotStart)
+		if err != nil {
+			continue
+		}
+		start = start.UTC()
+		end := start.Add(time.Duration(signups[i].SlotMinutes) * time.Minute)
+		reminder := signups[i].ReminderMinutes
+		if reminder <= 0 {
+			reminder = PrayerVigilDefaultReminderMinutes
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:vigil-%d-%d-%d@%s", signups[i].VigilID, start.Unix(), signups[i].UserID, Config.RootAPIDomain),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+start.Format("20060102T150405Z"),
+			"DTEND:"+end.Format("20060102T150405Z"),
+			"SUMMARY:"+escapeICalText(signups[i].VigilTitle),
+			"DESCRIPTION:"+escapeICalText(fmt.Sprintf("Your prayer vigil slot in %s", signups[i].CommunityName)),
+			"BEGIN:VALARM",
+			"ACTION:DISPLAY",
+			"DESCRIPTION:"+escapeICalText(signups[i].VigilTitle),
+			fmt.Sprintf("TRIGGER:-PT%dM", reminder),
+			"END:VALARM",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	folded := []string{}
+	for i := range lines {
+		folded = append(folded, foldICalLine(lines[i]))
+	}
+	return strings.Join(folded, "\r\n") + "\r\n"
+}
+
+// escapeICalText escapes the characters that have meaning in iCalendar text values
+func escapeICalText(input string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(input)
+}
+
+// foldICalLine splits lines longer than 75 octets as required by RFC 5545, without splitting a multi-byte character
+func foldICalLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	parts := []string{}
+	current := ""
+	limit := 75
+	for _, r := range line {
+		if len(current)+len(string(r)) > limit {
+			parts = append(parts, current)
+			current = ""
+			// continuation lines start with a space, which counts toward the limit
+			limit = 74
+		}
+		current += string(r)
+	}
+	parts = append(parts, current)
+	return strings.Join(parts, "\r\n ")
+}
+
+// processForDB ensures data consistency
+func (input *PrayerVigil) processForDB() {
+	if t, err := ParseTime(input.StartTime); err == nil {
+		input.StartTime = t.UTC().Format("2006-01-02 15:04:05")
+	}
+	if t, err := ParseTime(input.EndTime); err == nil {
+		input.EndTime = t.UTC().Format("2006-01-02 15:04:05")
+	}
+	if input.ReminderMinutes <= 0 {
+		input.ReminderMinutes = PrayerVigilDefaultReminderMinutes
+	}
+}
+
+// processForAPI cleans up the output
+func (input *PrayerVigil) processForAPI() {
+	if input == nil {
+		return
+	}
+	_, _, _, input.SlotCount, _ = input.GetSlotTimes()
+	input.StartTime, _ = ParseTimeToISO(input.StartTime)
+	input.EndTime, _ = ParseTimeToISO(input.EndTime)
+	if input.Created == "1970-01-01 00:00:00" {
+		input.Created = ""
+	} else {
+		input.Created, _ = ParseTimeToISO(input.Created)
+	}
+}
+
+// processForAPI cleans up the output
+func (input *PrayerVigilSignup) processForAPI() {
+	input.SlotStart, _ = ParseTimeToISO(input.SlotStart)
+	input.Created, _ = ParseTimeToISO(input.Created)
+}
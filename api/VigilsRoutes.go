@@ -0,0 +1,459 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *PrayerVigil) Bind(r *http.Request) error {
+	return nil
+}
+
+// CreatePrayerVigilRoute allows a community admin to schedule a vigil
+func CreatePrayerVigilRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := PrayerVigil{}
+	render.Bind(r, &input)
+	input.Title, _ = sanitize(input.Title)
+	input.Description, _ = sanitize(input.Description)
+	if input.Title == "" {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_missing_data", "title is required", input)
+		return
+	}
+	if input.SlotMinutes < PrayerVigilMinSlotMinutes || input.SlotMinutes > PrayerVigilMaxSlotMinutes {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_invalid_slot", fmt.Sprintf("slotMinutes must be between %d and %d", PrayerVigilMinSlotMinutes, PrayerVigilMaxSlotMinutes), input)
+		return
+	}
+	if input.ReminderMinutes < 0 || input.ReminderMinutes > 24*60 {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_invalid_reminder", "reminderMinutes must be between 0 and 1440", input)
+		return
+	}
+	start, end, _, count, err := input.GetSlotTimes()
+	if err != nil || !start.Before(end) {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_invalid_times", "startTime and endTime must be valid and startTime must be before endTime", input)
+		return
+	}
+	if count > PrayerVigilMaxSlots {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_too_many_slots", fmt.Sprintf("a vigil may have at most %d slots", PrayerVigilMaxSlots), map[string]int64{
+			"slots": count,
+		})
+		return
+	}
+	input.CommunityID = communityID
+	input.CreatedBy = jwtUser.ID
+
+	err = CreatePrayerVigil(&input)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_create_error", "could not create that vigil", err)
+		return
+	}
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetPrayerVigilsRoute gets the upcoming vigils in a community. Pass includePast=true to include vigils that have ended
+func GetPrayerVigilsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	vigils, err := GetPrayerVigilsForCommunity(communityID, r.URL.Query().Get("includePast") == "true", count, offset)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigils_error", "could not get the vigils", err)
+		return
+	}
+	SendPage(w, r, http.StatusOK, vigils, offsetPage(count, offset, len(vigils)))
+	return
+}
+
+// GetPrayerVigilRoute gets a vigil with all of its slots, signups, coverage gaps, and linked requests
+func GetPrayerVigilRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	vigil, err := getFullPrayerVigil(communityID, vigilID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_not_found", "that vigil could not be found", nil)
+		return
+	}
+	Send(w, http.StatusOK, vigil)
+	return
+}
+
+// UpdatePrayerVigilRoute allows an admin to update the title, description, or reminder of a vigil
+func UpdatePrayerVigilRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	vigil, err := GetPrayerVigil(communityID, vigilID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_not_found", "that vigil could not be found", nil)
+		return
+	}
+
+	input := PrayerVigil{}
+	render.Bind(r, &input)
+	if input.Title != "" {
+		vigil.Title, _ = sanitize(input.Title)
+	}
+	if input.Description != "" {
+		vigil.Description, _ = sanitize(input.Description)
+	}
+	if input.ReminderMinutes < 0 || input.ReminderMinutes > 24*60 {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_invalid_reminder", "reminderMinutes must be between 0 and 1440", input)
+		return
+	}
+	if input.ReminderMinutes != 0 {
+		vigil.ReminderMinutes = input.ReminderMinutes
+	}
+
+	err = UpdatePrayerVigil(vigil)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_update_error", "could not update that vigil", err)
+		return
+	}
+	Send(w, http.StatusOK, vigil)
+	return
+}
+
+// DeletePrayerVigilRoute allows an admin to delete a vigil
+func DeletePrayerVigilRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	err = DeletePrayerVigil(communityID, vigilID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_delete_error", "could not delete that vigil", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// SignUpForPrayerVigilSlotRoute signs the current user up for a slot in a vigil. Slots are addressed by their index, starting at 0
+func SignUpForPrayerVigilSlotRoute(w http.ResponseWriter, r *http.Request) {
+	handlePrayerVigilSignup(w, r, true)
+}
+
+// RemovePrayerVigilSignupRoute removes the current user from a slot in a vigil
+func RemovePrayerVigilSignupRoute(w http.ResponseWriter, r *http.Request) {
+	handlePrayerVigilSignup(w, r, false)
+}
+
+func handlePrayerVigilSignup(w http.ResponseWriter, r *http.Request, signUp bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	slotIndex, slotIndexErr := strconv.ParseInt(chi.URLParam(r, "slotIndex"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil || slotIndexErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role == "" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	vigil, err := GetPrayerVigil(communityID, vigilID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_not_found", "that vigil could not be found", nil)
+		return
+	}
+
+	slotStart, err := vigil.GetSlotStart(slotIndex)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_slot_not_found", "that slot is not in this vigil", nil)
+		return
+	}
+
+	if signUp {
+		if !slotStart.Add(time.Duration(vigil.SlotMinutes) * time.Minute).After(time.Now()) {
+			SendError(w, http.StatusBadRequest, "prayer_vigil_slot_ended", "that slot has already ended", nil)
+			return
+		}
+		err = SignUpForPrayerVigilSlot(vigilID, jwtUser.ID, slotStart)
+	} else {
+		err = RemovePrayerVigilSignup(vigilID, jwtUser.ID, slotStart)
+	}
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_signup_error", "could not update your signup", err)
+		return
+	}
+
+	full, _ := getFullPrayerVigil(communityID, vigilID)
+	Send(w, http.StatusOK, full)
+	return
+}
+
+// LinkPrayerRequestToVigilRoute allows an admin to link a request in the community to a vigil
+func LinkPrayerRequestToVigilRoute(w http.ResponseWriter, r *http.Request) {
+	handlePrayerVigilRequestLink(w, r, true)
+}
+
+// UnlinkPrayerRequestFromVigilRoute allows an admin to remove a request from a vigil
+func UnlinkPrayerRequestFromVigilRoute(w http.ResponseWriter, r *http.Request) {
+	handlePrayerVigilRequestLink(w, r, false)
+}
+
+func handlePrayerVigilRequestLink(w http.ResponseWriter, r *http.Request, link bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	requestID, requestIDErr := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil || requestIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	if _, err := GetPrayerVigil(communityID, vigilID); err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_not_found", "that vigil could not be found", nil)
+		return
+	}
+
+	if link {
+		// only requests the whole community can see may be linked
+		requestLink, err := GetPrayerRequestCommunityLink(requestID, communityID)
+		if err != nil || requestLink.Status != PrayerRequestCommunityLinkStatusApproved {
+			SendError(w, http.StatusBadRequest, "prayer_request_community_link_not_found", "that request is not in that community", nil)
+			return
+		}
+		err = LinkPrayerRequestToVigil(vigilID, requestID)
+	} else {
+		err = UnlinkPrayerRequestFromVigil(vigilID, requestID)
+	}
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_request_error", "could not update the requests for that vigil", err)
+		return
+	}
+
+	requests, _ := GetPrayerRequestsForVigil(vigilID)
+	Send(w, http.StatusOK, requests)
+	return
+}
+
+// GetPrayerVigilCoverageReportRoute gets the coverage report for a vigil; it is only available to admins
+func GetPrayerVigilCoverageReportRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	vigilID, vigilIDErr := strconv.ParseInt(chi.URLParam(r, "vigilID"), 10, 64)
+	if communityIDErr != nil || vigilIDErr != nil {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	vigil, err := GetPrayerVigil(communityID, vigilID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "prayer_vigil_not_found", "that vigil could not be found", nil)
+		return
+	}
+
+	report, err := GetPrayerVigilCoverageReport(vigil)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_report_error", "could not generate that report", err)
+		return
+	}
+	Send(w, http.StatusOK, report)
+	return
+}
+
+// GetMyPrayerVigilSignupsRoute gets the current user's upcoming vigil slots across all communities
+func GetMyPrayerVigilSignupsRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	signups, err := GetUpcomingPrayerVigilSignupsForUser(jwtUser.ID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_signups_error", "could not get your vigil signups", err)
+		return
+	}
+	Send(w, http.StatusOK, signups)
+	return
+}
+
+// GetMyPrayerVigilCalendarURLRoute gets the private URL of the current user's iCalendar feed. Pass reset=true to invalidate the
+// old URL and get a new one
+func GetMyPrayerVigilCalendarURLRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	token, err := GetTokenForUser(jwtUser.ID, TokenCalendar)
+	if err != nil || token == "" || r.URL.Query().Get("reset") == "true" {
+		token, err = GenerateToken(jwtUser.ID, TokenCalendar)
+		if err != nil {
+			SendError(w, http.StatusBadRequest, "prayer_vigil_calendar_error", "could not create your calendar link", err)
+			return
+		}
+	}
+
+	Send(w, http.StatusOK, map[string]string{
+		"url": fmt.Sprintf("%s/calendar/%s.ics", strings.TrimSuffix(Config.RootAPIURL, "/"), token),
+	})
+	return
+}
+
+// GetPrayerVigilCalendarRoute serves a user's vigil slots as an iCalendar feed. It is authenticated by the token in the URL rather than
+// a JWT so that calendar apps can subscribe to it
+func GetPrayerVigilCalendarRoute(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSuffix(chi.URLParam(r, "token"), ".ics")
+	userID, err := LookupToken(token, TokenCalendar)
+	if err != nil || userID == 0 {
+		SendError(w, http.StatusNotFound, "calendar_not_found", "that calendar could not be found", nil)
+		return
+	}
+
+	signups, err := GetUpcomingPrayerVigilSignupsForUser(userID)
+	if err != nil {
+		SendError(w, http.StatusBadRequest, "prayer_vigil_signups_error", "could not get the vigil signups", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(GeneratePrayerVigilCalendar(signups)))
+	return
+}
+
+// getFullPrayerVigil gets a vigil with its slots, gaps, and requests filled in
+func getFullPrayerVigil(communityID, vigilID int64) (*PrayerVigil, error) {
+	vigil, err := GetPrayerVigil(communityID, vigilID)
+	if err != nil {
+		return vigil, err
+	}
+	signups, err := GetPrayerVigilSignups(vigilID)
+	if err != nil {
+		return vigil, err
+	}
+	err = vigil.BuildSlots(signups)
+	if err != nil {
+		return vigil, err
+	}
+	vigil.Requests, err = GetPrayerRequestsForVigil(vigilID)
+	return vigil, err
+}
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerVigilsRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	outsider := User{}
+	err = CreateTestUser(&outsider)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&outsider)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	url := fmt.Sprintf("/communities/%d/vigils", community.ID)
+	start := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Hour)
+
+	// only admins can create, and the input must be valid
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":       "24 Hours",
+		"startTime":   start.Format(time.RFC3339),
+		"endTime":     start.Add(24 * time.Hour).Format(time.RFC3339),
+		"slotMinutes": 30,
+	})
+	code, _, _ := TestAPICall(http.MethodPost, url, b, CreatePrayerVigilRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":       "Backwards",
+		"startTime":   start.Format(time.RFC3339),
+		"endTime":     start.Add(-1 * time.Hour).Format(time.RFC3339),
+		"slotMinutes": 30,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, url, b, CreatePrayerVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":       "Tiny",
+		"startTime":   start.Format(time.RFC3339),
+		"endTime":     start.Add(time.Hour).Format(time.RFC3339),
+		"slotMinutes": 1,
+	})
+	code, _, _ = TestAPICall(http.MethodPost, url, b, CreatePrayerVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":       "24 Hours",
+		"startTime":   start.Format(time.RFC3339),
+		"endTime":     start.Add(24 * time.Hour).Format(time.RFC3339),
+		"slotMinutes": 30,
+	})
+	code, res, _ := TestAPICall(http.MethodPost, url, b, CreatePrayerVigilRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	vigil := PrayerVigil{}
+	mapstructure.Decode(body, &vigil)
+	require.NotZero(t, vigil.ID)
+	assert.Equal(t, int64(48), vigil.SlotCount)
+	vigilURL := fmt.Sprintf("%s/%d", url, vigil.ID)
+
+	code, res, _ = TestAPICall(http.MethodGet, url, b, GetPrayerVigilsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+	code, _, _ = TestAPICall(http.MethodGet, url, b, GetPrayerVigilsRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// sign up for a slot
+	code, _, _ = TestAPICall(http.MethodPut, vigilURL+"/slots/48", b, SignUpForPrayerVigilSlotRoute, member.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodPut, vigilURL+"/slots/3", b, SignUpForPrayerVigilSlotRoute, outsider.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodPut, vigilURL+"/slots/3", b, SignUpForPrayerVigilSlotRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	full := PrayerVigil{}
+	mapstructure.Decode(body, &full)
+	require.Equal(t, 48, len(full.Slots))
+	assert.Equal(t, 1, len(full.Slots[3].Signups))
+	assert.Equal(t, 2, len(full.Gaps))
+	assert.Equal(t, int64(1), full.CoveredSlotCount)
+
+	// the report is only for admins
+	code, _, _ = TestAPICall(http.MethodGet, vigilURL+"/report", b, GetPrayerVigilCoverageReportRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodGet, vigilURL+"/report", b, GetPrayerVigilCoverageReportRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	covered, _ := convertTestJSONFloatToInt(body["coveredSlots"])
+	assert.Equal(t, int64(1), covered)
+
+	// calendar feed
+	code, res, _ = TestAPICall(http.MethodGet, "/me/vigils/calendar", b, GetMyPrayerVigilCalendarURLRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	calURL := body["url"].(string)
+	require.True(t, strings.Contains(calURL, "/calendar/"))
+	calPath := calURL[strings.Index(calURL, "/calendar/"):]
+	code, res, _ = TestAPICall(http.MethodGet, calPath, b, GetPrayerVigilCalendarRoute, "", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, res.String(), "BEGIN:VEVENT")
+	code, _, _ = TestAPICall(http.MethodGet, "/calendar/nope.ics", b, GetPrayerVigilCalendarRoute, "", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, res, _ = TestAPICall(http.MethodGet, "/me/vigils", b, GetMyPrayerVigilSignupsRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+
+	code, _, _ = TestAPICall(http.MethodDelete, vigilURL+"/slots/3", b, RemovePrayerVigilSignupRoute, member.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	// only requests in the community can be linked
+	request := PrayerRequest{
+		Title:     "Vigil request",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/requests/%d", vigilURL, request.ID), b, LinkPrayerRequestToVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	AddPrayerRequestToCommunity(request.ID, community.ID)
+	code, _, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/requests/%d", vigilURL, request.ID), b, LinkPrayerRequestToVigilRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, res, _ = TestAPICall(http.MethodPut, fmt.Sprintf("%s/requests/%d", vigilURL, request.ID), b, LinkPrayerRequestToVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 1, len(bodyA))
+
+	// update and delete
+	b.Reset()
+	enc.Encode(map[string]interface{}{
+		"title":           "Updated",
+		"reminderMinutes": 60,
+	})
+	code, res, _ = TestAPICall(http.MethodPatch, vigilURL, b, UpdatePrayerVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	mapstructure.Decode(body, &vigil)
+	assert.Equal(t, "Updated", vigil.Title)
+	assert.Equal(t, int64(60), vigil.ReminderMinutes)
+
+	code, _, _ = TestAPICall(http.MethodDelete, vigilURL, b, DeletePrayerVigilRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _, _ = TestAPICall(http.MethodDelete, vigilURL, b, DeletePrayerVigilRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodGet, vigilURL, b, GetPrayerVigilRoute, member.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
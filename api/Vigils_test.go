@@ -0,0 +1,209 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrayerVigilSlots(t *testing.T) {
+	vigil := PrayerVigil{
+		StartTime:   "2026-01-01T22:00:00Z",
+		EndTime:     "2026-01-02T00:10:00Z",
+		SlotMinutes: 30,
+	}
+	start, end, length, count, err := vigil.GetSlotTimes()
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Minute, length)
+	// the last slot is shortened
+	assert.Equal(t, int64(5), count)
+	assert.True(t, start.Before(end))
+
+	slotStart, err := vigil.GetSlotStart(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "2026-01-01T23:00:00Z", slotStart.Format(time.RFC3339))
+	_, err = vigil.GetSlotStart(5)
+	assert.NotNil(t, err)
+	_, err = vigil.GetSlotStart(-1)
+	assert.NotNil(t, err)
+
+	signups := []PrayerVigilSignup{
+		{UserID: 1, SlotStart: "2026-01-01T22:00:00Z"},
+		{UserID: 2, SlotStart: "2026-01-01 22:00:00"},
+		{UserID: 1, SlotStart: "2026-01-01T23:30:00Z"},
+	}
+	err = vigil.BuildSlots(signups)
+	assert.Nil(t, err)
+	require.Equal(t, 5, len(vigil.Slots))
+	assert.Equal(t, 2, len(vigil.Slots[0].Signups))
+	assert.Equal(t, "2026-01-02T00:10:00Z", vigil.Slots[4].End)
+	assert.Equal(t, int64(2), vigil.CoveredSlotCount)
+	require.Equal(t, 2, len(vigil.Gaps))
+	assert.Equal(t, "2026-01-01T22:30:00Z", vigil.Gaps[0].Start)
+	assert.Equal(t, "2026-01-01T23:30:00Z", vigil.Gaps[0].End)
+	assert.Equal(t, int64(2), vigil.Gaps[0].Slots)
+	assert.Equal(t, int64(1), vigil.Gaps[1].Slots)
+
+	bad := PrayerVigil{
+		StartTime: "not a time",
+	}
+	_, _, _, _, err = bad.GetSlotTimes()
+	assert.NotNil(t, err)
+}
+
+func TestPrayerVigilCalendar(t *testing.T) {
+	ConfigSetup()
+	cal := GeneratePrayerVigilCalendar([]PrayerVigilSignup{
+		{
+			VigilID:         1,
+			UserID:          2,
+			SlotStart:       "2026-01-01T22:00:00Z",
+			SlotMinutes:     30,
+			ReminderMinutes: 45,
+			VigilTitle:      "Night Watch; Part 1, the long one",
+			CommunityName:   strings.Repeat("A very long community name ", 5),
+		},
+		{
+			VigilID:     1,
+			UserID:      2,
+			SlotStart:   "2026-01-01T22:30:00Z",
+			SlotMinutes: 30,
+			VigilTitle:  "Night Watch",
+		},
+	})
+	assert.True(t, strings.HasPrefix(cal, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(cal, "END:VCALENDAR\r\n"))
+	assert.Contains(t, cal, "DTSTART:20260101T220000Z")
+	assert.Contains(t, cal, "DTEND:20260101T223000Z")
+	assert.Contains(t, cal, `SUMMARY:Night Watch\; Part 1\, the long one`)
+	// the alarm uses the vigil's reminder, or the default when it isn't set
+	assert.Contains(t, cal, "TRIGGER:-PT45M")
+	assert.Contains(t, cal, fmt.Sprintf("TRIGGER:-PT%dM", PrayerVigilDefaultReminderMinutes))
+	for _, line := range strings.Split(cal, "\r\n") {
+		assert.True(t, len(line) <= 75, line)
+	}
+}
+
+func TestPrayerVigilsCRUD(t *testing.T) {
+	ConfigSetup()
+	admin := User{}
+	err := CreateTestUser(&admin)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+
+	member := User{}
+	err = CreateTestUser(&member)
+	assert.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+
+	community := Community{
+		Name:      fmt.Sprintf("Test_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	// the vigil starts soon so that the first slot is inside the reminder window
+	start := time.Now().UTC().Add(10 * time.Minute).Truncate(time.Minute)
+	vigil := PrayerVigil{
+		CommunityID: community.ID,
+		CreatedBy:   admin.ID,
+		Title:       "Vigil",
+		StartTime:   start.Format(time.RFC3339),
+		EndTime:     start.Add(2 * time.Hour).Format(time.RFC3339),
+		SlotMinutes: 60,
+	}
+	err = CreatePrayerVigil(&vigil)
+	require.Nil(t, err)
+	assert.NotZero(t, vigil.ID)
+	assert.Equal(t, int64(PrayerVigilDefaultReminderMinutes), vigil.ReminderMinutes)
+	assert.Equal(t, int64(2), vigil.SlotCount)
+
+	vigils, err := GetPrayerVigilsForCommunity(community.ID, false, 100, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(vigils))
+
+	first, _ := vigil.GetSlotStart(0)
+	second, _ := vigil.GetSlotStart(1)
+	err = SignUpForPrayerVigilSlot(vigil.ID, member.ID, first)
+	assert.Nil(t, err)
+	err = SignUpForPrayerVigilSlot(vigil.ID, member.ID, second)
+	assert.Nil(t, err)
+	// signing up twice is fine
+	err = SignUpForPrayerVigilSlot(vigil.ID, member.ID, second)
+	assert.Nil(t, err)
+
+	found, err := GetPrayerVigil(community.ID, vigil.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), found.CoveredSlotCount)
+
+	upcoming, err := GetUpcomingPrayerVigilSignupsForUser(member.ID)
+	assert.Nil(t, err)
+	require.Equal(t, 2, len(upcoming))
+	assert.Equal(t, community.Name, upcoming[0].CommunityName)
+	assert.Equal(t, "Vigil", upcoming[0].VigilTitle)
+
+	// only the first slot is within the reminder window, and it is only sent once
+	err = SendPrayerVigilReminders()
+	assert.Nil(t, err)
+	sent := int64(0)
+	Config.DbConn.Get(&sent, "SELECT COUNT(*) FROM PrayerVigilSignups WHERE vigilId = ? AND reminderSent = 'yes'", vigil.ID)
+	assert.Equal(t, int64(1), sent)
+	err = SendPrayerVigilReminders()
+	assert.Nil(t, err)
+
+	err = RemovePrayerVigilSignup(vigil.ID, member.ID, second)
+	assert.Nil(t, err)
+	report, err := GetPrayerVigilCoverageReport(found)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), report.TotalSlots)
+	assert.Equal(t, int64(1), report.CoveredSlots)
+	assert.Equal(t, float64(50), report.CoveragePercent)
+	assert.Equal(t, int64(1), report.Participants)
+	assert.Equal(t, 2, len(report.Signups.Data))
+	assert.Equal(t, int64(1), report.Signups.Total)
+	assert.Equal(t, 1, len(report.Gaps))
+
+	// linked requests
+	request := PrayerRequest{
+		Title:     "Vigil request",
+		Body:      "Please pray",
+		CreatedBy: member.ID,
+	}
+	err = CreatePrayerRequest(&request)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(request.ID)
+	err = LinkPrayerRequestToVigil(vigil.ID, request.ID)
+	assert.Nil(t, err)
+	requests, err := GetPrayerRequestsForVigil(vigil.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(requests))
+	err = UnlinkPrayerRequestFromVigil(vigil.ID, request.ID)
+	assert.Nil(t, err)
+	requests, _ = GetPrayerRequestsForVigil(vigil.ID)
+	assert.Equal(t, 0, len(requests))
+
+	// deleting a request drops its vigil links too
+	err = LinkPrayerRequestToVigil(vigil.ID, request.ID)
+	assert.Nil(t, err)
+	err = DeletePrayerRequest(request.ID)
+	assert.Nil(t, err)
+	links := 0
+	err = Config.DbConn.Get(&links, "SELECT COUNT(*) FROM PrayerVigilRequestLinks WHERE prayerRequestId = ?", request.ID)
+	assert.Nil(t, err)
+	assert.Zero(t, links)
+
+	err = DeletePrayerVigil(community.ID, vigil.ID)
+	assert.Nil(t, err)
+	_, err = GetPrayerVigil(community.ID, vigil.ID)
+	assert.NotNil(t, err)
+	upcoming, _ = GetUpcomingPrayerVigilSignupsForUser(member.ID)
+	assert.Equal(t, 0, len(upcoming))
+}
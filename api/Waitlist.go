@@ -0,0 +1,133 @@
+package api
+
+import "fmt"
+
+// JoinCommunityWaitlist adds the user to the end of a full community's waitlist and returns their position. An invitation
+// or declined link is replaced; any other existing link is left alone and their current position, if any, is returned
+func JoinCommunityWaitlist(communityID, userID int64, shortCode string) (int64, error) {
+	// the position and short code are updated before the status since MySQL applies the assignments in order
+	_, err := Config.DbConn.Exec(`INSERT INTO CommunityUserLinks (communityId, userId, role, status, shortCode, waitlistPosition)
+		SELECT ?, ?, ?, ?, ?, COALESCE(MAX(cul.waitlistPosition), 0) + 1 FROM CommunityUserLinks cul
+		WHERE cul.communityId = ? AND cul.status = ?
+		ON DUPLICATE KEY UPDATE waitlistPosition = IF(status IN ('invited', 'declined'), VALUES(waitlistPosition), waitlistPosition),
+		shortCode = IF(status IN ('invited', 'declined'), VALUES(shortCode), shortCode),
+		status = IF(status IN ('invited', 'declined'), VALUES(status), status)`,
+		communityID, userID, CommunityUserRoleMember, CommunityUserLinkStatusWaitlisted, shortCode, communityID, CommunityUserLinkStatusWaitlisted)
+	if err != nil {
+		return 0, err
+	}
+	position := int64(0)
+	err = Config.DbConn.Get(&position, "SELECT waitlistPosition FROM CommunityUserLinks WHERE communityId = ? AND userId = ?", communityID, userID)
+	return position, err
+}
+
+// GetCountOfCommunityWaitlist gets how many people are on a community's waitlist
+func GetCountOfCommunityWaitlist(communityID int64) (int64, error) {
+	count := int64(0)
+	err := Config.DbConn.Get(&count, "SELECT COUNT(*) FROM CommunityUserLinks WHERE communityId = ? AND status = ?", communityID, CommunityUserLinkStatusWaitlisted)
+	return count, err
+}
+
+// GetCommunityWaitlist gets the waitlisted links for a community in the order they will be promoted
+func GetCommunityWaitlist(communityID int64) ([]CommunityUserLink, error) {
+	links := []CommunityUserLink{}
+	err := Config.DbConn.Select(&links, `SELECT cul.*, u.firstName, u.lastName, u.email, u.username FROM CommunityUserLinks cul, Users u
+		WHERE cul.communityId = ? AND cul.status = ? AND cul.userId = u.id ORDER BY cul.waitlistPosition`, communityID, CommunityUserLinkStatusWaitlisted)
+	for i := range links {
+		links[i].processForAPI()
+	}
+	return links, err
+}
+
+// GetOpenSeatsInCommunity gets how many more people the community's plan allows. In communities that require approval,
+// pending requests hold a seat so the waitlist isn't promoted past what the admins could approve
+func GetOpenSeatsInCommunity(community *Community) (int64, error) {
+	plan := plans[community.Plan]
+	taken := int64(0)
+	err := Config.DbConn.Get(&taken, `SELECT COUNT(*) FROM CommunityUserLinks WHERE communityId = ? AND (status = ? OR (status = ? AND ?))`,
+		community.ID, CommunityUserLinkStatusAccepted, CommunityUserLinkStatusRequested, community.UserSignupStatus != CommunityUserSignupStatusAccept)
+	if err != nil {
+		return 0, err
+	}
+	if taken >= plan.AllowedUsers {
+		return 0, nil
+	}
+	return plan.AllowedUsers - taken, nil
+}
+
+// PromoteCommunityWaitlist moves people off the front of the waitlist while there are open seats. In communities that
+// auto accept they become members; otherwise they become requests for the admins to review. Each promoted user is emailed
+func PromoteCommunityWaitlist(communityID int64) ([]CommunityUserLink, error) {
+	promoted := []CommunityUserLink{}
+	community, err := GetCommunityByID(communityID)
+	if err != nil || community.IsArchived() {
+		return promoted, err
+	}
+	seats, err := GetOpenSeatsInCommunity(community)
+	if err != nil || seats == 0 {
+		return promoted, err
+	}
+	waitlist, err := GetCommunityWaitlist(communityID)
+	if err != nil {
+		return promoted, err
+	}
+
+	status := CommunityUserLinkStatusRequested
+	if community.UserSignupStatus == CommunityUserSignupStatusAccept {
+		status = CommunityUserLinkStatusAccepted
+	}
+	for i := range waitlist {
+		if int64(i) >= seats {
+			break
+		}
+		err = UpdateCommunityUserLink(communityID, waitlist[i].UserID, status)
+		if err != nil {
+			return promoted, err
+		}
+		err = removeFromCommunityWaitlist(communityID, waitlist[i].UserID, waitlist[i].WaitlistPosition)
+		if err != nil {
+			return promoted, err
+		}
+		waitlist[i].Status = status
+		waitlist[i].WaitlistPosition = 0
+		promoted = append(promoted, waitlist[i])
+		if status == CommunityUserLinkStatusAccepted {
+			QueueMemberJoinedWebhookEvent(communityID, waitlist[i].UserID)
+		}
+
+		content := fmt.Sprintf("<p>A spot has opened up in %s and you have been added as a member.</p>", community.Name)
+		if status == CommunityUserLinkStatusRequested {
+			content = fmt.Sprintf("<p>A spot has opened up in %s and your request to join has been sent to the community's admins.</p>", community.Name)
+		}
+		NotifyUser(waitlist[i].UserID, communityID, NotificationTypeCommunityWaitlist, fmt.Sprintf("You're Off the Waitlist for %s", community.Name), content)
+	}
+	return promoted, nil
+}
+
+// PromoteCommunityWaitlists promotes the waitlist of every community that has one. Removing a member promotes immediately, so
+// this mainly catches seats opened by a plan upgrade
+func PromoteCommunityWaitlists() error {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, "SELECT DISTINCT communityId FROM CommunityUserLinks WHERE status = ?", CommunityUserLinkStatusWaitlisted)
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		_, err = PromoteCommunityWaitlist(ids[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeFromCommunityWaitlist clears a user's position and moves everyone behind them up one spot
+func removeFromCommunityWaitlist(communityID, userID, position int64) error {
+	_, err := Config.DbConn.Exec("UPDATE CommunityUserLinks SET waitlistPosition = 0 WHERE communityId = ? AND userId = ?", communityID, userID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec(`UPDATE CommunityUserLinks SET waitlistPosition = waitlistPosition - 1
+		WHERE communityId = ? AND status = ? AND waitlistPosition > ?`, communityID, CommunityUserLinkStatusWaitlisted, position)
+	return err
+}
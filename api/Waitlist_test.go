@@ -0,0 +1,88 @@
+package api
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommunityWaitlist(t *testing.T) {
+	ConfigSetup()
+
+	// shrink the free plan so the community fills up quickly
+	original := plans[CommunityPlanFree]
+	plans[CommunityPlanFree] = CommunityPlan{AllowedUsers: 1, AllowedActiveRequests: original.AllowedActiveRequests, AllowedSubGroups: original.AllowedSubGroups}
+	defer func() { plans[CommunityPlanFree] = original }()
+
+	users := make([]User, 4)
+	for i := range users {
+		err := CreateTestUser(&users[i])
+		require.Nil(t, err)
+		defer DeleteUser(users[i].ID)
+	}
+
+	community := Community{
+		Name:             fmt.Sprintf("Waitlist %d", rand.Int63n(999999999)),
+		ShortCode:        fmt.Sprintf("wait_%d", rand.Int63n(999999999)),
+		Plan:             CommunityPlanFree,
+		UserSignupStatus: CommunityUserSignupStatusAccept,
+	}
+	err := CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	err = CreateCommunityUserLink(community.ID, users[0].ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	require.Nil(t, err)
+
+	seats, err := GetOpenSeatsInCommunity(&community)
+	require.Nil(t, err)
+	assert.Equal(t, int64(0), seats)
+
+	for i := 1; i < len(users); i++ {
+		position, err := JoinCommunityWaitlist(community.ID, users[i].ID, "")
+		require.Nil(t, err)
+		assert.Equal(t, int64(i), position)
+	}
+	// joining again keeps the spot
+	position, err := JoinCommunityWaitlist(community.ID, users[2].ID, "")
+	require.Nil(t, err)
+	assert.Equal(t, int64(2), position)
+
+	// nothing happens while the community is full
+	promoted, err := PromoteCommunityWaitlist(community.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(promoted))
+
+	// leaving the waitlist moves everyone behind up
+	err = DeleteCommunityUserLink(community.ID, users[1].ID)
+	require.Nil(t, err)
+	waitlist, err := GetCommunityWaitlist(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(waitlist))
+	assert.Equal(t, users[2].ID, waitlist[0].UserID)
+	assert.Equal(t, int64(1), waitlist[0].WaitlistPosition)
+	assert.Equal(t, int64(2), waitlist[1].WaitlistPosition)
+
+	// a larger plan makes room for one more
+	plans[CommunityPlanFree] = CommunityPlan{AllowedUsers: 2}
+	promoted, err = PromoteCommunityWaitlist(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(promoted))
+	assert.Equal(t, users[2].ID, promoted[0].UserID)
+	link, err := GetCommunityUserLink(community.ID, users[2].ID)
+	require.Nil(t, err)
+	assert.Equal(t, CommunityUserLinkStatusAccepted, link.Status)
+	assert.Equal(t, int64(0), link.WaitlistPosition)
+
+	waitlist, err = GetCommunityWaitlist(community.ID)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(waitlist))
+	assert.Equal(t, users[3].ID, waitlist[0].UserID)
+	assert.Equal(t, int64(1), waitlist[0].WaitlistPosition)
+
+	// waitlisted users have no role in the community
+	_, err = GetUserRoleForCommunity(community.ID, users[3].ID)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,573 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Webhook is an endpoint that is sent a signed POST when subscribed events happen. Webhooks belong to a community or,
+// when CommunityID is 0, to the whole site
+type Webhook struct {
+	ID          int64  `json:"id" db:"id"`
+	CommunityID int64  `json:"communityId" db:"communityId"`
+	URL         string `json:"url" db:"url"`
+	// Secret signs each payload; it is only shown when the webhook is created
+	Secret    string   `json:"secret,omitempty" db:"secret"`
+	Events    []string `json:"events" db:"-"`
+	EventsRaw string   `json:"-" db:"events"`
+	Status    string   `json:"status" db:"status"`
+	CreatedBy int64    `json:"createdBy" db:"createdBy"`
+	Created   string   `json:"created" db:"created"`
+}
+
+// WebhookDelivery is a single event sent, or waiting to be sent, to a webhook
+type WebhookDelivery struct {
+	ID           int64  `json:"id" db:"id"`
+	WebhookID    int64  `json:"webhookId" db:"webhookId"`
+	Event        string `json:"event" db:"event"`
+	Payload      string `json:"payload" db:"payload"`
+	Status       string `json:"status" db:"status"`
+	Attempts     int64  `json:"attempts" db:"attempts"`
+	ResponseCode int64  `json:"responseCode" db:"responseCode"`
+	// ResponseBody is no longer stored; endpoints could point anywhere, so what they send back is never shown to admins
+	ResponseBody string `json:"-" db:"responseBody"`
+	LastError    string `json:"lastError" db:"lastError"`
+	NextAttempt  string `json:"nextAttempt,omitempty" db:"nextAttempt"`
+	Created      string `json:"created" db:"created"`
+	Delivered    string `json:"delivered,omitempty" db:"delivered"`
+}
+
+// webhookPayload is the body POSTed to a webhook
+type webhookPayload struct {
+	Event       string      `json:"event"`
+	CommunityID int64       `json:"communityId,omitempty"`
+	Created     string      `json:"created"`
+	Data        interface{} `json:"data"`
+}
+
+const (
+	// WebhookEventRequestCreated is sent when a request is created or, for a community, when it is shown in the community
+	WebhookEventRequestCreated = "request_created"
+	// WebhookEventRequestAnswered is sent when a request is marked as answered
+	WebhookEventRequestAnswered = "request_answered"
+	// WebhookEventPrayerMade is sent when someone prays for a request
+	WebhookEventPrayerMade = "prayer_made"
+	// WebhookEventMemberJoined is sent when a user becomes a member of a community
+	WebhookEventMemberJoined = "member_joined"
+	// WebhookEventReportFiled is sent when a request is reported
+	WebhookEventReportFiled = "report_filed"
+
+	// WebhookStatusActive is a webhook that is sent events
+	WebhookStatusActive = "active"
+	// WebhookStatusDisabled is a webhook that is kept but not sent events
+	WebhookStatusDisabled = "disabled"
+
+	// WebhookDeliveryStatusPending is waiting to be sent or retried
+	WebhookDeliveryStatusPending = "pending"
+	// WebhookDeliveryStatusDelivered was accepted with a 2xx response
+	WebhookDeliveryStatusDelivered = "delivered"
+	// WebhookDeliveryStatusFailed ran out of attempts
+	WebhookDeliveryStatusFailed = "failed"
+
+	// WebhookSignatureHeader holds the hex HMAC-SHA256 of the body, signed with the webhook's secret
+	WebhookSignatureHeader = "X-Pregxas-Signature"
+	// WebhookEventHeader holds the event name
+	WebhookEventHeader = "X-Pregxas-Event"
+	// WebhookDeliveryHeader holds the delivery id, which stays the same across retries
+	WebhookDeliveryHeader = "X-Pregxas-Delivery"
+
+	// WebhookMaxAttempts is how many times a delivery is tried before it is marked as failed
+	WebhookMaxAttempts = 8
+	// WebhookTimeout is how long to wait on an endpoint
+	WebhookTimeout = 10 * time.Second
+
+	webhookResponseBodyMaxLength = 1024
+	webhookLastErrorMaxLength    = 1024
+)
+
+var webhookEvents = []string{
+	WebhookEventRequestCreated,
+	WebhookEventRequestAnswered,
+	WebhookEventPrayerMade,
+	WebhookEventMemberJoined,
+	WebhookEventReportFiled,
+}
+
+var webhookClient = newOutboundHTTPClient(WebhookTimeout)
+
+// isBlockedOutboundIP checks addresses as outbound connections are made; tests replace it to reach a local server
+var isBlockedOutboundIP = isBlockedIP
+
+// newOutboundHTTPClient creates a client for requests to URLs that users control, such as webhooks and federated servers.
+// The address is checked when connecting, after DNS has been resolved, so a hostname that points at the server's own network
+// is refused too. Redirects are never followed, since they could point anywhere
+func newOutboundHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || isBlockedOutboundIP(ip) {
+				return fmt.Errorf("%s is not an allowed address", host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// GetWebhookEvents gets the events a webhook can subscribe to
+func GetWebhookEvents() []string {
+	return webhookEvents
+}
+
+// IsValidWebhookEvent checks if the input is a known event
+func IsValidWebhookEvent(input string) bool {
+	for i := range webhookEvents {
+		if webhookEvents[i] == input {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidWebhookURL checks that the URL is an absolute http or https URL. Hosts that are obviously internal, such as
+// localhost or a private IP address, are rejected up front; hostnames that resolve to one are refused when connecting
+func IsValidWebhookURL(input string) bool {
+	parsed, err := url.Parse(input)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || !isBlockedIP(ip)
+}
+
+// isBlockedIP checks if the address is in one of the networks outbound requests can't be sent to
+func isBlockedIP(ip net.IP) bool {
+	for i := range webhookBlockedNetworks {
+		if webhookBlockedNetworks[i].Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookBlockedNetworks are the loopback, private, shared (CGNAT), link-local, multicast, reserved, and unspecified ranges
+// webhooks can't be sent to
+var webhookBlockedNetworks = func() []*net.IPNet {
+	cidrs := []string{"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8"}
+	networks := []*net.IPNet{}
+	for i := range cidrs {
+		_, network, _ := net.ParseCIDR(cidrs[i])
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// CreateWebhook creates a new webhook with a random secret
+func CreateWebhook(input *Webhook) error {
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return err
+	}
+	input.Secret = secret
+	if input.Status == "" {
+		input.Status = WebhookStatusActive
+	}
+	input.processForDB()
+	defer input.processForAPI()
+	res, err := Config.DbConn.NamedExec(`INSERT INTO Webhooks (communityId, url, secret, events, status, createdBy, created)
+		VALUES (:communityId, :url, :secret, :events, :status, :createdBy, NOW())`, input)
+	if err != nil {
+		return err
+	}
+	input.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// GetWebhook gets a webhook in a community, or a site-wide webhook when communityID is 0. The secret is removed
+func GetWebhook(communityID, webhookID int64) (*Webhook, error) {
+	webhook := &Webhook{}
+	err := Config.DbConn.Get(webhook, "SELECT * FROM Webhooks WHERE id = ? AND communityId = ?", webhookID, communityID)
+	if err != nil {
+		return nil, err
+	}
+	webhook.processForAPI()
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// GetWebhooks gets the webhooks for a community, or the site-wide webhooks when communityID is 0
+func GetWebhooks(communityID int64) ([]Webhook, error) {
+	webhooks := []Webhook{}
+	err := Config.DbConn.Select(&webhooks, "SELECT * FROM Webhooks WHERE communityId = ? ORDER BY id", communityID)
+	for i := range webhooks {
+		webhooks[i].processForAPI()
+		webhooks[i].Secret = ""
+	}
+	return webhooks, err
+}
+
+// UpdateWebhook updates a webhook's URL, events, and status
+func UpdateWebhook(input *Webhook) error {
+	input.processForDB()
+	defer input.processForAPI()
+	_, err := Config.DbConn.NamedExec("UPDATE Webhooks SET url = :url, events = :events, status = :status WHERE id = :id", input)
+	return err
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func DeleteWebhook(webhookID int64) error {
+	_, err := Config.DbConn.Exec("DELETE FROM Webhooks WHERE id = ?", webhookID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM WebhookDeliveries WHERE webhookId = ?", webhookID)
+	return err
+}
+
+// DeleteWebhooksForCommunity deletes all of a community's webhooks and their delivery logs
+func DeleteWebhooksForCommunity(communityID int64) error {
+	_, err := Config.DbConn.Exec(`DELETE wd FROM WebhookDeliveries wd INNER JOIN Webhooks w ON w.id = wd.webhookId
+		WHERE w.communityId = ?`, communityID)
+	if err != nil {
+		return err
+	}
+	_, err = Config.DbConn.Exec("DELETE FROM Webhooks WHERE communityId = ?", communityID)
+	return err
+}
+
+// GetWebhookDeliveries gets the delivery log for a webhook, newest first
+func GetWebhookDeliveries(webhookID int64, count, offset int) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	err := Config.DbConn.Select(&deliveries, "SELECT * FROM WebhookDeliveries WHERE webhookId = ? ORDER BY id DESC LIMIT ?,?", webhookID, offset, count)
+	for i := range deliveries {
+		deliveries[i].processForAPI()
+	}
+	return deliveries, err
+}
+
+// GetWebhookDelivery gets a single delivery for a webhook
+func GetWebhookDelivery(webhookID, deliveryID int64) (*WebhookDelivery, error) {
+	delivery := &WebhookDelivery{}
+	err := Config.DbConn.Get(delivery, "SELECT * FROM WebhookDeliveries WHERE id = ? AND webhookId = ?", deliveryID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	delivery.processForAPI()
+	return delivery, nil
+}
+
+// RedeliverWebhookDelivery queues a new delivery with the same event and payload as an earlier one and tries to send it
+func RedeliverWebhookDelivery(original *WebhookDelivery) (*WebhookDelivery, error) {
+	id, err := insertWebhookDelivery(original.WebhookID, original.Event, original.Payload)
+	if err != nil {
+		return nil, err
+	}
+	go attemptWebhookDelivery(id)
+	return GetWebhookDelivery(original.WebhookID, id)
+}
+
+// QueueWebhookEvent queues the event for the active site-wide webhooks and the active webhooks of each community that are
+// subscribed to it. Deliveries are sent in the background
+func QueueWebhookEvent(event string, communityIDs []int64, data interface{}) error {
+	err := queueWebhookEventForCommunity(event, 0, data)
+	if err != nil {
+		return err
+	}
+	for i := range communityIDs {
+		err = queueWebhookEventForCommunity(event, communityIDs[i], data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueueCommunityWebhookEvent queues the event for a single community's webhooks, leaving out the site-wide webhooks
+func QueueCommunityWebhookEvent(event string, communityID int64, data interface{}) error {
+	if communityID == 0 {
+		return nil
+	}
+	return queueWebhookEventForCommunity(event, communityID, data)
+}
+
+// QueueMemberJoinedWebhookEvent queues a member_joined event for the community and the site
+func QueueMemberJoinedWebhookEvent(communityID, userID int64) error {
+	link, err := GetCommunityUserLink(communityID, userID)
+	if err != nil {
+		return err
+	}
+	return QueueWebhookEvent(WebhookEventMemberJoined, []int64{communityID}, map[string]interface{}{
+		"communityId": communityID,
+		"userId":      link.UserID,
+		"username":    link.Username,
+		"firstName":   link.FirstName,
+		"lastName":    link.LastName,
+		"role":        link.Role,
+		"joined":      link.Joined,
+	})
+}
+
+// QueuePrayerRequestWebhookEvent queues a request event for every community the request is shown in. The site-wide webhooks
+// only hear about public requests, since anyone subscribed to them would otherwise see requests they couldn't open
+func QueuePrayerRequestWebhookEvent(event string, requestID int64, data interface{}) error {
+	privacy := ""
+	err := Config.DbConn.Get(&privacy, "SELECT privacy FROM PrayerRequests WHERE id = ?", requestID)
+	if err != nil {
+		return err
+	}
+	communities, err := GetCommunitiesPrayerRequestIsIn(requestID)
+	if err != nil {
+		return err
+	}
+	if privacy == PrayerRequestPrivacyPublic {
+		err = queueWebhookEventForCommunity(event, 0, data)
+		if err != nil {
+			return err
+		}
+	}
+	for i := range communities {
+		err = QueueCommunityWebhookEvent(event, communities[i].ID, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeliverPendingWebhooks sends the deliveries that are due, including retries
+func DeliverPendingWebhooks() error {
+	ids := []int64{}
+	err := Config.DbConn.Select(&ids, `SELECT id FROM WebhookDeliveries WHERE status = ? AND nextAttempt <= NOW() ORDER BY id LIMIT 100`,
+		WebhookDeliveryStatusPending)
+	if err != nil {
+		return err
+	}
+	for i := range ids {
+		attemptWebhookDelivery(ids[i])
+	}
+	return nil
+}
+
+// SignWebhookPayload signs the payload with the secret
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookRetryDelay gets how long to wait after the given number of failed attempts; it doubles each time, starting at a minute
+func WebhookRetryDelay(attempts int64) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return time.Minute * time.Duration(int64(1)<<uint(attempts-1))
+}
+
+func queueWebhookEventForCommunity(event string, communityID int64, data interface{}) error {
+	webhooks := []Webhook{}
+	err := Config.DbConn.Select(&webhooks, "SELECT * FROM Webhooks WHERE communityId = ? AND status = ?", communityID, WebhookStatusActive)
+	if err != nil {
+		return err
+	}
+	data = hideAnonymousAuthorInWebhookData(data)
+	for i := range webhooks {
+		webhooks[i].processForAPI()
+		if !webhooks[i].isSubscribedTo(event) {
+			continue
+		}
+		payload, err := json.Marshal(webhookPayload{
+			Event:       event,
+			CommunityID: communityID,
+			Created:     time.Now().UTC().Format(time.RFC3339),
+			Data:        data,
+		})
+		if err != nil {
+			return err
+		}
+		id, err := insertWebhookDelivery(webhooks[i].ID, event, string(payload))
+		if err != nil {
+			return err
+		}
+		go attemptWebhookDelivery(id)
+	}
+	return nil
+}
+
+// hideAnonymousAuthorInWebhookData removes the author from an anonymous request before it leaves the platform. The request is
+// copied so the caller's value is left alone
+func hideAnonymousAuthorInWebhookData(data interface{}) interface{} {
+	switch request := data.(type) {
+	case *PrayerRequest:
+		hidden := *request
+		hidden.HideAnonymousAuthor()
+		return &hidden
+	case PrayerRequest:
+		request.HideAnonymousAuthor()
+		return request
+	}
+	return data
+}
+
+func insertWebhookDelivery(webhookID int64, event, payload string) (int64, error) {
+	res, err := Config.DbConn.Exec(`INSERT INTO WebhookDeliveries (webhookId, event, payload, status, attempts, responseBody, nextAttempt, created)
+		VALUES (?, ?, ?, ?, 0, '', NOW(), NOW())`, webhookID, event, payload, WebhookDeliveryStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// attemptWebhookDelivery sends a delivery once. The delivery is claimed first by pushing its next attempt into the future so
+// the scheduled task and the background send after queueing never send it at the same time
+func attemptWebhookDelivery(deliveryID int64) error {
+	res, err := Config.DbConn.Exec(`UPDATE WebhookDeliveries SET nextAttempt = DATE_ADD(NOW(), INTERVAL 5 MINUTE)
+		WHERE id = ? AND status = ? AND nextAttempt <= NOW()`, deliveryID, WebhookDeliveryStatusPending)
+	if err != nil {
+		return err
+	}
+	if claimed, _ := res.RowsAffected(); claimed == 0 {
+		return nil
+	}
+
+	delivery := WebhookDelivery{}
+	err = Config.DbConn.Get(&delivery, "SELECT * FROM WebhookDeliveries WHERE id = ?", deliveryID)
+	if err != nil {
+		return err
+	}
+	webhook := Webhook{}
+	err = Config.DbConn.Get(&webhook, "SELECT * FROM Webhooks WHERE id = ?", delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	code, sendErr := sendWebhookRequest(&webhook, &delivery)
+	delivery.Attempts++
+	delivery.ResponseCode = int64(code)
+	delivery.ResponseBody = ""
+	delivery.LastError = ""
+	if sendErr != nil {
+		delivery.LastError = sendErr.Error()
+	}
+
+	if sendErr == nil && code >= 200 && code < 300 {
+		_, err = Config.DbConn.Exec(`UPDATE WebhookDeliveries SET status = ?, attempts = ?, responseCode = ?, responseBody = ?, lastError = '',
+			delivered = NOW() WHERE id = ?`, WebhookDeliveryStatusDelivered, delivery.Attempts, delivery.ResponseCode, delivery.ResponseBody, deliveryID)
+		return err
+	}
+	if sendErr == nil {
+		delivery.LastError = fmt.Sprintf("endpoint responded with %d", code)
+	}
+	if len(delivery.LastError) > webhookLastErrorMaxLength {
+		delivery.LastError = delivery.LastError[:webhookLastErrorMaxLength]
+	}
+	status := WebhookDeliveryStatusPending
+	if delivery.Attempts >= WebhookMaxAttempts {
+		status = WebhookDeliveryStatusFailed
+	}
+	delay := int64(WebhookRetryDelay(delivery.Attempts) / time.Minute)
+	_, err = Config.DbConn.Exec(`UPDATE WebhookDeliveries SET status = ?, attempts = ?, responseCode = ?, responseBody = ?, lastError = ?,
+		nextAttempt = DATE_ADD(NOW(), INTERVAL ? MINUTE) WHERE id = ?`, status, delivery.Attempts, delivery.ResponseCode, delivery.ResponseBody, delivery.LastError, delay, deliveryID)
+	return err
+}
+
+// sendWebhookRequest POSTs the delivery and gets the status code. The response body is read, up to a limit, so the connection
+// can be reused, but it is thrown away
+func sendWebhookRequest(webhook *Webhook, delivery *WebhookDelivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Pregxas-Webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, fmt.Sprintf("%d", delivery.ID))
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(webhook.Secret, []byte(delivery.Payload)))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, webhookResponseBodyMaxLength))
+	return resp.StatusCode, nil
+}
+
+// generateWebhookSecret creates a random secret for signing payloads
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (input *Webhook) isSubscribedTo(event string) bool {
+	for i := range input.Events {
+		if input.Events[i] == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (input *Webhook) processForDB() {
+	input.EventsRaw = strings.Join(input.Events, ",")
+	if input.Status != WebhookStatusDisabled {
+		input.Status = WebhookStatusActive
+	}
+}
+
+func (input *Webhook) processForAPI() {
+	input.Events = []string{}
+	if input.EventsRaw != "" {
+		input.Events = strings.Split(input.EventsRaw, ",")
+	}
+	input.Created, _ = ParseTimeToISO(input.Created)
+}
+
+func (input *WebhookDelivery) processForAPI() {
+	input.Created, _ = ParseTimeToISO(input.Created)
+	if input.Delivered == "1970-01-01 00:00:00" {
+		input.Delivered = ""
+	} else {
+		input.Delivered, _ = ParseTimeToISO(input.Delivered)
+	}
+	if input.Status != WebhookDeliveryStatusPending {
+		input.NextAttempt = ""
+	} else {
+		input.NextAttempt, _ = ParseTimeToISO(input.NextAttempt)
+	}
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Bind binds data
+func (data *Webhook) Bind(r *http.Request) error {
+	return nil
+}
+
+// GetWebhookEventsRoute gets the events a webhook can subscribe to
+func GetWebhookEventsRoute(w http.ResponseWriter, r *http.Request) {
+	Send(w, http.StatusOK, GetWebhookEvents())
+	return
+}
+
+// CreateWebhookRoute registers a new webhook. The secret used to sign payloads is only returned here
+func CreateWebhookRoute(w http.ResponseWriter, r *http.Request) {
+	jwtUser, communityID, ok := checkWebhookPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	input := Webhook{}
+	render.Bind(r, &input)
+	if !IsValidWebhookURL(input.URL) {
+		SendError(w, http.StatusBadRequest, "webhook_invalid_url", "url must be a public http or https address", input)
+		return
+	}
+	if !validateWebhookEvents(input.Events) {
+		SendError(w, http.StatusBadRequest, "webhook_invalid_events", "events must contain at least one known event", GetWebhookEvents())
+		return
+	}
+	input.CommunityID = communityID
+	input.CreatedBy = jwtUser.ID
+	input.Status = WebhookStatusActive
+
+	err := CreateWebhook(&input)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_create_error", "could not create that webhook", err)
+		return
+	}
+	Send(w, http.StatusCreated, input)
+	return
+}
+
+// GetWebhooksRoute gets the webhooks for a community or the site
+func GetWebhooksRoute(w http.ResponseWriter, r *http.Request) {
+	_, communityID, ok := checkWebhookPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return
+	}
+
+	webhooks, err := GetWebhooks(communityID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_error", "could not get the webhooks", err)
+		return
+	}
+	Send(w, http.StatusOK, webhooks)
+	return
+}
+
+// GetWebhookRoute gets a single webhook
+func GetWebhookRoute(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := getWebhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+	Send(w, http.StatusOK, webhook)
+	return
+}
+
+// UpdateWebhookRoute updates the url, events, or status of a webhook
+func UpdateWebhookRoute(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := getWebhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	input := Webhook{}
+	render.Bind(r, &input)
+	if input.URL != "" {
+		if !IsValidWebhookURL(input.URL) {
+			SendError(w, http.StatusBadRequest, "webhook_invalid_url", "url must be a public http or https address", input)
+			return
+		}
+		webhook.URL = input.URL
+	}
+	if input.Events != nil {
+		if !validateWebhookEvents(input.Events) {
+			SendError(w, http.StatusBadRequest, "webhook_invalid_events", "events must contain at least one known event", GetWebhookEvents())
+			return
+		}
+		webhook.Events = input.Events
+	}
+	if input.Status != "" {
+		if input.Status != WebhookStatusActive && input.Status != WebhookStatusDisabled {
+			SendError(w, http.StatusBadRequest, "webhook_invalid_status", "status must be active or disabled", input)
+			return
+		}
+		webhook.Status = input.Status
+	}
+
+	err := UpdateWebhook(webhook)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_update_error", "could not update that webhook", err)
+		return
+	}
+	Send(w, http.StatusOK, webhook)
+	return
+}
+
+// DeleteWebhookRoute deletes a webhook and its delivery log
+func DeleteWebhookRoute(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := getWebhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := DeleteWebhook(webhook.ID)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_delete_error", "could not delete that webhook", err)
+		return
+	}
+	Send(w, http.StatusOK, map[string]bool{
+		"deleted": true,
+	})
+	return
+}
+
+// GetWebhookDeliveriesRoute gets the delivery log for a webhook, newest first
+func GetWebhookDeliveriesRoute(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := getWebhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !CheckPageCursor(w, r) {
+		return
+	}
+	_, _, count, offset, _, _, _, _ := ProcessQuery(r)
+	deliveries, err := GetWebhookDeliveries(webhook.ID, count, offset)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_error", "could not get the deliveries", err)
+		return
+	}
+	SendPage(w, r, http.StatusOK, deliveries, offsetPage(count, offset, len(deliveries)))
+	return
+}
+
+// RedeliverWebhookRoute sends an earlier delivery's payload again as a new delivery
+func RedeliverWebhookRoute(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := getWebhookFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, deliveryIDErr := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if deliveryIDErr != nil {
+		SendError(w, http.StatusNotFound, "webhook_delivery_not_found", "that delivery could not be found", nil)
+		return
+	}
+	original, err := GetWebhookDelivery(webhook.ID, deliveryID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "webhook_delivery_not_found", "that delivery could not be found", err)
+		return
+	}
+
+	delivery, err := RedeliverWebhookDelivery(original)
+	if err != nil {
+		SendError(w, http.StatusInternalServerError, "webhook_redeliver_error", "could not redeliver", err)
+		return
+	}
+	Send(w, http.StatusCreated, delivery)
+	return
+}
+
+// checkWebhookPermission checks that the user can manage the webhooks in scope. Routes with a communityID manage that
+// community's webhooks and need a community admin; the others manage the site-wide webhooks and need a platform admin
+func checkWebhookPermission(r *http.Request) (JWTUser, int64, bool) {
+	jwtUser, err := CheckForUser(r)
+	if err != nil || jwtUser.ID == 0 {
+		return jwtUser, 0, false
+	}
+	if chi.URLParam(r, "communityID") == "" {
+		return jwtUser, 0, jwtUser.PlatformRole == "admin"
+	}
+	communityID, communityIDErr := strconv.ParseInt(chi.URLParam(r, "communityID"), 10, 64)
+	if communityIDErr != nil || communityID == 0 {
+		return jwtUser, 0, false
+	}
+	role, err := GetUserRoleForCommunity(communityID, jwtUser.ID)
+	if err != nil || role != "admin" {
+		return jwtUser, 0, false
+	}
+	return jwtUser, communityID, true
+}
+
+// getWebhookFromRequest checks permissions and loads the webhook in the url, sending the error if either fails
+func getWebhookFromRequest(w http.ResponseWriter, r *http.Request) (*Webhook, bool) {
+	_, communityID, ok := checkWebhookPermission(r)
+	if !ok {
+		SendError(w, http.StatusForbidden, "permission_denied", "you don't have permission", nil)
+		return nil, false
+	}
+	webhookID, webhookIDErr := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+	if webhookIDErr != nil {
+		SendError(w, http.StatusNotFound, "webhook_not_found", "that webhook could not be found", nil)
+		return nil, false
+	}
+	webhook, err := GetWebhook(communityID, webhookID)
+	if err != nil {
+		SendError(w, http.StatusNotFound, "webhook_not_found", "that webhook could not be found", err)
+		return nil, false
+	}
+	return webhook, true
+}
+
+func validateWebhookEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for i := range events {
+		if !IsValidWebhookEvent(events[i]) {
+			return false
+		}
+	}
+	return true
+}
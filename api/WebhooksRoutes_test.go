@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRoutes(t *testing.T) {
+	ConfigSetup()
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+
+	admin := User{}
+	err := CreateTestUser(&admin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&admin)
+	member := User{}
+	err = CreateTestUser(&member)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&member)
+	platformAdmin := User{
+		PlatformRole: "admin",
+	}
+	err = CreateTestUser(&platformAdmin)
+	require.Nil(t, err)
+	defer DeleteUserFromTest(&platformAdmin)
+
+	community := Community{
+		Name:      fmt.Sprintf("Webhooks_%d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("abc_%d", rand.Int63n(99999)),
+		OwnerID:   admin.ID,
+	}
+	err = CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+	CreateCommunityUserLink(community.ID, admin.ID, CommunityUserRoleAdmin, CommunityUserLinkStatusAccepted, "")
+	CreateCommunityUserLink(community.ID, member.ID, CommunityUserRoleMember, CommunityUserLinkStatusAccepted, "")
+
+	code, res, _ := TestAPICall(http.MethodGet, "/webhooks/events", b, GetWebhookEventsRoute, "", "")
+	assert.Equal(t, http.StatusOK, code)
+	_, bodyA, _ := UnmarshalTestArray(res)
+	assert.Equal(t, len(GetWebhookEvents()), len(bodyA))
+
+	base := fmt.Sprintf("/communities/%d/webhooks", community.ID)
+	// the report_filed event is used since nothing in this test files a report, so nothing is actually sent
+	good := map[string]interface{}{"url": "https://example.com/hooks/pregxas", "events": []string{WebhookEventReportFiled}}
+	b.Reset()
+	enc.Encode(good)
+	code, _, _ = TestAPICall(http.MethodPost, base, b, CreateWebhookRoute, member.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	bad := []map[string]interface{}{
+		{"url": "http://localhost/hook", "events": []string{WebhookEventReportFiled}},
+		{"url": "https://example.com/hook", "events": []string{}},
+		{"url": "https://example.com/hook", "events": []string{"request_deleted"}},
+	}
+	for i := range bad {
+		b.Reset()
+		enc.Encode(bad[i])
+		code, _, _ = TestAPICall(http.MethodPost, base, b, CreateWebhookRoute, admin.JWT, "")
+		assert.Equal(t, http.StatusBadRequest, code, fmt.Sprintf("%v", bad[i]))
+	}
+
+	b.Reset()
+	enc.Encode(good)
+	code, res, _ = TestAPICall(http.MethodPost, base, b, CreateWebhookRoute, admin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ := UnmarshalTestMap(res)
+	assert.NotNil(t, body["secret"])
+	webhookID, _ := convertTestJSONFloatToInt(body["id"])
+
+	// the secret is never shown again
+	code, res, _ = TestAPICall(http.MethodGet, base, b, GetWebhooksRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	require.Equal(t, 1, len(bodyA))
+	assert.Nil(t, bodyA[0].(map[string]interface{})["secret"])
+
+	b.Reset()
+	enc.Encode(map[string]interface{}{"status": "paused"})
+	code, _, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("%s/%d", base, webhookID), b, UpdateWebhookRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	b.Reset()
+	enc.Encode(map[string]interface{}{"status": WebhookStatusDisabled, "events": []string{WebhookEventReportFiled, WebhookEventRequestAnswered}})
+	code, res, _ = TestAPICall(http.MethodPatch, fmt.Sprintf("%s/%d", base, webhookID), b, UpdateWebhookRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, body, _ = UnmarshalTestMap(res)
+	assert.Equal(t, WebhookStatusDisabled, body["status"])
+	assert.Equal(t, 2, len(body["events"].([]interface{})))
+
+	code, res, _ = TestAPICall(http.MethodGet, fmt.Sprintf("%s/%d/deliveries", base, webhookID), b, GetWebhookDeliveriesRoute, admin.JWT, "")
+	require.Equal(t, http.StatusOK, code)
+	_, bodyA, _ = UnmarshalTestArray(res)
+	assert.Equal(t, 0, len(bodyA))
+	code, _, _ = TestAPICall(http.MethodPost, fmt.Sprintf("%s/%d/deliveries/999999999/redeliver", base, webhookID), b, RedeliverWebhookRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// the community's webhook isn't reachable through the site-wide routes
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("/admin/webhooks/%d", webhookID), b, GetWebhookRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _, _ = TestAPICall(http.MethodGet, "/admin/webhooks", b, GetWebhooksRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	b.Reset()
+	enc.Encode(good)
+	code, res, _ = TestAPICall(http.MethodPost, "/admin/webhooks", b, CreateWebhookRoute, platformAdmin.JWT, "")
+	require.Equal(t, http.StatusCreated, code)
+	_, body, _ = UnmarshalTestMap(res)
+	siteWebhookID, _ := convertTestJSONFloatToInt(body["id"])
+	assert.Equal(t, float64(0), body["communityId"])
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("/admin/webhooks/%d", siteWebhookID), b, DeleteWebhookRoute, platformAdmin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _, _ = TestAPICall(http.MethodDelete, fmt.Sprintf("%s/%d", base, webhookID), b, DeleteWebhookRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = TestAPICall(http.MethodGet, fmt.Sprintf("%s/%d", base, webhookID), b, GetWebhookRoute, admin.JWT, "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookValidation(t *testing.T) {
+	assert.True(t, IsValidWebhookURL("https://hooks.slack.com/services/abc"))
+	assert.True(t, IsValidWebhookURL("http://example.com:8080/hook"))
+	assert.False(t, IsValidWebhookURL("ftp://example.com/hook"))
+	assert.False(t, IsValidWebhookURL("/relative/hook"))
+	assert.False(t, IsValidWebhookURL("http://localhost:3000/hook"))
+	assert.False(t, IsValidWebhookURL("http://127.0.0.1/hook"))
+	assert.False(t, IsValidWebhookURL("http://10.1.2.3/hook"))
+	assert.False(t, IsValidWebhookURL("http://192.168.1.1/hook"))
+	assert.False(t, IsValidWebhookURL("http://169.254.169.254/latest/meta-data"))
+	assert.False(t, IsValidWebhookURL("http://[::1]/hook"))
+	assert.False(t, IsValidWebhookURL("http://100.64.0.1/hook"))
+	assert.False(t, IsValidWebhookURL("http://[::ffff:127.0.0.1]/hook"))
+
+	assert.True(t, IsValidWebhookEvent(WebhookEventPrayerMade))
+	assert.False(t, IsValidWebhookEvent("request_deleted"))
+
+	// known HMAC-SHA256 vector
+	assert.Equal(t, "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		SignWebhookPayload("key", []byte("The quick brown fox jumps over the lazy dog")))
+
+	assert.Equal(t, time.Minute, WebhookRetryDelay(1))
+	assert.Equal(t, 2*time.Minute, WebhookRetryDelay(2))
+	assert.Equal(t, 64*time.Minute, WebhookRetryDelay(7))
+}
+
+func TestOutboundHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/inside", http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	// addresses are checked when connecting, so a hostname that resolves to a blocked address is refused too
+	client := newOutboundHTTPClient(time.Second)
+	_, err := client.Get(server.URL)
+	assert.NotNil(t, err)
+	_, err = client.Get(fmt.Sprintf("http://localhost:%s/hook", port))
+	assert.NotNil(t, err)
+
+	// redirects are returned instead of followed
+	isBlockedOutboundIP = func(net.IP) bool { return false }
+	defer func() { isBlockedOutboundIP = isBlockedIP }()
+	resp, err := client.Get(server.URL + "/redirect")
+	require.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ConfigSetup()
+	isBlockedOutboundIP = func(net.IP) bool { return false }
+	defer func() { isBlockedOutboundIP = isBlockedIP }()
+
+	// the endpoint fails the first time and succeeds after
+	var mu sync.Mutex
+	received := []*http.Request{}
+	bodies := [][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	community := Community{
+		Name:      fmt.Sprintf("Webhooks %d", rand.Int63n(999999999)),
+		ShortCode: fmt.Sprintf("hook_%d", rand.Int63n(999999999)),
+	}
+	err := CreateCommunity(&community)
+	require.Nil(t, err)
+	defer DeleteCommunity(community.ID)
+
+	webhook := Webhook{
+		CommunityID: community.ID,
+		URL:         server.URL,
+		Events:      []string{WebhookEventMemberJoined},
+	}
+	err = CreateWebhook(&webhook)
+	require.Nil(t, err)
+	require.NotEqual(t, "", webhook.Secret)
+	secret := webhook.Secret
+
+	// events the webhook isn't subscribed to are skipped
+	err = QueueCommunityWebhookEvent(WebhookEventPrayerMade, community.ID, map[string]int{"prayerRequestId": 1})
+	require.Nil(t, err)
+	err = QueueCommunityWebhookEvent(WebhookEventMemberJoined, community.ID, map[string]int{"userId": 1})
+	require.Nil(t, err)
+
+	deliveries := waitForWebhookDeliveries(t, webhook.ID, func(d []WebhookDelivery) bool {
+		return len(d) == 1 && d[0].Attempts == 1
+	})
+	delivery := deliveries[0]
+	assert.Equal(t, WebhookEventMemberJoined, delivery.Event)
+	assert.Equal(t, WebhookDeliveryStatusPending, delivery.Status)
+	assert.Equal(t, int64(500), delivery.ResponseCode)
+	assert.NotEqual(t, "", delivery.NextAttempt)
+
+	mu.Lock()
+	require.Equal(t, 1, len(received))
+	assert.Equal(t, "sha256="+SignWebhookPayload(secret, bodies[0]), received[0].Header.Get(WebhookSignatureHeader))
+	assert.Equal(t, WebhookEventMemberJoined, received[0].Header.Get(WebhookEventHeader))
+	payload := map[string]interface{}{}
+	json.Unmarshal(bodies[0], &payload)
+	assert.Equal(t, WebhookEventMemberJoined, payload["event"])
+	assert.Equal(t, float64(community.ID), payload["communityId"])
+	mu.Unlock()
+
+	// the retry isn't due yet, so nothing is sent
+	err = DeliverPendingWebhooks()
+	require.Nil(t, err)
+	mu.Lock()
+	assert.Equal(t, 1, len(received))
+	mu.Unlock()
+
+	// a manual redelivery is a new delivery with the same payload
+	redelivered, err := RedeliverWebhookDelivery(&delivery)
+	require.Nil(t, err)
+	assert.NotEqual(t, delivery.ID, redelivered.ID)
+	deliveries = waitForWebhookDeliveries(t, webhook.ID, func(d []WebhookDelivery) bool {
+		return len(d) == 2 && d[0].Status == WebhookDeliveryStatusDelivered
+	})
+	assert.Equal(t, redelivered.ID, deliveries[0].ID)
+	assert.Equal(t, delivery.Payload, deliveries[0].Payload)
+	assert.NotEqual(t, "", deliveries[0].Delivered)
+
+	// disabled webhooks aren't sent anything
+	webhook.Status = WebhookStatusDisabled
+	err = UpdateWebhook(&webhook)
+	require.Nil(t, err)
+	err = QueueCommunityWebhookEvent(WebhookEventMemberJoined, community.ID, map[string]int{"userId": 2})
+	require.Nil(t, err)
+	deliveries, err = GetWebhookDeliveries(webhook.ID, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 2, len(deliveries))
+
+	err = DeleteWebhook(webhook.ID)
+	require.Nil(t, err)
+	_, err = GetWebhook(community.ID, webhook.ID)
+	assert.NotNil(t, err)
+}
+
+// waitForWebhookDeliveries polls the delivery log until check passes, since deliveries are sent in the background
+func waitForWebhookDeliveries(t *testing.T, webhookID int64, check func([]WebhookDelivery) bool) []WebhookDelivery {
+	for i := 0; i < 50; i++ {
+		deliveries, err := GetWebhookDeliveries(webhookID, 10, 0)
+		require.Nil(t, err)
+		if check(deliveries) {
+			return deliveries
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.FailNow(t, "webhook deliveries did not reach the expected state")
+	return nil
+}
+
+func TestSiteWebhookRequestPrivacy(t *testing.T) {
+	ConfigSetup()
+	author := User{}
+	err := CreateTestUser(&author)
+	require.Nil(t, err)
+	defer DeleteUser(author.ID)
+
+	webhook := Webhook{
+		URL:    "https://hooks.example.com/site",
+		Events: []string{WebhookEventRequestCreated},
+	}
+	err = CreateWebhook(&webhook)
+	require.Nil(t, err)
+	defer DeleteWebhook(webhook.ID)
+
+	// the site's webhooks never see the title or body of a request that isn't public
+	private := PrayerRequest{
+		Title:     "Private",
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   PrayerRequestPrivacyPrivate,
+	}
+	err = CreatePrayerRequest(&private)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(private.ID)
+	err = QueuePrayerRequestWebhookEvent(WebhookEventRequestCreated, private.ID, private)
+	require.Nil(t, err)
+	deliveries, err := GetWebhookDeliveries(webhook.ID, 10, 0)
+	require.Nil(t, err)
+	assert.Zero(t, len(deliveries))
+
+	public := PrayerRequest{
+		Title:     "Public",
+		Body:      "Please pray",
+		CreatedBy: author.ID,
+		Privacy:   PrayerRequestPrivacyPublic,
+	}
+	err = CreatePrayerRequest(&public)
+	require.Nil(t, err)
+	defer DeletePrayerRequest(public.ID)
+	err = QueuePrayerRequestWebhookEvent(WebhookEventRequestCreated, public.ID, public)
+	require.Nil(t, err)
+	deliveries, err = GetWebhookDeliveries(webhook.ID, 10, 0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, len(deliveries))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"os"
 	"time"
 
 	api "github.com/treelightsoftware/pregxas-api/api"
@@ -15,6 +16,25 @@ func main() {
 
 	r := api.SetupApp()
 
+	// running with reindex rebuilds the search index and exits
+	if len(os.Args) > 1 && os.Args[1] == "reindex" {
+		// the in-process index only lives inside the server, so there is nothing for this process to rebuild
+		if _, ok := api.Config.Search.(*api.MemorySearch); ok {
+			fmt.Println("Search is using the in-process index, which is rebuilt every time the server starts; set PREGXAS_SEARCH_ADDRESS to reindex Sonic")
+			os.Exit(1)
+		}
+		err := api.ReindexSearch()
+		if err != nil {
+			fmt.Printf("Could not rebuild the search index: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Search index rebuilt")
+		return
+	}
+
+	api.StartScheduledTasks()
+	api.StartSearch()
+
 	api.Log("info", fmt.Sprintf("Listening on %v", api.Config.RootAPIPort), "server_start", map[string]string{
 		"port": api.Config.RootAPIPort,
 	})
@@ -0,0 +1,8 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `ownerId` int(11) NOT NULL DEFAULT 0, -- the user responsible for the community, including billing
+  ADD COLUMN `pendingOwnerId` int(11) NOT NULL DEFAULT 0, -- set while an ownership transfer awaits acceptance
+  ADD KEY `ownerId` (`ownerId`);
+
+-- existing communities are assigned to their first admin
+UPDATE `Communities` c SET c.`ownerId` = COALESCE((SELECT cul.`userId` FROM `CommunityUserLinks` cul 
+  WHERE cul.`communityId` = c.`id` AND cul.`role` = 'admin' AND cul.`status` = 'accepted' ORDER BY cul.`userId` LIMIT 1), 0);
@@ -0,0 +1,10 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `requestModeration` ENUM('auto_approve', 'review_required') NOT NULL DEFAULT 'auto_approve';
+
+ALTER TABLE `PrayerRequestCommunityLinks` 
+  ADD COLUMN `status` ENUM('approved', 'pending_review', 'rejected') NOT NULL DEFAULT 'approved',
+  ADD COLUMN `added` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  ADD COLUMN `reviewedBy` int(11) NOT NULL DEFAULT 0,
+  ADD COLUMN `reviewed` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  ADD COLUMN `rejectionReason` varchar(1024) NOT NULL DEFAULT '',
+  ADD KEY `community_status` (`communityId`, `status`);
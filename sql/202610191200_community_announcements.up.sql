@@ -0,0 +1,28 @@
+CREATE TABLE `CommunityAnnouncements` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `createdBy` int(11) NOT NULL,
+  `title` varchar(256) NOT NULL DEFAULT '',
+  `body` text NOT NULL,
+  `pinned` ENUM('no', 'yes') NOT NULL DEFAULT 'no',
+  `expires` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- 1970 means it never expires
+  `created` datetime NOT NULL,
+  `updated` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  PRIMARY KEY (`id`),
+  KEY `communityId` (`communityId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `CommunityAnnouncementReads` (
+  `announcementId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `readOn` datetime NOT NULL,
+  PRIMARY KEY (`announcementId`, `userId`),
+  KEY `userId` (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `UserNotificationPreferences` (
+  `userId` int(11) NOT NULL,
+  `notificationType` varchar(64) NOT NULL,
+  `channel` ENUM('email', 'none') NOT NULL DEFAULT 'email',
+  PRIMARY KEY (`userId`, `notificationType`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,25 @@
+CREATE TABLE `CommunitySubGroups` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL, -- the parent community, which is billed and whose plan limits apply
+  `name` varchar(128) NOT NULL DEFAULT '',
+  `description` varchar(1024) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `communityId` (`communityId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `CommunitySubGroupUserLinks` (
+  `subGroupId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `role` ENUM('member', 'leader') NOT NULL DEFAULT 'member',
+  PRIMARY KEY (`subGroupId`, `userId`),
+  KEY `userId` (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `PrayerRequestSubGroupLinks` (
+  `prayerRequestId` int(11) NOT NULL,
+  `subGroupId` int(11) NOT NULL,
+  `added` datetime NOT NULL,
+  PRIMARY KEY (`prayerRequestId`, `subGroupId`),
+  KEY `subGroupId` (`subGroupId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
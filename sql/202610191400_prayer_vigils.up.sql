@@ -0,0 +1,34 @@
+CREATE TABLE `PrayerVigils` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `createdBy` int(11) NOT NULL,
+  `title` varchar(256) NOT NULL DEFAULT '',
+  `description` varchar(2048) NOT NULL DEFAULT '',
+  `startTime` datetime NOT NULL,
+  `endTime` datetime NOT NULL,
+  `slotMinutes` int(11) NOT NULL DEFAULT 30,
+  `reminderMinutes` int(11) NOT NULL DEFAULT 30,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `community_start` (`communityId`, `startTime`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `PrayerVigilSignups` (
+  `vigilId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `slotStart` datetime NOT NULL,
+  `reminderSent` ENUM('no', 'yes') NOT NULL DEFAULT 'no',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`vigilId`, `slotStart`, `userId`),
+  KEY `userId` (`userId`),
+  KEY `reminder` (`reminderSent`, `slotStart`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `PrayerVigilRequestLinks` (
+  `vigilId` int(11) NOT NULL,
+  `prayerRequestId` int(11) NOT NULL,
+  PRIMARY KEY (`vigilId`, `prayerRequestId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `UserTokens` 
+  MODIFY COLUMN `tokenType` enum('email','password_reset','refresh','calendar') NOT NULL DEFAULT 'email';
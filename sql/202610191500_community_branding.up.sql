@@ -0,0 +1,4 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `logo` varchar(256) NOT NULL DEFAULT '', -- the storage key of the logo
+  ADD COLUMN `accentColor` varchar(7) NOT NULL DEFAULT '',
+  ADD COLUMN `emailFooter` varchar(1024) NOT NULL DEFAULT '';
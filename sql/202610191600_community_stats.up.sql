@@ -0,0 +1,10 @@
+ALTER TABLE `CommunityUserLinks` 
+  ADD COLUMN `joined` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- when the link was first accepted
+  ADD KEY `community_joined` (`communityId`, `joined`);
+
+ALTER TABLE `PrayerRequests` 
+  ADD COLUMN `answered` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- when the status was changed to answered
+  ADD KEY `answered` (`answered`);
+
+-- existing answered requests have no record of when they were answered, so the best guess is when they were created
+UPDATE `PrayerRequests` SET `answered` = `created` WHERE `status` = 'answered';
@@ -0,0 +1,4 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `archived` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- archived communities are purged after the retention window
+  ADD COLUMN `archivedBy` int(11) NOT NULL DEFAULT 0,
+  ADD KEY `archived` (`archived`);
@@ -0,0 +1,24 @@
+CREATE TABLE `CommunityInvites` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `code` varchar(32) NOT NULL,
+  `createdBy` int(11) NOT NULL,
+  `role` enum('member','admin') NOT NULL DEFAULT 'member',
+  `approval` enum('auto_accept','approval_required') NOT NULL DEFAULT 'auto_accept',
+  `maxUses` int(11) NOT NULL DEFAULT 0, -- 0 means there is no limit
+  `uses` int(11) NOT NULL DEFAULT 0,
+  `expires` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- the sentinel means it never expires
+  `status` enum('active','revoked') NOT NULL DEFAULT 'active',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `code` (`code`),
+  KEY `communityId` (`communityId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `CommunityInviteUses` (
+  `inviteId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `used` datetime NOT NULL,
+  KEY `inviteId` (`inviteId`),
+  KEY `userId` (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
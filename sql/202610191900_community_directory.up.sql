@@ -0,0 +1,16 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `denomination` varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN `language` varchar(2) NOT NULL DEFAULT '', -- ISO 639-1 code
+  ADD COLUMN `city` varchar(128) NOT NULL DEFAULT '',
+  ADD COLUMN `region` varchar(128) NOT NULL DEFAULT '',
+  ADD COLUMN `latitude` decimal(9,6) DEFAULT NULL,
+  ADD COLUMN `longitude` decimal(9,6) DEFAULT NULL,
+  ADD KEY `privacy_language` (`privacy`, `language`),
+  ADD KEY `region` (`region`);
+
+CREATE TABLE `CommunityCategoryLinks` (
+  `communityId` int(11) NOT NULL,
+  `category` varchar(32) NOT NULL,
+  UNIQUE KEY `link` (`communityId`, `category`),
+  KEY `category` (`category`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
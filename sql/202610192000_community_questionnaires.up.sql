@@ -0,0 +1,26 @@
+ALTER TABLE `Communities` 
+  ADD COLUMN `questionnaireVersion` int(11) NOT NULL DEFAULT 0;
+
+CREATE TABLE `CommunityQuestions` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `version` int(11) NOT NULL,
+  `position` int(11) NOT NULL DEFAULT 0,
+  `questionType` enum('text','choice','yes_no') NOT NULL DEFAULT 'text',
+  `prompt` varchar(512) NOT NULL,
+  `choices` text NOT NULL, -- JSON array, only used for choice questions
+  `required` tinyint(1) NOT NULL DEFAULT 0,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `community_version` (`communityId`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `CommunityQuestionAnswers` (
+  `questionId` int(11) NOT NULL,
+  `communityId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `answer` text NOT NULL,
+  `created` datetime NOT NULL,
+  UNIQUE KEY `answer` (`questionId`, `userId`),
+  KEY `community_user` (`communityId`, `userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,4 @@
+ALTER TABLE `CommunityUserLinks` 
+  MODIFY COLUMN `status` enum('invited','requested','accepted','declined','waitlisted') NOT NULL DEFAULT 'invited',
+  ADD COLUMN `waitlistPosition` int(11) NOT NULL DEFAULT 0, -- place in line while waitlisted, starting at 1
+  ADD KEY `community_status` (`communityId`, `status`);
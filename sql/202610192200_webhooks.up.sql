@@ -0,0 +1,30 @@
+CREATE TABLE `Webhooks` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL DEFAULT 0, -- 0 is a site-wide webhook
+  `url` varchar(2048) NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `events` varchar(512) NOT NULL DEFAULT '', -- comma separated event names
+  `status` enum('active','disabled') NOT NULL DEFAULT 'active',
+  `createdBy` int(11) NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `community_status` (`communityId`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `WebhookDeliveries` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `webhookId` int(11) NOT NULL,
+  `event` varchar(64) NOT NULL,
+  `payload` mediumtext NOT NULL,
+  `status` enum('pending','delivered','failed') NOT NULL DEFAULT 'pending',
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `responseCode` int(11) NOT NULL DEFAULT 0,
+  `responseBody` text NOT NULL,
+  `lastError` varchar(1024) NOT NULL DEFAULT '',
+  `nextAttempt` datetime NOT NULL,
+  `created` datetime NOT NULL,
+  `delivered` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  PRIMARY KEY (`id`),
+  KEY `webhook` (`webhookId`),
+  KEY `status_next` (`status`, `nextAttempt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,19 @@
+CREATE TABLE `CommunityExports` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `requestedBy` int(11) NOT NULL,
+  `exportType` enum('members','requests','prayers') NOT NULL,
+  `format` enum('csv','json') NOT NULL DEFAULT 'csv',
+  `start` datetime NOT NULL,
+  `end` datetime NOT NULL,
+  `status` enum('pending','processing','ready','failed') NOT NULL DEFAULT 'pending',
+  `storageKey` varchar(256) NOT NULL DEFAULT '', -- always under private/ so it is never served from /assets
+  `error` varchar(1024) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  `completed` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  `expires` datetime NOT NULL DEFAULT '1970-01-01 00:00:00', -- when the file is deleted
+  PRIMARY KEY (`id`),
+  KEY `community` (`communityId`),
+  KEY `status` (`status`),
+  KEY `expires` (`expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
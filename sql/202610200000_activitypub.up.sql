@@ -0,0 +1,55 @@
+CREATE TABLE `ActivityPubKeys` (
+  `communityId` int(11) NOT NULL,
+  `publicKey` text NOT NULL,
+  `privateKey` text NOT NULL, -- signs activities sent on behalf of the community
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`communityId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `ActivityPubFollowers` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `actorId` varchar(512) NOT NULL,
+  `inbox` varchar(2048) NOT NULL, -- the shared inbox when the remote server has one
+  `followActivityId` varchar(512) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `community_actor` (`communityId`, `actorId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `ActivityPubRemoteActors` (
+  `actorId` varchar(512) NOT NULL,
+  `inbox` varchar(2048) NOT NULL DEFAULT '',
+  `sharedInbox` varchar(2048) NOT NULL DEFAULT '',
+  `keyId` varchar(512) NOT NULL DEFAULT '',
+  `publicKey` text NOT NULL,
+  `fetched` datetime NOT NULL, -- actors are fetched again once the cache is a day old
+  PRIMARY KEY (`actorId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- each remote prayer is also a row in Prayers with a userId of 0 so it is included in the counts
+CREATE TABLE `ActivityPubPrayers` (
+  `activityId` varchar(512) NOT NULL,
+  `actorId` varchar(512) NOT NULL,
+  `prayerRequestId` int(11) NOT NULL,
+  `whenPrayed` datetime NOT NULL,
+  PRIMARY KEY (`activityId`),
+  KEY `actor_request` (`actorId`, `prayerRequestId`),
+  KEY `prayerRequestId` (`prayerRequestId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `ActivityPubDeliveries` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `communityId` int(11) NOT NULL,
+  `inbox` varchar(2048) NOT NULL,
+  `activity` mediumtext NOT NULL,
+  `status` enum('pending','delivered','failed') NOT NULL DEFAULT 'pending',
+  `attempts` int(11) NOT NULL DEFAULT 0,
+  `lastError` varchar(1024) NOT NULL DEFAULT '',
+  `nextAttempt` datetime NOT NULL,
+  `created` datetime NOT NULL,
+  `delivered` datetime NOT NULL DEFAULT '1970-01-01 00:00:00',
+  PRIMARY KEY (`id`),
+  KEY `community` (`communityId`),
+  KEY `status_next` (`status`, `nextAttempt`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,11 @@
+CREATE TABLE `PrayerRequestUpdates` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `prayerRequestId` int(11) NOT NULL,
+  `createdBy` int(11) NOT NULL,
+  `body` text NOT NULL,
+  `status` varchar(32) NOT NULL DEFAULT '', -- the status the request was changed to, if any
+  `previousStatus` varchar(32) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `request_created` (`prayerRequestId`, `created`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,15 @@
+CREATE TABLE `PrayerRequestComments` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `prayerRequestId` int(11) NOT NULL,
+  `userId` int(11) NOT NULL,
+  `body` varchar(512) NOT NULL,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `request_created` (`prayerRequestId`, `created`),
+  KEY `userId` (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `PrayerRequests` ADD COLUMN `commentStatus` enum('enabled','disabled') NOT NULL DEFAULT 'enabled';
+
+-- reports on a comment still point to the request so the existing queues show them; 0 means the report is on the request itself
+ALTER TABLE `Reports` ADD COLUMN `commentId` int(11) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- anonymous requests hide the author from everyone except the author and moderators
+ALTER TABLE `PrayerRequests` ADD COLUMN `anonymous` tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,8 @@
+-- requests that go too long without an update remind the author and are then archived; 0 turns the policy off
+ALTER TABLE `Site` ADD COLUMN `requestExpiryDays` int(11) NOT NULL DEFAULT 0;
+-- 0 uses the site's policy
+ALTER TABLE `Communities` ADD COLUMN `requestExpiryDays` int(11) NOT NULL DEFAULT 0;
+
+ALTER TABLE `PrayerRequests` ADD COLUMN `reminded` datetime NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE `PrayerRequests` ADD COLUMN `archived` datetime NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE `PrayerRequests` ADD KEY `status_archived` (`status`, `archived`);
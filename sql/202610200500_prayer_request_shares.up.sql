@@ -0,0 +1,14 @@
+-- a share gives a single community or person access to a request; exactly one of communityId and userId is set
+CREATE TABLE `PrayerRequestShares` (
+  `id` int(11) NOT NULL AUTO_INCREMENT,
+  `prayerRequestId` int(11) NOT NULL,
+  `communityId` int(11) NOT NULL DEFAULT 0,
+  `userId` int(11) NOT NULL DEFAULT 0,
+  `created` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `request_share` (`prayerRequestId`, `communityId`, `userId`),
+  KEY `communityId` (`communityId`),
+  KEY `userId` (`userId`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE `PrayerRequests` MODIFY COLUMN `privacy` enum('public','private','selected_communities','people') DEFAULT 'private';
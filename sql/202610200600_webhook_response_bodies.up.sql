@@ -0,0 +1,2 @@
+-- webhook response bodies are no longer stored or shown, so clear the ones already saved
+UPDATE `WebhookDeliveries` SET `responseBody` = '';
@@ -0,0 +1,2 @@
+-- edits made by the author count as activity, so reminders measure idle time from the latest of creation, updates, and edits
+ALTER TABLE `PrayerRequests` ADD COLUMN `lastActive` datetime NOT NULL DEFAULT '1970-01-01 00:00:00';